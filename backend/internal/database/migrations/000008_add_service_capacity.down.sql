@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_bookings_service_dates;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_date_range_check;
+ALTER TABLE services DROP COLUMN IF EXISTS capacity;
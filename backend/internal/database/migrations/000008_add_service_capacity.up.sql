@@ -0,0 +1,18 @@
+-- This migration adds a capacity to services so that a service can hold a fixed number
+-- of concurrent bookings, prevents bookings with an empty or inverted date range,
+-- and indexes bookings for the overlap lookups done by the availability checks.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS capacity INTEGER NOT NULL DEFAULT 1 CHECK (capacity > 0);
+
+-- Bookings recorded before the check are normalised first: inverted ranges are swapped
+-- and empty ones last a day.
+UPDATE bookings
+SET booking_date_start = booking_date_end, booking_date_end = booking_date_start
+WHERE booking_date_end < booking_date_start;
+
+UPDATE bookings
+SET booking_date_end = booking_date_start + INTERVAL '1 day'
+WHERE booking_date_end = booking_date_start;
+
+ALTER TABLE bookings ADD CONSTRAINT bookings_date_range_check CHECK (booking_date_end > booking_date_start);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_service_dates ON bookings (service_id, booking_date_start, booking_date_end);
@@ -0,0 +1 @@
+ALTER TABLE service_types DROP COLUMN IF EXISTS pricing_unit;
@@ -0,0 +1,8 @@
+-- This migration adds a pricing unit to service types so that booking totals can be
+-- computed on the server from the service price and the booking duration.
+ALTER TABLE service_types ADD COLUMN IF NOT EXISTS pricing_unit VARCHAR(20) NOT NULL DEFAULT 'flat'
+    CHECK (pricing_unit IN ('flat', 'per_night', 'per_day', 'per_hour'));
+
+-- Set the pricing unit of the default service types
+UPDATE service_types SET pricing_unit = 'per_night' WHERE name IN ('Hotels & Guesthouses', 'Nomado Love', 'Nomado Lux');
+UPDATE service_types SET pricing_unit = 'per_day' WHERE name IN ('Car Rental & Rides');
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS booking_status_history;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_status_check;
@@ -0,0 +1,21 @@
+-- This migration restricts bookings to the statuses of the booking lifecycle and creates
+-- the booking_status_history table recording who moved a booking between statuses and why.
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('pending', 'confirmed', 'checked_in', 'completed', 'cancelled', 'no_show', 'expired'));
+
+CREATE TABLE IF NOT EXISTS booking_status_history (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_status_history_booking ON booking_status_history (booking_id, created_at);
+
+-- Record the current status of existing bookings as their initial status
+INSERT INTO booking_status_history (booking_id, from_status, to_status, changed_by, reason, created_at)
+SELECT id, NULL, status, NULL, 'initial status', created_at
+FROM bookings;
@@ -0,0 +1,3 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS payment_type;
+ALTER TABLE services DROP COLUMN IF EXISTS cancellation_policy_id;
+DROP TABLE IF EXISTS cancellation_policies;
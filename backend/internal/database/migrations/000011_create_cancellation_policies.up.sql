@@ -0,0 +1,25 @@
+-- This migration creates the cancellation_policies table holding the refund tiers applied
+-- when a booking is cancelled, links services to a policy, and lets the payments table
+-- hold refunds next to payments.
+CREATE TABLE IF NOT EXISTS cancellation_policies (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    tiers JSONB NOT NULL DEFAULT '[]',
+    created_by INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Insert the platform policies
+-- Platform policies have no creator and can be used by every provider.
+INSERT INTO cancellation_policies (name, description, tiers)
+VALUES
+('flexible', 'Full refund until 24 hours before the start date.', '[{"hours_before": 24, "refund_percent": 100}]'),
+('moderate', 'Full refund until 5 days before the start date, 50% refund until 24 hours before.', '[{"hours_before": 120, "refund_percent": 100}, {"hours_before": 24, "refund_percent": 50}]'),
+('strict', 'Full refund until 7 days before the start date, 50% refund until 48 hours before.', '[{"hours_before": 168, "refund_percent": 100}, {"hours_before": 48, "refund_percent": 50}]');
+
+ALTER TABLE services ADD COLUMN IF NOT EXISTS cancellation_policy_id INTEGER REFERENCES cancellation_policies(id) ON DELETE SET NULL;
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS payment_type VARCHAR(20) NOT NULL DEFAULT 'payment'
+    CHECK (payment_type IN ('payment', 'refund'));
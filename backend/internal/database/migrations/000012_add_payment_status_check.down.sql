@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_booking;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
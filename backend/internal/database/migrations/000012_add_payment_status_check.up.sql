@@ -0,0 +1,6 @@
+-- This migration restricts payments to the statuses known by the payment service
+-- and indexes payments by booking for balance lookups.
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('pending', 'completed', 'failed', 'cancelled', 'refunded'));
+
+CREATE INDEX IF NOT EXISTS idx_payments_booking ON payments (booking_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_payments_parent;
+DROP INDEX IF EXISTS idx_payments_provider_reference;
+ALTER TABLE payments DROP COLUMN IF EXISTS parent_payment_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS failure_reason;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider_reference;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider;
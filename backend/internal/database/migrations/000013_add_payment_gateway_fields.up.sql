@@ -0,0 +1,13 @@
+-- This migration records which payment gateway handled each payment, the gateway's own
+-- reference for it and why it failed. Refunds point at the payment they give money back for.
+-- Payments recorded before gateways existed are marked as manual.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS provider VARCHAR(50) NOT NULL DEFAULT 'manual';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS provider_reference VARCHAR(255);
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS failure_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS parent_payment_id INTEGER REFERENCES payments(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_reference
+    ON payments (provider, provider_reference)
+    WHERE provider_reference IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_parent ON payments (parent_payment_id);
@@ -0,0 +1,19 @@
+-- This migration creates a table for the raw events payment gateways send to the webhook
+-- receiver. Events are unique per provider so a retried delivery is processed only once.
+CREATE TABLE IF NOT EXISTS payment_events (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payment_id INTEGER REFERENCES payments(id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'received'
+        CHECK (status IN ('received', 'processed', 'ignored')),
+    error TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP,
+    UNIQUE (provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_events_payment ON payment_events (payment_id);
@@ -0,0 +1,4 @@
+-- Hashed codes cannot be restored: unverified users have to request a new code
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_code VARCHAR(255);
+
+DROP TABLE IF EXISTS email_verification_codes;
@@ -0,0 +1,23 @@
+-- This migration moves email verification codes out of users.verification_code, where they
+-- were kept in plaintext and never expired. Codes are stored as SHA-256 hashes with an
+-- expiry and a count of failed attempts; the rows also record when each code was sent,
+-- which is what resends are throttled on.
+CREATE TABLE IF NOT EXISTS email_verification_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_codes_user ON email_verification_codes (user_id, created_at);
+
+-- Outstanding codes keep working for another 24 hours
+INSERT INTO email_verification_codes (user_id, code_hash, expires_at)
+SELECT id, encode(sha256(convert_to(verification_code, 'UTF8')), 'hex'), CURRENT_TIMESTAMP + INTERVAL '24 hours'
+FROM users
+WHERE email_verified = FALSE AND verification_code IS NOT NULL AND verification_code <> '';
+
+ALTER TABLE users DROP COLUMN IF EXISTS verification_code;
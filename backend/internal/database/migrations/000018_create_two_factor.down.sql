@@ -0,0 +1,5 @@
+ALTER TABLE auth_sessions DROP COLUMN IF EXISTS two_factor_verified;
+
+DROP TABLE IF EXISTS two_factor_challenges;
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
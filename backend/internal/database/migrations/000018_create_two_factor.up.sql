@@ -0,0 +1,36 @@
+-- This migration adds TOTP two-factor authentication.
+-- user_two_factor holds the encrypted TOTP secret of a user; enabled_at stays NULL until the
+-- user proves the authenticator app works, and last_used_step stops a code being replayed.
+-- Recovery codes are stored as SHA-256 hashes. A login of a user with 2FA first returns a
+-- challenge, which is redeemed with a code for the session; auth_sessions.two_factor_verified
+-- records whether a session passed the second factor.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_user ON two_factor_recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    id CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_user ON two_factor_challenges (user_id);
+
+ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS two_factor_verified BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS auth_throttles;
+DROP TABLE IF EXISTS auth_failures;
@@ -0,0 +1,25 @@
+-- This migration creates the brute-force protection of the login and email verification endpoints.
+-- auth_failures is the audit trail of every failed attempt. auth_throttles counts recent failures
+-- per account ("account:<email>") and per client IP ("ip:<address>") to slow down and lock out
+-- attackers. Only a SHA-256 hash of the unlock token emailed to a locked-out user is stored.
+CREATE TABLE IF NOT EXISTS auth_failures (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(30) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    reason VARCHAR(30) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_failures_email ON auth_failures (email, created_at);
+CREATE INDEX IF NOT EXISTS idx_auth_failures_ip ON auth_failures (ip_address, created_at);
+
+CREATE TABLE IF NOT EXISTS auth_throttles (
+    key VARCHAR(300) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    unlock_token_hash CHAR(64) UNIQUE
+);
@@ -0,0 +1,9 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+UPDATE users SET role = 'user' WHERE role NOT IN ('admin', 'provider', 'user');
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(20);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'provider', 'user'));
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
@@ -0,0 +1,64 @@
+-- This migration replaces the three hard-coded user roles with roles stored in the database.
+-- A role grants a set of permissions such as "bookings:read". The built-in roles user, provider
+-- and admin keep the access they had; support, finance and content_editor are seeded as examples
+-- of staff roles and can be changed like any role created later.
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_name VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'View users'),
+    ('users:roles', 'Assign roles to users'),
+    ('roles:manage', 'Create, change and delete roles'),
+    ('destinations:write', 'Create, change and delete destinations'),
+    ('service_types:write', 'Create, change and delete service types'),
+    ('services:write', 'Create, change and delete services'),
+    ('cancellation_policies:write', 'Create cancellation policies'),
+    ('bookings:read', 'View the bookings of any user'),
+    ('bookings:write', 'Change the status of any booking'),
+    ('payments:read', 'View the payments of any user'),
+    ('payments:refund', 'Refund payments')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description, built_in) VALUES
+    ('user', 'Travellers booking services', TRUE),
+    ('provider', 'Providers offering services', TRUE),
+    ('admin', 'Administrators with every permission', TRUE),
+    ('support', 'Support agents who look into users and their bookings', FALSE),
+    ('finance', 'Finance staff who look into payments', FALSE),
+    ('content_editor', 'Editors of destinations and service types', FALSE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('provider', 'services:write'),
+    ('provider', 'cancellation_policies:write'),
+    ('support', 'users:read'),
+    ('support', 'bookings:read'),
+    ('finance', 'payments:read'),
+    ('content_editor', 'destinations:write'),
+    ('content_editor', 'service_types:write')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(50);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
@@ -0,0 +1,10 @@
+DELETE FROM role_permissions WHERE permission = 'providers:review';
+DELETE FROM permissions WHERE name = 'providers:review';
+
+DROP TABLE IF EXISTS provider_applications;
+
+ALTER TABLE users DROP COLUMN IF EXISTS verified;
+ALTER TABLE users DROP COLUMN IF EXISTS address;
+ALTER TABLE users DROP COLUMN IF EXISTS website;
+ALTER TABLE users DROP COLUMN IF EXISTS description;
+ALTER TABLE users DROP COLUMN IF EXISTS company_name;
@@ -0,0 +1,39 @@
+-- This migration creates the provider_applications table. Users no longer choose their role
+-- when they register: they apply to become a provider with their company details and
+-- supporting documents, and staff with the providers:review permission approve or reject
+-- the application. Approval copies the company details to the user, marks them verified
+-- and gives them the provider role.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS company_name VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS website VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS address TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS provider_applications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    company_name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    website VARCHAR(255) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    documents JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    review_note TEXT NOT NULL DEFAULT '',
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A user has at most one application waiting for review
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_applications_pending_user
+    ON provider_applications (user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_provider_applications_status ON provider_applications (status, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+    ('providers:review', 'Approve or reject provider applications')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('admin', 'providers:review')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS provider_application_history;
+
+DROP INDEX IF EXISTS idx_provider_applications_open_user;
+UPDATE provider_applications SET status = 'pending' WHERE status = 'changes_requested';
+UPDATE provider_applications SET status = 'rejected' WHERE status = 'revoked';
+ALTER TABLE provider_applications DROP CONSTRAINT IF EXISTS provider_applications_status_check;
+ALTER TABLE provider_applications ADD CONSTRAINT provider_applications_status_check
+    CHECK (status IN ('pending', 'approved', 'rejected'));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_applications_pending_user
+    ON provider_applications (user_id) WHERE status = 'pending';
+
+ALTER TABLE provider_applications ADD COLUMN IF NOT EXISTS documents JSONB NOT NULL DEFAULT '[]';
+
+DROP TABLE IF EXISTS provider_documents;
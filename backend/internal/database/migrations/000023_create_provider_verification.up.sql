@@ -0,0 +1,48 @@
+-- This migration turns provider applications into a verification workflow. Applicants upload
+-- their documents (registration certificate, identity document) instead of linking them,
+-- reviewers may ask for changes before deciding, an approval may later be revoked, and
+-- every status change is recorded with the reason given.
+CREATE TABLE IF NOT EXISTS provider_documents (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    application_id INTEGER REFERENCES provider_applications(id) ON DELETE SET NULL,
+    document_type VARCHAR(30) NOT NULL CHECK (document_type IN ('registration_certificate', 'identity_document', 'other')),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_documents_user ON provider_documents (user_id);
+CREATE INDEX IF NOT EXISTS idx_provider_documents_application ON provider_documents (application_id);
+
+ALTER TABLE provider_applications DROP COLUMN IF EXISTS documents;
+
+ALTER TABLE provider_applications DROP CONSTRAINT IF EXISTS provider_applications_status_check;
+ALTER TABLE provider_applications ADD CONSTRAINT provider_applications_status_check
+    CHECK (status IN ('pending', 'changes_requested', 'approved', 'rejected', 'revoked'));
+
+-- A user has at most one application in review, including one waiting for their changes
+DROP INDEX IF EXISTS idx_provider_applications_pending_user;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_applications_open_user
+    ON provider_applications (user_id) WHERE status IN ('pending', 'changes_requested');
+
+CREATE TABLE IF NOT EXISTS provider_application_history (
+    id SERIAL PRIMARY KEY,
+    application_id INTEGER NOT NULL REFERENCES provider_applications(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_application_history_application ON provider_application_history (application_id, created_at);
+
+-- Record the submission and the review of existing applications
+INSERT INTO provider_application_history (application_id, from_status, to_status, changed_by, note, created_at)
+SELECT id, NULL, 'pending', user_id, 'application submitted', created_at FROM provider_applications;
+
+INSERT INTO provider_application_history (application_id, from_status, to_status, changed_by, note, created_at)
+SELECT id, 'pending', status, reviewed_by, review_note, reviewed_at FROM provider_applications WHERE reviewed_at IS NOT NULL;
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission = 'services:manage';
+DELETE FROM permissions WHERE name = 'services:manage';
+
+DROP INDEX IF EXISTS idx_services_user_id;
+ALTER TABLE services DROP COLUMN IF EXISTS user_id;
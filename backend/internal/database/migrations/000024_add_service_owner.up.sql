@@ -0,0 +1,14 @@
+-- This migration records the user who owns each service so that providers can only
+-- change their own listings. Services created before it (such as the sample services)
+-- have no owner and are platform services, managed by roles with services:manage.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_services_user_id ON services (user_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('services:manage', 'Change and delete the services of any provider')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('admin', 'services:manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS organisation_invitations;
+DROP TABLE IF EXISTS organisation_members;
+DROP TABLE IF EXISTS provider_organisations;
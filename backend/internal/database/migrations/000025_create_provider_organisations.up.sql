@@ -0,0 +1,39 @@
+-- This migration lets providers run a team. A provider owns an organisation, invites staff
+-- members by email and gives each one an organisation role (manager, front_desk or
+-- accountant) that decides what they can do with the services and bookings of the provider.
+CREATE TABLE IF NOT EXISTS provider_organisations (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A user works for at most one organisation
+CREATE TABLE IF NOT EXISTS organisation_members (
+    id SERIAL PRIMARY KEY,
+    organisation_id INTEGER NOT NULL REFERENCES provider_organisations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('manager', 'front_desk', 'accountant')),
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_organisation_members_organisation ON organisation_members (organisation_id);
+
+-- Only the hash of an invitation token is stored. Inviting the same email again replaces
+-- the previous invitation, and accepting or revoking an invitation deletes it.
+CREATE TABLE IF NOT EXISTS organisation_invitations (
+    id SERIAL PRIMARY KEY,
+    organisation_id INTEGER NOT NULL REFERENCES provider_organisations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('manager', 'front_desk', 'accountant')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organisation_invitations_email
+    ON organisation_invitations (organisation_id, (LOWER(email)));
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS accommodation_amenities;
+DROP TABLE IF EXISTS accommodation_room_types;
+DROP TABLE IF EXISTS accommodation_details;
+DROP TABLE IF EXISTS amenities;
@@ -0,0 +1,65 @@
+-- This migration adds the accommodation details of services priced per night (the
+-- HOUSEBOOKINGDETAILS entity of the ERD): the property address, check-in and check-out
+-- times, the room types with their bed configurations and guests, and the amenities
+-- chosen from a shared catalogue. The nightly rate is the price of the service.
+CREATE TABLE IF NOT EXISTS amenities (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT 'general'
+);
+
+INSERT INTO amenities (code, name, category) VALUES
+    ('wifi', 'Wi-Fi', 'general'),
+    ('air_conditioning', 'Air conditioning', 'general'),
+    ('backup_power', 'Backup power', 'general'),
+    ('hot_water', 'Hot water', 'general'),
+    ('security', '24-hour security', 'general'),
+    ('parking', 'Free parking', 'general'),
+    ('pet_friendly', 'Pets allowed', 'general'),
+    ('wheelchair_accessible', 'Wheelchair accessible', 'general'),
+    ('tv', 'Television', 'room'),
+    ('kitchen', 'Kitchen', 'room'),
+    ('private_bathroom', 'Private bathroom', 'room'),
+    ('balcony', 'Balcony', 'room'),
+    ('breakfast', 'Breakfast included', 'food'),
+    ('restaurant', 'Restaurant', 'food'),
+    ('bar', 'Bar', 'food'),
+    ('pool', 'Swimming pool', 'leisure'),
+    ('gym', 'Gym', 'leisure'),
+    ('spa', 'Spa', 'leisure'),
+    ('beach_access', 'Beach access', 'leisure'),
+    ('airport_shuttle', 'Airport shuttle', 'services'),
+    ('laundry', 'Laundry', 'services'),
+    ('room_service', 'Room service', 'services')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS accommodation_details (
+    service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    property_address TEXT NOT NULL DEFAULT '',
+    check_in_time TIME NOT NULL DEFAULT '14:00',
+    check_out_time TIME NOT NULL DEFAULT '11:00',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Beds hold the bed configuration of a room type, e.g. [{"type": "double", "count": 1}]
+CREATE TABLE IF NOT EXISTS accommodation_room_types (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER NOT NULL REFERENCES accommodation_details(service_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    max_guests INTEGER NOT NULL CHECK (max_guests > 0),
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    beds JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS idx_accommodation_room_types_service ON accommodation_room_types (service_id);
+
+CREATE TABLE IF NOT EXISTS accommodation_amenities (
+    service_id INTEGER NOT NULL REFERENCES accommodation_details(service_id) ON DELETE CASCADE,
+    amenity_id INTEGER NOT NULL REFERENCES amenities(id) ON DELETE CASCADE,
+    PRIMARY KEY (service_id, amenity_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_accommodation_amenities_amenity ON accommodation_amenities (amenity_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_bookings_vehicle;
+ALTER TABLE bookings DROP COLUMN IF EXISTS dropoff_location_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS pickup_location_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS vehicle_class_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS vehicle_id;
+
+DROP TABLE IF EXISTS vehicles;
+DROP TABLE IF EXISTS vehicle_classes;
+DROP TABLE IF EXISTS rental_locations;
+DROP TABLE IF EXISTS car_rental_details;
@@ -0,0 +1,62 @@
+-- This migration adds the car rental details of services priced per day (the CARRENTALDETAILS
+-- entity of the ERD): the pickup and dropoff locations, the vehicle classes with their daily
+-- rate and mileage limit, and the fleet of individual vehicles. Bookings of a car rental
+-- reserve one vehicle, chosen by the guest or assigned from the class they asked for.
+CREATE TABLE IF NOT EXISTS car_rental_details (
+    service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    min_driver_age INTEGER NOT NULL DEFAULT 18 CHECK (min_driver_age >= 16),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS rental_locations (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER NOT NULL REFERENCES car_rental_details(service_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    is_pickup BOOLEAN NOT NULL DEFAULT TRUE,
+    is_dropoff BOOLEAN NOT NULL DEFAULT TRUE,
+    CHECK (is_pickup OR is_dropoff)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rental_locations_service ON rental_locations (service_id);
+
+-- A NULL mileage limit means unlimited mileage
+CREATE TABLE IF NOT EXISTS vehicle_classes (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER NOT NULL REFERENCES car_rental_details(service_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    daily_rate DECIMAL(10,2) NOT NULL CHECK (daily_rate >= 0),
+    mileage_limit_km INTEGER CHECK (mileage_limit_km > 0),
+    extra_km_fee DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (extra_km_fee >= 0),
+    UNIQUE (service_id, name)
+);
+
+-- Classes cannot be removed while vehicles still belong to them, but removing the car rental
+-- details of a service removes its classes and vehicles together
+CREATE TABLE IF NOT EXISTS vehicles (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER NOT NULL REFERENCES car_rental_details(service_id) ON DELETE CASCADE,
+    class_id INTEGER NOT NULL REFERENCES vehicle_classes(id),
+    make VARCHAR(50) NOT NULL,
+    model VARCHAR(50) NOT NULL,
+    year INTEGER,
+    plate_number VARCHAR(20) NOT NULL UNIQUE,
+    seats INTEGER NOT NULL CHECK (seats > 0),
+    transmission VARCHAR(20) NOT NULL CHECK (transmission IN ('manual', 'automatic')),
+    fuel_policy VARCHAR(20) NOT NULL CHECK (fuel_policy IN ('full_to_full', 'same_to_same', 'pre_purchase')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicles_service ON vehicles (service_id);
+CREATE INDEX IF NOT EXISTS idx_vehicles_class ON vehicles (class_id);
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE SET NULL;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS vehicle_class_id INTEGER REFERENCES vehicle_classes(id) ON DELETE SET NULL;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS pickup_location_id INTEGER REFERENCES rental_locations(id) ON DELETE SET NULL;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS dropoff_location_id INTEGER REFERENCES rental_locations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_bookings_vehicle ON bookings (vehicle_id) WHERE vehicle_id IS NOT NULL;
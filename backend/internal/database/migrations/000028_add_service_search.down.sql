@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_services_price;
+DROP INDEX IF EXISTS idx_services_destination;
+DROP INDEX IF EXISTS idx_services_search_vector;
+
+ALTER TABLE services DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE services DROP COLUMN IF EXISTS reviews;
+ALTER TABLE services DROP COLUMN IF EXISTS rating;
+ALTER TABLE services DROP COLUMN IF EXISTS destination_id;
@@ -0,0 +1,15 @@
+-- This migration prepares services for the catalogue search: a full-text search vector over
+-- the name (weighted highest) and description, the destination a service is offered at, and
+-- its rating. Like destinations, a service keeps its average rating and number of reviews on
+-- the row; the rating stays NULL until the service has been reviewed.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS destination_id INTEGER REFERENCES destinations(id) ON DELETE SET NULL;
+ALTER TABLE services ADD COLUMN IF NOT EXISTS rating FLOAT CHECK (rating >= 0 AND rating <= 5);
+ALTER TABLE services ADD COLUMN IF NOT EXISTS reviews INTEGER NOT NULL DEFAULT 0 CHECK (reviews >= 0);
+ALTER TABLE services ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_services_search_vector ON services USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_services_destination ON services (destination_id);
+CREATE INDEX IF NOT EXISTS idx_services_price ON services (price);
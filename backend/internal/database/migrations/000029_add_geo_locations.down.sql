@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_services_latitude;
+DROP INDEX IF EXISTS idx_destinations_latitude;
+
+ALTER TABLE services DROP CONSTRAINT IF EXISTS services_coordinates_check;
+ALTER TABLE services DROP COLUMN IF EXISTS longitude;
+ALTER TABLE services DROP COLUMN IF EXISTS latitude;
+
+ALTER TABLE destinations DROP CONSTRAINT IF EXISTS destinations_coordinates_check;
+ALTER TABLE destinations DROP COLUMN IF EXISTS area;
+ALTER TABLE destinations DROP COLUMN IF EXISTS longitude;
+ALTER TABLE destinations DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,26 @@
+-- This migration locates destinations and services on the map by their latitude and longitude
+-- in decimal degrees; either both are set or neither. A destination can also cover an area, a
+-- polygon of (longitude, latitude) points that services are matched against.
+ALTER TABLE destinations ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude >= -90 AND latitude <= 90);
+ALTER TABLE destinations ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude >= -180 AND longitude <= 180);
+ALTER TABLE destinations ADD COLUMN IF NOT EXISTS area POLYGON;
+ALTER TABLE destinations DROP CONSTRAINT IF EXISTS destinations_coordinates_check;
+ALTER TABLE destinations ADD CONSTRAINT destinations_coordinates_check
+    CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+ALTER TABLE services ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude >= -90 AND latitude <= 90);
+ALTER TABLE services ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude >= -180 AND longitude <= 180);
+ALTER TABLE services DROP CONSTRAINT IF EXISTS services_coordinates_check;
+ALTER TABLE services ADD CONSTRAINT services_coordinates_check
+    CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- Radius searches first narrow rows down to a band of latitudes
+CREATE INDEX IF NOT EXISTS idx_destinations_latitude ON destinations (latitude) WHERE latitude IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_services_latitude ON services (latitude) WHERE latitude IS NOT NULL;
+
+-- Locate the sample destinations at their city centres
+UPDATE destinations SET latitude = -33.9249, longitude = 18.4241 WHERE name = 'Cape Town' AND latitude IS NULL;
+UPDATE destinations SET latitude = 25.2048, longitude = 55.2708 WHERE name = 'Dubai' AND latitude IS NULL;
+UPDATE destinations SET latitude = 6.5244, longitude = 3.3792 WHERE name = 'Lagos' AND latitude IS NULL;
+UPDATE destinations SET latitude = -1.2921, longitude = 36.8219 WHERE name = 'Nairobi' AND latitude IS NULL;
+UPDATE destinations SET latitude = -6.1659, longitude = 39.2026 WHERE name = 'Zanzibar' AND latitude IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_destinations_place;
+ALTER TABLE destinations DROP COLUMN IF EXISTS place_id;
+
+DROP TABLE IF EXISTS places;
@@ -0,0 +1,293 @@
+-- This migration creates the place hierarchy destinations are browsed by: continents,
+-- countries, regions and cities, each below the one before. Places are known by their code:
+-- continents by their UN M49 area code, countries by their ISO 3166-1 alpha-2 code, regions by
+-- their ISO 3166-2 subdivision code and cities by their UN/LOCODE without the space. The
+-- continents and every ISO 3166-1 country are loaded here; regions and cities are added by
+-- admins as destinations need them.
+CREATE TABLE IF NOT EXISTS places (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(10) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    level VARCHAR(20) NOT NULL CHECK (level IN ('continent', 'country', 'region', 'city')),
+    parent_id INTEGER REFERENCES places(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((level = 'continent') = (parent_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_places_parent ON places (parent_id);
+
+ALTER TABLE destinations ADD COLUMN IF NOT EXISTS place_id INTEGER REFERENCES places(id);
+CREATE INDEX IF NOT EXISTS idx_destinations_place ON destinations (place_id);
+
+INSERT INTO places (code, name, level) VALUES
+    ('002', 'Africa', 'continent'),
+    ('003', 'North America', 'continent'),
+    ('005', 'South America', 'continent'),
+    ('009', 'Oceania', 'continent'),
+    ('010', 'Antarctica', 'continent'),
+    ('142', 'Asia', 'continent'),
+    ('150', 'Europe', 'continent')
+ON CONFLICT (code) DO NOTHING;
+
+-- ISO 3166-1 countries, with the continent of their UN M49 region
+INSERT INTO places (code, name, level, parent_id)
+SELECT country.code, country.name, 'country', continent.id
+FROM (VALUES
+    ('AD', 'Andorra', '150'),
+    ('AE', 'United Arab Emirates', '142'),
+    ('AF', 'Afghanistan', '142'),
+    ('AG', 'Antigua and Barbuda', '003'),
+    ('AI', 'Anguilla', '003'),
+    ('AL', 'Albania', '150'),
+    ('AM', 'Armenia', '142'),
+    ('AO', 'Angola', '002'),
+    ('AQ', 'Antarctica', '010'),
+    ('AR', 'Argentina', '005'),
+    ('AS', 'American Samoa', '009'),
+    ('AT', 'Austria', '150'),
+    ('AU', 'Australia', '009'),
+    ('AW', 'Aruba', '003'),
+    ('AX', 'Åland Islands', '150'),
+    ('AZ', 'Azerbaijan', '142'),
+    ('BA', 'Bosnia and Herzegovina', '150'),
+    ('BB', 'Barbados', '003'),
+    ('BD', 'Bangladesh', '142'),
+    ('BE', 'Belgium', '150'),
+    ('BF', 'Burkina Faso', '002'),
+    ('BG', 'Bulgaria', '150'),
+    ('BH', 'Bahrain', '142'),
+    ('BI', 'Burundi', '002'),
+    ('BJ', 'Benin', '002'),
+    ('BL', 'Saint Barthélemy', '003'),
+    ('BM', 'Bermuda', '003'),
+    ('BN', 'Brunei Darussalam', '142'),
+    ('BO', 'Bolivia', '005'),
+    ('BQ', 'Bonaire, Sint Eustatius and Saba', '003'),
+    ('BR', 'Brazil', '005'),
+    ('BS', 'Bahamas', '003'),
+    ('BT', 'Bhutan', '142'),
+    ('BV', 'Bouvet Island', '005'),
+    ('BW', 'Botswana', '002'),
+    ('BY', 'Belarus', '150'),
+    ('BZ', 'Belize', '003'),
+    ('CA', 'Canada', '003'),
+    ('CC', 'Cocos (Keeling) Islands', '009'),
+    ('CD', 'Congo, The Democratic Republic of the', '002'),
+    ('CF', 'Central African Republic', '002'),
+    ('CG', 'Congo', '002'),
+    ('CH', 'Switzerland', '150'),
+    ('CI', 'Côte d''Ivoire', '002'),
+    ('CK', 'Cook Islands', '009'),
+    ('CL', 'Chile', '005'),
+    ('CM', 'Cameroon', '002'),
+    ('CN', 'China', '142'),
+    ('CO', 'Colombia', '005'),
+    ('CR', 'Costa Rica', '003'),
+    ('CU', 'Cuba', '003'),
+    ('CV', 'Cabo Verde', '002'),
+    ('CW', 'Curaçao', '003'),
+    ('CX', 'Christmas Island', '009'),
+    ('CY', 'Cyprus', '142'),
+    ('CZ', 'Czechia', '150'),
+    ('DE', 'Germany', '150'),
+    ('DJ', 'Djibouti', '002'),
+    ('DK', 'Denmark', '150'),
+    ('DM', 'Dominica', '003'),
+    ('DO', 'Dominican Republic', '003'),
+    ('DZ', 'Algeria', '002'),
+    ('EC', 'Ecuador', '005'),
+    ('EE', 'Estonia', '150'),
+    ('EG', 'Egypt', '002'),
+    ('EH', 'Western Sahara', '002'),
+    ('ER', 'Eritrea', '002'),
+    ('ES', 'Spain', '150'),
+    ('ET', 'Ethiopia', '002'),
+    ('FI', 'Finland', '150'),
+    ('FJ', 'Fiji', '009'),
+    ('FK', 'Falkland Islands (Malvinas)', '005'),
+    ('FM', 'Micronesia, Federated States of', '009'),
+    ('FO', 'Faroe Islands', '150'),
+    ('FR', 'France', '150'),
+    ('GA', 'Gabon', '002'),
+    ('GB', 'United Kingdom', '150'),
+    ('GD', 'Grenada', '003'),
+    ('GE', 'Georgia', '142'),
+    ('GF', 'French Guiana', '005'),
+    ('GG', 'Guernsey', '150'),
+    ('GH', 'Ghana', '002'),
+    ('GI', 'Gibraltar', '150'),
+    ('GL', 'Greenland', '003'),
+    ('GM', 'Gambia', '002'),
+    ('GN', 'Guinea', '002'),
+    ('GP', 'Guadeloupe', '003'),
+    ('GQ', 'Equatorial Guinea', '002'),
+    ('GR', 'Greece', '150'),
+    ('GS', 'South Georgia and the South Sandwich Islands', '005'),
+    ('GT', 'Guatemala', '003'),
+    ('GU', 'Guam', '009'),
+    ('GW', 'Guinea-Bissau', '002'),
+    ('GY', 'Guyana', '005'),
+    ('HK', 'Hong Kong', '142'),
+    ('HM', 'Heard Island and McDonald Islands', '009'),
+    ('HN', 'Honduras', '003'),
+    ('HR', 'Croatia', '150'),
+    ('HT', 'Haiti', '003'),
+    ('HU', 'Hungary', '150'),
+    ('ID', 'Indonesia', '142'),
+    ('IE', 'Ireland', '150'),
+    ('IL', 'Israel', '142'),
+    ('IM', 'Isle of Man', '150'),
+    ('IN', 'India', '142'),
+    ('IO', 'British Indian Ocean Territory', '002'),
+    ('IQ', 'Iraq', '142'),
+    ('IR', 'Iran', '142'),
+    ('IS', 'Iceland', '150'),
+    ('IT', 'Italy', '150'),
+    ('JE', 'Jersey', '150'),
+    ('JM', 'Jamaica', '003'),
+    ('JO', 'Jordan', '142'),
+    ('JP', 'Japan', '142'),
+    ('KE', 'Kenya', '002'),
+    ('KG', 'Kyrgyzstan', '142'),
+    ('KH', 'Cambodia', '142'),
+    ('KI', 'Kiribati', '009'),
+    ('KM', 'Comoros', '002'),
+    ('KN', 'Saint Kitts and Nevis', '003'),
+    ('KP', 'North Korea', '142'),
+    ('KR', 'South Korea', '142'),
+    ('KW', 'Kuwait', '142'),
+    ('KY', 'Cayman Islands', '003'),
+    ('KZ', 'Kazakhstan', '142'),
+    ('LA', 'Laos', '142'),
+    ('LB', 'Lebanon', '142'),
+    ('LC', 'Saint Lucia', '003'),
+    ('LI', 'Liechtenstein', '150'),
+    ('LK', 'Sri Lanka', '142'),
+    ('LR', 'Liberia', '002'),
+    ('LS', 'Lesotho', '002'),
+    ('LT', 'Lithuania', '150'),
+    ('LU', 'Luxembourg', '150'),
+    ('LV', 'Latvia', '150'),
+    ('LY', 'Libya', '002'),
+    ('MA', 'Morocco', '002'),
+    ('MC', 'Monaco', '150'),
+    ('MD', 'Moldova', '150'),
+    ('ME', 'Montenegro', '150'),
+    ('MF', 'Saint Martin (French part)', '003'),
+    ('MG', 'Madagascar', '002'),
+    ('MH', 'Marshall Islands', '009'),
+    ('MK', 'North Macedonia', '150'),
+    ('ML', 'Mali', '002'),
+    ('MM', 'Myanmar', '142'),
+    ('MN', 'Mongolia', '142'),
+    ('MO', 'Macao', '142'),
+    ('MP', 'Northern Mariana Islands', '009'),
+    ('MQ', 'Martinique', '003'),
+    ('MR', 'Mauritania', '002'),
+    ('MS', 'Montserrat', '003'),
+    ('MT', 'Malta', '150'),
+    ('MU', 'Mauritius', '002'),
+    ('MV', 'Maldives', '142'),
+    ('MW', 'Malawi', '002'),
+    ('MX', 'Mexico', '003'),
+    ('MY', 'Malaysia', '142'),
+    ('MZ', 'Mozambique', '002'),
+    ('NA', 'Namibia', '002'),
+    ('NC', 'New Caledonia', '009'),
+    ('NE', 'Niger', '002'),
+    ('NF', 'Norfolk Island', '009'),
+    ('NG', 'Nigeria', '002'),
+    ('NI', 'Nicaragua', '003'),
+    ('NL', 'Netherlands', '150'),
+    ('NO', 'Norway', '150'),
+    ('NP', 'Nepal', '142'),
+    ('NR', 'Nauru', '009'),
+    ('NU', 'Niue', '009'),
+    ('NZ', 'New Zealand', '009'),
+    ('OM', 'Oman', '142'),
+    ('PA', 'Panama', '003'),
+    ('PE', 'Peru', '005'),
+    ('PF', 'French Polynesia', '009'),
+    ('PG', 'Papua New Guinea', '009'),
+    ('PH', 'Philippines', '142'),
+    ('PK', 'Pakistan', '142'),
+    ('PL', 'Poland', '150'),
+    ('PM', 'Saint Pierre and Miquelon', '003'),
+    ('PN', 'Pitcairn', '009'),
+    ('PR', 'Puerto Rico', '003'),
+    ('PS', 'Palestine, State of', '142'),
+    ('PT', 'Portugal', '150'),
+    ('PW', 'Palau', '009'),
+    ('PY', 'Paraguay', '005'),
+    ('QA', 'Qatar', '142'),
+    ('RE', 'Réunion', '002'),
+    ('RO', 'Romania', '150'),
+    ('RS', 'Serbia', '150'),
+    ('RU', 'Russian Federation', '150'),
+    ('RW', 'Rwanda', '002'),
+    ('SA', 'Saudi Arabia', '142'),
+    ('SB', 'Solomon Islands', '009'),
+    ('SC', 'Seychelles', '002'),
+    ('SD', 'Sudan', '002'),
+    ('SE', 'Sweden', '150'),
+    ('SG', 'Singapore', '142'),
+    ('SH', 'Saint Helena, Ascension and Tristan da Cunha', '002'),
+    ('SI', 'Slovenia', '150'),
+    ('SJ', 'Svalbard and Jan Mayen', '150'),
+    ('SK', 'Slovakia', '150'),
+    ('SL', 'Sierra Leone', '002'),
+    ('SM', 'San Marino', '150'),
+    ('SN', 'Senegal', '002'),
+    ('SO', 'Somalia', '002'),
+    ('SR', 'Suriname', '005'),
+    ('SS', 'South Sudan', '002'),
+    ('ST', 'Sao Tome and Principe', '002'),
+    ('SV', 'El Salvador', '003'),
+    ('SX', 'Sint Maarten (Dutch part)', '003'),
+    ('SY', 'Syria', '142'),
+    ('SZ', 'Eswatini', '002'),
+    ('TC', 'Turks and Caicos Islands', '003'),
+    ('TD', 'Chad', '002'),
+    ('TF', 'French Southern Territories', '002'),
+    ('TG', 'Togo', '002'),
+    ('TH', 'Thailand', '142'),
+    ('TJ', 'Tajikistan', '142'),
+    ('TK', 'Tokelau', '009'),
+    ('TL', 'Timor-Leste', '142'),
+    ('TM', 'Turkmenistan', '142'),
+    ('TN', 'Tunisia', '002'),
+    ('TO', 'Tonga', '009'),
+    ('TR', 'Türkiye', '142'),
+    ('TT', 'Trinidad and Tobago', '003'),
+    ('TV', 'Tuvalu', '009'),
+    ('TW', 'Taiwan', '142'),
+    ('TZ', 'Tanzania', '002'),
+    ('UA', 'Ukraine', '150'),
+    ('UG', 'Uganda', '002'),
+    ('UM', 'United States Minor Outlying Islands', '009'),
+    ('US', 'United States', '003'),
+    ('UY', 'Uruguay', '005'),
+    ('UZ', 'Uzbekistan', '142'),
+    ('VA', 'Holy See (Vatican City State)', '150'),
+    ('VC', 'Saint Vincent and the Grenadines', '003'),
+    ('VE', 'Venezuela', '005'),
+    ('VG', 'Virgin Islands, British', '003'),
+    ('VI', 'Virgin Islands, U.S.', '003'),
+    ('VN', 'Vietnam', '142'),
+    ('VU', 'Vanuatu', '009'),
+    ('WF', 'Wallis and Futuna', '009'),
+    ('WS', 'Samoa', '009'),
+    ('YE', 'Yemen', '142'),
+    ('YT', 'Mayotte', '002'),
+    ('ZA', 'South Africa', '002'),
+    ('ZM', 'Zambia', '002'),
+    ('ZW', 'Zimbabwe', '002')
+) AS country (code, name, continent)
+JOIN places continent ON continent.code = country.continent
+ON CONFLICT (code) DO NOTHING;
+
+-- Link the destinations that name a country as their location to it
+UPDATE destinations d SET place_id = p.id
+FROM places p
+WHERE p.level = 'country' AND lower(p.name) = lower(d.location) AND d.place_id IS NULL;
@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/service"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -38,6 +43,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.ClientInfo = clientInfo(r)
 	response, err := h.authService.Register(&req)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, err.Error())
@@ -53,13 +59,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login handles user login
 // @Summary Login user
-// @Description Login user with email and password
+// @Description Login user with email and password. Users with two-factor authentication get a challenge token to complete at /auth/2fa/verify instead of tokens.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body models.LoginRequest true "Login request"
 // @Success 200 {object} models.APIResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
@@ -68,11 +75,63 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Login(&req)
+	req.ClientInfo = clientInfo(r)
+	response, challenge, err := h.authService.Login(&req)
 	if err != nil {
+		if respondWithThrottle(w, err) {
+			return
+		}
 		respondWithError(w, http.StatusUnauthorized, err.Error())
 		return
 	}
+	if challenge != nil {
+		respondWithJSON(w, http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Two-factor authentication required",
+			Data:    challenge,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    response,
+	})
+}
+
+// VerifyTwoFactor handles the second step of a login
+// @Summary Verify two-factor login
+// @Description Complete a login with the challenge token returned by login and a TOTP code or a recovery code
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "Two-factor login request"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	req.ClientInfo = clientInfo(r)
+	response, err := h.authService.VerifyTwoFactorLogin(&req)
+	if err != nil {
+		if respondWithThrottle(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTwoFactorChallenge) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		h.logger.Error("Failed to verify two-factor login", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify two-factor login")
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, models.APIResponse{
 		Success: true,
@@ -81,6 +140,258 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RefreshToken handles exchanging a refresh token for new tokens
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token can be used once; reusing one revokes its session.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "Refresh token request"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	req.ClientInfo = clientInfo(r)
+	response, err := h.authService.RefreshToken(&req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			respondWithError(w, http.StatusUnauthorized, service.ErrInvalidRefreshToken.Error())
+			return
+		}
+		h.logger.Error("Failed to refresh token", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Token refreshed successfully",
+		Data:    response,
+	})
+}
+
+// Logout handles ending the session of a refresh token
+// @Summary Logout
+// @Description Revoke the session of a refresh token together with its access tokens
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.LogoutRequest true "Logout request"
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req models.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.authService.Logout(req.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		h.logger.Error("Failed to logout", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	})
+}
+
+// LogoutAll handles ending every session of the current user
+// @Summary Logout from all devices
+// @Description Revoke every session of the current user
+// @Tags Authentication
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.authService.LogoutAll(userID); err != nil {
+		h.logger.Error("Failed to logout from all devices", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to logout from all devices")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged out from all devices successfully",
+	})
+}
+
+// GetSessions handles listing the active sessions of the current user
+// @Summary Get sessions
+// @Description Get the devices the current user is signed in on
+// @Tags Authentication
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	sessions, err := h.authService.GetSessions(userID)
+	if err != nil {
+		h.logger.Error("Failed to get sessions", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get sessions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// ForgotPassword handles requesting a password reset email
+// @Summary Forgot password
+// @Description Email a single-use password reset link. The response is the same whether or not the email belongs to an account.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Forgot password request"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	req.ClientInfo = clientInfo(r)
+	if err := h.authService.ForgotPassword(&req); err != nil {
+		if respondWithThrottle(w, err) {
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword handles setting a new password with a reset token
+// @Summary Reset password
+// @Description Set a new password with the token of a password reset email. Signs the user out of every device.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.authService.ResetPassword(&req); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			respondWithError(w, http.StatusBadRequest, service.ErrInvalidResetToken.Error())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Password reset successfully",
+	})
+}
+
+// UnlockAccount handles lifting a lockout with the link from the account locked email
+// @Summary Unlock account
+// @Description Lift the lockout of an account after repeated failed attempts, with the token of the account locked email
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.UnlockAccountRequest true "Unlock account request"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/unlock [post]
+func (h *AuthHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.UnlockAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.authService.UnlockAccount(&req); err != nil {
+		if errors.Is(err, service.ErrInvalidUnlockToken) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to unlock account", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlock account")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Account unlocked, you can sign in again",
+	})
+}
+
+// respondWithThrottle answers 429 with a Retry-After header when the brute-force
+// protection refused an attempt, and reports whether it did
+func respondWithThrottle(w http.ResponseWriter, err error) bool {
+	var throttled *service.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	respondWithError(w, http.StatusTooManyRequests, throttled.Error())
+	return true
+}
+
+// clientInfo describes the device a request comes from. X-Forwarded-For is only
+// trusted when TRUST_PROXY_HEADERS is set, since clients can send it themselves.
+func clientInfo(r *http.Request) models.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	return models.ClientInfo{ClientIP: ip, UserAgent: r.UserAgent()}
+}
+
 // AuthMiddleware validates JWT tokens
 func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -96,14 +407,15 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		userID, err := h.authService.ValidateToken(tokenString)
+		session, err := h.authService.ValidateSession(tokenString)
 		if err != nil {
 			respondWithError(w, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
-		// Add user ID to request context
-		r.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
+		// Add user ID and session ID to request context
+		r.Header.Set("X-User-ID", fmt.Sprintf("%d", session.UserID))
+		r.Header.Set("X-Session-ID", session.ID)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -133,6 +445,8 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 // @Param request body models.VerifyEmailRequest true "Verify email request"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /auth/verify-email [post]
 func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	var req models.VerifyEmailRequest
@@ -141,8 +455,19 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.ClientInfo = clientInfo(r)
 	if err := h.authService.VerifyEmail(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		if respondWithThrottle(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrVerificationCodeExpired):
+			respondWithError(w, http.StatusGone, err.Error())
+		case errors.Is(err, service.ErrVerificationAttemptsExceeded):
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
 		return
 	}
 
@@ -161,6 +486,7 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 // @Param request body models.ResendVerificationRequest true "Resend verification request"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /auth/resend-verification [post]
 func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
 	var req models.ResendVerificationRequest
@@ -169,7 +495,15 @@ func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	req.ClientInfo = clientInfo(r)
 	if err := h.authService.ResendVerification(&req); err != nil {
+		if respondWithThrottle(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrVerificationResendLimited) {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
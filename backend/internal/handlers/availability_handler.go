@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultAvailabilityWindow is used when the client does not pass a 'to' parameter
+const defaultAvailabilityWindow = 30 * 24 * time.Hour
+
+// AvailabilityHandler handles service availability requests
+type AvailabilityHandler struct {
+	availabilityService service.AvailabilityService
+	logger              *logger.Logger
+}
+
+// NewAvailabilityHandler creates a new availability handler
+func NewAvailabilityHandler(availabilityService service.AvailabilityService, logger *logger.Logger) *AvailabilityHandler {
+	return &AvailabilityHandler{availabilityService: availabilityService, logger: logger}
+}
+
+// GetServiceAvailability handles GET /api/services/{id}/availability
+// @Summary Get service availability
+// @Description Get the free and blocked intervals of a service between two dates
+// @Tags Services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param from query string false "Start of the window (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Param to query string false "End of the window (RFC3339 or YYYY-MM-DD), defaults to 30 days after from"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /services/{id}/availability [get]
+func (h *AvailabilityHandler) GetServiceAvailability(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	from := time.Now().UTC()
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'from' date")
+			return
+		}
+	}
+
+	to := from.Add(defaultAvailabilityWindow)
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'to' date")
+			return
+		}
+	}
+
+	availability, err := h.availabilityService.GetServiceAvailability(id, from, to)
+	if err != nil {
+		if err.Error() == "service not found" {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Availability retrieved successfully",
+		Data:    availability,
+	})
+}
+
+// parseTimeParam parses a query parameter given either as RFC3339 or as a plain date
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
 	"nomado-houses/internal/service"
 	"strconv"
 
@@ -24,7 +26,7 @@ func NewBookingHandler(bookingService service.BookingService, logger *logger.Log
 
 // CreateBooking handles POST /api/bookings
 // @Summary Create booking
-// @Description Create a new booking for a service
+// @Description Create a new booking for a service; car rentals are booked with a vehicle or a vehicle class and a pickup location
 // @Tags Bookings
 // @Accept json
 // @Produce json
@@ -33,6 +35,7 @@ func NewBookingHandler(bookingService service.BookingService, logger *logger.Log
 // @Success 201 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /bookings [post]
 func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateBookingRequest
@@ -50,16 +53,27 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	booking := &models.Booking{
-		UserID:           userID,
-		ServiceID:        req.ServiceID,
-		BookingDateStart: req.BookingDateStart,
-		BookingDateEnd:   req.BookingDateEnd,
-		TotalPrice:       req.TotalPrice,
-		Status:           "pending",
+		UserID:            userID,
+		ServiceID:         req.ServiceID,
+		BookingDateStart:  req.BookingDateStart,
+		BookingDateEnd:    req.BookingDateEnd,
+		Status:            models.BookingStatusPending,
+		VehicleID:         req.VehicleID,
+		VehicleClassID:    req.VehicleClassID,
+		PickupLocationID:  req.PickupLocationID,
+		DropoffLocationID: req.DropoffLocationID,
 	}
 
 	if err := h.bookingService.CreateBooking(booking); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		switch {
+		case errors.Is(err, service.ErrInvalidBookingDates), errors.Is(err, service.ErrBookingInPast),
+			errors.Is(err, service.ErrInvalidVehicleSelection):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrServiceUnavailable):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -70,13 +84,49 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// QuoteBooking handles POST /api/bookings/quote
+// @Summary Quote booking
+// @Description Compute the itemised price of a booking without creating it
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Param request body models.BookingQuoteRequest true "Booking quote request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /bookings/quote [post]
+func (h *BookingHandler) QuoteBooking(w http.ResponseWriter, r *http.Request) {
+	var req models.BookingQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	quote, err := h.bookingService.QuoteBooking(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Quote computed successfully",
+		Data:    quote,
+	})
+}
+
 // GetUserBookings handles GET /api/bookings
 // @Summary Get user bookings
-// @Description Get all bookings for the authenticated user
+// @Description Get a page of the bookings of the authenticated user, the most recent first by default; filter with filter[field][operator]=value
 // @Tags Bookings
 // @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
 // @Security Bearer
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 // @Router /bookings [get]
 func (h *BookingHandler) GetUserBookings(w http.ResponseWriter, r *http.Request) {
@@ -88,20 +138,76 @@ func (h *BookingHandler) GetUserBookings(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	bookings, err := h.bookingService.GetBookingsByUserID(userID)
+	query, err := parseListQuery(r.URL.Query())
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bookings, page, err := h.bookingService.GetBookingsByUserID(userID, query)
+	if err != nil {
+		respondWithBookingError(w, err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Bookings retrieved successfully",
-		Data:    bookings,
+		Success:    true,
+		Message:    "Bookings retrieved successfully",
+		Data:       bookings,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// GetAllBookings handles GET /api/admin/bookings
+// @Summary Get all bookings
+// @Description Get a page of the bookings of every user (bookings:read permission), the most recent first by default; filter with filter[field][operator]=value
+// @Tags Bookings
+// @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/bookings [get]
+func (h *BookingHandler) GetAllBookings(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bookings, page, err := h.bookingService.GetAllBookings(query)
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidListQuery) {
+			h.logger.Error("Failed to get all bookings", err)
+		}
+		respondWithBookingError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Bookings retrieved successfully",
+		Data:       bookings,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
 	})
 }
 
 // GetBookingByID handles GET /api/bookings/{id}
+// @Summary Get booking
+// @Description Get a booking (owner or staff with the bookings:read permission)
+// @Tags Bookings
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /bookings/{id} [get]
 func (h *BookingHandler) GetBookingByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -110,9 +216,16 @@ func (h *BookingHandler) GetBookingByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	booking, err := h.bookingService.GetBookingByID(id)
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	booking, err := h.bookingService.GetBookingByID(id, userID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		respondWithBookingError(w, err)
 		return
 	}
 
@@ -123,7 +236,19 @@ func (h *BookingHandler) GetBookingByID(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// UpdateBookingStatus handles PUT /api/bookings/{id}/status
+// UpdateBookingStatus handles PUT /api/admin/bookings/{id}/status
+// @Summary Update booking status
+// @Description Move a booking to another status of its lifecycle (Admin only)
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body models.UpdateBookingStatusRequest true "Update booking status request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/bookings/{id}/status [put]
 func (h *BookingHandler) UpdateBookingStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -138,8 +263,15 @@ func (h *BookingHandler) UpdateBookingStatus(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.bookingService.UpdateBookingStatus(id, req.Status); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	// Get the admin making the change from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	if err := h.bookingService.UpdateBookingStatus(id, req.Status, user.ID, req.Reason); err != nil {
+		respondWithBookingError(w, err)
 		return
 	}
 
@@ -149,3 +281,200 @@ func (h *BookingHandler) UpdateBookingStatus(w http.ResponseWriter, r *http.Requ
 	})
 }
 
+// CancelBooking handles POST /api/bookings/{id}/cancel
+// @Summary Cancel booking
+// @Description Cancel a booking of the authenticated user and compute the refund due under the service cancellation policy
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body models.CancelBookingRequest false "Cancel booking request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /bookings/{id}/cancel [post]
+func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// The body is optional, a missing reason falls back to a default one
+	var req models.CancelBookingRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	result, err := h.bookingService.CancelBooking(id, userID, req.Reason)
+	if err != nil {
+		respondWithBookingError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Booking cancelled successfully",
+		Data:    result,
+	})
+}
+
+// AcceptBooking handles POST /api/provider/dashboard/bookings/{id}/accept
+// @Summary Accept booking
+// @Description Confirm a pending booking of a service of the authenticated provider
+// @Tags ProviderDashboard
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/dashboard/bookings/{id}/accept [post]
+func (h *BookingHandler) AcceptBooking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	// Get the provider or their staff member from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	booking, err := h.bookingService.AcceptBooking(id, user)
+	if err != nil {
+		respondWithBookingError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Booking accepted successfully",
+		Data:    booking,
+	})
+}
+
+// DeclineBooking handles POST /api/provider/dashboard/bookings/{id}/decline
+// @Summary Decline booking
+// @Description Cancel a pending booking of a service of the authenticated provider and refund everything the guest paid
+// @Tags ProviderDashboard
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body models.DeclineBookingRequest false "Decline booking request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/dashboard/bookings/{id}/decline [post]
+func (h *BookingHandler) DeclineBooking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	// Get the provider or their staff member from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	// The body is optional, a missing reason falls back to a default one
+	var req models.DeclineBookingRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	result, err := h.bookingService.DeclineBooking(id, user, req.Reason)
+	if err != nil {
+		respondWithBookingError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Booking declined successfully",
+		Data:    result,
+	})
+}
+
+// GetBookingHistory handles GET /api/bookings/{id}/history
+// @Summary Get booking status history
+// @Description Get the status changes of a booking (owner or staff with the bookings:read permission)
+// @Tags Bookings
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /bookings/{id}/history [get]
+func (h *BookingHandler) GetBookingHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	history, err := h.bookingService.GetBookingHistory(id, userID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		respondWithBookingError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Booking history retrieved successfully",
+		Data:    history,
+	})
+}
+
+// respondWithBookingError maps booking service errors to HTTP status codes
+func respondWithBookingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidBookingStatus), errors.Is(err, service.ErrInvalidListQuery):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrBookingAccessDenied), errors.Is(err, service.ErrTeamAccessDenied),
+		errors.Is(err, service.ErrTwoFactorSessionRequired):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrIllegalBookingTransition), errors.Is(err, repository.ErrBookingStatusChanged):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err.Error() == "booking not found":
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+)
+
+// CancellationPolicyHandler handles cancellation policy requests
+type CancellationPolicyHandler struct {
+	policyService service.CancellationPolicyService
+	logger        *logger.Logger
+}
+
+// NewCancellationPolicyHandler creates a new cancellation policy handler
+func NewCancellationPolicyHandler(policyService service.CancellationPolicyService, logger *logger.Logger) *CancellationPolicyHandler {
+	return &CancellationPolicyHandler{policyService: policyService, logger: logger}
+}
+
+// GetPlatformPolicies handles GET /api/cancellation-policies
+// @Summary Get platform cancellation policies
+// @Description Get the flexible, moderate and strict cancellation policies
+// @Tags CancellationPolicies
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /cancellation-policies [get]
+func (h *CancellationPolicyHandler) GetPlatformPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.policyService.GetPlatformPolicies()
+	if err != nil {
+		h.logger.Error("Failed to get cancellation policies", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Cancellation policies retrieved successfully",
+		Data:    policies,
+	})
+}
+
+// GetProviderPolicies handles GET /api/provider/cancellation-policies
+// @Summary Get own cancellation policies
+// @Description Get the custom cancellation policies created by the provider
+// @Tags CancellationPolicies
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /provider/cancellation-policies [get]
+func (h *CancellationPolicyHandler) GetProviderPolicies(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	policies, err := h.policyService.GetPoliciesByCreator(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get provider cancellation policies", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Cancellation policies retrieved successfully",
+		Data:    policies,
+	})
+}
+
+// CreatePolicy handles POST /api/provider/cancellation-policies
+// @Summary Create cancellation policy
+// @Description Create a custom cancellation policy with refund tiers (Provider or Admin)
+// @Tags CancellationPolicies
+// @Accept json
+// @Produce json
+// @Param request body models.CreateCancellationPolicyRequest true "Create cancellation policy request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /provider/cancellation-policies [post]
+func (h *CancellationPolicyHandler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.CreateCancellationPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	policy := &models.CancellationPolicy{
+		Name:        req.Name,
+		Description: req.Description,
+		Tiers:       req.Tiers,
+		CreatedBy:   &user.ID,
+	}
+
+	if err := h.policyService.CreatePolicy(policy); err != nil {
+		if errors.Is(err, service.ErrInvalidCancellationPolicy) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to create cancellation policy", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Cancellation policy created successfully",
+		Data:    policy,
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
@@ -45,16 +46,30 @@ func (h *DestinationsHandler) respondWithJSON(w http.ResponseWriter, code int, p
 
 // GetAllDestinations handles GET /api/destinations
 // @Summary Get all destinations
-// @Description Get all available destinations from the database
+// @Description Get a page of destinations from the database, the best rated first by default
 // @Tags Destinations
 // @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /destinations [get]
 func (h *DestinationsHandler) GetAllDestinations(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Fetching all destinations from database")
 
-	destinations, err := h.destinationService.GetAllDestinations()
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	destinations, page, err := h.destinationService.GetAllDestinations(query)
+	if errors.Is(err, service.ErrInvalidListQuery) {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to get destinations", err)
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve destinations")
@@ -63,9 +78,11 @@ func (h *DestinationsHandler) GetAllDestinations(w http.ResponseWriter, r *http.
 
 	h.logger.Info("Successfully retrieved destinations from database")
 	h.respondWithJSON(w, http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Destinations retrieved successfully",
-		Data:    destinations,
+		Success:    true,
+		Message:    "Destinations retrieved successfully",
+		Data:       destinations,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
 	})
 }
 
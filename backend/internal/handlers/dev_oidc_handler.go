@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/service"
+)
+
+// devLoginPage asks who signs in at the development identity provider. The parameters of
+// the authorization request are carried along as hidden fields.
+var devLoginPage = template.Must(template.New("dev-login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Development identity provider</title></head>
+<body>
+<h1>Development identity provider</h1>
+<p>Sign in as any email address. For development and testing only.</p>
+<form method="get">
+{{range $name, $values := .}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}<p><label>Email <input type="email" name="login_hint" required></label></p>
+<p><label>Name <input type="text" name="name"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>
+`))
+
+// DevOIDCHandler serves the development identity provider
+type DevOIDCHandler struct {
+	provider *service.DevIdentityProvider
+	logger   *logger.Logger
+}
+
+// NewDevOIDCHandler creates a new development identity provider handler
+func NewDevOIDCHandler(provider *service.DevIdentityProvider, logger *logger.Logger) *DevOIDCHandler {
+	return &DevOIDCHandler{provider: provider, logger: logger}
+}
+
+// Discovery serves the discovery document
+func (h *DevOIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.provider.Discovery())
+}
+
+// JWKS serves the signing key
+func (h *DevOIDCHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.provider.JWKS())
+}
+
+// Authorize signs in the user named by login_hint and redirects back to the client. Without
+// a login_hint it shows a form asking for the email.
+func (h *DevOIDCHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	redirectURL, err := h.provider.Authorize(params)
+	if errors.Is(err, service.ErrDevLoginHintRequired) {
+		params.Del("login_hint")
+		params.Del("name")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := devLoginPage.Execute(w, params); err != nil {
+			h.logger.Error("Failed to render development login page", err)
+		}
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// Token redeems an authorization code
+func (h *DevOIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	token, err := h.provider.Token(r.PostForm)
+	if err != nil {
+		respondWithJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_grant",
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, token)
+}
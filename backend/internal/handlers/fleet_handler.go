@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// FleetHandler handles the vehicle requests of car rentals
+type FleetHandler struct {
+	fleetService service.FleetService
+	logger       *logger.Logger
+}
+
+// NewFleetHandler creates a new fleet handler
+func NewFleetHandler(fleetService service.FleetService, logger *logger.Logger) *FleetHandler {
+	return &FleetHandler{fleetService: fleetService, logger: logger}
+}
+
+// GetServiceVehicles handles GET /api/services/{id}/vehicles
+// @Summary Get bookable vehicles
+// @Description Get the active vehicles of a car rental, only those free during a window when from and to are given
+// @Tags Services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param from query string false "Start of the window (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "End of the window (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /services/{id}/vehicles [get]
+func (h *FleetHandler) GetServiceVehicles(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	var from, to time.Time
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'from' date")
+			return
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'to' date")
+			return
+		}
+	}
+	if from.IsZero() != to.IsZero() {
+		respondWithError(w, http.StatusBadRequest, "Both 'from' and 'to' are required to filter by dates")
+		return
+	}
+
+	vehicles, err := h.fleetService.GetAvailableVehicles(id, from, to)
+	if err != nil {
+		h.respondWithFleetError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vehicles retrieved successfully",
+		Data:    vehicles,
+	})
+}
+
+// GetFleet handles GET /api/provider/services/{id}/vehicles
+// @Summary Get own fleet
+// @Description Get every vehicle of a car rental of the authenticated provider, with plate numbers and inactive vehicles
+// @Tags Services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/services/{id}/vehicles [get]
+func (h *FleetHandler) GetFleet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	vehicles, err := h.fleetService.GetFleet(id, user)
+	if err != nil {
+		h.respondWithFleetError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Fleet retrieved successfully",
+		Data:    vehicles,
+	})
+}
+
+// AddVehicle handles POST /api/provider/services/{id}/vehicles
+// @Summary Add vehicle
+// @Description Add a vehicle to the fleet of a car rental of the authenticated provider
+// @Tags Services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param request body models.VehicleRequest true "Vehicle request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/services/{id}/vehicles [post]
+func (h *FleetHandler) AddVehicle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.VehicleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	vehicle, err := h.fleetService.AddVehicle(id, &req, user)
+	if err != nil {
+		h.respondWithFleetError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Vehicle added successfully",
+		Data:    vehicle,
+	})
+}
+
+// UpdateVehicle handles PUT /api/provider/services/{id}/vehicles/{vehicleId}
+// @Summary Update vehicle
+// @Description Change a vehicle of a car rental of the authenticated provider; inactive vehicles keep their bookings but cannot be booked again
+// @Tags Services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param vehicleId path int true "Vehicle ID"
+// @Param request body models.VehicleRequest true "Vehicle request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/services/{id}/vehicles/{vehicleId} [put]
+func (h *FleetHandler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+	vehicleID, err := strconv.Atoi(vars["vehicleId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid vehicle ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.VehicleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	vehicle, err := h.fleetService.UpdateVehicle(id, vehicleID, &req, user)
+	if err != nil {
+		h.respondWithFleetError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vehicle updated successfully",
+		Data:    vehicle,
+	})
+}
+
+// RemoveVehicle handles DELETE /api/provider/services/{id}/vehicles/{vehicleId}
+// @Summary Remove vehicle
+// @Description Remove a vehicle from the fleet of a car rental of the authenticated provider, unless a booking that has not ended holds it
+// @Tags Services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param vehicleId path int true "Vehicle ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/services/{id}/vehicles/{vehicleId} [delete]
+func (h *FleetHandler) RemoveVehicle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid service ID")
+		return
+	}
+	vehicleID, err := strconv.Atoi(vars["vehicleId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid vehicle ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	if err := h.fleetService.RemoveVehicle(id, vehicleID, user); err != nil {
+		h.respondWithFleetError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vehicle removed successfully",
+	})
+}
+
+// respondWithFleetError maps fleet errors to HTTP status codes
+func (h *FleetHandler) respondWithFleetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidVehicle),
+		errors.Is(err, service.ErrNotCarRental),
+		errors.Is(err, service.ErrInvalidBookingDates):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrProviderNotVerified),
+		errors.Is(err, service.ErrServiceAccessDenied),
+		errors.Is(err, service.ErrTeamAccessDenied):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrServiceNotFound),
+		errors.Is(err, service.ErrVehicleNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrVehiclePlateTaken),
+		errors.Is(err, service.ErrVehicleBooked):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Failed to process fleet request", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
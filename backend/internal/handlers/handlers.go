@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
@@ -24,15 +25,96 @@ func NewDestinationHandler(destinationService service.DestinationService, logger
 
 // GetAllDestinations handles GET /api/destinations
 // @Summary Get all destinations
-// @Description Get all available destinations
+// @Description Get a page of destinations, the best rated first by default; filter with filter[field][operator]=value
 // @Tags Destinations
 // @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /destinations [get]
 func (h *DestinationHandler) GetAllDestinations(w http.ResponseWriter, r *http.Request) {
-	destinations, err := h.destinationService.GetAllDestinations()
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	destinations, page, err := h.destinationService.GetAllDestinations(query)
+	if errors.Is(err, service.ErrInvalidListQuery) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Destinations retrieved successfully",
+		Data:       destinations,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// GetNearbyDestinations handles GET /api/destinations/nearby
+// @Summary Get nearby destinations
+// @Description Get the destinations within a radius of a point, the closest first, with their distance from it
+// @Tags Destinations
+// @Produce json
+// @Param lat query number true "Latitude of the point, in decimal degrees"
+// @Param lng query number true "Longitude of the point, in decimal degrees"
+// @Param radius_km query number false "Search radius, 50 km by default and at most 500"
+// @Param limit query int false "Maximum number of destinations, 50 by default and at most 200"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /destinations/nearby [get]
+func (h *DestinationHandler) GetNearbyDestinations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("lat") == "" || query.Get("lng") == "" {
+		respondWithError(w, http.StatusBadRequest, "Both 'lat' and 'lng' are required")
+		return
+	}
+
+	var center models.GeoPoint
+	var radiusKm float64
+	var limit int
+	var err error
+	floats := []struct {
+		name   string
+		target *float64
+	}{
+		{"lat", &center.Lat},
+		{"lng", &center.Lng},
+		{"radius_km", &radiusKm},
+	}
+	for _, param := range floats {
+		if value := query.Get(param.name); value != "" {
+			if *param.target, err = strconv.ParseFloat(value, 64); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid '"+param.name+"' parameter")
+				return
+			}
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'limit' parameter")
+			return
+		}
+	}
+
+	destinations, err := h.destinationService.GetNearbyDestinations(center, radiusKm, limit)
+	if errors.Is(err, service.ErrInvalidNearbyQuery) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
+		h.logger.Error("Failed to get nearby destinations", err)
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -97,9 +179,17 @@ func (h *DestinationHandler) CreateDestination(w http.ResponseWriter, r *http.Re
 		Name:        req.Name,
 		Description: req.Description,
 		Location:    req.Location,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		Area:        req.Area,
+		PlaceCode:   req.PlaceCode,
 	}
 
 	if err := h.destinationService.CreateDestination(destination); err != nil {
+		if errors.Is(err, service.ErrInvalidDestination) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -143,9 +233,17 @@ func (h *DestinationHandler) UpdateDestination(w http.ResponseWriter, r *http.Re
 		Name:        req.Name,
 		Description: req.Description,
 		Location:    req.Location,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		Area:        req.Area,
+		PlaceCode:   req.PlaceCode,
 	}
 
 	if err := h.destinationService.UpdateDestination(destination); err != nil {
+		if errors.Is(err, service.ErrInvalidDestination) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"nomado-houses/internal/models"
+	"strconv"
+	"strings"
+)
+
+// parseListQuery reads the page of a list a client asks for from query parameters: cursor,
+// limit, sort and filters written filter[field]=value or filter[field][operator]=value
+func parseListQuery(values url.Values) (models.ListQuery, error) {
+	query := models.ListQuery{
+		Cursor: values.Get("cursor"),
+		Sort:   values.Get("sort"),
+	}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.New("Invalid 'limit' parameter")
+		}
+		query.Limit = limit
+	}
+
+	for key, params := range values {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]"), "][")
+		if len(parts) > 2 || parts[0] == "" {
+			return query, fmt.Errorf("Invalid filter parameter %q", key)
+		}
+		filter := models.ListFilter{Field: parts[0], Operator: models.FilterEq}
+		if len(parts) == 2 {
+			filter.Operator = models.FilterOperator(parts[1])
+		}
+		for _, value := range params {
+			filter.Value = value
+			query.Filters = append(query.Filters, filter)
+		}
+	}
+	return query, nil
+}
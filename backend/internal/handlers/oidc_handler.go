@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// OIDCHandler handles signing in with OpenID Connect providers
+type OIDCHandler struct {
+	oidcService service.OIDCService
+	logger      *logger.Logger
+}
+
+// NewOIDCHandler creates a new OpenID Connect handler
+func NewOIDCHandler(oidcService service.OIDCService, logger *logger.Logger) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, logger: logger}
+}
+
+// GetProviders handles listing the providers users can sign in with
+// @Summary List identity providers
+// @Description List the OpenID Connect providers that users can sign in with
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Identity providers retrieved successfully",
+		Data:    h.oidcService.GetProviders(),
+	})
+}
+
+// StartLogin handles starting a login with a provider
+// @Summary Start identity provider login
+// @Description Start the authorization code flow with PKCE. Send the browser to the returned authorization URL; the provider redirects it back to the frontend with a code and the state.
+// @Tags Authentication
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/oidc/{provider}/start [post]
+func (h *OIDCHandler) StartLogin(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	authorization, err := h.oidcService.StartLogin(provider)
+	if err != nil {
+		h.respondWithOIDCError(w, err, "Failed to start login")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Continue the login at the identity provider",
+		Data:    authorization,
+	})
+}
+
+// Callback handles finishing a login with the code and state the provider returned
+// @Summary Complete identity provider login
+// @Description Exchange the code and state the provider redirected back with for tokens. The identity is linked to the user with the same verified email, or a user is created. Users with two-factor authentication get a challenge to complete at /auth/2fa/verify.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param request body models.OIDCCallbackRequest true "Code and state"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /auth/oidc/{provider}/callback [post]
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	var req models.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	req.ClientInfo = clientInfo(r)
+	response, challenge, err := h.oidcService.CompleteLogin(provider, &req)
+	if err != nil {
+		h.respondWithOIDCError(w, err, "Failed to complete login")
+		return
+	}
+	if challenge != nil {
+		respondWithJSON(w, http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Two-factor authentication required",
+			Data:    challenge,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    response,
+	})
+}
+
+// respondWithOIDCError maps OpenID Connect service errors to HTTP responses
+func (h *OIDCHandler) respondWithOIDCError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrOIDCProviderNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, service.ErrOIDCEmailMissing):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrOIDCLoginFailed):
+		// The cause stays in the log, the client only learns that the provider refused
+		h.logger.Error(message, err)
+		respondWithError(w, http.StatusUnauthorized, service.ErrOIDCLoginFailed.Error())
+	case errors.Is(err, service.ErrOIDCEmailNotVerified):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	default:
+		h.logger.Error(message, err)
+		respondWithError(w, http.StatusInternalServerError, message)
+	}
+}
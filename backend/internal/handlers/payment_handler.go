@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"nomado-houses/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// PaymentHandler handles payment requests
+type PaymentHandler struct {
+	paymentService service.PaymentService
+	logger         *logger.Logger
+}
+
+// NewPaymentHandler creates a new payment handler
+func NewPaymentHandler(paymentService service.PaymentService, logger *logger.Logger) *PaymentHandler {
+	return &PaymentHandler{paymentService: paymentService, logger: logger}
+}
+
+// PayBooking handles POST /api/bookings/{id}/payments
+// @Summary Pay booking
+// @Description Pay all or part of the outstanding balance of a booking through the payment gateway. The booking is confirmed once fully paid.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Param request body models.CreatePaymentRequest true "Create payment request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Success 202 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /bookings/{id}/payments [post]
+func (h *PaymentHandler) PayBooking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bookingID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	receipt, err := h.paymentService.PayBooking(bookingID, userID, &req)
+	if err != nil {
+		h.respondWithPaymentError(w, err)
+		return
+	}
+
+	h.respondWithReceipt(w, receipt)
+}
+
+// ConfirmPayment handles POST /api/payments/{id}/confirm
+// @Summary Confirm payment
+// @Description Check a pending payment with the payment gateway again, after the customer completed the next action or when the gateway timed out
+// @Tags Payments
+// @Produce json
+// @Param id path int true "Payment ID"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Success 202 {object} models.APIResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /payments/{id}/confirm [post]
+func (h *PaymentHandler) ConfirmPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	receipt, err := h.paymentService.ConfirmPayment(id, userID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		h.respondWithPaymentError(w, err)
+		return
+	}
+
+	h.respondWithReceipt(w, receipt)
+}
+
+// respondWithReceipt answers 201 for a completed payment and 202 for one still awaiting the gateway
+func (h *PaymentHandler) respondWithReceipt(w http.ResponseWriter, receipt *models.PaymentReceipt) {
+	if receipt.Payment.Status == models.PaymentStatusCompleted {
+		respondWithJSON(w, http.StatusCreated, models.APIResponse{
+			Success: true,
+			Message: "Payment completed successfully",
+			Data:    receipt,
+		})
+		return
+	}
+
+	message := "Payment is pending, confirm it again later"
+	if receipt.NextActionURL != "" {
+		message = "Payment requires customer authentication at next_action_url, then confirmation"
+	}
+	respondWithJSON(w, http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    receipt,
+	})
+}
+
+// GetUserPayments handles GET /api/payments
+// @Summary Get user payments
+// @Description Get the payments and refunds of the authenticated user
+// @Tags Payments
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /payments [get]
+func (h *PaymentHandler) GetUserPayments(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	payments, err := h.paymentService.GetPaymentsByUserID(userID)
+	if err != nil {
+		h.logger.Error("Failed to get user payments", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payments retrieved successfully",
+		Data:    payments,
+	})
+}
+
+// GetPaymentByID handles GET /api/payments/{id}
+// @Summary Get payment by ID
+// @Description Get a payment of the authenticated user (or any payment for admins)
+// @Tags Payments
+// @Produce json
+// @Param id path int true "Payment ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /payments/{id} [get]
+func (h *PaymentHandler) GetPaymentByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	payment, err := h.paymentService.GetPaymentByID(id, userID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		h.respondWithPaymentError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payment retrieved successfully",
+		Data:    payment,
+	})
+}
+
+// GetAllPayments handles GET /api/admin/payments
+// @Summary Get all payments
+// @Description Get a page of payments and refunds (Admin only), the most recent first by default; filter with filter[field][operator]=value
+// @Tags Payments
+// @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/payments [get]
+func (h *PaymentHandler) GetAllPayments(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payments, page, err := h.paymentService.GetAllPayments(query)
+	if err != nil {
+		h.respondWithPaymentError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Payments retrieved successfully",
+		Data:       payments,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// GetBookingPayments handles GET /api/admin/bookings/{id}/payments
+// @Summary Get booking payments
+// @Description Get the payments and refunds of a booking (Admin only)
+// @Tags Payments
+// @Produce json
+// @Param id path int true "Booking ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/bookings/{id}/payments [get]
+func (h *PaymentHandler) GetBookingPayments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bookingID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	payments, err := h.paymentService.GetPaymentsByBookingID(bookingID)
+	if err != nil {
+		h.logger.Error("Failed to get booking payments", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payments retrieved successfully",
+		Data:    payments,
+	})
+}
+
+// ProcessRefund handles POST /api/admin/payments/{id}/refund
+// @Summary Process refund
+// @Description Send a pending refund to the payment gateway again (Admin only)
+// @Tags Payments
+// @Produce json
+// @Param id path int true "Refund payment ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/payments/{id}/refund [post]
+func (h *PaymentHandler) ProcessRefund(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	refunds, err := h.paymentService.ProcessRefund(id)
+	if err != nil {
+		h.respondWithPaymentError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Refund processed",
+		Data:    refunds,
+	})
+}
+
+// respondWithPaymentError maps payment service errors to HTTP status codes
+func (h *PaymentHandler) respondWithPaymentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPayment), errors.Is(err, service.ErrInvalidListQuery):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrBookingAccessDenied), errors.Is(err, service.ErrPaymentAccessDenied),
+		errors.Is(err, service.ErrTwoFactorSessionRequired):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrPaymentDeclined):
+		respondWithError(w, http.StatusPaymentRequired, err.Error())
+	case errors.Is(err, service.ErrBookingNotPayable), errors.Is(err, service.ErrPaymentExceedsBalance),
+		errors.Is(err, service.ErrPaymentNotPending), errors.Is(err, repository.ErrBookingStatusChanged):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrGatewayTimeout):
+		respondWithError(w, http.StatusGatewayTimeout, err.Error())
+	case err.Error() == "booking not found":
+		respondWithError(w, http.StatusNotFound, "Booking not found")
+	case err.Error() == "payment not found":
+		respondWithError(w, http.StatusNotFound, "Payment not found")
+	default:
+		h.logger.Error("Payment request failed", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
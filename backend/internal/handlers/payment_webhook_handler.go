@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// maxWebhookBodySize limits the size of a webhook delivery
+const maxWebhookBodySize = 1 << 20
+
+// PaymentWebhookHandler handles payment gateway webhook deliveries
+type PaymentWebhookHandler struct {
+	webhookService service.PaymentWebhookService
+	logger         *logger.Logger
+}
+
+// NewPaymentWebhookHandler creates a new payment webhook handler
+func NewPaymentWebhookHandler(webhookService service.PaymentWebhookService, logger *logger.Logger) *PaymentWebhookHandler {
+	return &PaymentWebhookHandler{webhookService: webhookService, logger: logger}
+}
+
+// HandlePaymentWebhook handles POST /api/webhooks/payments/{provider}
+// @Summary Payment gateway webhook
+// @Description Receive a signed event from a payment gateway. Deliveries are verified with the X-Webhook-Signature header and each event is processed once.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Payment gateway name"
+// @Param X-Webhook-Signature header string true "t=<unix time>,v1=<hex HMAC-SHA256 of \"<unix time>.<body>\">"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /webhooks/payments/{provider} [post]
+func (h *PaymentWebhookHandler) HandlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	event, err := h.webhookService.HandleWebhook(provider, payload, r.Header.Get("X-Webhook-Signature"))
+	switch {
+	case err == nil:
+		respondWithJSON(w, http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Event " + string(event.Status),
+			Data:    event,
+		})
+	case errors.Is(err, service.ErrPaymentEventAlreadyKnown):
+		// Acknowledge retried deliveries so the gateway stops sending them
+		respondWithJSON(w, http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Event already processed",
+			Data:    event,
+		})
+	case errors.Is(err, service.ErrUnknownPaymentProvider):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidWebhookSignature):
+		h.logger.Error("Rejected payment webhook from "+provider, err)
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrInvalidWebhookPayload):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		// Any other error is answered with a 5xx so the gateway retries the delivery
+		h.logger.Error("Failed to process payment webhook from "+provider, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process event")
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// PlaceHandler handles the requests of the place hierarchy destinations are browsed by
+type PlaceHandler struct {
+	placeService service.PlaceService
+	logger       *logger.Logger
+}
+
+// NewPlaceHandler creates a new place handler
+func NewPlaceHandler(placeService service.PlaceService, logger *logger.Logger) *PlaceHandler {
+	return &PlaceHandler{placeService: placeService, logger: logger}
+}
+
+// GetPlaces handles GET /api/places
+// @Summary Get places
+// @Description Get a page of the continents, countries, regions and cities, sorted by name by default; filter with filter[field][operator]=value, e.g. filter[level]=continent
+// @Tags Places
+// @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /places [get]
+func (h *PlaceHandler) GetPlaces(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	places, page, err := h.placeService.GetPlaces(query)
+	if err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Places retrieved successfully",
+		Data:       places,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// GetPlace handles GET /api/places/{code}
+// @Summary Get place
+// @Description Get a place by its code with the places above it, from its continent down
+// @Tags Places
+// @Produce json
+// @Param code path string true "Place code"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /places/{code} [get]
+func (h *PlaceHandler) GetPlace(w http.ResponseWriter, r *http.Request) {
+	place, err := h.placeService.GetPlace(mux.Vars(r)["code"])
+	if err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Place retrieved successfully",
+		Data:    place,
+	})
+}
+
+// GetPlaceChildren handles GET /api/places/{code}/children
+// @Summary Get places below a place
+// @Description Get a page of the places right below a place, such as the countries of a continent
+// @Tags Places
+// @Produce json
+// @Param code path string true "Place code"
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /places/{code}/children [get]
+func (h *PlaceHandler) GetPlaceChildren(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	places, page, err := h.placeService.GetChildren(mux.Vars(r)["code"], query)
+	if err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Places retrieved successfully",
+		Data:       places,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// GetPlaceDestinations handles GET /api/places/{code}/destinations
+// @Summary Get destinations of a place
+// @Description Get a page of the destinations of a place and of every place below it, the best rated first by default
+// @Tags Places
+// @Produce json
+// @Param code path string true "Place code"
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /places/{code}/destinations [get]
+func (h *PlaceHandler) GetPlaceDestinations(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	destinations, page, err := h.placeService.GetDestinations(mux.Vars(r)["code"], query)
+	if err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Destinations retrieved successfully",
+		Data:       destinations,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// CreatePlace handles POST /api/admin/places
+// @Summary Add place
+// @Description Add a country, region or city below its parent (Admin only)
+// @Tags Places
+// @Accept json
+// @Produce json
+// @Param request body models.PlaceRequest true "Place request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/places [post]
+func (h *PlaceHandler) CreatePlace(w http.ResponseWriter, r *http.Request) {
+	var req models.PlaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	place, err := h.placeService.CreatePlace(&req)
+	if err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Place created successfully",
+		Data:    place,
+	})
+}
+
+// UpdatePlace handles PUT /api/admin/places/{code}
+// @Summary Update place
+// @Description Rename a place or move it below another parent of the same country (Admin only); its code and level do not change
+// @Tags Places
+// @Accept json
+// @Produce json
+// @Param code path string true "Place code"
+// @Param request body models.PlaceRequest true "Place request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/places/{code} [put]
+func (h *PlaceHandler) UpdatePlace(w http.ResponseWriter, r *http.Request) {
+	var req models.PlaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	place, err := h.placeService.UpdatePlace(mux.Vars(r)["code"], &req)
+	if err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Place updated successfully",
+		Data:    place,
+	})
+}
+
+// DeletePlace handles DELETE /api/admin/places/{code}
+// @Summary Delete place
+// @Description Delete a place that has neither places nor destinations below it (Admin only)
+// @Tags Places
+// @Produce json
+// @Param code path string true "Place code"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/places/{code} [delete]
+func (h *PlaceHandler) DeletePlace(w http.ResponseWriter, r *http.Request) {
+	if err := h.placeService.DeletePlace(mux.Vars(r)["code"]); err != nil {
+		h.respondWithPlaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Place deleted successfully",
+	})
+}
+
+// respondWithPlaceError maps place errors to HTTP status codes
+func (h *PlaceHandler) respondWithPlaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPlace),
+		errors.Is(err, service.ErrInvalidListQuery):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPlaceNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrPlaceExists),
+		errors.Is(err, service.ErrPlaceInUse):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Failed to process place request", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,678 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Limits on document upload requests besides the document itself
+const (
+	maxUploadOverhead = 1 << 20 // bytes of multipart headers and form fields
+	maxUploadMemory   = 1 << 20 // bytes kept in memory before spilling to a temporary file
+)
+
+// ProviderApplicationHandler handles provider application requests
+type ProviderApplicationHandler struct {
+	applicationService service.ProviderApplicationService
+	logger             *logger.Logger
+}
+
+// NewProviderApplicationHandler creates a new provider application handler
+func NewProviderApplicationHandler(applicationService service.ProviderApplicationService, logger *logger.Logger) *ProviderApplicationHandler {
+	return &ProviderApplicationHandler{applicationService: applicationService, logger: logger}
+}
+
+// Apply handles POST /api/provider/applications
+// @Summary Apply to become a provider
+// @Description Submit company details with uploaded documents, including a registration certificate and an identity document. The user becomes a verified provider once staff approve the application.
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param request body models.ProviderApplicationRequest true "Provider application request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/applications [post]
+func (h *ProviderApplicationHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.ProviderApplicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	application, err := h.applicationService.Apply(userID, &req)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Provider application submitted successfully",
+		Data:    application,
+	})
+}
+
+// GetOwnApplications handles GET /api/provider/applications
+// @Summary Get own provider applications
+// @Description Get the provider applications of the authenticated user with their review outcome
+// @Tags ProviderApplications
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /provider/applications [get]
+func (h *ProviderApplicationHandler) GetOwnApplications(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	applications, err := h.applicationService.GetApplicationsByUserID(userID)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider applications retrieved successfully",
+		Data:    applications,
+	})
+}
+
+// GetOwnApplicationByID handles GET /api/provider/applications/{id}
+// @Summary Get own provider application
+// @Description Get a provider application of the authenticated user with its documents
+// @Tags ProviderApplications
+// @Produce json
+// @Param id path int true "Application ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/applications/{id} [get]
+func (h *ProviderApplicationHandler) GetOwnApplicationByID(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	h.getApplication(w, r, userID)
+}
+
+// Resubmit handles PUT /api/provider/applications/{id}
+// @Summary Resubmit provider application
+// @Description Update an application a reviewer asked changes for and put it back in review. The documents left out are detached and can be deleted.
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body models.ProviderApplicationRequest true "Provider application request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/applications/{id} [put]
+func (h *ProviderApplicationHandler) Resubmit(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid application ID")
+		return
+	}
+
+	var req models.ProviderApplicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	application, err := h.applicationService.Resubmit(id, userID, &req)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider application resubmitted successfully",
+		Data:    application,
+	})
+}
+
+// GetOwnApplicationHistory handles GET /api/provider/applications/{id}/history
+// @Summary Get own provider application history
+// @Description Get the status changes of a provider application of the authenticated user, with the reviewer notes
+// @Tags ProviderApplications
+// @Produce json
+// @Param id path int true "Application ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/applications/{id}/history [get]
+func (h *ProviderApplicationHandler) GetOwnApplicationHistory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	h.getApplicationHistory(w, r, userID)
+}
+
+// GetApplications handles GET /api/admin/provider-applications
+// @Summary Get provider applications
+// @Description Get the provider applications, oldest first, optionally filtered by status (providers:review permission)
+// @Tags ProviderApplications
+// @Produce json
+// @Param status query string false "pending, changes_requested, approved, rejected or revoked"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/provider-applications [get]
+func (h *ProviderApplicationHandler) GetApplications(w http.ResponseWriter, r *http.Request) {
+	status := models.ProviderApplicationStatus(r.URL.Query().Get("status"))
+
+	applications, err := h.applicationService.GetApplications(status)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider applications retrieved successfully",
+		Data:    applications,
+	})
+}
+
+// GetApplicationByID handles GET /api/admin/provider-applications/{id}
+// @Summary Get provider application
+// @Description Get a provider application with its documents (providers:review permission)
+// @Tags ProviderApplications
+// @Produce json
+// @Param id path int true "Application ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/provider-applications/{id} [get]
+func (h *ProviderApplicationHandler) GetApplicationByID(w http.ResponseWriter, r *http.Request) {
+	// Get the reviewer from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	h.getApplication(w, r, user.ID)
+}
+
+// GetApplicationHistory handles GET /api/admin/provider-applications/{id}/history
+// @Summary Get provider application history
+// @Description Get the status changes of a provider application with the reviewer notes (providers:review permission)
+// @Tags ProviderApplications
+// @Produce json
+// @Param id path int true "Application ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/provider-applications/{id}/history [get]
+func (h *ProviderApplicationHandler) GetApplicationHistory(w http.ResponseWriter, r *http.Request) {
+	// Get the reviewer from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	h.getApplicationHistory(w, r, user.ID)
+}
+
+// ApproveApplication handles POST /api/admin/provider-applications/{id}/approve
+// @Summary Approve provider application
+// @Description Approve a pending application: the applicant gets the provider role, their company details and the verified flag (providers:review permission)
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body models.ReviewProviderApplicationRequest false "Review request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/provider-applications/{id}/approve [post]
+func (h *ProviderApplicationHandler) ApproveApplication(w http.ResponseWriter, r *http.Request) {
+	h.reviewApplication(w, r, models.ProviderApplicationApproved, "Provider application approved successfully")
+}
+
+// RequestChanges handles POST /api/admin/provider-applications/{id}/request-changes
+// @Summary Request changes to provider application
+// @Description Send a pending application back to the applicant with a note on what to fix (providers:review permission)
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body models.ReviewProviderApplicationRequest true "Review request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/provider-applications/{id}/request-changes [post]
+func (h *ProviderApplicationHandler) RequestChanges(w http.ResponseWriter, r *http.Request) {
+	h.reviewApplication(w, r, models.ProviderApplicationChangesRequested, "Changes requested successfully")
+}
+
+// RejectApplication handles POST /api/admin/provider-applications/{id}/reject
+// @Summary Reject provider application
+// @Description Reject an application in review with a note telling the applicant why (providers:review permission)
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body models.ReviewProviderApplicationRequest true "Review request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/provider-applications/{id}/reject [post]
+func (h *ProviderApplicationHandler) RejectApplication(w http.ResponseWriter, r *http.Request) {
+	h.reviewApplication(w, r, models.ProviderApplicationRejected, "Provider application rejected successfully")
+}
+
+// RevokeApplication handles POST /api/admin/provider-applications/{id}/revoke
+// @Summary Revoke provider verification
+// @Description Withdraw the verification granted by an approved application, with a note telling the provider why. The provider can no longer publish services until a new application is approved (providers:review permission).
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body models.ReviewProviderApplicationRequest true "Review request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/provider-applications/{id}/revoke [post]
+func (h *ProviderApplicationHandler) RevokeApplication(w http.ResponseWriter, r *http.Request) {
+	h.reviewApplication(w, r, models.ProviderApplicationRevoked, "Provider verification revoked successfully")
+}
+
+// UploadDocument handles POST /api/provider/documents
+// @Summary Upload provider document
+// @Description Upload a PDF, JPEG or PNG file of at most 10 MB to attach to a provider application
+// @Tags ProviderApplications
+// @Accept multipart/form-data
+// @Produce json
+// @Param document_type formData string true "registration_certificate, identity_document or other"
+// @Param file formData file true "Document file"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Router /provider/documents [post]
+func (h *ProviderApplicationHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// Leave room for the multipart headers and the other form fields
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxProviderDocumentSize+maxUploadOverhead)
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Document is too large")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	documentType := models.ProviderDocumentType(r.FormValue("document_type"))
+	document, err := h.applicationService.UploadDocument(userID, documentType, header.Filename, file)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Document uploaded successfully",
+		Data:    document,
+	})
+}
+
+// GetDocuments handles GET /api/provider/documents
+// @Summary Get own provider documents
+// @Description Get the documents uploaded by the authenticated user, with the application they are part of
+// @Tags ProviderApplications
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /provider/documents [get]
+func (h *ProviderApplicationHandler) GetDocuments(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	documents, err := h.applicationService.GetDocuments(userID)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Documents retrieved successfully",
+		Data:    documents,
+	})
+}
+
+// DownloadDocument handles GET /api/provider/documents/{id}/file
+// @Summary Download provider document
+// @Description Download the file of a document. Available to its owner and to staff with the providers:review permission.
+// @Tags ProviderApplications
+// @Produce application/pdf,image/jpeg,image/png
+// @Param id path int true "Document ID"
+// @Security Bearer
+// @Success 200 {file} file
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/documents/{id}/file [get]
+func (h *ProviderApplicationHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	document, file, err := h.applicationService.OpenDocument(id, userID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+	defer file.Close()
+
+	// Always download, never render, so an uploaded file cannot run in the API origin
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(document.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": document.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		h.logger.Error("Failed to send provider document", err)
+	}
+}
+
+// DeleteDocument handles DELETE /api/provider/documents/{id}
+// @Summary Delete provider document
+// @Description Delete an uploaded document that is not part of an application
+// @Tags ProviderApplications
+// @Produce json
+// @Param id path int true "Document ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/documents/{id} [delete]
+func (h *ProviderApplicationHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	if err := h.applicationService.DeleteDocument(id, userID); err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Document deleted successfully",
+	})
+}
+
+// GetProviderProfile handles GET /api/provider/profile
+// @Summary Get provider profile
+// @Description Get the business profile of the authenticated user and whether they are a verified provider
+// @Tags ProviderApplications
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /provider/profile [get]
+func (h *ProviderApplicationHandler) GetProviderProfile(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.applicationService.GetProviderProfile(userID)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider profile retrieved successfully",
+		Data:    user,
+	})
+}
+
+// UpdateProviderProfile handles PUT /api/provider/profile
+// @Summary Update provider profile
+// @Description Update the description and website of a verified provider. The company name and address change through a new application.
+// @Tags ProviderApplications
+// @Accept json
+// @Produce json
+// @Param request body models.UpdateProviderProfileRequest true "Provider profile request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/profile [put]
+func (h *ProviderApplicationHandler) UpdateProviderProfile(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header (set by auth middleware)
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateProviderProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user, err := h.applicationService.UpdateProviderProfile(userID, &req)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider profile updated successfully",
+		Data:    user,
+	})
+}
+
+// getApplication responds with an application the requester may see
+func (h *ProviderApplicationHandler) getApplication(w http.ResponseWriter, r *http.Request, requesterID int) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid application ID")
+		return
+	}
+
+	application, err := h.applicationService.GetApplicationByID(id, requesterID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider application retrieved successfully",
+		Data:    application,
+	})
+}
+
+// getApplicationHistory responds with the status changes of an application the requester may see
+func (h *ProviderApplicationHandler) getApplicationHistory(w http.ResponseWriter, r *http.Request, requesterID int) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid application ID")
+		return
+	}
+
+	history, err := h.applicationService.GetApplicationHistory(id, requesterID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Provider application history retrieved successfully",
+		Data:    history,
+	})
+}
+
+// reviewApplication moves an application to the status decided by the reviewer in the request context
+func (h *ProviderApplicationHandler) reviewApplication(w http.ResponseWriter, r *http.Request,
+	status models.ProviderApplicationStatus, message string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid application ID")
+		return
+	}
+
+	// Get the reviewer from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	// The body is optional when approving
+	var req models.ReviewProviderApplicationRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	application, err := h.applicationService.UpdateApplicationStatus(id, status, user.ID, req.Note)
+	if err != nil {
+		h.respondWithApplicationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    application,
+	})
+}
+
+// respondWithApplicationError maps provider application and document errors to HTTP status codes
+func (h *ProviderApplicationHandler) respondWithApplicationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProviderApplication),
+		errors.Is(err, service.ErrInvalidProviderDocument):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrProviderApplicationAccessDenied),
+		errors.Is(err, service.ErrProviderNotVerified),
+		errors.Is(err, service.ErrTwoFactorSessionRequired):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrProviderApplicationNotFound),
+		errors.Is(err, service.ErrProviderDocumentNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrProviderApplicationPending),
+		errors.Is(err, service.ErrIllegalProviderApplicationTransition),
+		errors.Is(err, service.ErrAlreadyVerifiedProvider),
+		errors.Is(err, service.ErrProviderDocumentAttached):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Failed to process provider application", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
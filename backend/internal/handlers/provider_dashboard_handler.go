@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+	"strconv"
+	"time"
+)
+
+// ProviderDashboardHandler handles provider dashboard requests
+type ProviderDashboardHandler struct {
+	dashboardService service.ProviderDashboardService
+	logger           *logger.Logger
+}
+
+// NewProviderDashboardHandler creates a new provider dashboard handler
+func NewProviderDashboardHandler(dashboardService service.ProviderDashboardService, logger *logger.Logger) *ProviderDashboardHandler {
+	return &ProviderDashboardHandler{dashboardService: dashboardService, logger: logger}
+}
+
+// GetBookings handles GET /api/provider/dashboard/bookings
+// @Summary Get bookings of own services
+// @Description Get the bookings of the services of the authenticated provider, the most recent first, with the guest and the amount paid
+// @Tags ProviderDashboard
+// @Produce json
+// @Param status query string false "Booking status"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/dashboard/bookings [get]
+func (h *ProviderDashboardHandler) GetBookings(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	status := models.BookingStatus(r.URL.Query().Get("status"))
+	bookings, err := h.dashboardService.GetBookings(user, status)
+	if err != nil {
+		h.respondWithDashboardError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Bookings retrieved successfully",
+		Data:    bookings,
+	})
+}
+
+// GetCheckIns handles GET /api/provider/dashboard/check-ins
+// @Summary Get upcoming check-ins
+// @Description Get the confirmed bookings of the services of the authenticated provider starting within the next days, the earliest first
+// @Tags ProviderDashboard
+// @Produce json
+// @Param days query int false "Number of days ahead, 7 by default and at most 90"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/dashboard/check-ins [get]
+func (h *ProviderDashboardHandler) GetCheckIns(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	days := 0
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'days' parameter")
+			return
+		}
+	}
+
+	bookings, err := h.dashboardService.GetUpcomingCheckIns(user, days)
+	if err != nil {
+		h.respondWithDashboardError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Upcoming check-ins retrieved successfully",
+		Data:    bookings,
+	})
+}
+
+// GetRevenue handles GET /api/provider/dashboard/revenue
+// @Summary Get revenue
+// @Description Get the payments received and refunds given for the bookings of the services of the authenticated provider, per day, week or month
+// @Tags ProviderDashboard
+// @Produce json
+// @Param period query string false "day (default), week or month"
+// @Param from query string false "Start of the window, RFC3339 or YYYY-MM-DD (defaults to 30 days before 'to')"
+// @Param to query string false "End of the window, RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/dashboard/revenue [get]
+func (h *ProviderDashboardHandler) GetRevenue(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	from, to, ok := parseDashboardWindow(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.dashboardService.GetRevenue(user, r.URL.Query().Get("period"), from, to)
+	if err != nil {
+		h.respondWithDashboardError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Revenue retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetOccupancy handles GET /api/provider/dashboard/occupancy
+// @Summary Get occupancy
+// @Description Get, for each service of the authenticated provider, the share of its capacity held by confirmed, checked-in and completed bookings
+// @Tags ProviderDashboard
+// @Produce json
+// @Param from query string false "Start of the window, RFC3339 or YYYY-MM-DD (defaults to 30 days before 'to')"
+// @Param to query string false "End of the window, RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/dashboard/occupancy [get]
+func (h *ProviderDashboardHandler) GetOccupancy(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	from, to, ok := parseDashboardWindow(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.dashboardService.GetOccupancy(user, from, to)
+	if err != nil {
+		h.respondWithDashboardError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Occupancy retrieved successfully",
+		Data:    report,
+	})
+}
+
+// GetCancellations handles GET /api/provider/dashboard/cancellations
+// @Summary Get cancellation rate
+// @Description Get how many of the bookings made for the services of the authenticated provider were cancelled by the guest or declined
+// @Tags ProviderDashboard
+// @Produce json
+// @Param from query string false "Start of the window, RFC3339 or YYYY-MM-DD (defaults to 30 days before 'to')"
+// @Param to query string false "End of the window, RFC3339 or YYYY-MM-DD (defaults to now)"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/dashboard/cancellations [get]
+func (h *ProviderDashboardHandler) GetCancellations(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	from, to, ok := parseDashboardWindow(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.dashboardService.GetCancellations(user, from, to)
+	if err != nil {
+		h.respondWithDashboardError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Cancellation rate retrieved successfully",
+		Data:    report,
+	})
+}
+
+// parseDashboardWindow reads the optional from and to query parameters, responding with an
+// error when one is invalid. Zero times are filled in by the service.
+func parseDashboardWindow(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	var err error
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'from' date")
+			return from, to, false
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'to' date")
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// respondWithDashboardError maps provider dashboard errors to HTTP status codes
+func (h *ProviderDashboardHandler) respondWithDashboardError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDashboardQuery):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTeamAccessDenied):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	default:
+		h.logger.Error("Failed to get provider dashboard", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ProviderTeamHandler handles provider team requests
+type ProviderTeamHandler struct {
+	teamService service.ProviderTeamService
+	logger      *logger.Logger
+}
+
+// NewProviderTeamHandler creates a new provider team handler
+func NewProviderTeamHandler(teamService service.ProviderTeamService, logger *logger.Logger) *ProviderTeamHandler {
+	return &ProviderTeamHandler{teamService: teamService, logger: logger}
+}
+
+// GetTeam handles GET /api/provider/team
+// @Summary Get own team
+// @Description Get the organisation of the authenticated provider with its staff members and pending invitations
+// @Tags ProviderTeams
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/team [get]
+func (h *ProviderTeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	team, err := h.teamService.GetTeam(user)
+	if err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Team retrieved successfully",
+		Data:    team,
+	})
+}
+
+// InviteMember handles POST /api/provider/team/invitations
+// @Summary Invite team member
+// @Description Email an invitation to join the team of the authenticated provider with an organisation role: manager, front_desk or accountant
+// @Tags ProviderTeams
+// @Accept json
+// @Produce json
+// @Param request body models.InviteTeamMemberRequest true "Invite team member request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/team/invitations [post]
+func (h *ProviderTeamHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.InviteTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	invitation, err := h.teamService.InviteMember(user, &req)
+	if err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Invitation sent successfully",
+		Data:    invitation,
+	})
+}
+
+// RevokeInvitation handles DELETE /api/provider/team/invitations/{id}
+// @Summary Revoke team invitation
+// @Description Withdraw an invitation of the team of the authenticated provider that was not accepted yet
+// @Tags ProviderTeams
+// @Produce json
+// @Param id path int true "Invitation ID"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/team/invitations/{id} [delete]
+func (h *ProviderTeamHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	if err := h.teamService.RevokeInvitation(user, id); err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Invitation revoked successfully",
+	})
+}
+
+// UpdateMember handles PUT /api/provider/team/members/{userId}
+// @Summary Change team member role
+// @Description Change the organisation role of a staff member of the team of the authenticated provider
+// @Tags ProviderTeams
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID of the staff member"
+// @Param request body models.UpdateTeamMemberRequest true "Update team member request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/team/members/{userId} [put]
+func (h *ProviderTeamHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	memberID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.UpdateTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	member, err := h.teamService.UpdateMemberRole(user, memberID, req.Role)
+	if err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Team member updated successfully",
+		Data:    member,
+	})
+}
+
+// RemoveMember handles DELETE /api/provider/team/members/{userId}
+// @Summary Remove team member
+// @Description Remove a staff member from the team of the authenticated provider, who immediately loses access
+// @Tags ProviderTeams
+// @Produce json
+// @Param userId path int true "User ID of the staff member"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/team/members/{userId} [delete]
+func (h *ProviderTeamHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	memberID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	if err := h.teamService.RemoveMember(user, memberID); err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Team member removed successfully",
+	})
+}
+
+// AcceptInvitation handles POST /api/provider/team/invitations/accept
+// @Summary Accept team invitation
+// @Description Join the team of a provider with the token of an invitation sent to the verified email address of the authenticated user
+// @Tags ProviderTeams
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptTeamInvitationRequest true "Accept team invitation request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /provider/team/invitations/accept [post]
+func (h *ProviderTeamHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.AcceptTeamInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	membership, err := h.teamService.AcceptInvitation(user, req.Token)
+	if err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Invitation accepted successfully",
+		Data:    membership,
+	})
+}
+
+// GetMembership handles GET /api/provider/team/membership
+// @Summary Get own team membership
+// @Description Get the provider organisation the authenticated user works for and their role in it
+// @Tags ProviderTeams
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/team/membership [get]
+func (h *ProviderTeamHandler) GetMembership(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	membership, err := h.teamService.GetMembership(user.ID)
+	if err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Team membership retrieved successfully",
+		Data:    membership,
+	})
+}
+
+// LeaveTeam handles DELETE /api/provider/team/membership
+// @Summary Leave team
+// @Description Leave the provider organisation the authenticated user works for
+// @Tags ProviderTeams
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /provider/team/membership [delete]
+func (h *ProviderTeamHandler) LeaveTeam(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	if err := h.teamService.LeaveOrganisation(user.ID); err != nil {
+		h.respondWithTeamError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Team left successfully",
+	})
+}
+
+// respondWithTeamError maps provider team errors to HTTP status codes
+func (h *ProviderTeamHandler) respondWithTeamError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTeamInvitation):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTeamEmailNotVerified),
+		errors.Is(err, service.ErrTeamAccessDenied):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrTeamInvitationNotFound),
+		errors.Is(err, service.ErrTeamMemberNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrAlreadyTeamMember):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Failed to process provider team request", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// RoleHandler handles role and permission management requests
+type RoleHandler struct {
+	roleService service.RoleService
+	logger      *logger.Logger
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(roleService service.RoleService, logger *logger.Logger) *RoleHandler {
+	return &RoleHandler{roleService: roleService, logger: logger}
+}
+
+// GetPermissions handles GET /api/admin/permissions
+// @Summary Get permissions
+// @Description Get every permission that roles can grant (roles:manage permission)
+// @Tags Roles
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/permissions [get]
+func (h *RoleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.roleService.GetPermissions()
+	if err != nil {
+		h.logger.Error("Failed to get permissions", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Permissions retrieved successfully",
+		Data:    permissions,
+	})
+}
+
+// GetRoles handles GET /api/admin/roles
+// @Summary Get roles
+// @Description Get every role with the permissions it grants (roles:manage permission)
+// @Tags Roles
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/roles [get]
+func (h *RoleHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.GetRoles()
+	if err != nil {
+		h.logger.Error("Failed to get roles", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Roles retrieved successfully",
+		Data:    roles,
+	})
+}
+
+// GetRole handles GET /api/admin/roles/{name}
+// @Summary Get role
+// @Description Get a role with the permissions it grants (roles:manage permission)
+// @Tags Roles
+// @Produce json
+// @Param name path string true "Role name"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/roles/{name} [get]
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	role, err := h.roleService.GetRole(models.UserRole(mux.Vars(r)["name"]))
+	if err != nil {
+		h.respondWithRoleError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Role retrieved successfully",
+		Data:    role,
+	})
+}
+
+// CreateRole handles POST /api/admin/roles
+// @Summary Create role
+// @Description Create a role that grants a set of permissions (roles:manage permission)
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param request body models.RoleRequest true "Role request"
+// @Security Bearer
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/roles [post]
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	role, err := h.roleService.CreateRole(requester, &req)
+	if err != nil {
+		h.respondWithRoleError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Role created successfully",
+		Data:    role,
+	})
+}
+
+// UpdateRole handles PUT /api/admin/roles/{name}
+// @Summary Update role
+// @Description Replace the description and the permissions of a role. Built-in roles cannot be changed (roles:manage permission)
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param name path string true "Role name"
+// @Param request body models.RoleRequest true "Role request"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/roles/{name} [put]
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(requester, models.UserRole(mux.Vars(r)["name"]), &req)
+	if err != nil {
+		h.respondWithRoleError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Role updated successfully",
+		Data:    role,
+	})
+}
+
+// DeleteRole handles DELETE /api/admin/roles/{name}
+// @Summary Delete role
+// @Description Delete a role that is no longer assigned to any user. Built-in roles cannot be deleted (roles:manage permission)
+// @Tags Roles
+// @Produce json
+// @Param name path string true "Role name"
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/roles/{name} [delete]
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if err := h.roleService.DeleteRole(models.UserRole(mux.Vars(r)["name"])); err != nil {
+		h.respondWithRoleError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Role deleted successfully",
+	})
+}
+
+// respondWithRoleError maps role management errors to HTTP status codes
+func (h *RoleHandler) respondWithRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRole):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrBuiltInRole), errors.Is(err, service.ErrRoleEscalation):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrRoleNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRoleExists), errors.Is(err, service.ErrRoleInUse):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Failed to manage role", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
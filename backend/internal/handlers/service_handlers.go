@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/service"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -24,30 +27,247 @@ func NewServiceHandler(serviceService service.ServiceService, logger *logger.Log
 
 // GetAllServices handles GET /api/services
 // @Summary Get all services
-// @Description Get all available services, optionally filtered by category
+// @Description Get a page of the available services with their accommodation details, sorted by name by default and optionally filtered by category, guests, bed type, amenities and filter[field][operator]=value
 // @Tags Services
 // @Produce json
 // @Param category query string false "Service category"
+// @Param guests query int false "A room type must sleep at least this many guests"
+// @Param bed_type query string false "A room type must have a bed of this type: single, double, queen, king, sofa_bed or bunk"
+// @Param amenities query string false "Comma-separated amenity codes the accommodation must all have"
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /services [get]
 func (h *ServiceHandler) GetAllServices(w http.ResponseWriter, r *http.Request) {
-	category := r.URL.Query().Get("category")
+	filter, err := serviceFilterFromQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	services, page, err := h.serviceService.SearchServices(filter, query)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Services retrieved successfully",
+		Data:       services,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// GetDestinationServices handles GET /api/destinations/{id}/services
+// @Summary Get services in a destination
+// @Description Get a page of the available services located inside the area of a destination or, when it has none, within a radius of it, sorted by name by default
+// @Tags Services
+// @Produce json
+// @Param id path int true "Destination ID"
+// @Param radius_km query number false "Radius around a destination without an area, 25 km by default and at most 500"
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /destinations/{id}/services [get]
+func (h *ServiceHandler) GetDestinationServices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid destination ID")
+		return
+	}
+
+	var radiusKm float64
+	if value := r.URL.Query().Get("radius_km"); value != "" {
+		if radiusKm, err = strconv.ParseFloat(value, 64); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'radius_km' parameter")
+			return
+		}
+	}
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	services, page, err := h.serviceService.GetServicesInDestination(id, radiusKm, query)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success:    true,
+		Message:    "Services retrieved successfully",
+		Data:       services,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
+	})
+}
+
+// SearchServices handles GET /api/services/search
+// @Summary Search services
+// @Description Full-text search of the available services by name and description with filters, sorting and the facet counts of every matching service
+// @Tags Services
+// @Produce json
+// @Param q query string false "Search words, matched against the name and description"
+// @Param service_type_id query int false "Service type ID"
+// @Param category query string false "Service category"
+// @Param min_price query number false "Minimum base price"
+// @Param max_price query number false "Maximum base price"
+// @Param destination_id query int false "Destination ID"
+// @Param from query string false "Start of a window the service must have a free slot in (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "End of a window the service must have a free slot in (RFC3339 or YYYY-MM-DD)"
+// @Param min_rating query number false "Minimum rating, from 0 to 5"
+// @Param guests query int false "A room type must sleep at least this many guests"
+// @Param bed_type query string false "A room type must have a bed of this type: single, double, queen, king, sofa_bed or bunk"
+// @Param amenities query string false "Comma-separated amenity codes the accommodation must all have"
+// @Param sort query string false "relevance (default with q), name (default without q), price_asc, price_desc, rating or newest"
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /services/search [get]
+func (h *ServiceHandler) SearchServices(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := serviceFilterFromQuery(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	search := models.ServiceSearchQuery{
+		ServiceFilter: filter,
+		Text:          query.Get("q"),
+		Sort:          models.ServiceSort(query.Get("sort")),
+	}
 
-	var services []models.Service
-	var err error
+	ints := []struct {
+		name   string
+		target *int
+	}{
+		{"service_type_id", &search.ServiceTypeID},
+		{"destination_id", &search.DestinationID},
+		{"limit", &search.Limit},
+		{"offset", &search.Offset},
+	}
+	for _, param := range ints {
+		if value := query.Get(param.name); value != "" {
+			if *param.target, err = strconv.Atoi(value); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid '"+param.name+"' parameter")
+				return
+			}
+		}
+	}
 
-	if category != "" {
-		services, err = h.serviceService.GetServicesByCategory(category)
-	} else {
-		services, err = h.serviceService.GetAllServices()
+	if value := query.Get("min_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'min_price' parameter")
+			return
+		}
+		search.MinPrice = &price
+	}
+	if value := query.Get("max_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'max_price' parameter")
+			return
+		}
+		search.MaxPrice = &price
+	}
+	if value := query.Get("min_rating"); value != "" {
+		if search.MinRating, err = strconv.ParseFloat(value, 64); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'min_rating' parameter")
+			return
+		}
+	}
+	if value := query.Get("from"); value != "" {
+		if search.From, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'from' date")
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if search.To, err = parseTimeParam(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'to' date")
+			return
+		}
 	}
 
+	result, err := h.serviceService.SearchCatalogue(search)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Services retrieved successfully",
+		Data:    result,
+	})
+}
+
+// GetAmenities handles GET /api/amenities
+// @Summary Get amenities
+// @Description Get the amenity catalogue accommodation details choose from
+// @Tags Services
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /amenities [get]
+func (h *ServiceHandler) GetAmenities(w http.ResponseWriter, r *http.Request) {
+	amenities, err := h.serviceService.GetAmenities()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Amenities retrieved successfully",
+		Data:    amenities,
+	})
+}
+
+// GetProviderServices handles GET /api/provider/services
+// @Summary Get own services
+// @Description Get the services owned by the authenticated provider, including unavailable ones
+// @Tags Services
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /provider/services [get]
+func (h *ServiceHandler) GetProviderServices(w http.ResponseWriter, r *http.Request) {
+	// Get the provider from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	services, err := h.serviceService.GetServicesByOwner(user)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Services retrieved successfully",
@@ -66,7 +286,7 @@ func (h *ServiceHandler) GetServiceByID(w http.ResponseWriter, r *http.Request)
 
 	service, err := h.serviceService.GetServiceByID(id)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -79,6 +299,13 @@ func (h *ServiceHandler) GetServiceByID(w http.ResponseWriter, r *http.Request)
 
 // CreateService handles POST /api/services
 func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
+	// Get the publisher from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
 	var req models.CreateServiceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
@@ -86,15 +313,22 @@ func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 	}
 
 	service := &models.Service{
-		ServiceTypeID: req.ServiceTypeID,
-		Name:          req.Name,
-		Description:   req.Description,
-		Price:         req.Price,
-		Availability:  req.Availability,
+		ServiceTypeID:        req.ServiceTypeID,
+		Name:                 req.Name,
+		Description:          req.Description,
+		Price:                req.Price,
+		Availability:         req.Availability,
+		Capacity:             req.Capacity,
+		CancellationPolicyID: req.CancellationPolicyID,
+		DestinationID:        req.DestinationID,
+		Latitude:             req.Latitude,
+		Longitude:            req.Longitude,
+		Accommodation:        accommodationFromRequest(req.Accommodation),
+		CarRental:            carRentalFromRequest(req.CarRental),
 	}
 
-	if err := h.serviceService.CreateService(service); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := h.serviceService.CreateService(service, user); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -114,6 +348,13 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get the publisher from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
 	var req models.UpdateServiceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
@@ -121,15 +362,22 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 	}
 
 	service := &models.Service{
-		ID:            id,
-		ServiceTypeID: req.ServiceTypeID,
-		Name:          req.Name,
-		Description:   req.Description,
-		Price:         req.Price,
-		Availability:  req.Availability,
-	}
-	if err := h.serviceService.UpdateService(service); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		ID:                   id,
+		ServiceTypeID:        req.ServiceTypeID,
+		Name:                 req.Name,
+		Description:          req.Description,
+		Price:                req.Price,
+		Availability:         req.Availability,
+		Capacity:             req.Capacity,
+		CancellationPolicyID: req.CancellationPolicyID,
+		DestinationID:        req.DestinationID,
+		Latitude:             req.Latitude,
+		Longitude:            req.Longitude,
+		Accommodation:        accommodationFromRequest(req.Accommodation),
+		CarRental:            carRentalFromRequest(req.CarRental),
+	}
+	if err := h.serviceService.UpdateService(service, user); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -149,8 +397,15 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.serviceService.DeleteService(id); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	// Get the requester from context (set by role middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "User not found in context")
+		return
+	}
+
+	if err := h.serviceService.DeleteService(id, user); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -159,3 +414,117 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 		Message: "Service deleted successfully",
 	})
 }
+
+// accommodationFromRequest converts the accommodation details of a service request, which
+// name amenities by code
+func accommodationFromRequest(req *models.AccommodationRequest) *models.AccommodationDetails {
+	if req == nil {
+		return nil
+	}
+
+	details := &models.AccommodationDetails{
+		PropertyAddress: req.PropertyAddress,
+		CheckInTime:     req.CheckInTime,
+		CheckOutTime:    req.CheckOutTime,
+		RoomTypes:       make([]models.RoomType, len(req.RoomTypes)),
+		Amenities:       make([]models.Amenity, len(req.Amenities)),
+	}
+	for i, room := range req.RoomTypes {
+		details.RoomTypes[i] = models.RoomType{
+			Name:        room.Name,
+			Description: room.Description,
+			MaxGuests:   room.MaxGuests,
+			Quantity:    room.Quantity,
+			Beds:        room.Beds,
+		}
+	}
+	for i, code := range req.Amenities {
+		details.Amenities[i] = models.Amenity{Code: code}
+	}
+	return details
+}
+
+// carRentalFromRequest converts the car rental details of a service request, which the
+// service layer validates. Locations are used for both pickup and dropoff by default.
+func carRentalFromRequest(req *models.CarRentalRequest) *models.CarRentalDetails {
+	if req == nil {
+		return nil
+	}
+
+	details := &models.CarRentalDetails{
+		MinDriverAge:   req.MinDriverAge,
+		Locations:      make([]models.RentalLocation, len(req.Locations)),
+		VehicleClasses: make([]models.VehicleClass, len(req.VehicleClasses)),
+	}
+	for i, location := range req.Locations {
+		details.Locations[i] = models.RentalLocation{
+			ID:        location.ID,
+			Name:      location.Name,
+			Address:   location.Address,
+			IsPickup:  location.IsPickup == nil || *location.IsPickup,
+			IsDropoff: location.IsDropoff == nil || *location.IsDropoff,
+		}
+	}
+	for i, class := range req.VehicleClasses {
+		details.VehicleClasses[i] = models.VehicleClass{
+			ID:             class.ID,
+			Name:           class.Name,
+			Description:    class.Description,
+			DailyRate:      class.DailyRate,
+			MileageLimitKm: class.MileageLimitKm,
+			ExtraKmFee:     class.ExtraKmFee,
+		}
+	}
+	return details
+}
+
+// serviceFilterFromQuery reads the category and accommodation criteria of a service filter
+// from query parameters
+func serviceFilterFromQuery(query url.Values) (models.ServiceFilter, error) {
+	filter := models.ServiceFilter{
+		Category: query.Get("category"),
+		BedType:  models.BedType(query.Get("bed_type")),
+	}
+	if value := query.Get("guests"); value != "" {
+		guests, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid 'guests' parameter")
+		}
+		filter.Guests = guests
+	}
+	if value := query.Get("amenities"); value != "" {
+		seen := make(map[string]bool)
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.ToLower(strings.TrimSpace(code)); code != "" && !seen[code] {
+				seen[code] = true
+				filter.Amenities = append(filter.Amenities, code)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// respondWithServiceError maps service catalogue errors to HTTP status codes
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCancellationPolicy),
+		errors.Is(err, service.ErrInvalidServiceQuery),
+		errors.Is(err, service.ErrInvalidListQuery),
+		errors.Is(err, service.ErrInvalidAccommodation),
+		errors.Is(err, service.ErrInvalidCarRental),
+		errors.Is(err, service.ErrInvalidServiceDestination),
+		errors.Is(err, service.ErrInvalidServiceLocation):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrProviderNotVerified),
+		errors.Is(err, service.ErrServiceAccessDenied),
+		errors.Is(err, service.ErrTeamAccessDenied):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrServiceNotFound),
+		errors.Is(err, service.ErrDestinationNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrDestinationNotLocated):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
@@ -45,16 +46,30 @@ func (h *ServiceTypeHandler) respondWithJSON(w http.ResponseWriter, code int, pa
 
 // GetAllServiceTypes handles GET /api/service-types
 // @Summary Get all service types
-// @Description Get all available service types from the database
+// @Description Get a page of service types, sorted by name by default; filter with filter[field][operator]=value
 // @Tags ServiceTypes
 // @Produce json
+// @Param cursor query string false "Cursor of the page, the next_cursor of the previous page"
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param sort query string false "Field to sort by, prefixed with - for descending order"
 // @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /service-types [get]
 func (h *ServiceTypeHandler) GetAllServiceTypes(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Fetching all service types from database")
 
-	serviceTypes, err := h.serviceTypeService.GetAllServiceTypes()
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	serviceTypes, page, err := h.serviceTypeService.GetAllServiceTypes(query)
+	if errors.Is(err, service.ErrInvalidListQuery) {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to get service types", err)
 		h.respondWithError(w, http.StatusInternalServerError, "Failed to retrieve service types")
@@ -63,9 +78,11 @@ func (h *ServiceTypeHandler) GetAllServiceTypes(w http.ResponseWriter, r *http.R
 
 	h.logger.Info("Successfully retrieved service types from database")
 	h.respondWithJSON(w, http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Service types retrieved successfully",
-		Data:    serviceTypes,
+		Success:    true,
+		Message:    "Service types retrieved successfully",
+		Data:       serviceTypes,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
 	})
 }
 
@@ -130,6 +147,7 @@ func (h *ServiceTypeHandler) CreateServiceType(w http.ResponseWriter, r *http.Re
 	serviceType := &models.ServiceType{
 		Name:        req.Name,
 		Description: req.Description,
+		PricingUnit: req.PricingUnit,
 	}
 
 	if err := h.serviceTypeService.CreateServiceType(serviceType); err != nil {
@@ -181,6 +199,7 @@ func (h *ServiceTypeHandler) UpdateServiceType(w http.ResponseWriter, r *http.Re
 		ID:          id,
 		Name:        req.Name,
 		Description: req.Description,
+		PricingUnit: req.PricingUnit,
 	}
 
 	if err := h.serviceTypeService.UpdateServiceType(serviceType); err != nil {
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/service"
+	"strconv"
+)
+
+// TwoFactorHandler handles two-factor authentication enrolment requests
+type TwoFactorHandler struct {
+	twoFactorService service.TwoFactorService
+	logger           *logger.Logger
+}
+
+// NewTwoFactorHandler creates a new two-factor handler
+func NewTwoFactorHandler(twoFactorService service.TwoFactorService, logger *logger.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorService: twoFactorService, logger: logger}
+}
+
+// GetStatus handles getting the two-factor authentication status of the current user
+// @Summary Get two-factor status
+// @Description Get whether two-factor authentication is enabled or required for the current user
+// @Tags Two-Factor Authentication
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/2fa [get]
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	status, err := h.twoFactorService.GetStatus(userID)
+	if err != nil {
+		h.logger.Error("Failed to get two-factor status", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get two-factor status")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor status retrieved successfully",
+		Data:    status,
+	})
+}
+
+// Setup handles starting the enrolment of an authenticator app
+// @Summary Set up two-factor authentication
+// @Description Generate a TOTP secret and its otpauth:// provisioning URI to show as a QR code. Two-factor authentication is enabled once a code is confirmed.
+// @Tags Two-Factor Authentication
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.APIResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	setup, err := h.twoFactorService.Setup(userID)
+	if err != nil {
+		h.respondWithTwoFactorError(w, err, "Failed to set up two-factor authentication")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Scan the QR code with your authenticator app, then confirm a code to enable two-factor authentication",
+		Data:    setup,
+	})
+}
+
+// Enable handles finishing the enrolment with a code from the authenticator app
+// @Summary Enable two-factor authentication
+// @Description Confirm a TOTP code from the authenticator app. Returns recovery codes, which are only shown once.
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.TwoFactorCodeRequest true "Authentication code"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(userID, r.Header.Get("X-Session-ID"), req.Code)
+	if err != nil {
+		h.respondWithTwoFactorError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication enabled. Store your recovery codes somewhere safe.",
+		Data:    codes,
+	})
+}
+
+// Disable handles turning two-factor authentication off
+// @Summary Disable two-factor authentication
+// @Description Turn two-factor authentication off with a TOTP code or a recovery code. Not allowed when the role of the user requires it.
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.TwoFactorCodeRequest true "Authentication code"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.twoFactorService.Disable(userID, req.Code, clientInfo(r)); err != nil {
+		h.respondWithTwoFactorError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes handles replacing the recovery codes of the current user
+// @Summary Regenerate recovery codes
+// @Description Replace the recovery codes after confirming a TOTP code or a recovery code. The old codes stop working.
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.TwoFactorCodeRequest true "Authentication code"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code, clientInfo(r))
+	if err != nil {
+		h.respondWithTwoFactorError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Recovery codes regenerated. Store them somewhere safe.",
+		Data:    codes,
+	})
+}
+
+// respondWithTwoFactorError maps two-factor service errors to HTTP responses
+func (h *TwoFactorHandler) respondWithTwoFactorError(w http.ResponseWriter, err error, message string) {
+	if respondWithThrottle(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode), errors.Is(err, service.ErrTwoFactorNotSetUp),
+		errors.Is(err, service.ErrTwoFactorNotEnabled):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTwoFactorRequiredByPolicy):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error(message, err)
+		respondWithError(w, http.StatusInternalServerError, message)
+	}
+}
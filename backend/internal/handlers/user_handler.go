@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
@@ -27,7 +28,17 @@ func NewUserHandler(userService service.UserService, logger *logger.Logger) *Use
 
 // GetAllUsers handles GET /api/admin/users (Admin only)
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.userService.GetAllUsers()
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, page, err := h.userService.GetAllUsers(query)
+	if errors.Is(err, service.ErrInvalidListQuery) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to get all users", err)
 		http.Error(w, "Failed to get users", http.StatusInternalServerError)
@@ -36,9 +47,11 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.APIResponse{
-		Success: true,
-		Message: "Users retrieved successfully",
-		Data:    users,
+		Success:    true,
+		Message:    "Users retrieved successfully",
+		Data:       users,
+		NextCursor: page.NextCursor,
+		Total:      &page.Total,
 	})
 }
 
@@ -48,13 +61,13 @@ func (h *UserHandler) GetUsersByRole(w http.ResponseWriter, r *http.Request) {
 	roleStr := vars["role"]
 
 	role := models.UserRole(roleStr)
-	if !role.IsValid() {
-		http.Error(w, "Invalid role", http.StatusBadRequest)
-		return
-	}
 
 	users, err := h.userService.GetUsersByRole(role)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
 		h.logger.Error("Failed to get users by role", err)
 		http.Error(w, "Failed to get users", http.StatusInternalServerError)
 		return
@@ -70,6 +83,12 @@ func (h *UserHandler) GetUsersByRole(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUserRole handles PUT /api/admin/users/{id}/role (Admin only)
 func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusInternalServerError)
+		return
+	}
+
 	vars := mux.Vars(r)
 	userID, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -86,12 +105,19 @@ func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !req.Role.IsValid() {
-		http.Error(w, "Invalid role", http.StatusBadRequest)
-		return
-	}
-
-	if err := h.userService.UpdateUserRole(userID, req.Role); err != nil {
+	if err := h.userService.UpdateUserRole(requester, userID, req.Role); err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrRoleEscalation) || errors.Is(err, service.ErrOwnRole) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err.Error() == "user not found" {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Error("Failed to update user role", err)
 		http.Error(w, "Failed to update user role", http.StatusInternalServerError)
 		return
@@ -115,7 +141,6 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Clear sensitive data
 	user.Password = ""
-	user.VerificationCode = ""
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.APIResponse{
@@ -158,7 +183,6 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Clear sensitive data
 	user.Password = ""
-	user.VerificationCode = ""
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.APIResponse{
@@ -25,6 +25,25 @@ func NewRoleMiddleware(authService service.AuthService, userService service.User
 
 // RequireRole middleware that checks if user has the required role
 func (rm *RoleMiddleware) RequireRole(allowedRoles ...models.UserRole) func(http.Handler) http.Handler {
+	return rm.authorize(func(user *models.User) bool {
+		for _, allowedRole := range allowedRoles {
+			if user.HasRole(allowedRole) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequirePermission middleware that checks if the role of the user grants a permission
+func (rm *RoleMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return rm.authorize(func(user *models.User) bool {
+		return user.HasPermission(permission)
+	})
+}
+
+// authorize authenticates the request and lets it through when allowed accepts the user
+func (rm *RoleMiddleware) authorize(allowed func(user *models.User) bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get the token from Authorization header
@@ -43,31 +62,28 @@ func (rm *RoleMiddleware) RequireRole(allowedRoles ...models.UserRole) func(http
 
 			token := parts[1]
 
-			// Validate token and get user ID
-			userID, err := rm.authService.ValidateToken(token)
+			// Validate token and get its session
+			session, err := rm.authService.ValidateSession(token)
 			if err != nil {
 				rm.unauthorizedResponse(w, "Invalid token")
 				return
 			}
 
 			// Get user details to check role
-			user, err := rm.userService.GetUserByID(userID)
+			user, err := rm.userService.GetUserByID(session.UserID)
 			if err != nil {
 				rm.unauthorizedResponse(w, "User not found")
 				return
 			}
 
-			// Check if user has any of the allowed roles
-			hasPermission := false
-			for _, allowedRole := range allowedRoles {
-				if user.HasRole(allowedRole) {
-					hasPermission = true
-					break
-				}
+			if !allowed(user) {
+				rm.forbiddenResponse(w, "Insufficient permissions")
+				return
 			}
 
-			if !hasPermission {
-				rm.forbiddenResponse(w, "Insufficient permissions")
+			// Refuse sessions that skipped the second factor when the role requires it
+			if rm.authService.TwoFactorRequired(user.Role) && !session.TwoFactorVerified {
+				rm.forbiddenResponse(w, "Two-factor authentication required: enable it or login again with your authentication code")
 				return
 			}
 
@@ -93,9 +109,9 @@ func (rm *RoleMiddleware) RequireAdminOrProvider() func(http.Handler) http.Handl
 	return rm.RequireRole(models.RoleAdmin, models.RoleProvider)
 }
 
-// RequireAnyRole middleware that allows any authenticated user
+// RequireAnyRole middleware that allows any authenticated user, whatever their role
 func (rm *RoleMiddleware) RequireAnyRole() func(http.Handler) http.Handler {
-	return rm.RequireRole(models.RoleUser, models.RoleAdmin, models.RoleProvider)
+	return rm.authorize(func(user *models.User) bool { return true })
 }
 
 func (rm *RoleMiddleware) unauthorizedResponse(w http.ResponseWriter, message string) {
@@ -1,19 +1,39 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
-// UserRole represents the different user roles in the system
+// UserRole names a role stored in the roles table. Besides the built-in roles below,
+// roles can be created with any set of permissions.
 type UserRole string
 
+// Built-in roles
 const (
 	RoleUser     UserRole = "user"
 	RoleAdmin    UserRole = "admin"
 	RoleProvider UserRole = "provider"
 )
 
-// IsValid checks if the role is valid
+// Permissions that roles grant
+const (
+	PermissionUsersRead         = "users:read"
+	PermissionUsersRoles        = "users:roles"
+	PermissionRolesManage       = "roles:manage"
+	PermissionDestinationsWrite = "destinations:write"
+	PermissionServiceTypesWrite = "service_types:write"
+	PermissionServicesWrite     = "services:write"
+	PermissionServicesManage    = "services:manage"
+	PermissionPoliciesWrite     = "cancellation_policies:write"
+	PermissionBookingsRead      = "bookings:read"
+	PermissionBookingsWrite     = "bookings:write"
+	PermissionPaymentsRead      = "payments:read"
+	PermissionPaymentsRefund    = "payments:refund"
+	PermissionProvidersReview   = "providers:review"
+)
+
+// IsValid checks if the role is one of the built-in roles
 func (r UserRole) IsValid() bool {
 	switch r {
 	case RoleUser, RoleAdmin, RoleProvider:
@@ -29,15 +49,17 @@ func (r UserRole) String() string {
 
 // User represents a user in the system
 type User struct {
-	ID               int      `json:"id" db:"id"`
-	Email            string   `json:"email" db:"email"`
-	Password         string   `json:"-" db:"password"`
-	FirstName        string   `json:"first_name" db:"first_name"`
-	LastName         string   `json:"last_name" db:"last_name"`
-	Phone            string   `json:"phone" db:"phone"`
-	Role             UserRole `json:"role" db:"role"`
-	EmailVerified    bool     `json:"email_verified" db:"email_verified"`
-	VerificationCode string   `json:"-" db:"verification_code"`
+	ID            int      `json:"id" db:"id"`
+	Email         string   `json:"email" db:"email"`
+	Password      string   `json:"-" db:"password"`
+	FirstName     string   `json:"first_name" db:"first_name"`
+	LastName      string   `json:"last_name" db:"last_name"`
+	Phone         string   `json:"phone" db:"phone"`
+	Role          UserRole `json:"role" db:"role"`
+	EmailVerified bool     `json:"email_verified" db:"email_verified"`
+
+	// Permissions granted by the role, loaded with the user by ID or email
+	Permissions []string `json:"permissions,omitempty" db:"-"`
 
 	// Provider-specific fields (only used when role is provider)
 	CompanyName string `json:"company_name,omitempty" db:"company_name"`
@@ -55,6 +77,16 @@ func (u *User) HasRole(role UserRole) bool {
 	return u.Role == role
 }
 
+// HasPermission checks if the role of the user grants a permission
+func (u *User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // IsAdmin checks if the user is an admin
 func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
@@ -70,105 +102,1100 @@ func (u *User) IsUser() bool {
 	return u.Role == RoleUser
 }
 
+// Permission describes a capability that roles grant
+type Permission struct {
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// Role is a named set of permissions assigned to users. Built-in roles cannot be changed or deleted.
+type Role struct {
+	Name        UserRole  `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	BuiltIn     bool      `json:"built_in" db:"built_in"`
+	Permissions []string  `json:"permissions" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RoleRequest represents the request to create or change a role
+type RoleRequest struct {
+	Name        UserRole `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// ProviderApplicationStatus represents the review state of a provider application
+type ProviderApplicationStatus string
+
+const (
+	ProviderApplicationPending          ProviderApplicationStatus = "pending"
+	ProviderApplicationChangesRequested ProviderApplicationStatus = "changes_requested"
+	ProviderApplicationApproved         ProviderApplicationStatus = "approved"
+	ProviderApplicationRejected         ProviderApplicationStatus = "rejected"
+	ProviderApplicationRevoked          ProviderApplicationStatus = "revoked"
+)
+
+// IsValid checks if the provider application status is valid
+func (s ProviderApplicationStatus) IsValid() bool {
+	switch s {
+	case ProviderApplicationPending, ProviderApplicationChangesRequested, ProviderApplicationApproved,
+		ProviderApplicationRejected, ProviderApplicationRevoked:
+		return true
+	}
+	return false
+}
+
+// ProviderDocumentType represents what a provider document proves
+type ProviderDocumentType string
+
+const (
+	ProviderDocumentRegistrationCertificate ProviderDocumentType = "registration_certificate"
+	ProviderDocumentIdentity                ProviderDocumentType = "identity_document"
+	ProviderDocumentOther                   ProviderDocumentType = "other"
+)
+
+// IsValid checks if the provider document type is valid
+func (t ProviderDocumentType) IsValid() bool {
+	switch t {
+	case ProviderDocumentRegistrationCertificate, ProviderDocumentIdentity, ProviderDocumentOther:
+		return true
+	}
+	return false
+}
+
+// ProviderDocument is a file uploaded by a user to support their provider application
+type ProviderDocument struct {
+	ID            int                  `json:"id" db:"id"`
+	UserID        int                  `json:"user_id" db:"user_id"`
+	ApplicationID *int                 `json:"application_id,omitempty" db:"application_id"`
+	Type          ProviderDocumentType `json:"document_type" db:"document_type"`
+	FileName      string               `json:"file_name" db:"file_name"`
+	ContentType   string               `json:"content_type" db:"content_type"`
+	Size          int64                `json:"size_bytes" db:"size_bytes"`
+	StorageKey    string               `json:"-" db:"storage_key"`
+	CreatedAt     time.Time            `json:"created_at" db:"created_at"`
+}
+
+// ProviderApplicationStatusHistory records a status change of a provider application
+type ProviderApplicationStatusHistory struct {
+	ID            int                        `json:"id" db:"id"`
+	ApplicationID int                        `json:"application_id" db:"application_id"`
+	FromStatus    *ProviderApplicationStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus      ProviderApplicationStatus  `json:"to_status" db:"to_status"`
+	ChangedBy     *int                       `json:"changed_by,omitempty" db:"changed_by"`
+	Note          string                     `json:"note" db:"note"`
+	CreatedAt     time.Time                  `json:"created_at" db:"created_at"`
+}
+
+// ProviderApplication represents the request of a user to become a provider
+type ProviderApplication struct {
+	ID          int                       `json:"id" db:"id"`
+	UserID      int                       `json:"user_id" db:"user_id"`
+	CompanyName string                    `json:"company_name" db:"company_name"`
+	Description string                    `json:"description" db:"description"`
+	Website     string                    `json:"website" db:"website"`
+	Address     string                    `json:"address" db:"address"`
+	Documents   []ProviderDocument        `json:"documents" db:"-"`
+	Status      ProviderApplicationStatus `json:"status" db:"status"`
+	ReviewNote  string                    `json:"review_note,omitempty" db:"review_note"`
+	ReviewedBy  *int                      `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt  *time.Time                `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt   time.Time                 `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time                 `json:"updated_at" db:"updated_at"`
+}
+
+// OrganisationRole represents what a staff member may do for the provider organisation they work for
+type OrganisationRole string
+
+const (
+	OrganisationRoleManager    OrganisationRole = "manager"
+	OrganisationRoleFrontDesk  OrganisationRole = "front_desk"
+	OrganisationRoleAccountant OrganisationRole = "accountant"
+)
+
+// OrganisationScope represents an area of the business of a provider that organisation roles grant access to
+type OrganisationScope string
+
+const (
+	OrganisationScopeServices OrganisationScope = "services" // create, change and delete services
+	OrganisationScopeBookings OrganisationScope = "bookings" // see, accept and decline bookings, check-ins and occupancy
+	OrganisationScopeFinance  OrganisationScope = "finance"  // see revenue and cancellations
+)
+
+// organisationRoleScopes lists the scopes each organisation role grants; the owner has them all
+var organisationRoleScopes = map[OrganisationRole][]OrganisationScope{
+	OrganisationRoleManager:    {OrganisationScopeServices, OrganisationScopeBookings, OrganisationScopeFinance},
+	OrganisationRoleFrontDesk:  {OrganisationScopeBookings},
+	OrganisationRoleAccountant: {OrganisationScopeFinance},
+}
+
+// IsValid checks if the organisation role is known
+func (r OrganisationRole) IsValid() bool {
+	_, ok := organisationRoleScopes[r]
+	return ok
+}
+
+// Grants checks if the organisation role gives access to a scope
+func (r OrganisationRole) Grants(scope OrganisationScope) bool {
+	for _, s := range organisationRoleScopes[r] {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderOrganisation represents the team of a provider: the provider owns it and staff
+// members act on their behalf
+type ProviderOrganisation struct {
+	ID          int                      `json:"id" db:"id"`
+	OwnerID     int                      `json:"owner_id" db:"owner_id"`
+	Name        string                   `json:"name" db:"name"`
+	Members     []OrganisationMember     `json:"members" db:"-"`
+	Invitations []OrganisationInvitation `json:"invitations" db:"-"`
+	CreatedAt   time.Time                `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+// OrganisationMember represents a staff member of a provider organisation
+type OrganisationMember struct {
+	ID             int              `json:"id" db:"id"`
+	OrganisationID int              `json:"organisation_id" db:"organisation_id"`
+	UserID         int              `json:"user_id" db:"user_id"`
+	FirstName      string           `json:"first_name" db:"first_name"`
+	LastName       string           `json:"last_name" db:"last_name"`
+	Email          string           `json:"email" db:"email"`
+	Role           OrganisationRole `json:"role" db:"role"`
+	InvitedBy      *int             `json:"invited_by,omitempty" db:"invited_by"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// OrganisationInvitation represents an invitation to join a provider organisation, sent by email
+type OrganisationInvitation struct {
+	ID             int              `json:"id" db:"id"`
+	OrganisationID int              `json:"organisation_id" db:"organisation_id"`
+	Email          string           `json:"email" db:"email"`
+	Role           OrganisationRole `json:"role" db:"role"`
+	InvitedBy      *int             `json:"invited_by,omitempty" db:"invited_by"`
+	ExpiresAt      time.Time        `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+}
+
+// OrganisationMembership represents the organisation a staff member works for and their role in it
+type OrganisationMembership struct {
+	OrganisationID   int              `json:"organisation_id"`
+	OrganisationName string           `json:"organisation_name"`
+	OwnerID          int              `json:"owner_id"`
+	Role             OrganisationRole `json:"role"`
+	JoinedAt         time.Time        `json:"joined_at"`
+}
+
 // Service represents a category of services offered by the platform
 type Service struct {
-	ID            int       `json:"id" db:"id"`
-	UserID        int       `json:"user_id" db:"user_id"` // Changed from ProviderID to UserID
-	ServiceTypeID int       `json:"service_type_id" db:"service_type_id"`
-	Name          string    `json:"name" db:"name"`
-	Description   string    `json:"description" db:"description"`
-	Price         float64   `json:"price" db:"price"`
-	Availability  bool      `json:"availability" db:"availability"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID                   int       `json:"id" db:"id"`
+	UserID               *int      `json:"user_id,omitempty" db:"user_id"` // owner of the service, nil for platform services
+	ServiceTypeID        int       `json:"service_type_id" db:"service_type_id"`
+	Name                 string    `json:"name" db:"name"`
+	Description          string    `json:"description" db:"description"`
+	Price                float64   `json:"price" db:"price"`
+	Availability         bool      `json:"availability" db:"availability"`
+	Capacity             int       `json:"capacity" db:"capacity"`
+	CancellationPolicyID *int      `json:"cancellation_policy_id,omitempty" db:"cancellation_policy_id"` // nil uses the platform default policy
+	DestinationID        *int      `json:"destination_id,omitempty" db:"destination_id"`
+	Latitude             *float64  `json:"latitude,omitempty" db:"latitude"`
+	Longitude            *float64  `json:"longitude,omitempty" db:"longitude"`
+	Rating               *float64  `json:"rating,omitempty" db:"rating"` // nil until the service has been reviewed
+	Reviews              int       `json:"reviews" db:"reviews"`
+	CreatedAt            time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+
+	// Accommodation details, only for services priced per night that have them
+	Accommodation *AccommodationDetails `json:"accommodation,omitempty" db:"-"`
+	// Car rental details, only for services priced per day that have them
+	CarRental *CarRentalDetails `json:"car_rental,omitempty" db:"-"`
+}
+
+// ServiceFilter represents the criteria services are searched by. Zero values do not filter.
+type ServiceFilter struct {
+	Category  string   // name of the service type
+	Guests    int      // a room type must sleep at least this many guests
+	BedType   BedType  // a room type must have a bed of this type
+	Amenities []string // codes of amenities the accommodation must all have
+}
+
+// ServiceSearchQuery represents a full-text search of the catalogue. Zero values do not filter.
+type ServiceSearchQuery struct {
+	ServiceFilter
+	Text          string    // words matched against the name and description
+	ServiceTypeID int       // the service must be of this type
+	MinPrice      *float64  // the base price must be at least this
+	MaxPrice      *float64  // the base price must be at most this
+	DestinationID int       // the service must be offered at this destination
+	From          time.Time // with To, the service must have a free slot during [From, To)
+	To            time.Time
+	MinRating     float64 // the service must be rated at least this
+	Sort          ServiceSort
+	Limit         int
+	Offset        int
+}
+
+// ServiceSort represents the order of service search results
+type ServiceSort string
+
+const (
+	ServiceSortRelevance ServiceSort = "relevance" // best text match first, requires search words
+	ServiceSortName      ServiceSort = "name"
+	ServiceSortPriceAsc  ServiceSort = "price_asc"
+	ServiceSortPriceDesc ServiceSort = "price_desc"
+	ServiceSortRating    ServiceSort = "rating" // best rated first, unrated services last
+	ServiceSortNewest    ServiceSort = "newest"
+)
+
+// IsValid checks if the sort order is one of the known orders
+func (s ServiceSort) IsValid() bool {
+	switch s {
+	case ServiceSortRelevance, ServiceSortName, ServiceSortPriceAsc, ServiceSortPriceDesc,
+		ServiceSortRating, ServiceSortNewest:
+		return true
+	}
+	return false
+}
+
+// ServiceSearchResult represents a page of service search results with the facet counts of
+// every service matching the search
+type ServiceSearchResult struct {
+	Services []Service    `json:"services"`
+	Total    int          `json:"total"`
+	Limit    int          `json:"limit"`
+	Offset   int          `json:"offset"`
+	Sort     ServiceSort  `json:"sort"`
+	Facets   SearchFacets `json:"facets"`
+}
+
+// SearchFacets represents how the services matching a search are spread over the values of
+// each filter
+type SearchFacets struct {
+	ServiceTypes []FacetCount      `json:"service_types"`
+	Destinations []FacetCount      `json:"destinations"`
+	PriceRanges  []PriceRangeCount `json:"price_ranges"`
+	Ratings      []RatingCount     `json:"ratings"`
+}
+
+// FacetCount represents the number of matching services of a service type or destination
+type FacetCount struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PriceRangeCount represents the number of matching services priced within [Min, Max); the
+// highest range has no maximum
+type PriceRangeCount struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// RatingCount represents the number of matching services rated at least MinRating
+type RatingCount struct {
+	MinRating float64 `json:"min_rating"`
+	Count     int     `json:"count"`
+}
+
+// BedType represents a kind of bed in a room
+type BedType string
+
+const (
+	BedTypeSingle  BedType = "single"
+	BedTypeDouble  BedType = "double"
+	BedTypeQueen   BedType = "queen"
+	BedTypeKing    BedType = "king"
+	BedTypeSofaBed BedType = "sofa_bed"
+	BedTypeBunk    BedType = "bunk"
+)
+
+// IsValid checks if the bed type is valid
+func (b BedType) IsValid() bool {
+	switch b {
+	case BedTypeSingle, BedTypeDouble, BedTypeQueen, BedTypeKing, BedTypeSofaBed, BedTypeBunk:
+		return true
+	}
+	return false
+}
+
+// BedConfiguration represents the beds of one type in a room
+type BedConfiguration struct {
+	Type  BedType `json:"type"`
+	Count int     `json:"count"`
+}
+
+// RoomType represents a kind of room of an accommodation and how many of them it has
+type RoomType struct {
+	ID          int                `json:"id" db:"id"`
+	ServiceID   int                `json:"service_id" db:"service_id"`
+	Name        string             `json:"name" db:"name"`
+	Description string             `json:"description" db:"description"`
+	MaxGuests   int                `json:"max_guests" db:"max_guests"`
+	Quantity    int                `json:"quantity" db:"quantity"`
+	Beds        []BedConfiguration `json:"beds" db:"beds"`
+}
+
+// Amenity represents an entry of the amenity catalogue accommodations choose from
+type Amenity struct {
+	ID       int    `json:"id" db:"id"`
+	Code     string `json:"code" db:"code"`
+	Name     string `json:"name" db:"name"`
+	Category string `json:"category" db:"category"`
+}
+
+// AccommodationDetails represents the details of a hotel, guesthouse or other stay. The
+// nightly rate is the price of the service.
+type AccommodationDetails struct {
+	ServiceID       int        `json:"service_id" db:"service_id"`
+	PropertyAddress string     `json:"property_address" db:"property_address"`
+	CheckInTime     string     `json:"check_in_time" db:"check_in_time"`   // HH:MM, local time of the property
+	CheckOutTime    string     `json:"check_out_time" db:"check_out_time"` // HH:MM, local time of the property
+	NumberOfRooms   int        `json:"number_of_rooms" db:"-"`             // rooms of every type
+	MaxGuests       int        `json:"max_guests" db:"-"`                  // guests the largest room type sleeps
+	RoomTypes       []RoomType `json:"room_types" db:"-"`
+	Amenities       []Amenity  `json:"amenities" db:"-"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Transmission represents the gearbox of a vehicle
+type Transmission string
+
+const (
+	TransmissionManual    Transmission = "manual"
+	TransmissionAutomatic Transmission = "automatic"
+)
+
+// IsValid checks if the transmission is valid
+func (t Transmission) IsValid() bool {
+	return t == TransmissionManual || t == TransmissionAutomatic
+}
+
+// FuelPolicy represents how the fuel of a rented vehicle is paid for
+type FuelPolicy string
+
+const (
+	FuelPolicyFullToFull  FuelPolicy = "full_to_full" // picked up full, returned full
+	FuelPolicySameToSame  FuelPolicy = "same_to_same" // returned with the level it was picked up with
+	FuelPolicyPrePurchase FuelPolicy = "pre_purchase" // a full tank is paid at pickup, returned empty
+)
+
+// IsValid checks if the fuel policy is valid
+func (f FuelPolicy) IsValid() bool {
+	switch f {
+	case FuelPolicyFullToFull, FuelPolicySameToSame, FuelPolicyPrePurchase:
+		return true
+	}
+	return false
+}
+
+// RentalLocation represents a place where vehicles of a car rental are picked up, dropped off or both
+type RentalLocation struct {
+	ID        int    `json:"id" db:"id"`
+	ServiceID int    `json:"service_id" db:"service_id"`
+	Name      string `json:"name" db:"name"`
+	Address   string `json:"address" db:"address"`
+	IsPickup  bool   `json:"is_pickup" db:"is_pickup"`
+	IsDropoff bool   `json:"is_dropoff" db:"is_dropoff"`
+}
+
+// VehicleClass represents a group of similar vehicles of a car rental rented at the same daily rate
+type VehicleClass struct {
+	ID               int     `json:"id" db:"id"`
+	ServiceID        int     `json:"service_id" db:"service_id"`
+	Name             string  `json:"name" db:"name"`
+	Description      string  `json:"description" db:"description"`
+	DailyRate        float64 `json:"daily_rate" db:"daily_rate"`
+	MileageLimitKm   *int    `json:"mileage_limit_km,omitempty" db:"mileage_limit_km"` // per day, nil for unlimited mileage
+	ExtraKmFee       float64 `json:"extra_km_fee" db:"extra_km_fee"`                   // charged per km over the limit
+	NumberOfVehicles int     `json:"number_of_vehicles" db:"-"`                        // active vehicles of the class
+}
+
+// Vehicle represents an individual car of the fleet of a car rental
+type Vehicle struct {
+	ID           int          `json:"id" db:"id"`
+	ServiceID    int          `json:"service_id" db:"service_id"`
+	ClassID      int          `json:"class_id" db:"class_id"`
+	ClassName    string       `json:"class_name" db:"-"`
+	Make         string       `json:"make" db:"make"`
+	Model        string       `json:"model" db:"model"`
+	Year         *int         `json:"year,omitempty" db:"year"`
+	PlateNumber  string       `json:"plate_number,omitempty" db:"plate_number"` // only shown to the provider and their staff
+	Seats        int          `json:"seats" db:"seats"`
+	Transmission Transmission `json:"transmission" db:"transmission"`
+	FuelPolicy   FuelPolicy   `json:"fuel_policy" db:"fuel_policy"`
+	Active       bool         `json:"active" db:"active"` // inactive vehicles cannot be booked
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// CarRentalDetails represents the details of a car rental. Each vehicle class has its own
+// daily rate; the price of the service is the rate shown before a class is chosen.
+type CarRentalDetails struct {
+	ServiceID        int              `json:"service_id" db:"service_id"`
+	MinDriverAge     int              `json:"min_driver_age" db:"min_driver_age"`
+	Locations        []RentalLocation `json:"locations" db:"-"`
+	VehicleClasses   []VehicleClass   `json:"vehicle_classes" db:"-"`
+	NumberOfVehicles int              `json:"number_of_vehicles" db:"-"` // active vehicles of every class
+	UpdatedAt        time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// PricingUnit represents how the price of a service is applied over a booking
+type PricingUnit string
+
+const (
+	PricingFlat     PricingUnit = "flat"
+	PricingPerNight PricingUnit = "per_night"
+	PricingPerDay   PricingUnit = "per_day"
+	PricingPerHour  PricingUnit = "per_hour"
+)
+
+// IsValid checks if the pricing unit is valid
+func (p PricingUnit) IsValid() bool {
+	switch p {
+	case PricingFlat, PricingPerNight, PricingPerDay, PricingPerHour:
+		return true
+	}
+	return false
 }
 
 // ServiceType represents a type of service offered by the platform
 type ServiceType struct {
-	ID          int    `json:"id" db:"id"`
-	Name        string `json:"name" db:"name"`
-	Description string `json:"description" db:"description"`
+	ID          int         `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Description string      `json:"description" db:"description"`
+	PricingUnit PricingUnit `json:"pricing_unit" db:"pricing_unit"`
+}
+
+// BookingStatus represents the lifecycle status of a booking
+type BookingStatus string
+
+const (
+	BookingStatusPending   BookingStatus = "pending"
+	BookingStatusConfirmed BookingStatus = "confirmed"
+	BookingStatusCheckedIn BookingStatus = "checked_in"
+	BookingStatusCompleted BookingStatus = "completed"
+	BookingStatusCancelled BookingStatus = "cancelled"
+	BookingStatusNoShow    BookingStatus = "no_show"
+	BookingStatusExpired   BookingStatus = "expired"
+)
+
+// IsValid checks if the booking status is valid
+func (s BookingStatus) IsValid() bool {
+	switch s {
+	case BookingStatusPending, BookingStatusConfirmed, BookingStatusCheckedIn, BookingStatusCompleted,
+		BookingStatusCancelled, BookingStatusNoShow, BookingStatusExpired:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of the booking status
+func (s BookingStatus) String() string {
+	return string(s)
 }
 
 // Booking represents a booking made by a user for any services offered by the platform
 type Booking struct {
-	ID               int       `json:"id" db:"id"`
-	UserID           int       `json:"user_id" db:"user_id"`
-	ServiceID        int       `json:"service_id" db:"service_id"`
-	BookingDateStart time.Time `json:"booking_date_start" db:"booking_date_start"`
-	BookingDateEnd   time.Time `json:"booking_date_end" db:"booking_date_end"`
-	TotalPrice       float64   `json:"total_price" db:"total_price"`
-	Status           string    `json:"status" db:"status"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
-}
-
-// Payment represents a payment made by a user for a booking
+	ID               int           `json:"id" db:"id"`
+	UserID           int           `json:"user_id" db:"user_id"`
+	ServiceID        int           `json:"service_id" db:"service_id"`
+	BookingDateStart time.Time     `json:"booking_date_start" db:"booking_date_start"`
+	BookingDateEnd   time.Time     `json:"booking_date_end" db:"booking_date_end"`
+	TotalPrice       float64       `json:"total_price" db:"total_price"`
+	Status           BookingStatus `json:"status" db:"status"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+
+	// Vehicle reserved by a car rental booking, with the class it was priced at and where it is
+	// picked up and dropped off
+	VehicleID         *int `json:"vehicle_id,omitempty" db:"vehicle_id"`
+	VehicleClassID    *int `json:"vehicle_class_id,omitempty" db:"vehicle_class_id"`
+	PickupLocationID  *int `json:"pickup_location_id,omitempty" db:"pickup_location_id"`
+	DropoffLocationID *int `json:"dropoff_location_id,omitempty" db:"dropoff_location_id"`
+}
+
+// BlockingBookingStatuses lists the booking statuses that hold a slot of a service
+var BlockingBookingStatuses = []string{
+	string(BookingStatusPending),
+	string(BookingStatusConfirmed),
+	string(BookingStatusCheckedIn),
+}
+
+// BookingStatusHistory represents a single status change of a booking
+type BookingStatusHistory struct {
+	ID         int           `json:"id" db:"id"`
+	BookingID  int           `json:"booking_id" db:"booking_id"`
+	FromStatus BookingStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   BookingStatus `json:"to_status" db:"to_status"`
+	ChangedBy  *int          `json:"changed_by,omitempty" db:"changed_by"`
+	Reason     string        `json:"reason,omitempty" db:"reason"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+}
+
+// ProviderBooking represents a booking of a service of a provider with the guest who made it
+type ProviderBooking struct {
+	Booking
+	ServiceName    string  `json:"service_name"`
+	GuestFirstName string  `json:"guest_first_name"`
+	GuestLastName  string  `json:"guest_last_name"`
+	GuestEmail     string  `json:"guest_email"`
+	AmountPaid     float64 `json:"amount_paid"` // completed payments minus pending and completed refunds
+}
+
+// RevenuePeriod represents the money a provider received and gave back in one period
+type RevenuePeriod struct {
+	PeriodStart time.Time `json:"period_start"`
+	Payments    int       `json:"payments"`
+	Gross       float64   `json:"gross"`
+	Refunded    float64   `json:"refunded"`
+	Net         float64   `json:"net"`
+}
+
+// RevenueReport represents the revenue of a provider over a window, split into periods
+type RevenueReport struct {
+	Period   string          `json:"period"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Gross    float64         `json:"gross"`
+	Refunded float64         `json:"refunded"`
+	Net      float64         `json:"net"`
+	Periods  []RevenuePeriod `json:"periods"`
+}
+
+// ServiceOccupancy represents how much of the capacity of a service was booked over a window
+type ServiceOccupancy struct {
+	ServiceID     int     `json:"service_id"`
+	ServiceName   string  `json:"service_name"`
+	Capacity      int     `json:"capacity"`
+	BookedDays    float64 `json:"booked_days"`
+	AvailableDays float64 `json:"available_days"`
+	OccupancyRate float64 `json:"occupancy_rate"` // booked days over available days, from 0 to 1
+}
+
+// OccupancyReport represents the occupancy of the services of a provider over a window
+type OccupancyReport struct {
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Services []ServiceOccupancy `json:"services"`
+}
+
+// CancellationReport represents how many of the bookings made over a window were cancelled
+type CancellationReport struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	TotalBookings      int       `json:"total_bookings"`
+	CancelledBookings  int       `json:"cancelled_bookings"`
+	DeclinedByProvider int       `json:"declined_by_provider"`
+	CancellationRate   float64   `json:"cancellation_rate"` // cancelled over total, from 0 to 1
+}
+
+// AvailabilityInterval represents a time window with a constant number of bookings
+type AvailabilityInterval struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Booked    int       `json:"booked"`
+	Remaining int       `json:"remaining"`
+}
+
+// ServiceAvailability represents the free and blocked windows of a service
+type ServiceAvailability struct {
+	ServiceID int                    `json:"service_id"`
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Capacity  int                    `json:"capacity"`
+	Free      []AvailabilityInterval `json:"free"`
+	Blocked   []AvailabilityInterval `json:"blocked"`
+}
+
+// QuoteLineItem represents a single line of a booking quote
+type QuoteLineItem struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+// BookingQuote represents the itemised price of a booking computed by the server
+type BookingQuote struct {
+	ServiceID        int             `json:"service_id"`
+	BookingDateStart time.Time       `json:"booking_date_start"`
+	BookingDateEnd   time.Time       `json:"booking_date_end"`
+	PricingUnit      PricingUnit     `json:"pricing_unit"`
+	Units            int             `json:"units"`
+	UnitPrice        float64         `json:"unit_price"`
+	Subtotal         float64         `json:"subtotal"`
+	ServiceFee       float64         `json:"service_fee"`
+	Taxes            float64         `json:"taxes"`
+	Total            float64         `json:"total"`
+	Currency         string          `json:"currency"`
+	LineItems        []QuoteLineItem `json:"line_items"`
+
+	// Vehicle class a car rental is priced at, with the kilometres included over the whole
+	// booking (nil for unlimited mileage) and the fee per kilometre driven beyond them
+	VehicleClass string  `json:"vehicle_class,omitempty"`
+	IncludedKm   *int    `json:"included_km,omitempty"`
+	ExtraKmFee   float64 `json:"extra_km_fee,omitempty"`
+}
+
+// RefundTier represents the share of the paid amount refunded when a booking is
+// cancelled at least HoursBefore hours before it starts
+type RefundTier struct {
+	HoursBefore   int     `json:"hours_before"`
+	RefundPercent float64 `json:"refund_percent"`
+}
+
+// CancellationPolicy represents the refund rules applied when a booking of a service is cancelled
+type CancellationPolicy struct {
+	ID          int          `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Description string       `json:"description" db:"description"`
+	Tiers       []RefundTier `json:"tiers" db:"tiers"`
+	CreatedBy   *int         `json:"created_by,omitempty" db:"created_by"` // nil for the platform policies
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// CancellationResult represents the outcome of a booking cancelled by its owner
+type CancellationResult struct {
+	Booking         *Booking      `json:"booking"`
+	PolicyName      string        `json:"policy_name"`
+	HoursBefore     float64       `json:"hours_before_start"`
+	RefundPercent   float64       `json:"refund_percent"`
+	PaidAmount      float64       `json:"paid_amount"`
+	RefundAmount    float64       `json:"refund_amount"`
+	RefundPaymentID int           `json:"refund_payment_id,omitempty"`
+	RefundStatus    PaymentStatus `json:"refund_status,omitempty"`
+}
+
+// PaymentStatus represents the status of a payment or refund
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusCompleted PaymentStatus = "completed"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	PaymentStatusCancelled PaymentStatus = "cancelled"
+	PaymentStatusRefunded  PaymentStatus = "refunded"
+)
+
+// IsValid checks if the payment status is valid
+func (s PaymentStatus) IsValid() bool {
+	switch s {
+	case PaymentStatusPending, PaymentStatusCompleted, PaymentStatusFailed, PaymentStatusCancelled, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// PaymentType distinguishes money received for a booking from money returned for it
+type PaymentType string
+
+const (
+	PaymentTypePayment PaymentType = "payment"
+	PaymentTypeRefund  PaymentType = "refund"
+)
+
+// PaymentMethods lists the accepted payment methods
+var PaymentMethods = []string{"credit_card", "debit_card", "paypal", "bank_transfer", "mobile_money"}
+
+// PaymentProviderManual marks payments recorded without a payment gateway
+const PaymentProviderManual = "manual"
+
+// Payment represents a payment made by a user for a booking, or a refund of one
 type Payment struct {
-	ID            int       `json:"id" db:"id"`
-	UserID        int       `json:"user_id" db:"user_id"`
-	BookingID     int       `json:"booking_id" db:"booking_id"`
-	Amount        float64   `json:"amount" db:"amount"`
-	PaymentDate   time.Time `json:"payment_date" db:"payment_date"`
-	PaymentMethod string    `json:"payment_method" db:"payment_method"`
-	Status        bool      `json:"status" db:"status"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID                int           `json:"id" db:"id"`
+	UserID            int           `json:"user_id" db:"user_id"`
+	BookingID         int           `json:"booking_id" db:"booking_id"`
+	Amount            float64       `json:"amount" db:"amount"`
+	PaymentDate       time.Time     `json:"payment_date" db:"payment_date"`
+	PaymentMethod     string        `json:"payment_method" db:"payment_method"`
+	Status            PaymentStatus `json:"status" db:"status"`
+	PaymentType       PaymentType   `json:"payment_type" db:"payment_type"`
+	Provider          string        `json:"provider" db:"provider"`
+	ProviderReference string        `json:"provider_reference,omitempty" db:"provider_reference"`
+	FailureReason     string        `json:"failure_reason,omitempty" db:"failure_reason"`
+	ParentPaymentID   *int          `json:"parent_payment_id,omitempty" db:"parent_payment_id"` // payment a refund gives money back for
+	CreatedAt         time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// PaymentReceipt represents the outcome of a payment made for a booking
+type PaymentReceipt struct {
+	Payment            *Payment      `json:"payment"`
+	BookingStatus      BookingStatus `json:"booking_status"`
+	AmountPaid         float64       `json:"amount_paid"`
+	OutstandingBalance float64       `json:"outstanding_balance"`
+	NextActionURL      string        `json:"next_action_url,omitempty"`
+}
+
+// PaymentEventStatus represents how a payment gateway event was handled
+type PaymentEventStatus string
+
+const (
+	PaymentEventReceived  PaymentEventStatus = "received"
+	PaymentEventProcessed PaymentEventStatus = "processed"
+	PaymentEventIgnored   PaymentEventStatus = "ignored"
+)
+
+// PaymentEvent represents a raw event delivered by a payment gateway webhook
+type PaymentEvent struct {
+	ID          int                `json:"id" db:"id"`
+	Provider    string             `json:"provider" db:"provider"`
+	EventID     string             `json:"event_id" db:"event_id"`
+	EventType   string             `json:"event_type" db:"event_type"`
+	PaymentID   *int               `json:"payment_id,omitempty" db:"payment_id"`
+	Payload     json.RawMessage    `json:"payload" db:"payload"`
+	Status      PaymentEventStatus `json:"status" db:"status"`
+	Error       string             `json:"error,omitempty" db:"error"`
+	OccurredAt  *time.Time         `json:"occurred_at,omitempty" db:"occurred_at"`
+	ReceivedAt  time.Time          `json:"received_at" db:"received_at"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty" db:"processed_at"`
 }
 
 // Destination represents a destination for travel or service
 type Destination struct {
-	ID          int       `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	Location    string    `json:"location" db:"location"`
-	ImageURL    string    `json:"image_url" db:"image_url"`
-	Rating      float64   `json:"rating" db:"rating"`
-	Reviews     int       `json:"reviews" db:"reviews"`
-	Price       float64   `json:"price" db:"price"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int        `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	Location    string     `json:"location" db:"location"`
+	ImageURL    string     `json:"image_url" db:"image_url"`
+	Rating      float64    `json:"rating" db:"rating"`
+	Reviews     int        `json:"reviews" db:"reviews"`
+	Price       float64    `json:"price" db:"price"`
+	Latitude    *float64   `json:"latitude,omitempty" db:"latitude"`
+	Longitude   *float64   `json:"longitude,omitempty" db:"longitude"`
+	Area        []GeoPoint `json:"area,omitempty" db:"area"` // corners of the area the destination covers, in order
+	PlaceID     *int       `json:"place_id,omitempty" db:"place_id"`
+	PlaceCode   string     `json:"place_code,omitempty" db:"place_code"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// PlaceLevel represents the level of a place in the place hierarchy
+type PlaceLevel string
+
+const (
+	PlaceContinent PlaceLevel = "continent"
+	PlaceCountry   PlaceLevel = "country"
+	PlaceRegion    PlaceLevel = "region"
+	PlaceCity      PlaceLevel = "city"
+)
+
+// IsValid checks if the place level is one of the known levels
+func (l PlaceLevel) IsValid() bool {
+	switch l {
+	case PlaceContinent, PlaceCountry, PlaceRegion, PlaceCity:
+		return true
+	}
+	return false
+}
+
+// Place represents a continent, country, region or city destinations are browsed by. Places
+// are known by their code, unique across levels.
+type Place struct {
+	ID         int        `json:"id" db:"id"`
+	Code       string     `json:"code" db:"code"`
+	Name       string     `json:"name" db:"name"`
+	Level      PlaceLevel `json:"level" db:"level"`
+	ParentID   *int       `json:"parent_id,omitempty" db:"parent_id"` // nil for continents
+	ParentCode string     `json:"parent_code,omitempty" db:"parent_code"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+
+	Ancestors []Place `json:"ancestors,omitempty"` // from the continent down to the parent
+}
+
+// GeoPoint represents a point on Earth in decimal degrees
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// GeoArea represents where services are searched: inside a polygon or, without one, within a
+// radius of a center
+type GeoArea struct {
+	Polygon  []GeoPoint
+	Center   GeoPoint
+	RadiusKm float64
+}
+
+// NearbyDestination represents a destination with its distance from the point searched from
+type NearbyDestination struct {
+	Destination
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// ClientInfo describes the device a request comes from. It is filled in by handlers, never from the body.
+type ClientInfo struct {
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // LoginRequest represents the login request payload
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	ClientInfo
 }
 
 // RegisterRequest represents the registration request payload
 type RegisterRequest struct {
-	Email     string   `json:"email"`
-	Password  string   `json:"password"`
-	FirstName string   `json:"first_name"`
-	LastName  string   `json:"last_name"`
-	Phone     string   `json:"phone"`
-	Role      UserRole `json:"role"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Phone     string `json:"phone"`
+	ClientInfo
 }
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
 	Token         string `json:"token"`
+	RefreshToken  string `json:"refresh_token"`
+	ExpiresIn     int    `json:"expires_in"` // lifetime of the access token in seconds
 	User          User   `json:"user"`
 	EmailVerified bool   `json:"email_verified"`
 }
 
+// RefreshTokenRequest represents the request to exchange a refresh token for new tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	ClientInfo
+}
+
+// LogoutRequest represents the request to end the session of a refresh token
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// AuthSession represents a signed-in device. Its refresh tokens form one rotation family.
+type AuthSession struct {
+	ID            string     `json:"id" db:"id"`
+	UserID        int        `json:"user_id" db:"user_id"`
+	UserAgent     string     `json:"user_agent" db:"user_agent"`
+	IPAddress     string     `json:"ip_address" db:"ip_address"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedReason string     `json:"revoked_reason,omitempty" db:"revoked_reason"`
+	// TwoFactorVerified is set when the session was opened with a second factor
+	TwoFactorVerified bool `json:"two_factor_verified" db:"two_factor_verified"`
+}
+
+// IsActive checks if the session can still be used
+func (s *AuthSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// UserTwoFactor holds the TOTP enrolment of a user
+type UserTwoFactor struct {
+	UserID int `json:"user_id" db:"user_id"`
+	// Secret is the encrypted TOTP secret
+	Secret    string     `json:"-" db:"secret"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty" db:"enabled_at"`
+	// LastUsedStep is the TOTP time step of the last accepted code
+	LastUsedStep int64     `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// IsEnabled checks if the user finished enrolling
+func (t *UserTwoFactor) IsEnabled() bool {
+	return t.EnabledAt != nil
+}
+
+// TwoFactorStatus describes the two-factor authentication of a user
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	Required               bool       `json:"required"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetup is returned when a user starts enrolling an authenticator app
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth:// URI to render as a QR code
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorChallenge is returned by a login that still needs a second factor
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in"` // seconds
+}
+
+// TwoFactorLoginRequest represents the second step of a login
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	// Code is a TOTP code or a recovery code
+	Code string `json:"code"`
+	ClientInfo
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP code or a recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// OIDCLoginState is the state of an OpenID Connect login between the redirect to the
+// provider and the callback
+type OIDCLoginState struct {
+	ID           string    `json:"-" db:"id"`
+	Provider     string    `json:"provider" db:"provider"`
+	Nonce        string    `json:"-" db:"nonce"`
+	CodeVerifier string    `json:"-" db:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserIdentity links an account at an OpenID Connect provider to a user
+type UserIdentity struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Provider    string    `json:"provider" db:"provider"`
+	Subject     string    `json:"-" db:"subject"`
+	Email       string    `json:"email" db:"email"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// OIDCAuthorization is returned when an OpenID Connect login starts
+type OIDCAuthorization struct {
+	// AuthorizationURL is where the browser is sent to sign in at the provider
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int    `json:"expires_in"` // seconds
+}
+
+// OIDCCallbackRequest carries the code and state the provider redirected the browser back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+	ClientInfo
+}
+
+// RecoveryCodesResponse carries newly generated recovery codes, which are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Actions protected against brute force, recorded on failed attempts
+const (
+	AuthActionLogin              = "login"
+	AuthActionVerifyEmail        = "verify_email"
+	AuthActionResendVerification = "resend_verification"
+	AuthActionTwoFactor          = "two_factor"
+	AuthActionForgotPassword     = "forgot_password"
+)
+
+// AuthFailure is the audit record of a failed login, two-factor or email verification attempt
+type AuthFailure struct {
+	ID        int64     `json:"id" db:"id"`
+	Action    string    `json:"action" db:"action"`
+	Email     string    `json:"email" db:"email"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
+	IPAddress string    `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent string    `json:"user_agent,omitempty" db:"user_agent"`
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuthThrottle counts the recent failed attempts of an account or a client IP
+type AuthThrottle struct {
+	Key           string     `json:"key" db:"key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+}
+
+// IsLocked checks if the account or client IP is locked out at the given time
+func (t *AuthThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && t.LockedUntil.After(now)
+}
+
 // VerifyEmailRequest represents the email verification request
 type VerifyEmailRequest struct {
 	Email            string `json:"email"`
 	VerificationCode string `json:"verification_code"`
+	ClientInfo
 }
 
 // ResendVerificationRequest represents the resend verification request
 type ResendVerificationRequest struct {
 	Email string `json:"email"`
+	ClientInfo
 }
 
-// APIResponse represents a generic API response
+// UnlockAccountRequest represents the request to lift a lockout with the token from the unlock email
+type UnlockAccountRequest struct {
+	Token string `json:"token"`
+}
+
+// ForgotPasswordRequest represents the request to email a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+	ClientInfo
+}
+
+// ResetPasswordRequest represents the request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// APIResponse represents a generic API response. Pages of lists also carry the cursor of the
+// next page, empty on the last page, and the number of items matching their filters.
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      *int        `json:"total,omitempty"`
+}
+
+// ListQuery represents the page of a list a client asks for: the cursor where the previous
+// page ended, the page size, the field to sort by and the filters. Zero values use the
+// defaults of the list.
+type ListQuery struct {
+	Cursor  string
+	Limit   int
+	Sort    string // field name, prefixed with - for descending order
+	Filters []ListFilter
+}
+
+// ListFilter represents a condition on a field of the items of a list
+type ListFilter struct {
+	Field    string
+	Operator FilterOperator
+	Value    string
+}
+
+// FilterOperator represents how a list filter compares a field with its value
+type FilterOperator string
+
+const (
+	FilterEq   FilterOperator = "eq"
+	FilterNe   FilterOperator = "ne"
+	FilterGt   FilterOperator = "gt"
+	FilterGte  FilterOperator = "gte"
+	FilterLt   FilterOperator = "lt"
+	FilterLte  FilterOperator = "lte"
+	FilterLike FilterOperator = "like" // case-insensitive substring of a text field
+	FilterIn   FilterOperator = "in"   // one of comma-separated values
+)
+
+// PageInfo represents where a page of a list ends and how many items match its filters
+type PageInfo struct {
+	NextCursor string // empty on the last page
+	Total      int
 }
 
 // ErrorResponse represents an error response
@@ -180,57 +1207,232 @@ type ErrorResponse struct {
 
 // CreateDestinationRequest represents the request to create a destination
 type CreateDestinationRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	Location    string `json:"location" validate:"required"`
+	Name        string     `json:"name" validate:"required"`
+	Description string     `json:"description" validate:"required"`
+	Location    string     `json:"location" validate:"required"`
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
+	Area        []GeoPoint `json:"area"`
+	PlaceCode   string     `json:"place_code"`
 }
 
 // UpdateDestinationRequest represents the request to update a destination
 type UpdateDestinationRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	Location    string `json:"location" validate:"required"`
+	Name        string     `json:"name" validate:"required"`
+	Description string     `json:"description" validate:"required"`
+	Location    string     `json:"location" validate:"required"`
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
+	Area        []GeoPoint `json:"area"`
+	PlaceCode   string     `json:"place_code"`
+}
+
+// PlaceRequest represents the request to add or change a place; the code and level of a place
+// cannot be changed
+type PlaceRequest struct {
+	Code       string     `json:"code"`
+	Name       string     `json:"name" validate:"required"`
+	Level      PlaceLevel `json:"level"`
+	ParentCode string     `json:"parent_code"`
 }
 
 // CreateServiceRequest represents the request to create a service
 type CreateServiceRequest struct {
-	ServiceTypeID int     `json:"service_type_id" validate:"required"`
-	Name          string  `json:"name" validate:"required"`
-	Description   string  `json:"description" validate:"required"`
-	Price         float64 `json:"price" validate:"required,gt=0"`
-	Availability  bool    `json:"availability"`
+	ServiceTypeID        int      `json:"service_type_id" validate:"required"`
+	Name                 string   `json:"name" validate:"required"`
+	Description          string   `json:"description" validate:"required"`
+	Price                float64  `json:"price" validate:"required,gt=0"`
+	Availability         bool     `json:"availability"`
+	Capacity             int      `json:"capacity" validate:"omitempty,gt=0"`
+	CancellationPolicyID *int     `json:"cancellation_policy_id"`
+	DestinationID        *int     `json:"destination_id"`
+	Latitude             *float64 `json:"latitude"`
+	Longitude            *float64 `json:"longitude"`
+
+	Accommodation *AccommodationRequest `json:"accommodation"`
+	CarRental     *CarRentalRequest     `json:"car_rental"`
 }
 
 // UpdateServiceRequest represents the request to update a service
 type UpdateServiceRequest struct {
-	ServiceTypeID int     `json:"service_type_id" validate:"required"`
-	Name          string  `json:"name" validate:"required"`
-	Description   string  `json:"description" validate:"required"`
-	Price         float64 `json:"price" validate:"required,gt=0"`
-	Availability  bool    `json:"availability"`
+	ServiceTypeID        int      `json:"service_type_id" validate:"required"`
+	Name                 string   `json:"name" validate:"required"`
+	Description          string   `json:"description" validate:"required"`
+	Price                float64  `json:"price" validate:"required,gt=0"`
+	Availability         bool     `json:"availability"`
+	Capacity             int      `json:"capacity" validate:"omitempty,gt=0"`
+	CancellationPolicyID *int     `json:"cancellation_policy_id"`
+	DestinationID        *int     `json:"destination_id"`
+	Latitude             *float64 `json:"latitude"`
+	Longitude            *float64 `json:"longitude"`
+
+	Accommodation *AccommodationRequest `json:"accommodation"`
+	CarRental     *CarRentalRequest     `json:"car_rental"`
+}
+
+// AccommodationRequest represents the accommodation details of a service priced per night
+type AccommodationRequest struct {
+	PropertyAddress string            `json:"property_address"`
+	CheckInTime     string            `json:"check_in_time"`  // HH:MM, 14:00 by default
+	CheckOutTime    string            `json:"check_out_time"` // HH:MM, 11:00 by default
+	RoomTypes       []RoomTypeRequest `json:"room_types" validate:"required,min=1"`
+	Amenities       []string          `json:"amenities"` // amenity codes
+}
+
+// RoomTypeRequest represents a room type of an accommodation
+type RoomTypeRequest struct {
+	Name        string             `json:"name" validate:"required"`
+	Description string             `json:"description"`
+	MaxGuests   int                `json:"max_guests" validate:"required,gt=0"`
+	Quantity    int                `json:"quantity" validate:"omitempty,gt=0"` // 1 by default
+	Beds        []BedConfiguration `json:"beds" validate:"required,min=1"`
+}
+
+// CarRentalRequest represents the car rental details of a service priced per day. Locations
+// and vehicle classes with an ID update the existing ones, those without are added and the
+// existing ones left out are removed.
+type CarRentalRequest struct {
+	MinDriverAge   int                     `json:"min_driver_age" validate:"omitempty,gte=16"` // 18 by default
+	Locations      []RentalLocationRequest `json:"locations"`
+	VehicleClasses []VehicleClassRequest   `json:"vehicle_classes" validate:"required,min=1"`
+}
+
+// RentalLocationRequest represents a pickup or dropoff location of a car rental
+type RentalLocationRequest struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name" validate:"required"`
+	Address   string `json:"address"`
+	IsPickup  *bool  `json:"is_pickup"`  // true by default
+	IsDropoff *bool  `json:"is_dropoff"` // true by default
+}
+
+// VehicleClassRequest represents a vehicle class of a car rental
+type VehicleClassRequest struct {
+	ID             int     `json:"id"`
+	Name           string  `json:"name" validate:"required"`
+	Description    string  `json:"description"`
+	DailyRate      float64 `json:"daily_rate" validate:"required,gt=0"`
+	MileageLimitKm *int    `json:"mileage_limit_km" validate:"omitempty,gt=0"` // per day, unlimited when left out
+	ExtraKmFee     float64 `json:"extra_km_fee" validate:"gte=0"`
+}
+
+// VehicleRequest represents the request to add a vehicle to the fleet of a car rental or change it
+type VehicleRequest struct {
+	ClassID      int          `json:"class_id" validate:"required"`
+	Make         string       `json:"make" validate:"required"`
+	Model        string       `json:"model" validate:"required"`
+	Year         *int         `json:"year"`
+	PlateNumber  string       `json:"plate_number" validate:"required"`
+	Seats        int          `json:"seats" validate:"required,gt=0"`
+	Transmission Transmission `json:"transmission" validate:"required,oneof=manual automatic"`
+	FuelPolicy   FuelPolicy   `json:"fuel_policy" validate:"required,oneof=full_to_full same_to_same pre_purchase"`
+	Active       *bool        `json:"active"` // true by default
 }
 
 // CreateServiceTypeRequest represents the request to create a service type
 type CreateServiceTypeRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
+	Name        string      `json:"name" validate:"required"`
+	Description string      `json:"description" validate:"required"`
+	PricingUnit PricingUnit `json:"pricing_unit" validate:"omitempty,oneof=flat per_night per_day per_hour"`
 }
 
 // UpdateServiceTypeRequest represents the request to update a service type
 type UpdateServiceTypeRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
+	Name        string      `json:"name" validate:"required"`
+	Description string      `json:"description" validate:"required"`
+	PricingUnit PricingUnit `json:"pricing_unit" validate:"omitempty,oneof=flat per_night per_day per_hour"`
 }
 
-// CreateBookingRequest represents the request to create a booking
+// CreateBookingRequest represents the request to create a booking.
+// The total price is always computed by the server.
 type CreateBookingRequest struct {
 	ServiceID        int       `json:"service_id" validate:"required"`
 	BookingDateStart time.Time `json:"booking_date_start" validate:"required"`
 	BookingDateEnd   time.Time `json:"booking_date_end" validate:"required"`
-	TotalPrice       float64   `json:"total_price" validate:"required,gt=0"`
+
+	// Car rentals only: a specific vehicle or any vehicle of a class, and where it is picked
+	// up and dropped off (the pickup location by default)
+	VehicleID         *int `json:"vehicle_id"`
+	VehicleClassID    *int `json:"vehicle_class_id"`
+	PickupLocationID  *int `json:"pickup_location_id"`
+	DropoffLocationID *int `json:"dropoff_location_id"`
+}
+
+// BookingQuoteRequest represents the request to price a booking before creating it
+type BookingQuoteRequest struct {
+	ServiceID        int       `json:"service_id" validate:"required"`
+	BookingDateStart time.Time `json:"booking_date_start" validate:"required"`
+	BookingDateEnd   time.Time `json:"booking_date_end" validate:"required"`
+
+	// Car rentals only: the vehicle or vehicle class to price
+	VehicleID      *int `json:"vehicle_id"`
+	VehicleClassID *int `json:"vehicle_class_id"`
+}
+
+// CancelBookingRequest represents the request of a user to cancel their booking
+type CancelBookingRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DeclineBookingRequest represents the request of a provider to decline a booking of their service
+type DeclineBookingRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CreateCancellationPolicyRequest represents the request to create a custom cancellation policy
+type CreateCancellationPolicyRequest struct {
+	Name        string       `json:"name" validate:"required"`
+	Description string       `json:"description"`
+	Tiers       []RefundTier `json:"tiers" validate:"required,min=1"`
+}
+
+// ProviderApplicationRequest represents the request of a user to become a provider, or to
+// resubmit their application after a reviewer asked for changes
+type ProviderApplicationRequest struct {
+	CompanyName string `json:"company_name" validate:"required"`
+	Description string `json:"description"`
+	Website     string `json:"website"`
+	Address     string `json:"address" validate:"required"`
+	DocumentIDs []int  `json:"document_ids" validate:"required,min=2"`
+}
+
+// UpdateProviderProfileRequest represents the business details a verified provider may change
+// without a new review
+type UpdateProviderProfileRequest struct {
+	Description string `json:"description"`
+	Website     string `json:"website"`
+}
+
+// InviteTeamMemberRequest represents the request of a provider to invite a staff member
+type InviteTeamMemberRequest struct {
+	Email string           `json:"email" validate:"required,email"`
+	Role  OrganisationRole `json:"role" validate:"required,oneof=manager front_desk accountant"`
+}
+
+// UpdateTeamMemberRequest represents the request of a provider to change the role of a staff member
+type UpdateTeamMemberRequest struct {
+	Role OrganisationRole `json:"role" validate:"required,oneof=manager front_desk accountant"`
+}
+
+// AcceptTeamInvitationRequest represents the request of a user to join the organisation that invited them
+type AcceptTeamInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ReviewProviderApplicationRequest represents the decision of a reviewer on a provider application
+type ReviewProviderApplicationRequest struct {
+	Note string `json:"note"`
+}
+
+// CreatePaymentRequest represents the request to pay for a booking
+type CreatePaymentRequest struct {
+	Amount        float64 `json:"amount" validate:"required,gt=0"`
+	PaymentMethod string  `json:"payment_method" validate:"required,oneof=credit_card debit_card paypal bank_transfer mobile_money"`
+	PaymentToken  string  `json:"payment_token"` // card or wallet token issued to the client by the payment gateway
 }
 
 // UpdateBookingStatusRequest represents the request to update booking status
 type UpdateBookingStatusRequest struct {
-	Status string `json:"status" validate:"required,oneof=pending confirmed cancelled completed"`
+	Status BookingStatus `json:"status" validate:"required,oneof=pending confirmed checked_in completed cancelled no_show expired"`
+	Reason string        `json:"reason"`
 }
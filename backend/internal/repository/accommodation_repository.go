@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// AccommodationRepository defines the interface for the accommodation details of services and
+// the amenity catalogue
+type AccommodationRepository interface {
+	GetAmenities() ([]models.Amenity, error)
+	GetDetails(serviceIDs []int) (map[int]*models.AccommodationDetails, error)
+	SaveDetails(details *models.AccommodationDetails) error
+	DeleteDetails(serviceID int) error
+}
+
+// accommodationRepository implements AccommodationRepository
+type accommodationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewAccommodationRepository creates a new accommodation repository
+func NewAccommodationRepository(db *sql.DB, logger *logger.Logger) AccommodationRepository {
+	return &accommodationRepository{db: db, logger: logger}
+}
+
+// GetAmenities retrieves the amenity catalogue, grouped by category
+func (r *accommodationRepository) GetAmenities() ([]models.Amenity, error) {
+	query := `
+		SELECT id, code, name, category
+		FROM amenities
+		ORDER BY category, name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get amenities: %w", err)
+	}
+	defer rows.Close()
+
+	amenities := []models.Amenity{}
+	for rows.Next() {
+		var amenity models.Amenity
+		if err := rows.Scan(&amenity.ID, &amenity.Code, &amenity.Name, &amenity.Category); err != nil {
+			return nil, fmt.Errorf("failed to scan amenity: %w", err)
+		}
+		amenities = append(amenities, amenity)
+	}
+	return amenities, nil
+}
+
+// GetDetails retrieves the accommodation details of services with their room types and
+// amenities, by service ID. Services without details are left out.
+func (r *accommodationRepository) GetDetails(serviceIDs []int) (map[int]*models.AccommodationDetails, error) {
+	details := make(map[int]*models.AccommodationDetails)
+	if len(serviceIDs) == 0 {
+		return details, nil
+	}
+
+	query := `
+		SELECT service_id, property_address, to_char(check_in_time, 'HH24:MI'), to_char(check_out_time, 'HH24:MI'), updated_at
+		FROM accommodation_details
+		WHERE service_id = ANY($1)`
+
+	rows, err := r.db.Query(query, pq.Array(serviceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accommodation details: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d := &models.AccommodationDetails{RoomTypes: []models.RoomType{}, Amenities: []models.Amenity{}}
+		if err := rows.Scan(&d.ServiceID, &d.PropertyAddress, &d.CheckInTime, &d.CheckOutTime, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan accommodation details: %w", err)
+		}
+		details[d.ServiceID] = d
+	}
+	if len(details) == 0 {
+		return details, nil
+	}
+
+	if err := r.loadRoomTypes(details, serviceIDs); err != nil {
+		return nil, err
+	}
+	if err := r.loadAmenities(details, serviceIDs); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// loadRoomTypes adds the room types of accommodations to their details and counts their
+// rooms and guests
+func (r *accommodationRepository) loadRoomTypes(details map[int]*models.AccommodationDetails, serviceIDs []int) error {
+	query := `
+		SELECT id, service_id, name, description, max_guests, quantity, beds
+		FROM accommodation_room_types
+		WHERE service_id = ANY($1)
+		ORDER BY service_id, id`
+
+	rows, err := r.db.Query(query, pq.Array(serviceIDs))
+	if err != nil {
+		return fmt.Errorf("failed to get room types: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var room models.RoomType
+		var beds []byte
+		err := rows.Scan(&room.ID, &room.ServiceID, &room.Name, &room.Description, &room.MaxGuests, &room.Quantity, &beds)
+		if err != nil {
+			return fmt.Errorf("failed to scan room type: %w", err)
+		}
+		if err := json.Unmarshal(beds, &room.Beds); err != nil {
+			return fmt.Errorf("failed to decode beds: %w", err)
+		}
+
+		d := details[room.ServiceID]
+		d.RoomTypes = append(d.RoomTypes, room)
+		d.NumberOfRooms += room.Quantity
+		if room.MaxGuests > d.MaxGuests {
+			d.MaxGuests = room.MaxGuests
+		}
+	}
+	return nil
+}
+
+// loadAmenities adds the amenities of accommodations to their details
+func (r *accommodationRepository) loadAmenities(details map[int]*models.AccommodationDetails, serviceIDs []int) error {
+	query := `
+		SELECT aa.service_id, a.id, a.code, a.name, a.category
+		FROM accommodation_amenities aa
+		JOIN amenities a ON a.id = aa.amenity_id
+		WHERE aa.service_id = ANY($1)
+		ORDER BY a.category, a.name`
+
+	rows, err := r.db.Query(query, pq.Array(serviceIDs))
+	if err != nil {
+		return fmt.Errorf("failed to get accommodation amenities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serviceID int
+		var amenity models.Amenity
+		if err := rows.Scan(&serviceID, &amenity.ID, &amenity.Code, &amenity.Name, &amenity.Category); err != nil {
+			return fmt.Errorf("failed to scan accommodation amenity: %w", err)
+		}
+		details[serviceID].Amenities = append(details[serviceID].Amenities, amenity)
+	}
+	return nil
+}
+
+// SaveDetails creates or replaces the accommodation details of a service, with all its room
+// types and amenities. The amenities must come from the catalogue.
+func (r *accommodationRepository) SaveDetails(details *models.AccommodationDetails) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO accommodation_details (service_id, property_address, check_in_time, check_out_time)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (service_id) DO UPDATE
+		SET property_address = EXCLUDED.property_address, check_in_time = EXCLUDED.check_in_time,
+			check_out_time = EXCLUDED.check_out_time, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+	err = tx.QueryRow(query, details.ServiceID, details.PropertyAddress, details.CheckInTime, details.CheckOutTime).
+		Scan(&details.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save accommodation details: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM accommodation_room_types WHERE service_id = $1`, details.ServiceID); err != nil {
+		return fmt.Errorf("failed to replace room types: %w", err)
+	}
+	details.NumberOfRooms, details.MaxGuests = 0, 0
+	for i := range details.RoomTypes {
+		room := &details.RoomTypes[i]
+		room.ServiceID = details.ServiceID
+
+		beds, err := json.Marshal(room.Beds)
+		if err != nil {
+			return fmt.Errorf("failed to encode beds: %w", err)
+		}
+		query := `
+			INSERT INTO accommodation_room_types (service_id, name, description, max_guests, quantity, beds)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`
+		err = tx.QueryRow(query, room.ServiceID, room.Name, room.Description, room.MaxGuests, room.Quantity, beds).
+			Scan(&room.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create room type: %w", err)
+		}
+
+		details.NumberOfRooms += room.Quantity
+		if room.MaxGuests > details.MaxGuests {
+			details.MaxGuests = room.MaxGuests
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM accommodation_amenities WHERE service_id = $1`, details.ServiceID); err != nil {
+		return fmt.Errorf("failed to replace accommodation amenities: %w", err)
+	}
+	amenityIDs := make([]int, len(details.Amenities))
+	for i, amenity := range details.Amenities {
+		amenityIDs[i] = amenity.ID
+	}
+	query = `
+		INSERT INTO accommodation_amenities (service_id, amenity_id)
+		SELECT $1, UNNEST($2::int[])
+		ON CONFLICT DO NOTHING`
+	if _, err := tx.Exec(query, details.ServiceID, pq.Array(amenityIDs)); err != nil {
+		return fmt.Errorf("failed to save accommodation amenities: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit accommodation details: %w", err)
+	}
+	return nil
+}
+
+// DeleteDetails removes the accommodation details of a service with its room types and amenities
+func (r *accommodationRepository) DeleteDetails(serviceID int) error {
+	if _, err := r.db.Exec(`DELETE FROM accommodation_details WHERE service_id = $1`, serviceID); err != nil {
+		return fmt.Errorf("failed to delete accommodation details: %w", err)
+	}
+	return nil
+}
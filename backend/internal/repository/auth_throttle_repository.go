@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"time"
+)
+
+// Errors returned by brute-force protection operations
+var (
+	ErrThrottleNotFound   = errors.New("no recent failed attempts")
+	ErrUnlockTokenInvalid = errors.New("unlock token is unknown or the lockout is over")
+)
+
+// AuthThrottleRepository defines the interface for recording failed authentication attempts
+type AuthThrottleRepository interface {
+	RecordFailure(failure *models.AuthFailure) error
+	GetThrottle(key string) (*models.AuthThrottle, error)
+	IncrementFailures(key string, now, windowStart time.Time) (*models.AuthThrottle, error)
+	Lock(key string, until time.Time, unlockTokenHash *string) error
+	Clear(key string) error
+	UnlockByToken(tokenHash string, now time.Time) (string, error)
+}
+
+// authThrottleRepository implements AuthThrottleRepository
+type authThrottleRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewAuthThrottleRepository creates a new brute-force protection repository
+func NewAuthThrottleRepository(db *sql.DB, logger *logger.Logger) AuthThrottleRepository {
+	return &authThrottleRepository{db: db, logger: logger}
+}
+
+// RecordFailure adds a failed attempt to the audit trail
+func (r *authThrottleRepository) RecordFailure(failure *models.AuthFailure) error {
+	query := `
+		INSERT INTO auth_failures (action, email, user_id, ip_address, user_agent, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, failure.Action, failure.Email, failure.UserID, failure.IPAddress,
+		failure.UserAgent, failure.Reason).Scan(&failure.ID, &failure.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+	return nil
+}
+
+// GetThrottle retrieves the failed attempt count of an account or client IP
+func (r *authThrottleRepository) GetThrottle(key string) (*models.AuthThrottle, error) {
+	throttle := &models.AuthThrottle{}
+	query := `SELECT key, failures, last_failure_at, locked_until FROM auth_throttles WHERE key = $1`
+
+	err := r.db.QueryRow(query, key).Scan(&throttle.Key, &throttle.Failures, &throttle.LastFailureAt, &throttle.LockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrThrottleNotFound
+		}
+		return nil, fmt.Errorf("failed to get throttle: %w", err)
+	}
+	return throttle, nil
+}
+
+// IncrementFailures counts a failed attempt. The count starts over when the previous failure
+// happened before windowStart or a lockout has run out.
+func (r *authThrottleRepository) IncrementFailures(key string, now, windowStart time.Time) (*models.AuthThrottle, error) {
+	throttle := &models.AuthThrottle{}
+	query := `
+		INSERT INTO auth_throttles (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN auth_throttles.last_failure_at < $3 OR auth_throttles.locked_until <= $2 THEN 1
+				ELSE auth_throttles.failures + 1
+			END,
+			locked_until = CASE WHEN auth_throttles.locked_until <= $2 THEN NULL ELSE auth_throttles.locked_until END,
+			unlock_token_hash = CASE WHEN auth_throttles.locked_until <= $2 THEN NULL ELSE auth_throttles.unlock_token_hash END,
+			last_failure_at = $2
+		RETURNING key, failures, last_failure_at, locked_until`
+
+	err := r.db.QueryRow(query, key, now, windowStart).Scan(&throttle.Key, &throttle.Failures,
+		&throttle.LastFailureAt, &throttle.LockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed attempt: %w", err)
+	}
+	return throttle, nil
+}
+
+// Lock locks an account or client IP out until the given time. The unlock token hash, if
+// any, lets the owner of the account lift the lockout early.
+func (r *authThrottleRepository) Lock(key string, until time.Time, unlockTokenHash *string) error {
+	query := `UPDATE auth_throttles SET locked_until = $1, unlock_token_hash = $2 WHERE key = $3`
+	if _, err := r.db.Exec(query, until, unlockTokenHash, key); err != nil {
+		return fmt.Errorf("failed to lock out: %w", err)
+	}
+	return nil
+}
+
+// Clear forgets the failed attempts of an account or client IP
+func (r *authThrottleRepository) Clear(key string) error {
+	if _, err := r.db.Exec(`DELETE FROM auth_throttles WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to clear throttle: %w", err)
+	}
+	return nil
+}
+
+// UnlockByToken lifts a running lockout with its unlock token and returns the key it applied to
+func (r *authThrottleRepository) UnlockByToken(tokenHash string, now time.Time) (string, error) {
+	var key string
+	query := `DELETE FROM auth_throttles WHERE unlock_token_hash = $1 AND locked_until > $2 RETURNING key`
+
+	err := r.db.QueryRow(query, tokenHash, now).Scan(&key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrUnlockTokenInvalid
+		}
+		return "", fmt.Errorf("failed to unlock: %w", err)
+	}
+	return key, nil
+}
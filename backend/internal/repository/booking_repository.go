@@ -2,17 +2,50 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// ErrBookingStatusChanged is returned when a booking no longer has the status a transition started from
+var ErrBookingStatusChanged = errors.New("booking status was changed by another request")
+
+// bookingColumns lists the columns of a booking in the order scanBooking reads them
+const bookingColumns = `id, user_id, service_id, booking_date_start, booking_date_end, total_price, status, created_at, updated_at,
+	vehicle_id, vehicle_class_id, pickup_location_id, dropoff_location_id`
+
+// bookingListSpec lists the fields bookings can be sorted and filtered by
+var bookingListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":                 {column: "id", kind: listInt, sortable: true},
+		"user_id":            {column: "user_id", kind: listInt, sortable: true},
+		"service_id":         {column: "service_id", kind: listInt, sortable: true},
+		"status":             {column: "status", kind: listText, sortable: true},
+		"booking_date_start": {column: "booking_date_start", kind: listTime, sortable: true},
+		"booking_date_end":   {column: "booking_date_end", kind: listTime, sortable: true},
+		"total_price":        {column: "total_price", kind: listFloat, sortable: true},
+		"vehicle_id":         {column: "vehicle_id", kind: listInt},
+		"created_at":         {column: "created_at", kind: listTime, sortable: true},
+	},
+	defaultSort: "-created_at",
+	idColumn:    "id",
+}
+
 // BookingRepository interface defines methods for booking operations
 type BookingRepository interface {
 	CreateBooking(booking *models.Booking) error
-	GetBookingsByUserID(userID int) ([]models.Booking, error)
+	CreateBookingIfAvailable(booking *models.Booking, check func(capacity int, overlapping []models.Booking) error) error
+	GetActiveBookingsForService(serviceID int, from, to time.Time) ([]models.Booking, error)
+	GetBookingsByUserID(userID int, query models.ListQuery) ([]models.Booking, *models.PageInfo, error)
+	GetAllBookings(query models.ListQuery) ([]models.Booking, *models.PageInfo, error)
 	GetBookingByID(id int) (*models.Booking, error)
-	UpdateBookingStatus(id int, status string) error
+	UpdateBookingStatus(id int, from, to models.BookingStatus, changedBy int, reason string) error
+	GetBookingStatusHistory(bookingID int) ([]models.BookingStatusHistory, error)
+	CancelBooking(booking *models.Booking, changedBy int, reason string, refundFor func(booking *models.Booking, netPaid float64) (float64, error)) (int, error)
 	DeleteBooking(id int) error
 }
 
@@ -27,67 +60,184 @@ func NewBookingRepository(db *sql.DB, logger *logger.Logger) BookingRepository {
 	return &bookingRepository{db: db, logger: logger}
 }
 
-// CreateBooking creates a new booking
+// CreateBooking creates a new booking and records its initial status
 func (r *bookingRepository) CreateBooking(booking *models.Booking) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertBooking(tx, booking); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit booking: %w", err)
+	}
+
+	return nil
+}
+
+// insertBooking inserts a booking together with the history entry of its initial status
+func insertBooking(q queryer, booking *models.Booking) error {
 	query := `
-		INSERT INTO bookings (user_id, service_id, booking_date_start, booking_date_end, total_price, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO bookings (user_id, service_id, booking_date_start, booking_date_end, total_price, status,
+			vehicle_id, vehicle_class_id, pickup_location_id, dropoff_location_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, booking.UserID, booking.ServiceID,
-		booking.BookingDateStart, booking.BookingDateEnd, booking.TotalPrice, booking.Status).Scan(
+	err := q.QueryRow(query, booking.UserID, booking.ServiceID,
+		booking.BookingDateStart, booking.BookingDateEnd, booking.TotalPrice, booking.Status,
+		booking.VehicleID, booking.VehicleClassID, booking.PickupLocationID, booking.DropoffLocationID).Scan(
 		&booking.ID, &booking.CreatedAt, &booking.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create booking: %w", err)
 	}
 
+	return insertBookingStatusHistory(q, booking.ID, "", booking.Status, booking.UserID, "booking created")
+}
+
+// CreateBookingIfAvailable creates a booking once check accepts the bookings that overlap it.
+// The service row is locked for the duration of the transaction so that concurrent
+// bookings for the same service are checked one after the other.
+func (r *bookingRepository) CreateBookingIfAvailable(booking *models.Booking, check func(capacity int, overlapping []models.Booking) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var capacity int
+	err = tx.QueryRow(`SELECT capacity FROM services WHERE id = $1 FOR UPDATE`, booking.ServiceID).Scan(&capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("service not found")
+		}
+		return fmt.Errorf("failed to lock service: %w", err)
+	}
+
+	overlapping, err := queryActiveBookings(tx, booking.ServiceID, booking.BookingDateStart, booking.BookingDateEnd)
+	if err != nil {
+		return err
+	}
+
+	if err := check(capacity, overlapping); err != nil {
+		return err
+	}
+
+	if err := insertBooking(tx, booking); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit booking: %w", err)
+	}
+
 	return nil
 }
 
-// GetBookingsByUserID retrieves bookings by user ID
-func (r *bookingRepository) GetBookingsByUserID(userID int) ([]models.Booking, error) {
+// GetActiveBookingsForService retrieves the bookings holding a slot of a service between from and to
+func (r *bookingRepository) GetActiveBookingsForService(serviceID int, from, to time.Time) ([]models.Booking, error) {
+	return queryActiveBookings(r.db, serviceID, from, to)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryActiveBookings retrieves the bookings of a service that overlap [from, to)
+// and are in a status that blocks the slot
+func queryActiveBookings(q queryer, serviceID int, from, to time.Time) ([]models.Booking, error) {
 	query := `
-		SELECT id, user_id, service_id, booking_date_start, booking_date_end, total_price, status, created_at, updated_at
+		SELECT ` + bookingColumns + `
 		FROM bookings
-		WHERE user_id = $1
-		ORDER BY created_at DESC`
+		WHERE service_id = $1
+		  AND booking_date_start < $3
+		  AND booking_date_end > $2
+		  AND status = ANY($4)
+		ORDER BY booking_date_start`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := q.Query(query, serviceID, from, to, pq.Array(models.BlockingBookingStatuses))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get bookings: %w", err)
+		return nil, fmt.Errorf("failed to get active bookings: %w", err)
 	}
 	defer rows.Close()
 
 	var bookings []models.Booking
 	for rows.Next() {
-		var booking models.Booking
-		err := rows.Scan(
-			&booking.ID, &booking.UserID, &booking.ServiceID,
-			&booking.BookingDateStart, &booking.BookingDateEnd,
-			&booking.TotalPrice, &booking.Status, &booking.CreatedAt, &booking.UpdatedAt,
-		)
+		booking, err := scanBooking(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan booking: %w", err)
 		}
-		bookings = append(bookings, booking)
+		bookings = append(bookings, *booking)
 	}
 
 	return bookings, nil
 }
 
-// GetBookingByID retrieves a booking by ID
-func (r *bookingRepository) GetBookingByID(id int) (*models.Booking, error) {
+// scanBooking scans a row selected with bookingColumns
+func scanBooking(row rowScanner) (*models.Booking, error) {
 	booking := &models.Booking{}
-	query := `
-		SELECT id, user_id, service_id, booking_date_start, booking_date_end, total_price, status, created_at, updated_at
-		FROM bookings WHERE id = $1`
-
-	err := r.db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&booking.ID, &booking.UserID, &booking.ServiceID,
 		&booking.BookingDateStart, &booking.BookingDateEnd,
 		&booking.TotalPrice, &booking.Status, &booking.CreatedAt, &booking.UpdatedAt,
+		&booking.VehicleID, &booking.VehicleClassID, &booking.PickupLocationID, &booking.DropoffLocationID,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// GetBookingsByUserID retrieves a page of the bookings of a user, the most recent first by default
+func (r *bookingRepository) GetBookingsByUserID(userID int, query models.ListQuery) ([]models.Booking, *models.PageInfo, error) {
+	bookings, page, err := r.listBookings(query, []string{"user_id = $1"}, []interface{}{userID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bookings: %w", err)
+	}
+	return bookings, page, nil
+}
+
+// GetAllBookings retrieves a page of the bookings of every user, the most recent first by default
+func (r *bookingRepository) GetAllBookings(query models.ListQuery) ([]models.Booking, *models.PageInfo, error) {
+	bookings, page, err := r.listBookings(query, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bookings: %w", err)
+	}
+	return bookings, page, nil
+}
+
+// listBookings retrieves a page of the bookings matching conditions
+func (r *bookingRepository) listBookings(query models.ListQuery, conditions []string, args []interface{}) ([]models.Booking, *models.PageInfo, error) {
+	bookings := []models.Booking{}
+	page, err := queryPage(r.db, bookingListSpec, query, bookingColumns, "bookings", conditions, args,
+		func(row rowScanner) error {
+			booking, err := scanBooking(row)
+			if err != nil {
+				return fmt.Errorf("failed to scan booking: %w", err)
+			}
+			bookings = append(bookings, *booking)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	return bookings, page, nil
+}
+
+// GetBookingByID retrieves a booking by ID
+func (r *bookingRepository) GetBookingByID(id int) (*models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings WHERE id = $1`
+
+	booking, err := scanBooking(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("booking not found")
@@ -98,15 +248,152 @@ func (r *bookingRepository) GetBookingByID(id int) (*models.Booking, error) {
 	return booking, nil
 }
 
-// UpdateBookingStatus updates booking status
-func (r *bookingRepository) UpdateBookingStatus(id int, status string) error {
-	query := `UPDATE bookings SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+// UpdateBookingStatus moves a booking from one status to another and records the change.
+// It returns ErrBookingStatusChanged when the booking is no longer in the from status.
+func (r *bookingRepository) UpdateBookingStatus(id int, from, to models.BookingStatus, changedBy int, reason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionBookingStatus(tx, id, from, to, changedBy, reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit booking status: %w", err)
+	}
+
+	return nil
+}
+
+// CancelBooking cancels a booking in a transaction holding a lock on it, so no payment can
+// settle meanwhile. refundFor receives the locked booking and the amount paid for it and
+// returns the amount to refund; when it is positive, a pending refund is recorded in the same
+// transaction. It returns the ID of the refund record, or 0.
+func (r *bookingRepository) CancelBooking(booking *models.Booking, changedBy int, reason string, refundFor func(booking *models.Booking, netPaid float64) (float64, error)) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockBooking(tx, booking.ID)
+	if err != nil {
+		return 0, err
+	}
+	if locked.Status != booking.Status {
+		return 0, ErrBookingStatusChanged
+	}
+
+	netPaid, err := netPaidAmount(tx, booking.ID)
+	if err != nil {
+		return 0, err
+	}
+	refundAmount, err := refundFor(locked, netPaid)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := transitionBookingStatus(tx, booking.ID, booking.Status, models.BookingStatusCancelled, changedBy, reason); err != nil {
+		return 0, err
+	}
+
+	refundID := 0
+	if refundAmount > 0 {
+		// The refund goes back through the method of the latest completed payment
+		query := `
+			INSERT INTO payments (user_id, booking_id, amount, payment_method, status, payment_type)
+			VALUES ($1, $2, $3, COALESCE((
+				SELECT payment_method FROM payments
+				WHERE booking_id = $2 AND payment_type = 'payment' AND status = 'completed'
+				ORDER BY payment_date DESC LIMIT 1
+			), 'original_method'), 'pending', 'refund')
+			RETURNING id`
+
+		if err := tx.QueryRow(query, booking.UserID, booking.ID, refundAmount).Scan(&refundID); err != nil {
+			return 0, fmt.Errorf("failed to create refund: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit cancellation: %w", err)
+	}
+
+	booking.Status = models.BookingStatusCancelled
+	return refundID, nil
+}
+
+// GetBookingStatusHistory retrieves the status changes of a booking, oldest first
+func (r *bookingRepository) GetBookingStatusHistory(bookingID int) ([]models.BookingStatusHistory, error) {
+	query := `
+		SELECT id, booking_id, COALESCE(from_status, ''), to_status, changed_by, reason, created_at
+		FROM booking_status_history
+		WHERE booking_id = $1
+		ORDER BY created_at, id`
 
-	_, err := r.db.Exec(query, status, id)
+	rows, err := r.db.Query(query, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.BookingStatusHistory{}
+	for rows.Next() {
+		var entry models.BookingStatusHistory
+		var changedBy sql.NullInt64
+		err := rows.Scan(
+			&entry.ID, &entry.BookingID, &entry.FromStatus, &entry.ToStatus,
+			&changedBy, &entry.Reason, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking status history: %w", err)
+		}
+		if changedBy.Valid {
+			id := int(changedBy.Int64)
+			entry.ChangedBy = &id
+		}
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// transitionBookingStatus updates the status of a booking only if it still has the from
+// status, and records the change in the booking status history
+func transitionBookingStatus(q queryer, id int, from, to models.BookingStatus, changedBy int, reason string) error {
+	query := `
+		UPDATE bookings
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3`
+
+	result, err := q.Exec(query, to, id, from)
 	if err != nil {
 		return fmt.Errorf("failed to update booking status: %w", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrBookingStatusChanged
+	}
+
+	return insertBookingStatusHistory(q, id, from, to, changedBy, reason)
+}
+
+// insertBookingStatusHistory records a status change of a booking.
+// An empty from status marks the initial status and a zero changedBy a system change.
+func insertBookingStatusHistory(q queryer, bookingID int, from, to models.BookingStatus, changedBy int, reason string) error {
+	query := `
+		INSERT INTO booking_status_history (booking_id, from_status, to_status, changed_by, reason)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), $5)`
+
+	if _, err := q.Exec(query, bookingID, string(from), to, changedBy, reason); err != nil {
+		return fmt.Errorf("failed to record booking status history: %w", err)
+	}
 	return nil
 }
 
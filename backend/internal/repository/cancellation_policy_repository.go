@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+)
+
+// CancellationPolicyRepository interface defines methods for cancellation policy operations
+type CancellationPolicyRepository interface {
+	GetPlatformPolicies() ([]models.CancellationPolicy, error)
+	GetPoliciesByCreator(userID int) ([]models.CancellationPolicy, error)
+	GetPolicyByID(id int) (*models.CancellationPolicy, error)
+	GetDefaultPolicy() (*models.CancellationPolicy, error)
+	CreatePolicy(policy *models.CancellationPolicy) error
+}
+
+// cancellationPolicyRepository implements CancellationPolicyRepository
+type cancellationPolicyRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewCancellationPolicyRepository creates a new cancellation policy repository
+func NewCancellationPolicyRepository(db *sql.DB, logger *logger.Logger) CancellationPolicyRepository {
+	return &cancellationPolicyRepository{db: db, logger: logger}
+}
+
+// defaultPolicyName is the platform policy applied to services without a policy
+const defaultPolicyName = "flexible"
+
+// GetPlatformPolicies retrieves the policies every provider can use
+func (r *cancellationPolicyRepository) GetPlatformPolicies() ([]models.CancellationPolicy, error) {
+	query := `
+		SELECT id, name, description, tiers, created_by, created_at, updated_at
+		FROM cancellation_policies
+		WHERE created_by IS NULL
+		ORDER BY id`
+
+	return r.queryPolicies(query)
+}
+
+// GetPoliciesByCreator retrieves the custom policies created by a user
+func (r *cancellationPolicyRepository) GetPoliciesByCreator(userID int) ([]models.CancellationPolicy, error) {
+	query := `
+		SELECT id, name, description, tiers, created_by, created_at, updated_at
+		FROM cancellation_policies
+		WHERE created_by = $1
+		ORDER BY created_at DESC`
+
+	return r.queryPolicies(query, userID)
+}
+
+// GetPolicyByID retrieves a cancellation policy by ID
+func (r *cancellationPolicyRepository) GetPolicyByID(id int) (*models.CancellationPolicy, error) {
+	query := `
+		SELECT id, name, description, tiers, created_by, created_at, updated_at
+		FROM cancellation_policies WHERE id = $1`
+
+	policy, err := scanPolicy(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("cancellation policy not found")
+		}
+		return nil, fmt.Errorf("failed to get cancellation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// GetDefaultPolicy retrieves the platform policy applied to services without a policy
+func (r *cancellationPolicyRepository) GetDefaultPolicy() (*models.CancellationPolicy, error) {
+	query := `
+		SELECT id, name, description, tiers, created_by, created_at, updated_at
+		FROM cancellation_policies
+		WHERE name = $1 AND created_by IS NULL
+		ORDER BY id LIMIT 1`
+
+	policy, err := scanPolicy(r.db.QueryRow(query, defaultPolicyName))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("default cancellation policy not found")
+		}
+		return nil, fmt.Errorf("failed to get default cancellation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// CreatePolicy creates a new cancellation policy
+func (r *cancellationPolicyRepository) CreatePolicy(policy *models.CancellationPolicy) error {
+	tiers, err := json.Marshal(policy.Tiers)
+	if err != nil {
+		return fmt.Errorf("failed to encode refund tiers: %w", err)
+	}
+
+	query := `
+		INSERT INTO cancellation_policies (name, description, tiers, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	err = r.db.QueryRow(query, policy.Name, policy.Description, tiers, policy.CreatedBy).Scan(
+		&policy.ID, &policy.CreatedAt, &policy.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create cancellation policy: %w", err)
+	}
+	return nil
+}
+
+// queryPolicies runs a query returning cancellation policy rows
+func (r *cancellationPolicyRepository) queryPolicies(query string, args ...interface{}) ([]models.CancellationPolicy, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellation policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.CancellationPolicy{}
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cancellation policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPolicy scans a cancellation policy row and decodes its refund tiers
+func scanPolicy(row rowScanner) (*models.CancellationPolicy, error) {
+	policy := &models.CancellationPolicy{}
+	var tiers []byte
+	err := row.Scan(
+		&policy.ID, &policy.Name, &policy.Description, &tiers,
+		&policy.CreatedBy, &policy.CreatedAt, &policy.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(tiers, &policy.Tiers); err != nil {
+		return nil, fmt.Errorf("failed to decode refund tiers: %w", err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Errors returned by the car rental repository
+var (
+	ErrVehicleNotFound        = errors.New("vehicle not found")
+	ErrVehiclePlateTaken      = errors.New("a vehicle with this plate number is already listed")
+	ErrVehicleBooked          = errors.New("vehicle has upcoming bookings")
+	ErrVehicleClassNotFound   = errors.New("vehicle class not found")
+	ErrRentalLocationNotFound = errors.New("rental location not found")
+)
+
+// vehicleColumns selects a vehicle with the name of its class for scanVehicle
+const vehicleColumns = `v.id, v.service_id, v.class_id, c.name, v.make, v.model, v.year, v.plate_number, v.seats,
+	v.transmission, v.fuel_policy, v.active, v.created_at, v.updated_at`
+
+// CarRentalRepository defines the interface for the car rental details of services and their
+// fleet of vehicles
+type CarRentalRepository interface {
+	GetDetails(serviceIDs []int) (map[int]*models.CarRentalDetails, error)
+	SaveDetails(details *models.CarRentalDetails) error
+	DeleteDetails(serviceID int) error
+	GetVehicles(serviceID int, activeOnly bool) ([]models.Vehicle, error)
+	GetVehicleByID(serviceID, vehicleID int) (*models.Vehicle, error)
+	CreateVehicle(vehicle *models.Vehicle) error
+	UpdateVehicle(vehicle *models.Vehicle) error
+	DeleteVehicle(serviceID, vehicleID int) error
+}
+
+// carRentalRepository implements CarRentalRepository
+type carRentalRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewCarRentalRepository creates a new car rental repository
+func NewCarRentalRepository(db *sql.DB, logger *logger.Logger) CarRentalRepository {
+	return &carRentalRepository{db: db, logger: logger}
+}
+
+// GetDetails retrieves the car rental details of services with their locations and vehicle
+// classes, by service ID. Services without details are left out.
+func (r *carRentalRepository) GetDetails(serviceIDs []int) (map[int]*models.CarRentalDetails, error) {
+	details := make(map[int]*models.CarRentalDetails)
+	if len(serviceIDs) == 0 {
+		return details, nil
+	}
+
+	query := `
+		SELECT service_id, min_driver_age, updated_at
+		FROM car_rental_details
+		WHERE service_id = ANY($1)`
+
+	rows, err := r.db.Query(query, pq.Array(serviceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car rental details: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d := &models.CarRentalDetails{Locations: []models.RentalLocation{}, VehicleClasses: []models.VehicleClass{}}
+		if err := rows.Scan(&d.ServiceID, &d.MinDriverAge, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan car rental details: %w", err)
+		}
+		details[d.ServiceID] = d
+	}
+	if len(details) == 0 {
+		return details, nil
+	}
+
+	if err := r.loadLocations(details, serviceIDs); err != nil {
+		return nil, err
+	}
+	if err := r.loadVehicleClasses(details, serviceIDs); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// loadLocations adds the pickup and dropoff locations of car rentals to their details
+func (r *carRentalRepository) loadLocations(details map[int]*models.CarRentalDetails, serviceIDs []int) error {
+	query := `
+		SELECT id, service_id, name, address, is_pickup, is_dropoff
+		FROM rental_locations
+		WHERE service_id = ANY($1)
+		ORDER BY service_id, id`
+
+	rows, err := r.db.Query(query, pq.Array(serviceIDs))
+	if err != nil {
+		return fmt.Errorf("failed to get rental locations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.RentalLocation
+		if err := rows.Scan(&l.ID, &l.ServiceID, &l.Name, &l.Address, &l.IsPickup, &l.IsDropoff); err != nil {
+			return fmt.Errorf("failed to scan rental location: %w", err)
+		}
+		details[l.ServiceID].Locations = append(details[l.ServiceID].Locations, l)
+	}
+	return nil
+}
+
+// loadVehicleClasses adds the vehicle classes of car rentals to their details and counts
+// their active vehicles
+func (r *carRentalRepository) loadVehicleClasses(details map[int]*models.CarRentalDetails, serviceIDs []int) error {
+	query := `
+		SELECT c.id, c.service_id, c.name, c.description, c.daily_rate, c.mileage_limit_km, c.extra_km_fee,
+			(SELECT COUNT(*) FROM vehicles v WHERE v.class_id = c.id AND v.active)
+		FROM vehicle_classes c
+		WHERE c.service_id = ANY($1)
+		ORDER BY c.service_id, c.daily_rate, c.id`
+
+	rows, err := r.db.Query(query, pq.Array(serviceIDs))
+	if err != nil {
+		return fmt.Errorf("failed to get vehicle classes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.VehicleClass
+		err := rows.Scan(&c.ID, &c.ServiceID, &c.Name, &c.Description, &c.DailyRate, &c.MileageLimitKm,
+			&c.ExtraKmFee, &c.NumberOfVehicles)
+		if err != nil {
+			return fmt.Errorf("failed to scan vehicle class: %w", err)
+		}
+
+		d := details[c.ServiceID]
+		d.VehicleClasses = append(d.VehicleClasses, c)
+		d.NumberOfVehicles += c.NumberOfVehicles
+	}
+	return nil
+}
+
+// SaveDetails creates or updates the car rental details of a service. Locations and vehicle
+// classes with an ID are updated, those without are created and the other existing ones are
+// removed; classes that still have vehicles cannot be removed.
+func (r *carRentalRepository) SaveDetails(details *models.CarRentalDetails) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO car_rental_details (service_id, min_driver_age)
+		VALUES ($1, $2)
+		ON CONFLICT (service_id) DO UPDATE
+		SET min_driver_age = EXCLUDED.min_driver_age, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+	if err := tx.QueryRow(query, details.ServiceID, details.MinDriverAge).Scan(&details.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save car rental details: %w", err)
+	}
+
+	if err := saveRentalLocations(tx, details); err != nil {
+		return err
+	}
+	if err := saveVehicleClasses(tx, details); err != nil {
+		return err
+	}
+	if err := syncFleetCapacity(tx, details.ServiceID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit car rental details: %w", err)
+	}
+	return nil
+}
+
+// saveRentalLocations replaces the locations of a car rental, keeping those with an ID
+func saveRentalLocations(q queryer, details *models.CarRentalDetails) error {
+	keep := []int{}
+	for _, l := range details.Locations {
+		if l.ID > 0 {
+			keep = append(keep, l.ID)
+		}
+	}
+	query := `DELETE FROM rental_locations WHERE service_id = $1 AND NOT (id = ANY($2))`
+	if _, err := q.Exec(query, details.ServiceID, pq.Array(keep)); err != nil {
+		return fmt.Errorf("failed to remove rental locations: %w", err)
+	}
+
+	for i := range details.Locations {
+		l := &details.Locations[i]
+		l.ServiceID = details.ServiceID
+
+		if l.ID == 0 {
+			query := `
+				INSERT INTO rental_locations (service_id, name, address, is_pickup, is_dropoff)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id`
+			if err := q.QueryRow(query, l.ServiceID, l.Name, l.Address, l.IsPickup, l.IsDropoff).Scan(&l.ID); err != nil {
+				return fmt.Errorf("failed to create rental location: %w", err)
+			}
+			continue
+		}
+
+		query := `
+			UPDATE rental_locations
+			SET name = $1, address = $2, is_pickup = $3, is_dropoff = $4
+			WHERE id = $5 AND service_id = $6`
+		result, err := q.Exec(query, l.Name, l.Address, l.IsPickup, l.IsDropoff, l.ID, l.ServiceID)
+		if err != nil {
+			return fmt.Errorf("failed to update rental location: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		} else if rows == 0 {
+			return ErrRentalLocationNotFound
+		}
+	}
+	return nil
+}
+
+// saveVehicleClasses replaces the vehicle classes of a car rental, keeping those with an ID
+func saveVehicleClasses(q queryer, details *models.CarRentalDetails) error {
+	keep := []int{}
+	for _, c := range details.VehicleClasses {
+		if c.ID > 0 {
+			keep = append(keep, c.ID)
+		}
+	}
+	query := `DELETE FROM vehicle_classes WHERE service_id = $1 AND NOT (id = ANY($2))`
+	if _, err := q.Exec(query, details.ServiceID, pq.Array(keep)); err != nil {
+		return fmt.Errorf("failed to remove vehicle classes: %w", err)
+	}
+
+	for i := range details.VehicleClasses {
+		c := &details.VehicleClasses[i]
+		c.ServiceID = details.ServiceID
+
+		if c.ID == 0 {
+			query := `
+				INSERT INTO vehicle_classes (service_id, name, description, daily_rate, mileage_limit_km, extra_km_fee)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id`
+			err := q.QueryRow(query, c.ServiceID, c.Name, c.Description, c.DailyRate, c.MileageLimitKm, c.ExtraKmFee).
+				Scan(&c.ID)
+			if err != nil {
+				return fmt.Errorf("failed to create vehicle class: %w", err)
+			}
+			continue
+		}
+
+		query := `
+			UPDATE vehicle_classes
+			SET name = $1, description = $2, daily_rate = $3, mileage_limit_km = $4, extra_km_fee = $5
+			WHERE id = $6 AND service_id = $7`
+		result, err := q.Exec(query, c.Name, c.Description, c.DailyRate, c.MileageLimitKm, c.ExtraKmFee, c.ID, c.ServiceID)
+		if err != nil {
+			return fmt.Errorf("failed to update vehicle class: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		} else if rows == 0 {
+			return ErrVehicleClassNotFound
+		}
+	}
+	return nil
+}
+
+// DeleteDetails removes the car rental details of a service with its locations, vehicle
+// classes and vehicles
+func (r *carRentalRepository) DeleteDetails(serviceID int) error {
+	if _, err := r.db.Exec(`DELETE FROM car_rental_details WHERE service_id = $1`, serviceID); err != nil {
+		return fmt.Errorf("failed to delete car rental details: %w", err)
+	}
+	return nil
+}
+
+// GetVehicles retrieves the fleet of a car rental by class, only the vehicles that can be
+// booked when activeOnly is set
+func (r *carRentalRepository) GetVehicles(serviceID int, activeOnly bool) ([]models.Vehicle, error) {
+	query := `
+		SELECT ` + vehicleColumns + `
+		FROM vehicles v
+		JOIN vehicle_classes c ON c.id = v.class_id
+		WHERE v.service_id = $1 AND (NOT $2 OR v.active)
+		ORDER BY c.daily_rate, c.id, v.make, v.model, v.id`
+
+	rows, err := r.db.Query(query, serviceID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vehicles: %w", err)
+	}
+	defer rows.Close()
+
+	vehicles := []models.Vehicle{}
+	for rows.Next() {
+		vehicle, err := scanVehicle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle: %w", err)
+		}
+		vehicles = append(vehicles, *vehicle)
+	}
+	return vehicles, nil
+}
+
+// GetVehicleByID retrieves a vehicle of the fleet of a car rental
+func (r *carRentalRepository) GetVehicleByID(serviceID, vehicleID int) (*models.Vehicle, error) {
+	query := `
+		SELECT ` + vehicleColumns + `
+		FROM vehicles v
+		JOIN vehicle_classes c ON c.id = v.class_id
+		WHERE v.id = $1 AND v.service_id = $2`
+
+	vehicle, err := scanVehicle(r.db.QueryRow(query, vehicleID, serviceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVehicleNotFound
+		}
+		return nil, fmt.Errorf("failed to get vehicle: %w", err)
+	}
+	return vehicle, nil
+}
+
+// CreateVehicle adds a vehicle to the fleet of a car rental. Plate numbers are unique
+// across the platform.
+func (r *carRentalRepository) CreateVehicle(vehicle *models.Vehicle) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO vehicles (service_id, class_id, make, model, year, plate_number, seats, transmission, fuel_policy, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (plate_number) DO NOTHING
+		RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(query, vehicle.ServiceID, vehicle.ClassID, vehicle.Make, vehicle.Model, vehicle.Year,
+		vehicle.PlateNumber, vehicle.Seats, vehicle.Transmission, vehicle.FuelPolicy, vehicle.Active).
+		Scan(&vehicle.ID, &vehicle.CreatedAt, &vehicle.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrVehiclePlateTaken
+		}
+		return fmt.Errorf("failed to create vehicle: %w", err)
+	}
+
+	if err := syncFleetCapacity(tx, vehicle.ServiceID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit vehicle: %w", err)
+	}
+	return nil
+}
+
+// UpdateVehicle updates a vehicle of the fleet of a car rental. The service row is locked
+// so that a vehicle is not taken out of the fleet while a booking is given to it.
+func (r *carRentalRepository) UpdateVehicle(vehicle *models.Vehicle) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockFleet(tx, vehicle.ServiceID); err != nil {
+		return err
+	}
+
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM vehicles WHERE plate_number = $1 AND id <> $2)`
+	if err := tx.QueryRow(query, vehicle.PlateNumber, vehicle.ID).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to check plate number: %w", err)
+	}
+	if taken {
+		return ErrVehiclePlateTaken
+	}
+
+	query = `
+		UPDATE vehicles
+		SET class_id = $1, make = $2, model = $3, year = $4, plate_number = $5, seats = $6,
+			transmission = $7, fuel_policy = $8, active = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND service_id = $11
+		RETURNING created_at, updated_at`
+	err = tx.QueryRow(query, vehicle.ClassID, vehicle.Make, vehicle.Model, vehicle.Year, vehicle.PlateNumber,
+		vehicle.Seats, vehicle.Transmission, vehicle.FuelPolicy, vehicle.Active, vehicle.ID, vehicle.ServiceID).
+		Scan(&vehicle.CreatedAt, &vehicle.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrVehicleNotFound
+		}
+		return fmt.Errorf("failed to update vehicle: %w", err)
+	}
+
+	if err := syncFleetCapacity(tx, vehicle.ServiceID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit vehicle: %w", err)
+	}
+	return nil
+}
+
+// DeleteVehicle removes a vehicle from the fleet of a car rental. Vehicles reserved by
+// bookings that have not ended yet cannot be removed and return ErrVehicleBooked.
+func (r *carRentalRepository) DeleteVehicle(serviceID, vehicleID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockFleet(tx, serviceID); err != nil {
+		return err
+	}
+
+	var booked bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM bookings
+			WHERE vehicle_id = $1 AND status = ANY($2) AND booking_date_end > CURRENT_TIMESTAMP
+		)`
+	if err := tx.QueryRow(query, vehicleID, pq.Array(models.BlockingBookingStatuses)).Scan(&booked); err != nil {
+		return fmt.Errorf("failed to check vehicle bookings: %w", err)
+	}
+	if booked {
+		return ErrVehicleBooked
+	}
+
+	result, err := tx.Exec(`DELETE FROM vehicles WHERE id = $1 AND service_id = $2`, vehicleID, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete vehicle: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrVehicleNotFound
+	}
+
+	if err := syncFleetCapacity(tx, serviceID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit vehicle deletion: %w", err)
+	}
+	return nil
+}
+
+// lockFleet locks the service row of a car rental, which bookings also lock while they are
+// given a vehicle
+func lockFleet(q queryer, serviceID int) error {
+	var id int
+	if err := q.QueryRow(`SELECT id FROM services WHERE id = $1 FOR UPDATE`, serviceID).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrServiceNotFound
+		}
+		return fmt.Errorf("failed to lock service: %w", err)
+	}
+	return nil
+}
+
+// syncFleetCapacity sets the capacity of a car rental to the number of its active vehicles,
+// so that its availability counts vehicles. A service always has a capacity of at least one.
+func syncFleetCapacity(q queryer, serviceID int) error {
+	query := `
+		UPDATE services
+		SET capacity = GREATEST(1, (SELECT COUNT(*) FROM vehicles WHERE service_id = $1 AND active)),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+	if _, err := q.Exec(query, serviceID); err != nil {
+		return fmt.Errorf("failed to update fleet capacity: %w", err)
+	}
+	return nil
+}
+
+// scanVehicle scans a row selected with vehicleColumns
+func scanVehicle(row rowScanner) (*models.Vehicle, error) {
+	v := &models.Vehicle{}
+	err := row.Scan(
+		&v.ID, &v.ServiceID, &v.ClassID, &v.ClassName, &v.Make, &v.Model, &v.Year, &v.PlateNumber, &v.Seats,
+		&v.Transmission, &v.FuelPolicy, &v.Active, &v.CreatedAt, &v.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -2,15 +2,45 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
+	"strings"
 )
 
+// ErrDestinationNotFound is returned when a destination does not exist
+var ErrDestinationNotFound = errors.New("destination not found")
+
+// destinationColumns selects a destination row for scanDestination
+const destinationColumns = `id, name, description, location, image_url, rating, reviews, price, latitude, longitude,
+	area::text, place_id, COALESCE((SELECT code FROM places WHERE places.id = destinations.place_id), ''),
+	created_at, updated_at`
+
+// destinationListSpec lists the fields destinations can be sorted and filtered by
+var destinationListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":         {column: "id", kind: listInt, sortable: true},
+		"name":       {column: "name", kind: listText, sortable: true},
+		"location":   {column: "location", kind: listText, sortable: true},
+		"rating":     {column: "rating", kind: listFloat, sortable: true},
+		"reviews":    {column: "reviews", kind: listInt, sortable: true},
+		"price":      {column: "price", kind: listFloat, sortable: true},
+		"latitude":   {column: "latitude", kind: listFloat},
+		"longitude":  {column: "longitude", kind: listFloat},
+		"place_id":   {column: "place_id", kind: listInt},
+		"created_at": {column: "created_at", kind: listTime, sortable: true},
+	},
+	defaultSort: "-rating",
+	idColumn:    "id",
+}
+
 // DestinationRepository interface defines methods for destination operations
 type DestinationRepository interface {
-	GetAllDestinations() ([]models.Destination, error)
+	GetAllDestinations(query models.ListQuery) ([]models.Destination, *models.PageInfo, error)
 	GetDestinationByID(id int) (*models.Destination, error)
+	GetDestinationsInPlace(placeID int, query models.ListQuery) ([]models.Destination, *models.PageInfo, error)
+	GetNearbyDestinations(center models.GeoPoint, radiusKm float64, limit int) ([]models.NearbyDestination, error)
 	CreateDestination(destination *models.Destination) error
 	UpdateDestination(destination *models.Destination) error
 	DeleteDestination(id int) error
@@ -18,7 +48,7 @@ type DestinationRepository interface {
 
 // destinationRepository implements DestinationRepository
 type destinationRepository struct {
-	db *sql.DB
+	db     *sql.DB
 	logger *logger.Logger
 }
 
@@ -27,51 +57,60 @@ func NewDestinationRepository(db *sql.DB, logger *logger.Logger) DestinationRepo
 	return &destinationRepository{db: db, logger: logger}
 }
 
-// GetAllDestinations retrieves all destinations
-func (r *destinationRepository) GetAllDestinations() ([]models.Destination, error) {
-	query := `
-		SELECT id, name, description, location, image_url, rating, reviews, price, created_at, updated_at
-		FROM destinations
-		ORDER BY rating DESC, reviews DESC`
-
-	rows, err := r.db.Query(query)
+// GetAllDestinations retrieves a page of destinations, the best rated first by default
+func (r *destinationRepository) GetAllDestinations(query models.ListQuery) ([]models.Destination, *models.PageInfo, error) {
+	destinations := []models.Destination{}
+	page, err := queryPage(r.db, destinationListSpec, query, destinationColumns, "destinations", nil, nil,
+		func(row rowScanner) error {
+			destination, err := scanDestination(row)
+			if err != nil {
+				return fmt.Errorf("failed to scan destination: %w", err)
+			}
+			destinations = append(destinations, *destination)
+			return nil
+		})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get destinations: %w", err)
+		return nil, nil, fmt.Errorf("failed to get destinations: %w", err)
 	}
-	defer rows.Close()
+	return destinations, page, nil
+}
 
-	var destinations []models.Destination
-	for rows.Next() {
-		var dest models.Destination
-		err := rows.Scan(
-			&dest.ID, &dest.Name, &dest.Description, &dest.Location,
-			&dest.ImageURL, &dest.Rating, &dest.Reviews, &dest.Price,
-			 &dest.CreatedAt, &dest.UpdatedAt,
+// GetDestinationsInPlace retrieves a page of the destinations linked to a place or to any place
+// below it, the best rated first by default
+func (r *destinationRepository) GetDestinationsInPlace(placeID int, query models.ListQuery) ([]models.Destination, *models.PageInfo, error) {
+	conditions := []string{`place_id IN (
+		WITH RECURSIVE tree AS (
+			SELECT id FROM places WHERE id = $1
+			UNION ALL
+			SELECT places.id FROM places JOIN tree ON places.parent_id = tree.id
 		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan destination: %w", err)
-		}
-		destinations = append(destinations, dest)
+		SELECT id FROM tree
+	)`}
+
+	destinations := []models.Destination{}
+	page, err := queryPage(r.db, destinationListSpec, query, destinationColumns, "destinations", conditions, []interface{}{placeID},
+		func(row rowScanner) error {
+			destination, err := scanDestination(row)
+			if err != nil {
+				return fmt.Errorf("failed to scan destination: %w", err)
+			}
+			destinations = append(destinations, *destination)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get destinations in place: %w", err)
 	}
-
-	return destinations, nil
+	return destinations, page, nil
 }
 
 // GetDestinationByID retrieves a destination by ID
 func (r *destinationRepository) GetDestinationByID(id int) (*models.Destination, error) {
-	destination := &models.Destination{}
-	query := `
-		SELECT id, name, description, location, image_url, rating, reviews, price, created_at, updated_at
-		FROM destinations WHERE id = $1`
+	query := `SELECT ` + destinationColumns + ` FROM destinations WHERE id = $1`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&destination.ID, &destination.Name, &destination.Description, &destination.Location,
-		&destination.ImageURL, &destination.Rating, &destination.Reviews, &destination.Price,
-		&destination.CreatedAt, &destination.UpdatedAt,
-	)
+	destination, err := scanDestination(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("destination not found")
+			return nil, ErrDestinationNotFound
 		}
 		return nil, fmt.Errorf("failed to get destination: %w", err)
 	}
@@ -79,14 +118,52 @@ func (r *destinationRepository) GetDestinationByID(id int) (*models.Destination,
 	return destination, nil
 }
 
+// GetNearbyDestinations retrieves the located destinations within a radius of a point, the
+// closest first
+func (r *destinationRepository) GetNearbyDestinations(center models.GeoPoint, radiusKm float64, limit int) ([]models.NearbyDestination, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions, distance := radiusConditions("latitude", "longitude", center, radiusKm, arg)
+	query := `
+		SELECT ` + destinationColumns + `, ` + distance + ` AS distance_km
+		FROM destinations
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY distance_km, id
+		LIMIT ` + arg(limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nearby destinations: %w", err)
+	}
+	defer rows.Close()
+
+	destinations := []models.NearbyDestination{}
+	for rows.Next() {
+		var distanceKm float64
+		destination, err := scanDestination(appendScanner{rows: rows, dest: []interface{}{&distanceKm}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan destination: %w", err)
+		}
+		destinations = append(destinations, models.NearbyDestination{Destination: *destination, DistanceKm: distanceKm})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get nearby destinations: %w", err)
+	}
+	return destinations, nil
+}
+
 // CreateDestination creates a new destination
 func (r *destinationRepository) CreateDestination(destination *models.Destination) error {
 	query := `
-		INSERT INTO destinations (name, description, location, image_url, rating, reviews, price)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO destinations (name, description, location, image_url, rating, reviews, price, latitude, longitude, area, place_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::polygon, $11)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, destination.Name, destination.Description, destination.Location, destination.ImageURL, destination.Rating, destination.Reviews, destination.Price).Scan(
+	err := r.db.QueryRow(query, destination.Name, destination.Description, destination.Location, destination.ImageURL, destination.Rating, destination.Reviews, destination.Price,
+		destination.Latitude, destination.Longitude, polygonValue(destination.Area), destination.PlaceID).Scan(
 		&destination.ID, &destination.CreatedAt, &destination.UpdatedAt,
 	)
 	if err != nil {
@@ -100,10 +177,12 @@ func (r *destinationRepository) CreateDestination(destination *models.Destinatio
 func (r *destinationRepository) UpdateDestination(destination *models.Destination) error {
 	query := `
 		UPDATE destinations 
-		SET name = $1, description = $2, location = $3, image_url = $4, rating = $5, reviews = $6, price = $7, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $8`
+		SET name = $1, description = $2, location = $3, image_url = $4, rating = $5, reviews = $6, price = $7,
+			latitude = $8, longitude = $9, area = $10::polygon, place_id = $11, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $12`
 
-	_, err := r.db.Exec(query, destination.Name, destination.Description, destination.Location, destination.ImageURL, destination.Rating, destination.Reviews, destination.Price, destination.ID)
+	_, err := r.db.Exec(query, destination.Name, destination.Description, destination.Location, destination.ImageURL, destination.Rating, destination.Reviews, destination.Price,
+		destination.Latitude, destination.Longitude, polygonValue(destination.Area), destination.PlaceID, destination.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update destination: %w", err)
 	}
@@ -120,3 +199,24 @@ func (r *destinationRepository) DeleteDestination(id int) error {
 	}
 	return nil
 }
+
+// scanDestination scans a destination row selected with destinationColumns
+func scanDestination(row rowScanner) (*models.Destination, error) {
+	destination := &models.Destination{}
+	var area sql.NullString
+	err := row.Scan(
+		&destination.ID, &destination.Name, &destination.Description, &destination.Location,
+		&destination.ImageURL, &destination.Rating, &destination.Reviews, &destination.Price,
+		&destination.Latitude, &destination.Longitude, &area, &destination.PlaceID, &destination.PlaceCode,
+		&destination.CreatedAt, &destination.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if area.Valid {
+		if destination.Area, err = parsePolygon(area.String); err != nil {
+			return nil, err
+		}
+	}
+	return destination, nil
+}
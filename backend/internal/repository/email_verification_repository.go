@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"time"
+)
+
+// Errors returned when an email verification code cannot be redeemed
+var (
+	ErrVerificationCodeNotFound     = errors.New("verification code not found")
+	ErrVerificationCodeExpired      = errors.New("verification code expired")
+	ErrVerificationCodeMismatch     = errors.New("verification code does not match")
+	ErrVerificationAttemptsExceeded = errors.New("too many failed verification attempts")
+)
+
+// EmailVerificationRepository defines the interface for email verification code operations
+type EmailVerificationRepository interface {
+	CreateCode(userID int, codeHash string, expiresAt time.Time, check func(sentLastHour int, lastSentAt *time.Time) error) error
+	VerifyCode(userID int, codeHash string, maxAttempts int) error
+}
+
+// emailVerificationRepository implements EmailVerificationRepository
+type emailVerificationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewEmailVerificationRepository creates a new email verification repository
+func NewEmailVerificationRepository(db *sql.DB, logger *logger.Logger) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db, logger: logger}
+}
+
+// CreateCode stores a new verification code for a user and invalidates the previous ones.
+// The user is locked while check decides, from the codes sent in the last hour, whether
+// another one may be sent; an error from check is returned unchanged.
+func (r *emailVerificationRepository) CreateCode(userID int, codeHash string, expiresAt time.Time, check func(sentLastHour int, lastSentAt *time.Time) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if check != nil {
+		var locked int
+		if err := tx.QueryRow(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&locked); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("user not found")
+			}
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var sentLastHour int
+		var lastSentAt sql.NullTime
+		query := `
+			SELECT COUNT(*) FILTER (WHERE created_at > CURRENT_TIMESTAMP - INTERVAL '1 hour'), MAX(created_at)
+			FROM email_verification_codes WHERE user_id = $1`
+		if err := tx.QueryRow(query, userID).Scan(&sentLastHour, &lastSentAt); err != nil {
+			return fmt.Errorf("failed to count verification codes: %w", err)
+		}
+
+		var last *time.Time
+		if lastSentAt.Valid {
+			last = &lastSentAt.Time
+		}
+		if err := check(sentLastHour, last); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE email_verification_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`
+	if _, err := tx.Exec(query, userID); err != nil {
+		return fmt.Errorf("failed to invalidate verification codes: %w", err)
+	}
+
+	query = `INSERT INTO email_verification_codes (user_id, code_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(query, userID, codeHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create verification code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit verification code: %w", err)
+	}
+	return nil
+}
+
+// VerifyCode checks a code against the latest code of a user and marks the email of the
+// user as verified when it matches. A wrong code counts as a failed attempt; once a code
+// has maxAttempts failed attempts it is refused even when it is right.
+func (r *emailVerificationRepository) VerifyCode(userID int, codeHash string, maxAttempts int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, attempts int
+	var storedHash string
+	var expiresAt time.Time
+	query := `
+		SELECT id, code_hash, expires_at, attempts
+		FROM email_verification_codes
+		WHERE user_id = $1 AND used_at IS NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+		FOR UPDATE`
+
+	err = tx.QueryRow(query, userID).Scan(&id, &storedHash, &expiresAt, &attempts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrVerificationCodeNotFound
+		}
+		return fmt.Errorf("failed to get verification code: %w", err)
+	}
+
+	if !time.Now().Before(expiresAt) {
+		return ErrVerificationCodeExpired
+	}
+	if attempts >= maxAttempts {
+		return ErrVerificationAttemptsExceeded
+	}
+
+	if subtle.ConstantTimeCompare([]byte(storedHash), []byte(codeHash)) != 1 {
+		if _, err := tx.Exec(`UPDATE email_verification_codes SET attempts = attempts + 1 WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to record verification attempt: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit verification attempt: %w", err)
+		}
+		return ErrVerificationCodeMismatch
+	}
+
+	if _, err := tx.Exec(`UPDATE email_verification_codes SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to use verification code: %w", err)
+	}
+
+	query = `UPDATE users SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := tx.Exec(query, userID); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit email verification: %w", err)
+	}
+	return nil
+}
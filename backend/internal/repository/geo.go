@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"nomado-houses/internal/models"
+	"strconv"
+	"strings"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// kmPerDegreeLatitude is the distance between two latitudes one degree apart
+const kmPerDegreeLatitude = 111.195
+
+// distanceKmSQL builds the expression of the great-circle distance in kilometres between the
+// coordinates of a row and a point, with the haversine formula
+func distanceKmSQL(latColumn, lngColumn, lat, lng string) string {
+	return fmt.Sprintf(`(%g * asin(LEAST(1, sqrt(
+		power(sin(radians(%s - %s) / 2), 2) +
+		cos(radians(%s)) * cos(radians(%s)) * power(sin(radians(%s - %s) / 2), 2)
+	))))`, 2*earthRadiusKm, latColumn, lat, lat, latColumn, lngColumn, lng)
+}
+
+// radiusConditions builds the conditions of rows located within a radius of a point, and the
+// expression of their distance from it; arg binds a value and returns its placeholder. Rows
+// are first narrowed down to the band of latitudes the radius spans, which indexes serve.
+func radiusConditions(latColumn, lngColumn string, center models.GeoPoint, radiusKm float64,
+	arg func(value interface{}) string) (conditions []string, distance string) {
+	band := radiusKm / kmPerDegreeLatitude
+	distance = distanceKmSQL(latColumn, lngColumn, arg(center.Lat), arg(center.Lng))
+	conditions = []string{
+		fmt.Sprintf("%s BETWEEN %s AND %s", latColumn, arg(center.Lat-band), arg(center.Lat+band)),
+		distance + " <= " + arg(radiusKm),
+	}
+	return conditions, distance
+}
+
+// polygonValue encodes the points of an area as a Postgres polygon of (longitude, latitude)
+// points, or NULL without points
+func polygonValue(points []models.GeoPoint) interface{} {
+	if len(points) == 0 {
+		return nil
+	}
+	corners := make([]string, len(points))
+	for i, point := range points {
+		corners[i] = "(" + strconv.FormatFloat(point.Lng, 'f', -1, 64) + "," +
+			strconv.FormatFloat(point.Lat, 'f', -1, 64) + ")"
+	}
+	return "(" + strings.Join(corners, ",") + ")"
+}
+
+// polygonBrackets strips the brackets from the text of a Postgres polygon
+var polygonBrackets = strings.NewReplacer("(", "", ")", "")
+
+// parsePolygon decodes the text of a Postgres polygon of (longitude, latitude) points
+func parsePolygon(text string) ([]models.GeoPoint, error) {
+	values := strings.Split(polygonBrackets.Replace(text), ",")
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("malformed polygon %q", text)
+	}
+	points := make([]models.GeoPoint, len(values)/2)
+	for i := range points {
+		lng, err := strconv.ParseFloat(strings.TrimSpace(values[2*i]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed polygon %q", text)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(values[2*i+1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed polygon %q", text)
+		}
+		points[i] = models.GeoPoint{Lat: lat, Lng: lng}
+	}
+	return points, nil
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"nomado-houses/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on the pages of lists
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+	maxInValues      = 100
+)
+
+// ListQueryError is returned when a list query sorts or filters by a field the list does not
+// allow, or holds a value that does not fit its field
+type ListQueryError struct {
+	Reason string
+}
+
+func (e *ListQueryError) Error() string {
+	return "invalid list query: " + e.Reason
+}
+
+func listQueryErrorf(format string, args ...interface{}) error {
+	return &ListQueryError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// listFieldKind is the type of the values of a list field
+type listFieldKind int
+
+const (
+	listText listFieldKind = iota
+	listInt
+	listFloat
+	listBool
+	listTime
+)
+
+// listField is a column the items of a list can be filtered by. Only columns that are never
+// NULL can be sorted by, since cursors compare sort values.
+type listField struct {
+	column   string
+	kind     listFieldKind
+	sortable bool
+}
+
+// listSpec describes the fields of the items of a list
+type listSpec struct {
+	fields      map[string]listField
+	defaultSort string // field name, prefixed with - for descending order
+	idColumn    string // unique column that orders items with equal sort values
+}
+
+// filterOperators maps the list filter operators that compare a single value to SQL
+var filterOperators = map[models.FilterOperator]string{
+	models.FilterEq:  "=",
+	models.FilterNe:  "IS DISTINCT FROM",
+	models.FilterGt:  ">",
+	models.FilterGte: ">=",
+	models.FilterLt:  "<",
+	models.FilterLte: "<=",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listCursor is where a page of a list ends: the sort order of the list and the sort value and
+// ID of the last item of the page
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// appendScanner scans a row into the destinations of a scan helper followed by extra columns,
+// such as the sort value and ID of a list item
+type appendScanner struct {
+	rows *sql.Rows
+	dest []interface{}
+}
+
+func (s appendScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, s.dest...)...)
+}
+
+// queryPage runs one page of a list, after its items matching conditions and the filters of
+// the query have been counted. from is the FROM clause of the list, args the values of
+// conditions, and scan reads one row selected with columns.
+func queryPage(db queryer, spec *listSpec, query models.ListQuery, columns, from string,
+	conditions []string, args []interface{}, scan func(row rowScanner) error) (*models.PageInfo, error) {
+	sort := query.Sort
+	if sort == "" {
+		sort = spec.defaultSort
+	}
+	name := strings.TrimPrefix(sort, "-")
+	field, ok := spec.fields[name]
+	if !ok || !field.sortable {
+		return nil, listQueryErrorf("cannot sort by %q", name)
+	}
+	order, after := "ASC", ">"
+	if name != sort {
+		order, after = "DESC", "<"
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return nil, listQueryErrorf("limit must be between 1 and %d", maxListLimit)
+	}
+
+	var cursor *listCursor
+	if query.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err == nil {
+			err = json.Unmarshal(data, &cursor)
+		}
+		if err != nil || cursor == nil || cursor.Sort != sort {
+			return nil, listQueryErrorf("the cursor is invalid or belongs to another sort order")
+		}
+	}
+
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	for _, filter := range query.Filters {
+		condition, err := spec.filterCondition(filter, arg)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	where := func() string {
+		if len(conditions) == 0 {
+			return ""
+		}
+		return " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &models.PageInfo{}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM `+from+where(), args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count list: %w", err)
+	}
+
+	// Items are ordered by the sort field and then by ID, so the sort value and ID of the last
+	// item of a page tell exactly where the next page starts
+	if cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, %s) %s (%s, %s)",
+			field.column, spec.idColumn, after, arg(cursor.Value), arg(cursor.ID)))
+	}
+	rows, err := db.Query(`
+		SELECT `+columns+`, (`+field.column+`)::text, `+spec.idColumn+`
+		FROM `+from+where()+`
+		ORDER BY `+field.column+` `+order+`, `+spec.idColumn+` `+order+`
+		LIMIT `+arg(limit+1), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	last := listCursor{Sort: sort}
+	for count := 0; rows.Next(); count++ {
+		// The item after the page only tells that there is a next page
+		if count == limit {
+			data, err := json.Marshal(last)
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+			break
+		}
+		if err := scan(appendScanner{rows: rows, dest: []interface{}{&last.Value, &last.ID}}); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	return page, nil
+}
+
+// filterCondition builds the condition of a list filter; arg binds a value and returns its
+// placeholder
+func (s *listSpec) filterCondition(filter models.ListFilter, arg func(value interface{}) string) (string, error) {
+	field, ok := s.fields[filter.Field]
+	if !ok {
+		return "", listQueryErrorf("cannot filter by %q", filter.Field)
+	}
+
+	switch filter.Operator {
+	case models.FilterLike:
+		if field.kind != listText {
+			return "", listQueryErrorf("%q is not a text field and cannot be filtered with like", filter.Field)
+		}
+		return field.column + ` ILIKE ` + arg("%"+likeEscaper.Replace(filter.Value)+"%"), nil
+	case models.FilterIn:
+		values := strings.Split(filter.Value, ",")
+		if len(values) > maxInValues {
+			return "", listQueryErrorf("in filters take at most %d values", maxInValues)
+		}
+		placeholders := make([]string, len(values))
+		for i, raw := range values {
+			value, err := parseListValue(filter.Field, field.kind, strings.TrimSpace(raw))
+			if err != nil {
+				return "", err
+			}
+			placeholders[i] = arg(value)
+		}
+		return field.column + ` IN (` + strings.Join(placeholders, ", ") + `)`, nil
+	}
+
+	operator, ok := filterOperators[filter.Operator]
+	if !ok {
+		return "", listQueryErrorf("unknown filter operator %q", filter.Operator)
+	}
+	value, err := parseListValue(filter.Field, field.kind, filter.Value)
+	if err != nil {
+		return "", err
+	}
+	return field.column + ` ` + operator + ` ` + arg(value), nil
+}
+
+// parseListValue parses a filter value of a list field according to its kind. Times are
+// RFC3339 or YYYY-MM-DD.
+func parseListValue(name string, kind listFieldKind, raw string) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch kind {
+	case listInt:
+		value, err = strconv.Atoi(raw)
+	case listFloat:
+		value, err = strconv.ParseFloat(raw, 64)
+	case listBool:
+		value, err = strconv.ParseBool(raw)
+	case listTime:
+		if value, err = time.Parse(time.RFC3339, raw); err != nil {
+			value, err = time.Parse("2006-01-02", raw)
+		}
+	default:
+		value = raw
+	}
+	if err != nil {
+		return nil, listQueryErrorf("%q is not a valid value for %q", raw, name)
+	}
+	return value, nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+)
+
+// Errors returned by OpenID Connect login operations
+var (
+	ErrOIDCStateInvalid = errors.New("login state is unknown, expired or already used")
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+// OIDCRepository defines the interface for OpenID Connect login state and identity operations
+type OIDCRepository interface {
+	CreateLoginState(state *models.OIDCLoginState) error
+	ConsumeLoginState(id, provider string) (*models.OIDCLoginState, error)
+	GetIdentity(provider, subject string) (*models.UserIdentity, error)
+	TouchIdentity(id int, email string) error
+	CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error
+	LinkIdentity(identity *models.UserIdentity, takeOverUnverified bool) error
+}
+
+// oidcRepository implements OIDCRepository
+type oidcRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewOIDCRepository creates a new OpenID Connect repository
+func NewOIDCRepository(db *sql.DB, logger *logger.Logger) OIDCRepository {
+	return &oidcRepository{db: db, logger: logger}
+}
+
+// CreateLoginState stores the state of a login that is redirected to a provider
+func (r *oidcRepository) CreateLoginState(state *models.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (id, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+
+	err := r.db.QueryRow(query, state.ID, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt).Scan(&state.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create login state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLoginState uses up the state of a login for the provider it was created for.
+// It returns ErrOIDCStateInvalid when the state is unknown, expired or already used.
+func (r *oidcRepository) ConsumeLoginState(id, provider string) (*models.OIDCLoginState, error) {
+	state := &models.OIDCLoginState{}
+	query := `
+		UPDATE oidc_login_states
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND provider = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING id, provider, nonce, code_verifier, expires_at, created_at`
+
+	err := r.db.QueryRow(query, id, provider).Scan(&state.ID, &state.Provider, &state.Nonce,
+		&state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOIDCStateInvalid
+		}
+		return nil, fmt.Errorf("failed to use login state: %w", err)
+	}
+	return state, nil
+}
+
+// GetIdentity retrieves the identity of an account at a provider
+func (r *oidcRepository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	err := r.db.QueryRow(query, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider,
+		&identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+// TouchIdentity records a login with an identity and the email the provider reported
+func (r *oidcRepository) TouchIdentity(id int, email string) error {
+	query := `UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP, email = $1 WHERE id = $2`
+	if _, err := r.db.Exec(query, email, id); err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity creates a user who signed up through a provider together with the
+// identity. The user has no password until they set one through the password reset flow.
+func (r *oidcRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	query := `
+		INSERT INTO users (email, password, first_name, last_name, phone, role, email_verified)
+		VALUES ($1, '', $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query, user.Email, user.FirstName, user.LastName, user.Phone, user.Role,
+		user.EmailVerified).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	identity.UserID = user.ID
+	if err := insertIdentity(tx, identity); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+	return nil
+}
+
+// LinkIdentity links an identity to an existing user. With takeOverUnverified, a user whose
+// email was never verified is handed to the identity: the email is marked verified, the
+// password is removed and the sessions are revoked, so whoever registered the address
+// without proving it loses access.
+func (r *oidcRepository) LinkIdentity(identity *models.UserIdentity, takeOverUnverified bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertIdentity(tx, identity); err != nil {
+		return err
+	}
+
+	if takeOverUnverified {
+		query := `
+			UPDATE users
+			SET email_verified = TRUE, password = '', updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND email_verified = FALSE`
+
+		result, err := tx.Exec(query, identity.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to verify user: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+
+		if rowsAffected > 0 {
+			query = `
+				UPDATE auth_sessions
+				SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'unverified account linked to identity provider'
+				WHERE user_id = $1 AND revoked_at IS NULL`
+			if _, err := tx.Exec(query, identity.UserID); err != nil {
+				return fmt.Errorf("failed to revoke sessions: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit identity: %w", err)
+	}
+	return nil
+}
+
+// insertIdentity stores an identity
+func insertIdentity(q queryer, identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, last_login_at`
+
+	err := q.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(
+		&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
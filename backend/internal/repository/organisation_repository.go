@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"strings"
+)
+
+// Errors returned by provider organisation operations
+var (
+	ErrOrganisationMemberNotFound     = errors.New("organisation member not found")
+	ErrOrganisationInvitationNotFound = errors.New("organisation invitation not found or expired")
+	ErrOrganisationInvitationEmail    = errors.New("organisation invitation was sent to another email address")
+	ErrAlreadyOrganisationMember      = errors.New("user already works for an organisation")
+)
+
+// organisationMemberColumns selects an organisation member with their user details for scanOrganisationMember
+const organisationMemberColumns = `m.id, m.organisation_id, m.user_id, u.first_name, u.last_name, u.email, m.role,
+	m.invited_by, m.created_at, m.updated_at`
+
+// OrganisationRepository defines the interface for provider organisation operations
+type OrganisationRepository interface {
+	EnsureOrganisation(ownerID int, name string) (*models.ProviderOrganisation, error)
+	GetMembers(organisationID int) ([]models.OrganisationMember, error)
+	GetMembershipByUserID(userID int) (*models.OrganisationMembership, error)
+	UpdateMemberRole(organisationID, userID int, role models.OrganisationRole) (*models.OrganisationMember, error)
+	DeleteMember(organisationID, userID int) error
+	CreateInvitation(invitation *models.OrganisationInvitation, tokenHash string) error
+	GetInvitations(organisationID int) ([]models.OrganisationInvitation, error)
+	DeleteInvitation(organisationID, id int) error
+	AcceptInvitation(tokenHash string, userID int, email string) (*models.OrganisationMembership, error)
+}
+
+// organisationRepository implements OrganisationRepository
+type organisationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewOrganisationRepository creates a new provider organisation repository
+func NewOrganisationRepository(db *sql.DB, logger *logger.Logger) OrganisationRepository {
+	return &organisationRepository{db: db, logger: logger}
+}
+
+// EnsureOrganisation retrieves the organisation of a provider, creating it with a name the
+// first time
+func (r *organisationRepository) EnsureOrganisation(ownerID int, name string) (*models.ProviderOrganisation, error) {
+	// The no-op update makes the existing row come back through RETURNING
+	query := `
+		INSERT INTO provider_organisations (owner_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (owner_id) DO UPDATE SET owner_id = EXCLUDED.owner_id
+		RETURNING id, owner_id, name, created_at, updated_at`
+
+	organisation := &models.ProviderOrganisation{}
+	err := r.db.QueryRow(query, ownerID, name).Scan(
+		&organisation.ID, &organisation.OwnerID, &organisation.Name, &organisation.CreatedAt, &organisation.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organisation: %w", err)
+	}
+	return organisation, nil
+}
+
+// GetMembers retrieves the staff members of an organisation, the earliest to join first
+func (r *organisationRepository) GetMembers(organisationID int) ([]models.OrganisationMember, error) {
+	query := `
+		SELECT ` + organisationMemberColumns + `
+		FROM organisation_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organisation_id = $1
+		ORDER BY m.created_at, m.id`
+
+	rows, err := r.db.Query(query, organisationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organisation members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.OrganisationMember{}
+	for rows.Next() {
+		member, err := scanOrganisationMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organisation member: %w", err)
+		}
+		members = append(members, *member)
+	}
+	return members, nil
+}
+
+// GetMembershipByUserID retrieves the organisation a user works for
+func (r *organisationRepository) GetMembershipByUserID(userID int) (*models.OrganisationMembership, error) {
+	query := `
+		SELECT o.id, o.name, o.owner_id, m.role, m.created_at
+		FROM organisation_members m
+		JOIN provider_organisations o ON o.id = m.organisation_id
+		WHERE m.user_id = $1`
+
+	membership := &models.OrganisationMembership{}
+	err := r.db.QueryRow(query, userID).Scan(
+		&membership.OrganisationID, &membership.OrganisationName, &membership.OwnerID, &membership.Role, &membership.JoinedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganisationMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to get organisation membership: %w", err)
+	}
+	return membership, nil
+}
+
+// UpdateMemberRole changes the role of a staff member of an organisation
+func (r *organisationRepository) UpdateMemberRole(organisationID, userID int, role models.OrganisationRole) (*models.OrganisationMember, error) {
+	query := `
+		UPDATE organisation_members
+		SET role = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE organisation_id = $2 AND user_id = $3`
+
+	result, err := r.db.Exec(query, role, organisationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organisation member: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organisation member: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrOrganisationMemberNotFound
+	}
+
+	query = `
+		SELECT ` + organisationMemberColumns + `
+		FROM organisation_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organisation_id = $1 AND m.user_id = $2`
+
+	member, err := scanOrganisationMember(r.db.QueryRow(query, organisationID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganisationMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to get organisation member: %w", err)
+	}
+	return member, nil
+}
+
+// DeleteMember removes a staff member from an organisation
+func (r *organisationRepository) DeleteMember(organisationID, userID int) error {
+	query := `DELETE FROM organisation_members WHERE organisation_id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(query, organisationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organisation member: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete organisation member: %w", err)
+	}
+	if rows == 0 {
+		return ErrOrganisationMemberNotFound
+	}
+	return nil
+}
+
+// CreateInvitation records an invitation, replacing the previous invitation of the organisation
+// to the same email address
+func (r *organisationRepository) CreateInvitation(invitation *models.OrganisationInvitation, tokenHash string) error {
+	query := `
+		INSERT INTO organisation_invitations (organisation_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (organisation_id, (LOWER(email))) DO UPDATE
+		SET email = EXCLUDED.email, role = EXCLUDED.role, token_hash = EXCLUDED.token_hash,
+			invited_by = EXCLUDED.invited_by, expires_at = EXCLUDED.expires_at, created_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, invitation.OrganisationID, invitation.Email, invitation.Role, tokenHash,
+		invitation.InvitedBy, invitation.ExpiresAt).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create organisation invitation: %w", err)
+	}
+	return nil
+}
+
+// GetInvitations retrieves the invitations of an organisation that were not accepted or revoked,
+// the most recent first
+func (r *organisationRepository) GetInvitations(organisationID int) ([]models.OrganisationInvitation, error) {
+	query := `
+		SELECT id, organisation_id, email, role, invited_by, expires_at, created_at
+		FROM organisation_invitations
+		WHERE organisation_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(query, organisationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organisation invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []models.OrganisationInvitation{}
+	for rows.Next() {
+		var invitation models.OrganisationInvitation
+		err := rows.Scan(
+			&invitation.ID, &invitation.OrganisationID, &invitation.Email, &invitation.Role,
+			&invitation.InvitedBy, &invitation.ExpiresAt, &invitation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organisation invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, nil
+}
+
+// DeleteInvitation revokes an invitation of an organisation
+func (r *organisationRepository) DeleteInvitation(organisationID, id int) error {
+	query := `DELETE FROM organisation_invitations WHERE id = $1 AND organisation_id = $2`
+
+	result, err := r.db.Exec(query, id, organisationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organisation invitation: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete organisation invitation: %w", err)
+	}
+	if rows == 0 {
+		return ErrOrganisationInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation makes a user a staff member of the organisation that invited them and
+// deletes the invitation. The invitation must not have expired and must have been sent to the
+// email address of the user.
+func (r *organisationRepository) AcceptInvitation(tokenHash string, userID int, email string) (*models.OrganisationMembership, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var invitation models.OrganisationInvitation
+	query := `
+		SELECT id, organisation_id, email, role, invited_by
+		FROM organisation_invitations
+		WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+		FOR UPDATE`
+	err = tx.QueryRow(query, tokenHash).Scan(
+		&invitation.ID, &invitation.OrganisationID, &invitation.Email, &invitation.Role, &invitation.InvitedBy,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganisationInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get organisation invitation: %w", err)
+	}
+	if !strings.EqualFold(invitation.Email, email) {
+		return nil, ErrOrganisationInvitationEmail
+	}
+
+	var memberID int
+	query = `
+		INSERT INTO organisation_members (organisation_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING id`
+	err = tx.QueryRow(query, invitation.OrganisationID, userID, invitation.Role, invitation.InvitedBy).Scan(&memberID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAlreadyOrganisationMember
+		}
+		return nil, fmt.Errorf("failed to create organisation member: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM organisation_invitations WHERE id = $1`, invitation.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete organisation invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit organisation invitation: %w", err)
+	}
+
+	return r.GetMembershipByUserID(userID)
+}
+
+// scanOrganisationMember scans a row selected with organisationMemberColumns
+func scanOrganisationMember(row rowScanner) (*models.OrganisationMember, error) {
+	member := &models.OrganisationMember{}
+	err := row.Scan(
+		&member.ID, &member.OrganisationID, &member.UserID, &member.FirstName, &member.LastName, &member.Email,
+		&member.Role, &member.InvitedBy, &member.CreatedAt, &member.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"time"
+)
+
+// Errors returned when a password reset token cannot be redeemed
+var (
+	ErrResetTokenNotFound = errors.New("password reset token not found")
+	ErrResetTokenExpired  = errors.New("password reset token expired")
+	ErrResetTokenUsed     = errors.New("password reset token was already used")
+)
+
+// PasswordResetRepository defines the interface for password reset token operations
+type PasswordResetRepository interface {
+	CreateResetToken(userID int, tokenHash string, expiresAt time.Time, check func(sentLastHour int, lastSentAt *time.Time) error) error
+	ResetPassword(tokenHash, passwordHash string) (int, error)
+}
+
+// passwordResetRepository implements PasswordResetRepository
+type passwordResetRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(db *sql.DB, logger *logger.Logger) PasswordResetRepository {
+	return &passwordResetRepository{db: db, logger: logger}
+}
+
+// CreateResetToken stores a new reset token for a user. Earlier tokens of the user that
+// were not used are invalidated, so only the most recent email works. The user is locked
+// while check decides, from the tokens created in the last hour, whether another one may
+// be sent; an error from check is returned unchanged.
+func (r *passwordResetRepository) CreateResetToken(userID int, tokenHash string, expiresAt time.Time, check func(sentLastHour int, lastSentAt *time.Time) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if check != nil {
+		var locked int
+		if err := tx.QueryRow(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&locked); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("user not found")
+			}
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var sentLastHour int
+		var lastSentAt sql.NullTime
+		query := `
+			SELECT COUNT(*) FILTER (WHERE created_at > CURRENT_TIMESTAMP - INTERVAL '1 hour'), MAX(created_at)
+			FROM password_reset_tokens WHERE user_id = $1`
+		if err := tx.QueryRow(query, userID).Scan(&sentLastHour, &lastSentAt); err != nil {
+			return fmt.Errorf("failed to count password reset tokens: %w", err)
+		}
+
+		var last *time.Time
+		if lastSentAt.Valid {
+			last = &lastSentAt.Time
+		}
+		if err := check(sentLastHour, last); err != nil {
+			return err
+		}
+	}
+
+	if err := invalidateResetTokens(tx, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(query, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit password reset token: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword redeems a reset token: it sets the new password hash of its user, uses up
+// every outstanding reset token of the user and revokes all of their sessions.
+// It returns the ID of the user.
+func (r *passwordResetRepository) ResetPassword(tokenHash, passwordHash string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	query := `
+		SELECT user_id, expires_at, used_at
+		FROM password_reset_tokens WHERE token_hash = $1
+		FOR UPDATE`
+
+	err = tx.QueryRow(query, tokenHash).Scan(&userID, &expiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrResetTokenNotFound
+		}
+		return 0, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	if usedAt.Valid {
+		return 0, ErrResetTokenUsed
+	}
+	if !time.Now().Before(expiresAt) {
+		return 0, ErrResetTokenExpired
+	}
+
+	query = `UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	if _, err := tx.Exec(query, passwordHash, userID); err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := invalidateResetTokens(tx, userID); err != nil {
+		return 0, err
+	}
+
+	query = `
+		UPDATE auth_sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'password reset'
+		WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.Exec(query, userID); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+	return userID, nil
+}
+
+// invalidateResetTokens uses up the outstanding reset tokens of a user
+func invalidateResetTokens(q queryer, userID int) error {
+	query := `UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`
+	if _, err := q.Exec(query, userID); err != nil {
+		return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+	return nil
+}
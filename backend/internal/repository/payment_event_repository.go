@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+)
+
+// ErrPaymentEventProcessed is returned when a payment event has already been handled
+var ErrPaymentEventProcessed = errors.New("payment event was already processed")
+
+// PaymentEventRepository defines the interface for payment gateway event operations
+type PaymentEventRepository interface {
+	SaveEvent(event *models.PaymentEvent) error
+	IgnoreEvent(id int, reason string) error
+	ApplyEvent(eventID, paymentID int, refundReference string, refundAmount float64, apply func(payment, refund *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error)) error
+}
+
+// paymentEventRepository implements PaymentEventRepository
+type paymentEventRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewPaymentEventRepository creates a new payment event repository
+func NewPaymentEventRepository(db *sql.DB, logger *logger.Logger) PaymentEventRepository {
+	return &paymentEventRepository{db: db, logger: logger}
+}
+
+// SaveEvent stores a raw gateway event. When the provider already delivered an event with
+// the same ID the stored event is kept and its ID, status and processing time are loaded.
+func (r *paymentEventRepository) SaveEvent(event *models.PaymentEvent) error {
+	query := `
+		INSERT INTO payment_events (provider, event_id, event_type, payload, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, event_id) DO UPDATE SET event_type = payment_events.event_type
+		RETURNING id, status, received_at, processed_at`
+
+	var processedAt sql.NullTime
+	err := r.db.QueryRow(query, event.Provider, event.EventID, event.EventType,
+		[]byte(event.Payload), event.OccurredAt).Scan(&event.ID, &event.Status, &event.ReceivedAt, &processedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save payment event: %w", err)
+	}
+	if processedAt.Valid {
+		event.ProcessedAt = &processedAt.Time
+	}
+	return nil
+}
+
+// IgnoreEvent marks an event that does not concern any known payment as handled
+func (r *paymentEventRepository) IgnoreEvent(id int, reason string) error {
+	query := `
+		UPDATE payment_events
+		SET status = 'ignored', error = $1, processed_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = 'received'`
+
+	if _, err := r.db.Exec(query, reason, id); err != nil {
+		return fmt.Errorf("failed to ignore payment event: %w", err)
+	}
+	return nil
+}
+
+// ApplyEvent applies a gateway event to a payment in one transaction holding locks on the
+// event, the booking and the payment, and marks the event processed. For refund events the
+// refund is looked up by its gateway reference, or else matched to a pending refund of the
+// payment for the same amount that has no reference yet. apply receives the locked rows
+// (refund is nil when none matches), updates the payment, returns the refund to record or
+// update, and decides whether the booking is confirmed.
+// It returns ErrPaymentEventProcessed when the event was handled by another delivery.
+func (r *paymentEventRepository) ApplyEvent(eventID, paymentID int, refundReference string, refundAmount float64, apply func(payment, refund *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error)) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status models.PaymentEventStatus
+	if err := tx.QueryRow(`SELECT status FROM payment_events WHERE id = $1 FOR UPDATE`, eventID).Scan(&status); err != nil {
+		return fmt.Errorf("failed to lock payment event: %w", err)
+	}
+	if status != models.PaymentEventReceived {
+		return ErrPaymentEventProcessed
+	}
+
+	var bookingID int
+	if err := tx.QueryRow(`SELECT booking_id FROM payments WHERE id = $1`, paymentID).Scan(&bookingID); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("payment not found")
+		}
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	booking, err := lockBooking(tx, bookingID)
+	if err != nil {
+		return err
+	}
+
+	payment, err := scanPayment(tx.QueryRow(`SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, paymentID))
+	if err != nil {
+		return err
+	}
+
+	var refund *models.Payment
+	if refundReference != "" {
+		refund, err = lockEventRefund(tx, payment, refundReference, refundAmount)
+		if err != nil {
+			return err
+		}
+	}
+
+	netPaid, err := netPaidAmount(tx, booking.ID)
+	if err != nil {
+		return err
+	}
+
+	confirm, refund, err := apply(payment, refund, booking, netPaid)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE payments
+		SET status = $1, provider_reference = NULLIF($2, ''), failure_reason = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4`
+	if _, err := tx.Exec(query, payment.Status, payment.ProviderReference, payment.FailureReason, payment.ID); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if refund != nil {
+		if refund.ID == 0 {
+			if err := insertPayment(tx, refund); err != nil {
+				return err
+			}
+		} else if _, err := tx.Exec(query, refund.Status, refund.ProviderReference, refund.FailureReason, refund.ID); err != nil {
+			return fmt.Errorf("failed to update refund: %w", err)
+		}
+	}
+
+	if confirm {
+		err := transitionBookingStatus(tx, booking.ID, booking.Status, models.BookingStatusConfirmed, payment.UserID, "booking fully paid")
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+		UPDATE payment_events
+		SET status = 'processed', payment_id = $1, processed_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+	if _, err := tx.Exec(query, payment.ID, eventID); err != nil {
+		return fmt.Errorf("failed to mark payment event processed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment event: %w", err)
+	}
+	return nil
+}
+
+// lockEventRefund locks the refund a gateway refund event is about, or returns nil when
+// the refund was not recorded here
+func lockEventRefund(tx *sql.Tx, payment *models.Payment, reference string, amount float64) (*models.Payment, error) {
+	query := `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE provider = $1 AND provider_reference = $2 AND payment_type = 'refund'
+		FOR UPDATE`
+
+	refund, err := scanPayment(tx.QueryRow(query, payment.Provider, reference))
+	if err == nil {
+		return refund, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// The refund may have been sent without its reference being recorded yet
+	query = `
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE parent_payment_id = $1 AND payment_type = 'refund' AND status = 'pending'
+		  AND provider_reference IS NULL AND amount = $2
+		ORDER BY id
+		LIMIT 1
+		FOR UPDATE`
+
+	refund, err = scanPayment(tx.QueryRow(query, payment.ID, amount))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return refund, err
+}
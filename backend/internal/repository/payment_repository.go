@@ -3,16 +3,49 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
 )
 
+// pendingPaymentHold is how long a pending gateway payment keeps its amount reserved
+// against the outstanding balance of a booking
+const pendingPaymentHold = "30 minutes"
+
+// paymentColumns lists the payment columns in the order scanPayment reads them
+const paymentColumns = `id, user_id, booking_id, amount, payment_date, payment_method, status, payment_type,
+		provider, COALESCE(provider_reference, ''), failure_reason, parent_payment_id, created_at, updated_at`
+
+// paymentListSpec lists the fields payments can be sorted and filtered by
+var paymentListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":                {column: "id", kind: listInt, sortable: true},
+		"user_id":           {column: "user_id", kind: listInt, sortable: true},
+		"booking_id":        {column: "booking_id", kind: listInt, sortable: true},
+		"amount":            {column: "amount", kind: listFloat, sortable: true},
+		"payment_date":      {column: "payment_date", kind: listTime, sortable: true},
+		"payment_method":    {column: "payment_method", kind: listText, sortable: true},
+		"status":            {column: "status", kind: listText, sortable: true},
+		"payment_type":      {column: "payment_type", kind: listText, sortable: true},
+		"provider":          {column: "provider", kind: listText, sortable: true},
+		"parent_payment_id": {column: "parent_payment_id", kind: listInt},
+	},
+	defaultSort: "-payment_date",
+	idColumn:    "id",
+}
+
 // PaymentRepository defines the interface for payment-related database operations
 type PaymentRepository interface {
-	GetAllPayments() ([]models.Payment, error)
+	GetAllPayments(query models.ListQuery) ([]models.Payment, *models.PageInfo, error)
 	GetPaymentsByUserID(userID int) ([]models.Payment, error)
 	GetPaymentByID(id int) (*models.Payment, error)
+	GetPaymentByProviderReference(provider, reference string) (*models.Payment, error)
+	GetPaymentsByBookingID(bookingID int) ([]models.Payment, error)
+	GetStalePayments(bookingID int) ([]models.Payment, error)
 	CreatePayment(payment *models.Payment) error
+	CreateBookingPayment(payment *models.Payment, check func(booking *models.Booking, netPaid, pendingPaid float64) (bool, error)) (*models.Booking, error)
+	SettlePayment(paymentID int, update func(payment *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error)) (*models.Payment, *models.Booking, error)
+	AllocateRefund(refundID int) ([]models.Payment, error)
 	UpdatePayment(payment *models.Payment) error
 	DeletePayment(id int) error
 }
@@ -28,111 +61,144 @@ func NewPaymentRepository(db *sql.DB, logger *logger.Logger) PaymentRepository {
 	return &paymentRepository{db: db, logger: logger}
 }
 
-// GetAllPayments retrieves all payments
-func (r *paymentRepository) GetAllPayments() ([]models.Payment, error) {
+// GetAllPayments retrieves a page of payments and refunds, the most recent first by default
+func (r *paymentRepository) GetAllPayments(query models.ListQuery) ([]models.Payment, *models.PageInfo, error) {
+	payments := []models.Payment{}
+	page, err := queryPage(r.db, paymentListSpec, query, paymentColumns, "payments", nil, nil,
+		func(row rowScanner) error {
+			payment, err := scanPayment(row)
+			if err != nil {
+				return err
+			}
+			payments = append(payments, *payment)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	return payments, page, nil
+}
+
+// GetPaymentsByUserID retrieves payments by user ID
+func (r *paymentRepository) GetPaymentsByUserID(userID int) ([]models.Payment, error) {
 	query := `
-		SELECT id, user_id, booking_id, amount, payment_date, payment_method, status, created_at, updated_at
+		SELECT ` + paymentColumns + `
 		FROM payments
+		WHERE user_id = $1
 		ORDER BY payment_date DESC`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get payments: %w", err)
+		return nil, fmt.Errorf("failed to get payments by user ID: %w", err)
 	}
 	defer rows.Close()
 
 	var payments []models.Payment
 	for rows.Next() {
-		var payment models.Payment
-		err := rows.Scan(
-			&payment.ID, &payment.UserID, &payment.BookingID,
-			&payment.Amount, &payment.PaymentDate, &payment.PaymentMethod,
-			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
-		)
+		payment, err := scanPayment(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan payment: %w", err)
+			return nil, err
 		}
-		payments = append(payments, payment)
+		payments = append(payments, *payment)
 	}
 	return payments, nil
 }
 
-// GetPaymentsByUserID retrieves payments by user ID
-func (r *paymentRepository) GetPaymentsByUserID(userID int) ([]models.Payment, error) {
+// GetPaymentByID retrieves a payment by ID
+func (r *paymentRepository) GetPaymentByID(id int) (*models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1`
+
+	payment, err := scanPayment(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found")
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+// GetPaymentByProviderReference retrieves a payment by the reference its gateway gave it
+func (r *paymentRepository) GetPaymentByProviderReference(provider, reference string) (*models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND provider_reference = $2`
+
+	payment, err := scanPayment(r.db.QueryRow(query, provider, reference))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found")
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+// GetPaymentsByBookingID retrieves the payments and refunds of a booking
+func (r *paymentRepository) GetPaymentsByBookingID(bookingID int) ([]models.Payment, error) {
 	query := `
-		SELECT id, user_id, booking_id, amount, payment_date, payment_method, status, created_at, updated_at
+		SELECT ` + paymentColumns + `
 		FROM payments
-		WHERE user_id = $1
+		WHERE booking_id = $1
 		ORDER BY payment_date DESC`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, bookingID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get payments by user ID: %w", err)
+		return nil, fmt.Errorf("failed to get payments by booking ID: %w", err)
 	}
 	defer rows.Close()
 
 	var payments []models.Payment
 	for rows.Next() {
-		var payment models.Payment
-		err := rows.Scan(
-			&payment.ID, &payment.UserID, &payment.BookingID,
-			&payment.Amount, &payment.PaymentDate, &payment.PaymentMethod,
-			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
-		)
+		payment, err := scanPayment(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan payment: %w", err)
+			return nil, err
 		}
-		payments = append(payments, payment)
+		payments = append(payments, *payment)
 	}
 	return payments, nil
 }
 
-// GetPaymentByID retrieves a payment by ID
-func (r *paymentRepository) GetPaymentByID(id int) (*models.Payment, error) {
-	payment := &models.Payment{}
+// GetStalePayments retrieves the pending payments of a booking that no longer hold their amount
+func (r *paymentRepository) GetStalePayments(bookingID int) ([]models.Payment, error) {
 	query := `
-		SELECT id, user_id, booking_id, amount, payment_date, payment_method, status, created_at, updated_at
-		FROM payments WHERE id = $1`
+		SELECT ` + paymentColumns + `
+		FROM payments
+		WHERE booking_id = $1 AND payment_type = 'payment' AND status = 'pending'
+		  AND created_at <= CURRENT_TIMESTAMP - INTERVAL '` + pendingPaymentHold + `'
+		ORDER BY created_at`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&payment.ID, &payment.UserID, &payment.BookingID,
-		&payment.Amount, &payment.PaymentDate, &payment.PaymentMethod,
-		&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
-	)
+	rows, err := r.db.Query(query, bookingID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("payment not found: %w", err)
+		return nil, fmt.Errorf("failed to get stale payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to get payment: %w", err)
+		payments = append(payments, *payment)
 	}
-	return payment, nil
+	return payments, nil
 }
 
 // CreatePayment creates a new payment
 func (r *paymentRepository) CreatePayment(payment *models.Payment) error {
-	query := `
-		INSERT INTO payments (user_id, booking_id, amount, payment_date, payment_method, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at`
-
-	err := r.db.QueryRow(query, payment.UserID, payment.BookingID,
-		payment.Amount, payment.PaymentDate, payment.PaymentMethod,
-		payment.Status).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
-	if err != nil {
-		return fmt.Errorf("failed to create payment: %w", err)
-	}
-	return nil
+	return insertPayment(r.db, payment)
 }
 
 // UpdatePayment updates an existing payment
 func (r *paymentRepository) UpdatePayment(payment *models.Payment) error {
 	query := `
-		UPDATE payments 
-		SET amount = $1, payment_date = $2, payment_method = $3, status = $4, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $5`
+		UPDATE payments
+		SET amount = $1, payment_date = $2, payment_method = $3, status = $4,
+			provider_reference = NULLIF($5, ''), failure_reason = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7`
 
 	_, err := r.db.Exec(query, payment.Amount, payment.PaymentDate,
-		payment.PaymentMethod, payment.Status, payment.ID)
+		payment.PaymentMethod, payment.Status, payment.ProviderReference,
+		payment.FailureReason, payment.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update payment: %w", err)
 	}
@@ -148,3 +214,326 @@ func (r *paymentRepository) DeletePayment(id int) error {
 	}
 	return nil
 }
+
+// CreateBookingPayment records a payment for a booking in a transaction holding a lock on
+// the booking. check receives the locked booking, the amount already paid for it and the
+// amount reserved by recent pending payments, may reject the payment, and decides whether
+// the booking is confirmed once the payment is recorded.
+// It returns the booking as it stands after the payment.
+func (r *paymentRepository) CreateBookingPayment(payment *models.Payment, check func(booking *models.Booking, netPaid, pendingPaid float64) (bool, error)) (*models.Booking, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	booking, err := lockBooking(tx, payment.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	netPaid, err := netPaidAmount(tx, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pendingPaid float64
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM payments
+		WHERE booking_id = $1 AND payment_type = 'payment' AND status = 'pending'
+		  AND created_at > CURRENT_TIMESTAMP - INTERVAL '` + pendingPaymentHold + `'`
+	if err := tx.QueryRow(query, booking.ID).Scan(&pendingPaid); err != nil {
+		return nil, fmt.Errorf("failed to get pending payments: %w", err)
+	}
+
+	confirm, err := check(booking, netPaid, pendingPaid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertPayment(tx, payment); err != nil {
+		return nil, err
+	}
+
+	if confirm {
+		err := transitionBookingStatus(tx, booking.ID, booking.Status, models.BookingStatusConfirmed, payment.UserID, "booking fully paid")
+		if err != nil {
+			return nil, err
+		}
+		booking.Status = models.BookingStatusConfirmed
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payment: %w", err)
+	}
+
+	return booking, nil
+}
+
+// SettlePayment records the outcome of a payment or refund in a transaction holding a lock
+// on its booking. update receives the locked payment, its booking and the amount already
+// paid for the booking, sets the new status, gateway reference and failure reason of the
+// payment, returns a refund to record along with it, and decides whether the booking is confirmed.
+func (r *paymentRepository) SettlePayment(paymentID int, update func(payment *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error)) (*models.Payment, *models.Booking, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var bookingID int
+	if err := tx.QueryRow(`SELECT booking_id FROM payments WHERE id = $1`, paymentID).Scan(&bookingID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("payment not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	// The booking is locked before the payment, in the same order as CreateBookingPayment
+	booking, err := lockBooking(tx, bookingID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment, err := scanPayment(tx.QueryRow(`SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, paymentID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	netPaid, err := netPaidAmount(tx, booking.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	confirm, refund, err := update(payment, booking, netPaid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		UPDATE payments
+		SET status = $1, provider_reference = NULLIF($2, ''), failure_reason = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING updated_at`
+
+	err = tx.QueryRow(query, payment.Status, payment.ProviderReference, payment.FailureReason, payment.ID).Scan(&payment.UpdatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if refund != nil {
+		if err := insertPayment(tx, refund); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if confirm {
+		err := transitionBookingStatus(tx, booking.ID, booking.Status, models.BookingStatusConfirmed, payment.UserID, "booking fully paid")
+		if err != nil {
+			return nil, nil, err
+		}
+		booking.Status = models.BookingStatusConfirmed
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit payment: %w", err)
+	}
+
+	return payment, booking, nil
+}
+
+// AllocateRefund assigns a pending refund to the completed payments of its booking, newest
+// first, so each part can be refunded through the gateway that took the money. When one
+// payment cannot cover the whole refund it is split into one refund per payment.
+// It returns the pending refunds to process; a refund that is already allocated is returned as is.
+func (r *paymentRepository) AllocateRefund(refundID int) ([]models.Payment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	refund, err := scanPayment(tx.QueryRow(`SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, refundID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment not found")
+		}
+		return nil, err
+	}
+	if refund.PaymentType != models.PaymentTypeRefund || refund.Status != models.PaymentStatusPending {
+		return nil, fmt.Errorf("payment %d is not a pending refund", refundID)
+	}
+	if refund.ParentPaymentID != nil {
+		return []models.Payment{*refund}, nil
+	}
+
+	query := `
+		SELECT p.id, p.payment_method, p.provider, p.amount - COALESCE((
+			SELECT SUM(r.amount) FROM payments r
+			WHERE r.parent_payment_id = p.id AND r.payment_type = 'refund' AND r.status IN ('pending', 'completed')
+		), 0)
+		FROM payments p
+		WHERE p.booking_id = $1 AND p.payment_type = 'payment' AND p.status = 'completed'
+		ORDER BY p.payment_date DESC, p.id DESC
+		FOR UPDATE`
+
+	rows, err := tx.Query(query, refund.BookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refundable payments: %w", err)
+	}
+
+	type refundable struct {
+		id       int
+		method   string
+		provider string
+		amount   float64
+	}
+	var candidates []refundable
+	for rows.Next() {
+		var c refundable
+		if err := rows.Scan(&c.id, &c.method, &c.provider, &c.amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan refundable payment: %w", err)
+		}
+		if c.amount > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	rows.Close()
+
+	var allocated []models.Payment
+	remaining := refund.Amount
+	for _, c := range candidates {
+		if remaining < 0.005 {
+			break
+		}
+		part := math.Round(math.Min(remaining, c.amount)*100) / 100
+		parentID := c.id
+
+		if len(allocated) == 0 {
+			query := `
+				UPDATE payments
+				SET amount = $1, parent_payment_id = $2, payment_method = $3, provider = $4, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $5`
+			if _, err := tx.Exec(query, part, parentID, c.method, c.provider, refund.ID); err != nil {
+				return nil, fmt.Errorf("failed to allocate refund: %w", err)
+			}
+			refund.Amount = part
+			refund.ParentPaymentID = &parentID
+			refund.PaymentMethod = c.method
+			refund.Provider = c.provider
+			allocated = append(allocated, *refund)
+		} else {
+			split := &models.Payment{
+				UserID:          refund.UserID,
+				BookingID:       refund.BookingID,
+				Amount:          part,
+				PaymentDate:     refund.PaymentDate,
+				PaymentMethod:   c.method,
+				Status:          models.PaymentStatusPending,
+				PaymentType:     models.PaymentTypeRefund,
+				Provider:        c.provider,
+				ParentPaymentID: &parentID,
+			}
+			if err := insertPayment(tx, split); err != nil {
+				return nil, err
+			}
+			allocated = append(allocated, *split)
+		}
+		remaining = math.Round((remaining-part)*100) / 100
+	}
+
+	if len(allocated) == 0 || remaining >= 0.005 {
+		return nil, fmt.Errorf("refund %d exceeds the refundable payments of booking %d", refund.ID, refund.BookingID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refund allocation: %w", err)
+	}
+
+	return allocated, nil
+}
+
+// insertPayment inserts a payment and fills in its ID and timestamps
+func insertPayment(q queryer, payment *models.Payment) error {
+	if payment.Provider == "" {
+		payment.Provider = models.PaymentProviderManual
+	}
+
+	query := `
+		INSERT INTO payments (user_id, booking_id, amount, payment_date, payment_method, status, payment_type,
+			provider, provider_reference, failure_reason, parent_payment_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
+		RETURNING id, created_at, updated_at`
+
+	err := q.QueryRow(query, payment.UserID, payment.BookingID,
+		payment.Amount, payment.PaymentDate, payment.PaymentMethod,
+		payment.Status, payment.PaymentType, payment.Provider, payment.ProviderReference,
+		payment.FailureReason, payment.ParentPaymentID).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+	return nil
+}
+
+// scanPayment scans a row selected with paymentColumns
+func scanPayment(row rowScanner) (*models.Payment, error) {
+	payment := &models.Payment{}
+	var parentID sql.NullInt64
+
+	err := row.Scan(
+		&payment.ID, &payment.UserID, &payment.BookingID,
+		&payment.Amount, &payment.PaymentDate, &payment.PaymentMethod,
+		&payment.Status, &payment.PaymentType, &payment.Provider, &payment.ProviderReference,
+		&payment.FailureReason, &parentID, &payment.CreatedAt, &payment.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan payment: %w", err)
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		payment.ParentPaymentID = &id
+	}
+	return payment, nil
+}
+
+// lockBooking selects a booking FOR UPDATE inside a transaction
+func lockBooking(q queryer, bookingID int) (*models.Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings WHERE id = $1
+		FOR UPDATE`
+
+	booking, err := scanBooking(q.QueryRow(query, bookingID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("booking not found")
+		}
+		return nil, fmt.Errorf("failed to lock booking: %w", err)
+	}
+	return booking, nil
+}
+
+// netPaidAmount sums the completed payments of a booking minus its pending and completed refunds
+func netPaidAmount(q queryer, bookingID int) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(
+			CASE
+				WHEN payment_type = 'payment' AND status = 'completed' THEN amount
+				WHEN payment_type = 'refund' AND status IN ('pending', 'completed') THEN -amount
+				ELSE 0
+			END), 0)
+		FROM payments
+		WHERE booking_id = $1`
+
+	var amount float64
+	if err := q.QueryRow(query, bookingID).Scan(&amount); err != nil {
+		return 0, fmt.Errorf("failed to get paid amount: %w", err)
+	}
+	return amount, nil
+}
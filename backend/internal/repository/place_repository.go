@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+)
+
+// Errors returned by the place repository
+var (
+	ErrPlaceNotFound = errors.New("place not found")
+	ErrPlaceExists   = errors.New("place already exists")
+	ErrPlaceInUse    = errors.New("place has places or destinations below it")
+)
+
+// placeColumns selects a place with the code of its parent for scanPlace
+const placeColumns = `p.id, p.code, p.name, p.level, p.parent_id,
+	COALESCE((SELECT parent.code FROM places parent WHERE parent.id = p.parent_id), ''), p.created_at, p.updated_at`
+
+// placeListSpec lists the fields places can be sorted and filtered by
+var placeListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":         {column: "p.id", kind: listInt, sortable: true},
+		"code":       {column: "p.code", kind: listText, sortable: true},
+		"name":       {column: "p.name", kind: listText, sortable: true},
+		"level":      {column: "p.level", kind: listText, sortable: true},
+		"parent_id":  {column: "p.parent_id", kind: listInt},
+		"created_at": {column: "p.created_at", kind: listTime, sortable: true},
+	},
+	defaultSort: "name",
+	idColumn:    "p.id",
+}
+
+// PlaceRepository defines the interface for the place hierarchy
+type PlaceRepository interface {
+	GetPlaces(parentID *int, query models.ListQuery) ([]models.Place, *models.PageInfo, error)
+	GetPlaceByCode(code string) (*models.Place, error)
+	GetAncestors(id int) ([]models.Place, error)
+	CreatePlace(place *models.Place) error
+	UpdatePlace(place *models.Place) error
+	DeletePlace(id int) error
+}
+
+// placeRepository implements PlaceRepository
+type placeRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewPlaceRepository creates a new place repository
+func NewPlaceRepository(db *sql.DB, logger *logger.Logger) PlaceRepository {
+	return &placeRepository{db: db, logger: logger}
+}
+
+// GetPlaces retrieves a page of places, only the places right below a parent when one is
+// given, sorted by name by default
+func (r *placeRepository) GetPlaces(parentID *int, query models.ListQuery) ([]models.Place, *models.PageInfo, error) {
+	var conditions []string
+	var args []interface{}
+	if parentID != nil {
+		conditions, args = []string{"p.parent_id = $1"}, []interface{}{*parentID}
+	}
+
+	places := []models.Place{}
+	page, err := queryPage(r.db, placeListSpec, query, placeColumns, "places p", conditions, args,
+		func(row rowScanner) error {
+			place, err := scanPlace(row)
+			if err != nil {
+				return fmt.Errorf("failed to scan place: %w", err)
+			}
+			places = append(places, *place)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get places: %w", err)
+	}
+	return places, page, nil
+}
+
+// GetPlaceByCode retrieves a place by its code
+func (r *placeRepository) GetPlaceByCode(code string) (*models.Place, error) {
+	query := `SELECT ` + placeColumns + ` FROM places p WHERE p.code = $1`
+
+	place, err := scanPlace(r.db.QueryRow(query, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPlaceNotFound
+		}
+		return nil, fmt.Errorf("failed to get place: %w", err)
+	}
+	return place, nil
+}
+
+// GetAncestors retrieves the places above a place, from its continent down to its parent
+func (r *placeRepository) GetAncestors(id int) ([]models.Place, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent_id AS id, 1 AS depth FROM places WHERE id = $1
+			UNION ALL
+			SELECT places.parent_id, ancestors.depth + 1
+			FROM places JOIN ancestors ON places.id = ancestors.id
+		)
+		SELECT ` + placeColumns + `
+		FROM ancestors JOIN places p ON p.id = ancestors.id
+		ORDER BY ancestors.depth DESC`
+
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get place ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	ancestors := []models.Place{}
+	for rows.Next() {
+		place, err := scanPlace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan place: %w", err)
+		}
+		ancestors = append(ancestors, *place)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get place ancestors: %w", err)
+	}
+	return ancestors, nil
+}
+
+// CreatePlace creates a place, unless another place already has its code
+func (r *placeRepository) CreatePlace(place *models.Place) error {
+	query := `
+		INSERT INTO places (code, name, level, parent_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, place.Code, place.Name, place.Level, place.ParentID).Scan(
+		&place.ID, &place.CreatedAt, &place.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPlaceExists
+		}
+		return fmt.Errorf("failed to create place: %w", err)
+	}
+	return nil
+}
+
+// UpdatePlace changes the name and parent of a place
+func (r *placeRepository) UpdatePlace(place *models.Place) error {
+	query := `
+		UPDATE places
+		SET name = $1, parent_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRow(query, place.Name, place.ParentID, place.ID).Scan(&place.CreatedAt, &place.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPlaceNotFound
+		}
+		return fmt.Errorf("failed to update place: %w", err)
+	}
+	return nil
+}
+
+// DeletePlace deletes a place that has neither places nor destinations below it
+func (r *placeRepository) DeletePlace(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the place so nothing can be linked to it while it is deleted
+	if _, err := tx.Exec(`SELECT 1 FROM places WHERE id = $1 FOR UPDATE`, id); err != nil {
+		return fmt.Errorf("failed to lock place: %w", err)
+	}
+
+	var inUse bool
+	query := `SELECT EXISTS (SELECT 1 FROM places WHERE parent_id = $1)
+		OR EXISTS (SELECT 1 FROM destinations WHERE place_id = $1)`
+	if err := tx.QueryRow(query, id).Scan(&inUse); err != nil {
+		return fmt.Errorf("failed to check place usage: %w", err)
+	}
+	if inUse {
+		return ErrPlaceInUse
+	}
+
+	result, err := tx.Exec(`DELETE FROM places WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete place: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrPlaceNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit place deletion: %w", err)
+	}
+	return nil
+}
+
+// scanPlace scans a place row selected with placeColumns
+func scanPlace(row rowScanner) (*models.Place, error) {
+	place := &models.Place{}
+	err := row.Scan(
+		&place.ID, &place.Code, &place.Name, &place.Level, &place.ParentID, &place.ParentCode,
+		&place.CreatedAt, &place.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return place, nil
+}
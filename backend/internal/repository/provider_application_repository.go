@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Errors returned by provider application operations
+var (
+	ErrProviderApplicationNotFound      = errors.New("provider application not found")
+	ErrProviderApplicationPending       = errors.New("a provider application is already in review")
+	ErrProviderApplicationStatusChanged = errors.New("provider application status was changed by another request")
+)
+
+// providerApplicationColumns selects a provider application row for scanProviderApplication
+const providerApplicationColumns = `id, user_id, company_name, description, website, address,
+	status, review_note, reviewed_by, reviewed_at, created_at, updated_at`
+
+// ProviderApplicationRepository defines the interface for provider application operations
+type ProviderApplicationRepository interface {
+	CreateApplication(application *models.ProviderApplication, documentIDs []int) error
+	ResubmitApplication(application *models.ProviderApplication, documentIDs []int) error
+	GetApplicationByID(id int) (*models.ProviderApplication, error)
+	GetApplicationsByUserID(userID int) ([]models.ProviderApplication, error)
+	GetApplications(status models.ProviderApplicationStatus) ([]models.ProviderApplication, error)
+	UpdateApplicationStatus(id int, from, to models.ProviderApplicationStatus, changedBy int, note string) (*models.ProviderApplication, error)
+	GetApplicationHistory(applicationID int) ([]models.ProviderApplicationStatusHistory, error)
+}
+
+// providerApplicationRepository implements ProviderApplicationRepository
+type providerApplicationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewProviderApplicationRepository creates a new provider application repository
+func NewProviderApplicationRepository(db *sql.DB, logger *logger.Logger) ProviderApplicationRepository {
+	return &providerApplicationRepository{db: db, logger: logger}
+}
+
+// CreateApplication creates a pending application with the given documents of the applicant,
+// unless the applicant already has an application in review
+func (r *providerApplicationRepository) CreateApplication(application *models.ProviderApplication, documentIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO provider_applications (user_id, company_name, description, website, address)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) WHERE status IN ('pending', 'changes_requested') DO NOTHING
+		RETURNING id, status, created_at, updated_at`
+
+	err = tx.QueryRow(query, application.UserID, application.CompanyName, application.Description,
+		application.Website, application.Address).Scan(
+		&application.ID, &application.Status, &application.CreatedAt, &application.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProviderApplicationPending
+		}
+		return fmt.Errorf("failed to create provider application: %w", err)
+	}
+
+	if err := attachProviderDocuments(tx, application, documentIDs); err != nil {
+		return err
+	}
+	if err := recordProviderApplicationStatus(tx, application.ID, nil, application.Status, application.UserID, "application submitted"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit provider application: %w", err)
+	}
+	return nil
+}
+
+// ResubmitApplication replaces the details and the documents of an application a reviewer
+// asked changes for, and puts it back in the review queue
+func (r *providerApplicationRepository) ResubmitApplication(application *models.ProviderApplication, documentIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE provider_applications
+		SET company_name = $1, description = $2, website = $3, address = $4, status = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND user_id = $7 AND status = $8
+		RETURNING ` + providerApplicationColumns
+
+	updated, err := scanProviderApplication(tx.QueryRow(query, application.CompanyName, application.Description,
+		application.Website, application.Address, models.ProviderApplicationPending, application.ID,
+		application.UserID, models.ProviderApplicationChangesRequested))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProviderApplicationStatusChanged
+		}
+		return fmt.Errorf("failed to resubmit provider application: %w", err)
+	}
+	*application = *updated
+
+	// Documents left out of the resubmission go back to the applicant's uploads
+	_, err = tx.Exec(`UPDATE provider_documents SET application_id = NULL WHERE application_id = $1 AND NOT (id = ANY($2))`,
+		application.ID, pq.Array(documentIDs))
+	if err != nil {
+		return fmt.Errorf("failed to detach provider documents: %w", err)
+	}
+	if err := attachProviderDocuments(tx, application, documentIDs); err != nil {
+		return err
+	}
+
+	from := models.ProviderApplicationChangesRequested
+	if err := recordProviderApplicationStatus(tx, application.ID, &from, application.Status, application.UserID, "application resubmitted"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit provider application: %w", err)
+	}
+	return nil
+}
+
+// GetApplicationByID retrieves a provider application by ID with its documents
+func (r *providerApplicationRepository) GetApplicationByID(id int) (*models.ProviderApplication, error) {
+	query := `SELECT ` + providerApplicationColumns + ` FROM provider_applications WHERE id = $1`
+
+	application, err := scanProviderApplication(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProviderApplicationNotFound
+		}
+		return nil, fmt.Errorf("failed to get provider application: %w", err)
+	}
+
+	application.Documents, err = queryProviderDocuments(r.db, `
+		SELECT `+providerDocumentColumns+`
+		FROM provider_documents
+		WHERE application_id = $1
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+// GetApplicationsByUserID retrieves the applications of a user, the most recent first
+func (r *providerApplicationRepository) GetApplicationsByUserID(userID int) ([]models.ProviderApplication, error) {
+	query := `
+		SELECT ` + providerApplicationColumns + `
+		FROM provider_applications
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+	return r.queryApplications(query, userID)
+}
+
+// GetApplications retrieves the applications with a status, or all of them when status is
+// empty, the oldest first so reviewers work through them in order
+func (r *providerApplicationRepository) GetApplications(status models.ProviderApplicationStatus) ([]models.ProviderApplication, error) {
+	query := `
+		SELECT ` + providerApplicationColumns + `
+		FROM provider_applications
+		WHERE $1::text = '' OR status = $1
+		ORDER BY created_at, id`
+	return r.queryApplications(query, status)
+}
+
+// UpdateApplicationStatus moves an application from one status to another and records the
+// change. Approving copies the company details to the applicant, marks them verified and
+// makes them a provider, though users with a staff role keep it; revoking withdraws the
+// verification. Returns ErrProviderApplicationStatusChanged if the application no longer
+// has the from status.
+func (r *providerApplicationRepository) UpdateApplicationStatus(id int, from, to models.ProviderApplicationStatus, changedBy int, note string) (*models.ProviderApplication, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE provider_applications
+		SET status = $1, review_note = $2, reviewed_by = $3, reviewed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = $5
+		RETURNING ` + providerApplicationColumns
+
+	application, err := scanProviderApplication(tx.QueryRow(query, to, note, changedBy, id, from))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProviderApplicationStatusChanged
+		}
+		return nil, fmt.Errorf("failed to update provider application status: %w", err)
+	}
+
+	if err := recordProviderApplicationStatus(tx, id, &from, to, changedBy, note); err != nil {
+		return nil, err
+	}
+
+	switch to {
+	case models.ProviderApplicationApproved:
+		query = `
+			UPDATE users
+			SET company_name = $1, description = $2, website = $3, address = $4, verified = TRUE,
+				role = CASE WHEN role = $5 THEN $6 ELSE role END, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $7`
+
+		_, err := tx.Exec(query, application.CompanyName, application.Description, application.Website,
+			application.Address, models.RoleUser, models.RoleProvider, application.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify provider: %w", err)
+		}
+	case models.ProviderApplicationRevoked:
+		_, err := tx.Exec(`UPDATE users SET verified = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, application.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke provider verification: %w", err)
+		}
+	}
+
+	application.Documents, err = queryProviderDocuments(tx, `
+		SELECT `+providerDocumentColumns+`
+		FROM provider_documents
+		WHERE application_id = $1
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit provider application status: %w", err)
+	}
+	return application, nil
+}
+
+// GetApplicationHistory retrieves the status changes of an application, oldest first
+func (r *providerApplicationRepository) GetApplicationHistory(applicationID int) ([]models.ProviderApplicationStatusHistory, error) {
+	query := `
+		SELECT id, application_id, from_status, to_status, changed_by, note, created_at
+		FROM provider_application_history
+		WHERE application_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := r.db.Query(query, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider application history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.ProviderApplicationStatusHistory{}
+	for rows.Next() {
+		var entry models.ProviderApplicationStatusHistory
+		err := rows.Scan(&entry.ID, &entry.ApplicationID, &entry.FromStatus, &entry.ToStatus,
+			&entry.ChangedBy, &entry.Note, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provider application history: %w", err)
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// queryApplications runs a query returning provider application rows and loads their documents
+func (r *providerApplicationRepository) queryApplications(query string, args ...interface{}) ([]models.ProviderApplication, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider applications: %w", err)
+	}
+	defer rows.Close()
+
+	applications := []models.ProviderApplication{}
+	index := make(map[int]int)
+	var ids []int
+	for rows.Next() {
+		application, err := scanProviderApplication(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provider application: %w", err)
+		}
+		application.Documents = []models.ProviderDocument{}
+		index[application.ID] = len(applications)
+		ids = append(ids, application.ID)
+		applications = append(applications, *application)
+	}
+	if len(ids) == 0 {
+		return applications, nil
+	}
+
+	documents, err := queryProviderDocuments(r.db, `
+		SELECT `+providerDocumentColumns+`
+		FROM provider_documents
+		WHERE application_id = ANY($1)
+		ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	for _, document := range documents {
+		i := index[*document.ApplicationID]
+		applications[i].Documents = append(applications[i].Documents, document)
+	}
+	return applications, nil
+}
+
+// attachProviderDocuments makes documents of the applicant part of an application and loads
+// them into it. Documents of other users or of another application are refused.
+func attachProviderDocuments(tx *sql.Tx, application *models.ProviderApplication, documentIDs []int) error {
+	query := `
+		UPDATE provider_documents
+		SET application_id = $1
+		WHERE id = ANY($2) AND user_id = $3 AND (application_id IS NULL OR application_id = $1)
+		RETURNING ` + providerDocumentColumns
+
+	documents, err := queryProviderDocuments(tx, query, application.ID, pq.Array(documentIDs), application.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to attach provider documents: %w", err)
+	}
+	if len(documents) != len(documentIDs) {
+		return ErrProviderDocumentUnavailable
+	}
+	application.Documents = documents
+	return nil
+}
+
+// recordProviderApplicationStatus adds a status change to the history of an application
+func recordProviderApplicationStatus(tx *sql.Tx, applicationID int, from *models.ProviderApplicationStatus, to models.ProviderApplicationStatus, changedBy int, note string) error {
+	query := `
+		INSERT INTO provider_application_history (application_id, from_status, to_status, changed_by, note)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.Exec(query, applicationID, from, to, changedBy, note); err != nil {
+		return fmt.Errorf("failed to record provider application status: %w", err)
+	}
+	return nil
+}
+
+// scanProviderApplication scans a provider application row
+func scanProviderApplication(row rowScanner) (*models.ProviderApplication, error) {
+	application := &models.ProviderApplication{}
+	err := row.Scan(
+		&application.ID, &application.UserID, &application.CompanyName, &application.Description,
+		&application.Website, &application.Address, &application.Status,
+		&application.ReviewNote, &application.ReviewedBy, &application.ReviewedAt,
+		&application.CreatedAt, &application.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return application, nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"time"
+)
+
+// providerBookingColumns selects a booking of a provider with its service and guest for scanProviderBooking
+const providerBookingColumns = `b.id, b.user_id, b.service_id, b.booking_date_start, b.booking_date_end, b.total_price,
+	b.status, b.created_at, b.updated_at, b.vehicle_id, b.vehicle_class_id, b.pickup_location_id, b.dropoff_location_id,
+	s.name, u.first_name, u.last_name, u.email,
+	COALESCE((
+		SELECT SUM(CASE
+			WHEN p.payment_type = 'payment' AND p.status = 'completed' THEN p.amount
+			WHEN p.payment_type = 'refund' AND p.status IN ('pending', 'completed') THEN -p.amount
+			ELSE 0
+		END)
+		FROM payments p WHERE p.booking_id = b.id
+	), 0)`
+
+// ProviderDashboardRepository defines the interface for the figures shown on the dashboard
+// of a provider, always limited to the services the provider owns
+type ProviderDashboardRepository interface {
+	GetBookings(providerID int, status models.BookingStatus) ([]models.ProviderBooking, error)
+	GetCheckIns(providerID int, from, to time.Time) ([]models.ProviderBooking, error)
+	GetRevenue(providerID int, period string, from, to time.Time) ([]models.RevenuePeriod, error)
+	GetOccupancy(providerID int, from, to time.Time) ([]models.ServiceOccupancy, error)
+	GetCancellations(providerID int, from, to time.Time) (*models.CancellationReport, error)
+}
+
+// providerDashboardRepository implements ProviderDashboardRepository
+type providerDashboardRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewProviderDashboardRepository creates a new provider dashboard repository
+func NewProviderDashboardRepository(db *sql.DB, logger *logger.Logger) ProviderDashboardRepository {
+	return &providerDashboardRepository{db: db, logger: logger}
+}
+
+// GetBookings retrieves the bookings of the services of a provider, the most recent first,
+// with a status or all of them when status is empty
+func (r *providerDashboardRepository) GetBookings(providerID int, status models.BookingStatus) ([]models.ProviderBooking, error) {
+	query := `
+		SELECT ` + providerBookingColumns + `
+		FROM bookings b
+		JOIN services s ON s.id = b.service_id
+		JOIN users u ON u.id = b.user_id
+		WHERE s.user_id = $1 AND ($2::text = '' OR b.status = $2::text)
+		ORDER BY b.created_at DESC, b.id DESC`
+	return r.queryProviderBookings(query, providerID, status)
+}
+
+// GetCheckIns retrieves the confirmed bookings of the services of a provider that start
+// within a window, the earliest first
+func (r *providerDashboardRepository) GetCheckIns(providerID int, from, to time.Time) ([]models.ProviderBooking, error) {
+	query := `
+		SELECT ` + providerBookingColumns + `
+		FROM bookings b
+		JOIN services s ON s.id = b.service_id
+		JOIN users u ON u.id = b.user_id
+		WHERE s.user_id = $1 AND b.status = 'confirmed'
+			AND b.booking_date_start >= $2 AND b.booking_date_start < $3
+		ORDER BY b.booking_date_start, b.id`
+	return r.queryProviderBookings(query, providerID, from, to)
+}
+
+// GetRevenue sums the completed payments and the pending and completed refunds of the
+// bookings of a provider made within a window, per day, week or month. Periods without
+// payments are left out.
+func (r *providerDashboardRepository) GetRevenue(providerID int, period string, from, to time.Time) ([]models.RevenuePeriod, error) {
+	query := `
+		SELECT date_trunc($2, p.payment_date) AS period_start,
+			COUNT(*) FILTER (WHERE p.payment_type = 'payment'),
+			COALESCE(SUM(p.amount) FILTER (WHERE p.payment_type = 'payment'), 0),
+			COALESCE(SUM(p.amount) FILTER (WHERE p.payment_type = 'refund'), 0)
+		FROM payments p
+		JOIN bookings b ON b.id = p.booking_id
+		JOIN services s ON s.id = b.service_id
+		WHERE s.user_id = $1 AND p.payment_date >= $3 AND p.payment_date < $4
+			AND ((p.payment_type = 'payment' AND p.status = 'completed')
+				OR (p.payment_type = 'refund' AND p.status IN ('pending', 'completed')))
+		GROUP BY period_start
+		ORDER BY period_start`
+
+	rows, err := r.db.Query(query, providerID, period, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revenue: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.RevenuePeriod{}
+	for rows.Next() {
+		var p models.RevenuePeriod
+		if err := rows.Scan(&p.PeriodStart, &p.Payments, &p.Gross, &p.Refunded); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue: %w", err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
+
+// GetOccupancy sums, for each service of a provider, the days within a window held by
+// confirmed, checked-in and completed bookings
+func (r *providerDashboardRepository) GetOccupancy(providerID int, from, to time.Time) ([]models.ServiceOccupancy, error) {
+	query := `
+		SELECT s.id, s.name, s.capacity,
+			COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(b.booking_date_end, $3) - GREATEST(b.booking_date_start, $2))), 0) / 86400
+		FROM services s
+		LEFT JOIN bookings b ON b.service_id = s.id
+			AND b.status IN ('confirmed', 'checked_in', 'completed')
+			AND b.booking_date_start < $3 AND b.booking_date_end > $2
+		WHERE s.user_id = $1
+		GROUP BY s.id, s.name, s.capacity
+		ORDER BY s.name, s.id`
+
+	rows, err := r.db.Query(query, providerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get occupancy: %w", err)
+	}
+	defer rows.Close()
+
+	services := []models.ServiceOccupancy{}
+	for rows.Next() {
+		var o models.ServiceOccupancy
+		if err := rows.Scan(&o.ServiceID, &o.ServiceName, &o.Capacity, &o.BookedDays); err != nil {
+			return nil, fmt.Errorf("failed to scan occupancy: %w", err)
+		}
+		services = append(services, o)
+	}
+	return services, nil
+}
+
+// GetCancellations counts the bookings of the services of a provider made within a window,
+// those cancelled and those the provider or their staff cancelled themselves
+func (r *providerDashboardRepository) GetCancellations(providerID int, from, to time.Time) (*models.CancellationReport, error) {
+	query := `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE b.status = 'cancelled'),
+			COUNT(*) FILTER (WHERE b.status = 'cancelled' AND EXISTS (
+				SELECT 1 FROM booking_status_history h
+				WHERE h.booking_id = b.id AND h.to_status = 'cancelled'
+					AND (h.changed_by = s.user_id OR h.changed_by IN (
+						SELECT m.user_id
+						FROM organisation_members m
+						JOIN provider_organisations o ON o.id = m.organisation_id
+						WHERE o.owner_id = s.user_id
+					))
+			))
+		FROM bookings b
+		JOIN services s ON s.id = b.service_id
+		WHERE s.user_id = $1 AND b.created_at >= $2 AND b.created_at < $3`
+
+	report := &models.CancellationReport{From: from, To: to}
+	err := r.db.QueryRow(query, providerID, from, to).Scan(
+		&report.TotalBookings, &report.CancelledBookings, &report.DeclinedByProvider,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellations: %w", err)
+	}
+	return report, nil
+}
+
+// queryProviderBookings runs a query returning rows selected with providerBookingColumns
+func (r *providerDashboardRepository) queryProviderBookings(query string, args ...interface{}) ([]models.ProviderBooking, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := []models.ProviderBooking{}
+	for rows.Next() {
+		var b models.ProviderBooking
+		err := rows.Scan(
+			&b.ID, &b.UserID, &b.ServiceID, &b.BookingDateStart, &b.BookingDateEnd, &b.TotalPrice,
+			&b.Status, &b.CreatedAt, &b.UpdatedAt, &b.VehicleID, &b.VehicleClassID, &b.PickupLocationID,
+			&b.DropoffLocationID, &b.ServiceName, &b.GuestFirstName, &b.GuestLastName, &b.GuestEmail, &b.AmountPaid,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provider booking: %w", err)
+		}
+		bookings = append(bookings, b)
+	}
+	return bookings, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+)
+
+// Errors returned by provider document operations
+var (
+	ErrProviderDocumentNotFound    = errors.New("provider document not found")
+	ErrProviderDocumentAttached    = errors.New("provider document is part of an application")
+	ErrProviderDocumentUnavailable = errors.New("provider document is not owned by the applicant or is part of another application")
+)
+
+// providerDocumentColumns selects a provider document row for scanProviderDocument
+const providerDocumentColumns = `id, user_id, application_id, document_type, file_name, content_type, size_bytes, storage_key, created_at`
+
+// ProviderDocumentRepository defines the interface for provider document operations
+type ProviderDocumentRepository interface {
+	CreateDocument(document *models.ProviderDocument) error
+	GetDocumentByID(id int) (*models.ProviderDocument, error)
+	GetDocumentsByUserID(userID int) ([]models.ProviderDocument, error)
+	DeleteDocument(id, userID int) error
+}
+
+// providerDocumentRepository implements ProviderDocumentRepository
+type providerDocumentRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewProviderDocumentRepository creates a new provider document repository
+func NewProviderDocumentRepository(db *sql.DB, logger *logger.Logger) ProviderDocumentRepository {
+	return &providerDocumentRepository{db: db, logger: logger}
+}
+
+// CreateDocument records an uploaded document that is not part of an application yet
+func (r *providerDocumentRepository) CreateDocument(document *models.ProviderDocument) error {
+	query := `
+		INSERT INTO provider_documents (user_id, document_type, file_name, content_type, size_bytes, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, document.UserID, document.Type, document.FileName, document.ContentType,
+		document.Size, document.StorageKey).Scan(&document.ID, &document.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create provider document: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentByID retrieves a provider document by ID
+func (r *providerDocumentRepository) GetDocumentByID(id int) (*models.ProviderDocument, error) {
+	query := `SELECT ` + providerDocumentColumns + ` FROM provider_documents WHERE id = $1`
+
+	document, err := scanProviderDocument(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProviderDocumentNotFound
+		}
+		return nil, fmt.Errorf("failed to get provider document: %w", err)
+	}
+	return document, nil
+}
+
+// GetDocumentsByUserID retrieves the documents uploaded by a user, the most recent first
+func (r *providerDocumentRepository) GetDocumentsByUserID(userID int) ([]models.ProviderDocument, error) {
+	query := `
+		SELECT ` + providerDocumentColumns + `
+		FROM provider_documents
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC`
+	return queryProviderDocuments(r.db, query, userID)
+}
+
+// DeleteDocument deletes a document of a user that is not part of an application
+func (r *providerDocumentRepository) DeleteDocument(id, userID int) error {
+	query := `DELETE FROM provider_documents WHERE id = $1 AND user_id = $2 AND application_id IS NULL`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete provider document: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete provider document: %w", err)
+	}
+	if rows == 0 {
+		return ErrProviderDocumentAttached
+	}
+	return nil
+}
+
+// queryProviderDocuments runs a query returning provider document rows
+func queryProviderDocuments(q queryer, query string, args ...interface{}) ([]models.ProviderDocument, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []models.ProviderDocument{}
+	for rows.Next() {
+		document, err := scanProviderDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provider document: %w", err)
+		}
+		documents = append(documents, *document)
+	}
+	return documents, nil
+}
+
+// scanProviderDocument scans a provider document row
+func scanProviderDocument(row rowScanner) (*models.ProviderDocument, error) {
+	document := &models.ProviderDocument{}
+	err := row.Scan(
+		&document.ID, &document.UserID, &document.ApplicationID, &document.Type, &document.FileName,
+		&document.ContentType, &document.Size, &document.StorageKey, &document.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
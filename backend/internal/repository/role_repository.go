@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Errors returned by role operations
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+	ErrRoleInUse    = errors.New("role is assigned to users")
+	ErrRoleBuiltIn  = errors.New("built-in roles cannot be changed or deleted")
+)
+
+// roleColumns selects a role together with its permissions
+const roleColumns = `name, description, built_in, created_at, updated_at,
+	ARRAY(SELECT permission FROM role_permissions WHERE role_name = roles.name ORDER BY permission)`
+
+// RoleRepository defines the interface for role and permission operations
+type RoleRepository interface {
+	GetPermissions() ([]models.Permission, error)
+	GetRoles() ([]models.Role, error)
+	GetRole(name models.UserRole) (*models.Role, error)
+	CreateRole(role *models.Role) error
+	UpdateRole(role *models.Role) error
+	DeleteRole(name models.UserRole) error
+}
+
+// roleRepository implements RoleRepository
+type roleRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *sql.DB, logger *logger.Logger) RoleRepository {
+	return &roleRepository{db: db, logger: logger}
+}
+
+// GetPermissions retrieves every permission roles can grant
+func (r *roleRepository) GetPermissions() ([]models.Permission, error) {
+	rows, err := r.db.Query(`SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []models.Permission
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, nil
+}
+
+// GetRoles retrieves every role with its permissions
+func (r *roleRepository) GetRoles() ([]models.Role, error) {
+	rows, err := r.db.Query(`SELECT ` + roleColumns + ` FROM roles ORDER BY built_in DESC, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+// GetRole retrieves a role with its permissions
+func (r *roleRepository) GetRole(name models.UserRole) (*models.Role, error) {
+	role, err := scanRole(r.db.QueryRow(`SELECT `+roleColumns+` FROM roles WHERE name = $1`, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// CreateRole creates a role with its permissions
+func (r *roleRepository) CreateRole(role *models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO roles (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING built_in, created_at, updated_at`
+
+	err = tx.QueryRow(query, role.Name, role.Description).Scan(&role.BuiltIn, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRoleExists
+		}
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	if err := setRolePermissions(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole replaces the description and the permissions of a role. Built-in roles are
+// reported as not found.
+func (r *roleRepository) UpdateRole(role *models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE roles
+		SET description = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE name = $2 AND built_in = FALSE
+		RETURNING built_in, created_at, updated_at`
+
+	err = tx.QueryRow(query, role.Description, role.Name).Scan(&role.BuiltIn, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_name = $1`, role.Name); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+	if err := setRolePermissions(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole deletes a role that is not built in and not assigned to any user
+func (r *roleRepository) DeleteRole(name models.UserRole) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the role so it cannot be assigned while it is deleted
+	var builtIn bool
+	err = tx.QueryRow(`SELECT built_in FROM roles WHERE name = $1 FOR UPDATE`, name).Scan(&builtIn)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+	if builtIn {
+		return ErrRoleBuiltIn
+	}
+
+	var assigned bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)`, name).Scan(&assigned); err != nil {
+		return fmt.Errorf("failed to check role assignments: %w", err)
+	}
+	if assigned {
+		return ErrRoleInUse
+	}
+
+	if _, err := tx.Exec(`DELETE FROM roles WHERE name = $1`, name); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role deletion: %w", err)
+	}
+	return nil
+}
+
+// setRolePermissions grants permissions to a role
+func setRolePermissions(tx *sql.Tx, name models.UserRole, permissions []string) error {
+	query := `
+		INSERT INTO role_permissions (role_name, permission)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING`
+
+	if _, err := tx.Exec(query, name, pq.Array(permissions)); err != nil {
+		return fmt.Errorf("failed to set role permissions: %w", err)
+	}
+	return nil
+}
+
+// scanRole scans a role selected with roleColumns
+func scanRole(row rowScanner) (*models.Role, error) {
+	role := &models.Role{}
+	err := row.Scan(&role.Name, &role.Description, &role.BuiltIn, &role.CreatedAt, &role.UpdatedAt,
+		pq.Array(&role.Permissions))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+	return role, nil
+}
@@ -2,16 +2,55 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
+// ErrServiceNotFound is returned when a service does not exist
+var ErrServiceNotFound = errors.New("service not found")
+
+// serviceColumns selects a service row for scanService
+const serviceColumns = `s.id, s.user_id, s.service_type_id, s.name, s.description, s.price, s.availability, s.capacity,
+	s.cancellation_policy_id, s.destination_id, s.latitude, s.longitude, s.rating, s.reviews, s.created_at, s.updated_at`
+
+// serviceListSpec lists the fields services can be sorted and filtered by
+var serviceListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":              {column: "s.id", kind: listInt, sortable: true},
+		"name":            {column: "s.name", kind: listText, sortable: true},
+		"service_type_id": {column: "s.service_type_id", kind: listInt, sortable: true},
+		"user_id":         {column: "s.user_id", kind: listInt},
+		"destination_id":  {column: "s.destination_id", kind: listInt},
+		"latitude":        {column: "s.latitude", kind: listFloat},
+		"longitude":       {column: "s.longitude", kind: listFloat},
+		"price":           {column: "s.price", kind: listFloat, sortable: true},
+		"capacity":        {column: "s.capacity", kind: listInt, sortable: true},
+		"rating":          {column: "s.rating", kind: listFloat},
+		"reviews":         {column: "s.reviews", kind: listInt, sortable: true},
+		"created_at":      {column: "s.created_at", kind: listTime, sortable: true},
+	},
+	defaultSort: "name",
+	idColumn:    "s.id",
+}
+
+// priceFacetBounds are the prices that separate the price ranges of search facets
+var priceFacetBounds = []float64{50, 100, 200, 500}
+
+// ratingFacetThresholds are the minimum ratings counted by search facets
+var ratingFacetThresholds = []float64{4, 3, 2, 1}
+
 // ServiceRepository interface defines methods for service operations
 type ServiceRepository interface {
-	GetAllServices() ([]models.Service, error)
+	SearchServices(filter models.ServiceFilter, query models.ListQuery) ([]models.Service, *models.PageInfo, error)
+	SearchCatalogue(query models.ServiceSearchQuery) (*models.ServiceSearchResult, error)
+	GetServicesInArea(area models.GeoArea, query models.ListQuery) ([]models.Service, *models.PageInfo, error)
 	GetServicesByServiceType(serviceTypeID int) ([]models.Service, error)
-	GetServicesByCategory(category string) ([]models.Service, error)
+	GetServicesByUserID(userID int) ([]models.Service, error)
 	GetServiceByID(id int) (*models.Service, error)
 	CreateService(service *models.Service) error
 	UpdateService(service *models.Service) error
@@ -29,115 +68,377 @@ func NewServiceRepository(db *sql.DB, logger *logger.Logger) ServiceRepository {
 	return &serviceRepository{db: db, logger: logger}
 }
 
-// GetAllServices retrieves all services
-func (r *serviceRepository) GetAllServices() ([]models.Service, error) {
-	query := `
-		SELECT id, service_type_id, name, description, price, availability, created_at, updated_at
-		FROM services
-		WHERE availability = true
-		ORDER BY name`
+// SearchServices retrieves a page of the available services matching a filter, sorted by name
+// by default. Room and amenity criteria only match services with accommodation details.
+func (r *serviceRepository) SearchServices(filter models.ServiceFilter, query models.ListQuery) ([]models.Service, *models.PageInfo, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions := append([]string{"s.availability = true"}, serviceFilterConditions(filter, arg)...)
+
+	services := []models.Service{}
+	page, err := queryPage(r.db, serviceListSpec, query, serviceColumns, "services s", conditions, args,
+		func(row rowScanner) error {
+			service, err := scanService(row)
+			if err != nil {
+				return fmt.Errorf("failed to scan service: %w", err)
+			}
+			services = append(services, *service)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search services: %w", err)
+	}
+	return services, page, nil
+}
+
+// GetServicesInArea retrieves a page of the available located services inside the polygon of an
+// area or, without one, within its radius of its center, sorted by name by default
+func (r *serviceRepository) GetServicesInArea(area models.GeoArea, query models.ListQuery) ([]models.Service, *models.PageInfo, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions := []string{"s.availability = true", "s.latitude IS NOT NULL"}
+	if len(area.Polygon) > 0 {
+		conditions = append(conditions, arg(polygonValue(area.Polygon))+"::polygon @> point(s.longitude, s.latitude)")
+	} else {
+		radius, _ := radiusConditions("s.latitude", "s.longitude", area.Center, area.RadiusKm, arg)
+		conditions = append(conditions, radius...)
+	}
+
+	services := []models.Service{}
+	page, err := queryPage(r.db, serviceListSpec, query, serviceColumns, "services s", conditions, args,
+		func(row rowScanner) error {
+			service, err := scanService(row)
+			if err != nil {
+				return fmt.Errorf("failed to scan service: %w", err)
+			}
+			services = append(services, *service)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get services in area: %w", err)
+	}
+	return services, page, nil
+}
+
+// SearchCatalogue retrieves a page of the available services matching a full-text search,
+// together with the facet counts of every matching service. The window, when given, only
+// matches services whose busiest moment during it still leaves a slot free.
+func (r *serviceRepository) SearchCatalogue(query models.ServiceSearchQuery) (*models.ServiceSearchResult, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions := append([]string{"s.availability = true"}, serviceFilterConditions(query.ServiceFilter, arg)...)
+
+	var rank string
+	if query.Text != "" {
+		tsquery := "websearch_to_tsquery('english', " + arg(query.Text) + ")"
+		conditions = append(conditions, "s.search_vector @@ "+tsquery)
+		rank = "ts_rank(s.search_vector, " + tsquery + ")"
+	}
+	if query.ServiceTypeID > 0 {
+		conditions = append(conditions, "s.service_type_id = "+arg(query.ServiceTypeID))
+	}
+	if query.MinPrice != nil {
+		conditions = append(conditions, "s.price >= "+arg(*query.MinPrice))
+	}
+	if query.MaxPrice != nil {
+		conditions = append(conditions, "s.price <= "+arg(*query.MaxPrice))
+	}
+	if query.DestinationID > 0 {
+		conditions = append(conditions, "s.destination_id = "+arg(query.DestinationID))
+	}
+	if query.MinRating > 0 {
+		conditions = append(conditions, "s.rating >= "+arg(query.MinRating))
+	}
+	if !query.From.IsZero() && !query.To.IsZero() {
+		// Occupancy only rises when a booking starts, so the busiest moment of the window is
+		// its start or the start of one of the bookings overlapping it
+		from, to, statuses := arg(query.From), arg(query.To), arg(pq.Array(models.BlockingBookingStatuses))
+		conditions = append(conditions, `(
+			SELECT COALESCE(MAX((
+				SELECT COUNT(*) FROM bookings o
+				WHERE o.service_id = s.id AND o.status = ANY(`+statuses+`)
+				  AND o.booking_date_start <= p.instant AND o.booking_date_end > p.instant
+			)), 0)
+			FROM (
+				SELECT GREATEST(b.booking_date_start, `+from+`) AS instant
+				FROM bookings b
+				WHERE b.service_id = s.id AND b.status = ANY(`+statuses+`)
+				  AND b.booking_date_start < `+to+` AND b.booking_date_end > `+from+`
+			) p
+		) < s.capacity`)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	result := &models.ServiceSearchResult{
+		Services: []models.Service{},
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+		Sort:     query.Sort,
+	}
+	facets, total, err := r.searchFacets(where, args)
+	if err != nil {
+		return nil, err
+	}
+	result.Facets, result.Total = *facets, total
+	if total == 0 {
+		return result, nil
+	}
+
+	// Without search words there is nothing to rank by and results are sorted by name
+	var orderBy string
+	switch query.Sort {
+	case models.ServiceSortRelevance:
+		orderBy = "s.name"
+		if rank != "" {
+			orderBy = rank + " DESC, s.name"
+		}
+	case models.ServiceSortPriceAsc:
+		orderBy = "s.price, s.name"
+	case models.ServiceSortPriceDesc:
+		orderBy = "s.price DESC, s.name"
+	case models.ServiceSortRating:
+		orderBy = "s.rating DESC NULLS LAST, s.reviews DESC, s.name"
+	case models.ServiceSortNewest:
+		orderBy = "s.created_at DESC"
+	default:
+		orderBy = "s.name"
+	}
+
+	page := `
+		SELECT ` + serviceColumns + `
+		FROM services s
+		WHERE ` + where + `
+		ORDER BY ` + orderBy + `, s.id
+		LIMIT ` + arg(query.Limit) + ` OFFSET ` + arg(query.Offset)
+
+	services, err := r.queryServices(page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search catalogue: %w", err)
+	}
+	if services != nil {
+		result.Services = services
+	}
+	return result, nil
+}
+
+// searchFacets counts the services matching the conditions of a search by service type,
+// destination, price range and rating, and returns how many services match in total
+func (r *serviceRepository) searchFacets(where string, args []interface{}) (*models.SearchFacets, int, error) {
+	matches := `
+		WITH matches AS (
+			SELECT s.service_type_id, s.destination_id, s.price, s.rating
+			FROM services s
+			WHERE ` + where + `
+		)`
+	facets := &models.SearchFacets{}
+
+	var total int
+	if err := r.db.QueryRow(matches+` SELECT COUNT(*) FROM matches`, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	var err error
+	facets.ServiceTypes, err = r.queryFacetCounts(matches+`
+		SELECT st.id, st.name, COUNT(*)
+		FROM matches m
+		JOIN service_types st ON st.id = m.service_type_id
+		GROUP BY st.id, st.name
+		ORDER BY COUNT(*) DESC, st.name`, args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count services by type: %w", err)
+	}
+	facets.Destinations, err = r.queryFacetCounts(matches+`
+		SELECT d.id, d.name, COUNT(*)
+		FROM matches m
+		JOIN destinations d ON d.id = m.destination_id
+		GROUP BY d.id, d.name
+		ORDER BY COUNT(*) DESC, d.name`, args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count services by destination: %w", err)
+	}
 
-	rows, err := r.db.Query(query)
+	if facets.PriceRanges, err = r.countPriceRanges(matches, args); err != nil {
+		return nil, 0, err
+	}
+	if facets.Ratings, err = r.countRatings(matches, args); err != nil {
+		return nil, 0, err
+	}
+	return facets, total, nil
+}
+
+// countPriceRanges counts the services matching a search in each price range
+func (r *serviceRepository) countPriceRanges(matches string, args []interface{}) ([]models.PriceRangeCount, error) {
+	// width_bucket numbers the ranges from 0, below the first bound, to len(bounds)
+	args = append(append([]interface{}{}, args...), pq.Array(priceFacetBounds))
+	rows, err := r.db.Query(matches+fmt.Sprintf(`
+		SELECT width_bucket(m.price::float8, $%d::float8[]) AS bucket, COUNT(*)
+		FROM matches m
+		GROUP BY bucket`, len(args)), args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get services: %w", err)
+		return nil, fmt.Errorf("failed to count services by price: %w", err)
 	}
 	defer rows.Close()
 
-	var services []models.Service
+	buckets := make([]int, len(priceFacetBounds)+1)
 	for rows.Next() {
-		var service models.Service
-		err := rows.Scan(
-			&service.ID, &service.ServiceTypeID,
-			&service.Name, &service.Description, &service.Price, &service.Availability,
-			&service.CreatedAt, &service.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan service: %w", err)
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan price range: %w", err)
 		}
-		services = append(services, service)
+		buckets[bucket] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count services by price: %w", err)
 	}
 
-	return services, nil
+	ranges := make([]models.PriceRangeCount, len(buckets))
+	for i, count := range buckets {
+		ranges[i].Count = count
+		if i > 0 {
+			ranges[i].Min = priceFacetBounds[i-1]
+		}
+		if i < len(priceFacetBounds) {
+			bound := priceFacetBounds[i]
+			ranges[i].Max = &bound
+		}
+	}
+	return ranges, nil
 }
 
-// GetServicesByServiceType retrieves services by service type
-func (r *serviceRepository) GetServicesByServiceType(serviceTypeID int) ([]models.Service, error) {
-	query := `
-		SELECT id, service_type_id, name, description, price, availability, created_at, updated_at
-		FROM services
-		WHERE service_type_id = $1 AND availability = true
-		ORDER BY name`
+// countRatings counts the services matching a search rated at least each rating threshold
+func (r *serviceRepository) countRatings(matches string, args []interface{}) ([]models.RatingCount, error) {
+	args = append(append([]interface{}{}, args...), pq.Array(ratingFacetThresholds))
+	rows, err := r.db.Query(matches+fmt.Sprintf(`
+		SELECT t.threshold, COUNT(m.rating)
+		FROM unnest($%d::float8[]) AS t(threshold)
+		LEFT JOIN matches m ON m.rating >= t.threshold
+		GROUP BY t.threshold
+		ORDER BY t.threshold DESC`, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count services by rating: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := []models.RatingCount{}
+	for rows.Next() {
+		var rating models.RatingCount
+		if err := rows.Scan(&rating.MinRating, &rating.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan rating count: %w", err)
+		}
+		ratings = append(ratings, rating)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count services by rating: %w", err)
+	}
+	return ratings, nil
+}
 
-	rows, err := r.db.Query(query, serviceTypeID)
+// queryFacetCounts runs a query returning id, name and count rows
+func (r *serviceRepository) queryFacetCounts(query string, args []interface{}) ([]models.FacetCount, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get services by service type: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	var services []models.Service
+	counts := []models.FacetCount{}
 	for rows.Next() {
-		var service models.Service
-		err := rows.Scan(
-			&service.ID, &service.ServiceTypeID,
-			&service.Name, &service.Description, &service.Price, &service.Availability,
-			&service.CreatedAt, &service.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan service: %w", err)
+		var count models.FacetCount
+		if err := rows.Scan(&count.ID, &count.Name, &count.Count); err != nil {
+			return nil, err
 		}
-		services = append(services, service)
+		counts = append(counts, count)
 	}
+	return counts, rows.Err()
+}
 
-	return services, nil
+// serviceFilterConditions builds the conditions a service must meet to match a filter; arg
+// binds a value and returns its placeholder
+func serviceFilterConditions(filter models.ServiceFilter, arg func(value interface{}) string) []string {
+	var conditions []string
+	if filter.Category != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM service_types st WHERE st.id = s.service_type_id AND st.name = `+arg(filter.Category)+`
+		)`)
+	}
+
+	// Guests and bed type must be satisfied by the same room type
+	var roomConditions []string
+	if filter.Guests > 0 {
+		roomConditions = append(roomConditions, "rt.max_guests >= "+arg(filter.Guests))
+	}
+	if filter.BedType != "" {
+		roomConditions = append(roomConditions,
+			"rt.beds @> jsonb_build_array(jsonb_build_object('type', "+arg(string(filter.BedType))+"::text))")
+	}
+	if len(roomConditions) > 0 {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM accommodation_room_types rt
+			WHERE rt.service_id = s.id AND `+strings.Join(roomConditions, " AND ")+`
+		)`)
+	}
+
+	// Every amenity asked for must be offered, however often it is repeated
+	if len(filter.Amenities) > 0 {
+		conditions = append(conditions, `NOT EXISTS (
+			SELECT 1 FROM unnest(`+arg(pq.Array(filter.Amenities))+`::text[]) AS wanted(code)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM accommodation_amenities aa
+				JOIN amenities a ON a.id = aa.amenity_id
+				WHERE aa.service_id = s.id AND a.code = wanted.code
+			)
+		)`)
+	}
+	return conditions
 }
 
-// GetServicesByCategory retrieves services by category name
-func (r *serviceRepository) GetServicesByCategory(category string) ([]models.Service, error) {
+// GetServicesByServiceType retrieves services by service type
+func (r *serviceRepository) GetServicesByServiceType(serviceTypeID int) ([]models.Service, error) {
 	query := `
-		SELECT s.id, s.service_type_id, s.name, s.description, s.price, s.availability, s.created_at, s.updated_at
+		SELECT ` + serviceColumns + `
 		FROM services s
-		JOIN service_types st ON s.service_type_id = st.id
-		WHERE st.name = $1 AND s.availability = true
+		WHERE s.service_type_id = $1 AND s.availability = true
 		ORDER BY s.name`
 
-	rows, err := r.db.Query(query, category)
+	services, err := r.queryServices(query, serviceTypeID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get services by category: %w", err)
+		return nil, fmt.Errorf("failed to get services by service type: %w", err)
 	}
-	defer rows.Close()
+	return services, nil
+}
 
-	var services []models.Service
-	for rows.Next() {
-		var service models.Service
-		err := rows.Scan(
-			&service.ID, &service.ServiceTypeID,
-			&service.Name, &service.Description, &service.Price, &service.Availability,
-			&service.CreatedAt, &service.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan service: %w", err)
-		}
-		services = append(services, service)
-	}
+// GetServicesByUserID retrieves the services owned by a user, including unavailable ones
+func (r *serviceRepository) GetServicesByUserID(userID int) ([]models.Service, error) {
+	query := `
+		SELECT ` + serviceColumns + `
+		FROM services s
+		WHERE s.user_id = $1
+		ORDER BY s.name`
 
+	services, err := r.queryServices(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get services by owner: %w", err)
+	}
 	return services, nil
 }
 
 // GetServiceByID retrieves a service by ID
 func (r *serviceRepository) GetServiceByID(id int) (*models.Service, error) {
-	service := &models.Service{}
-	query := `
-		SELECT id, service_type_id, name, description, price, availability, created_at, updated_at
-		FROM services WHERE id = $1`
+	query := `SELECT ` + serviceColumns + ` FROM services s WHERE s.id = $1`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&service.ID, &service.ServiceTypeID,
-		&service.Name, &service.Description, &service.Price, &service.Availability,
-		&service.CreatedAt, &service.UpdatedAt,
-	)
+	service, err := scanService(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("service not found")
+			return nil, ErrServiceNotFound
 		}
 		return nil, fmt.Errorf("failed to get service: %w", err)
 	}
@@ -148,13 +449,15 @@ func (r *serviceRepository) GetServiceByID(id int) (*models.Service, error) {
 // CreateService creates a new service
 func (r *serviceRepository) CreateService(service *models.Service) error {
 	query := `
-		INSERT INTO services (service_type_id, name, description, price, availability)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at`
+		INSERT INTO services (user_id, service_type_id, name, description, price, availability, capacity,
+			cancellation_policy_id, destination_id, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, reviews, created_at, updated_at`
 
-	err := r.db.QueryRow(query, service.ServiceTypeID,
-		service.Name, service.Description, service.Price, service.Availability).Scan(
-		&service.ID, &service.CreatedAt, &service.UpdatedAt,
+	err := r.db.QueryRow(query, service.UserID, service.ServiceTypeID,
+		service.Name, service.Description, service.Price, service.Availability, service.Capacity,
+		service.CancellationPolicyID, service.DestinationID, service.Latitude, service.Longitude).Scan(
+		&service.ID, &service.Reviews, &service.CreatedAt, &service.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
@@ -163,16 +466,24 @@ func (r *serviceRepository) CreateService(service *models.Service) error {
 	return nil
 }
 
-// UpdateService updates a service
+// UpdateService updates a service; its owner and rating do not change
 func (r *serviceRepository) UpdateService(service *models.Service) error {
 	query := `
 		UPDATE services 
-		SET service_type_id = $1, name = $2, description = $3, price = $4, availability = $5, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $6`
+		SET service_type_id = $1, name = $2, description = $3, price = $4, availability = $5, capacity = $6,
+			cancellation_policy_id = $7, destination_id = $8, latitude = $9, longitude = $10, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $11
+		RETURNING rating, reviews, created_at, updated_at`
 
-	_, err := r.db.Exec(query, service.ServiceTypeID,
-		service.Name, service.Description, service.Price, service.Availability, service.ID)
+	err := r.db.QueryRow(query, service.ServiceTypeID,
+		service.Name, service.Description, service.Price, service.Availability, service.Capacity,
+		service.CancellationPolicyID, service.DestinationID, service.Latitude, service.Longitude, service.ID).Scan(
+		&service.Rating, &service.Reviews, &service.CreatedAt, &service.UpdatedAt,
+	)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrServiceNotFound
+		}
 		return fmt.Errorf("failed to update service: %w", err)
 	}
 	return nil
@@ -187,3 +498,37 @@ func (r *serviceRepository) DeleteService(id int) error {
 	}
 	return nil
 }
+
+// queryServices runs a query returning service rows
+func (r *serviceRepository) queryServices(query string, args ...interface{}) ([]models.Service, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []models.Service
+	for rows.Next() {
+		service, err := scanService(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service: %w", err)
+		}
+		services = append(services, *service)
+	}
+
+	return services, nil
+}
+
+// scanService scans a service row selected with serviceColumns
+func scanService(row rowScanner) (*models.Service, error) {
+	service := &models.Service{}
+	err := row.Scan(
+		&service.ID, &service.UserID, &service.ServiceTypeID,
+		&service.Name, &service.Description, &service.Price, &service.Availability, &service.Capacity, &service.CancellationPolicyID,
+		&service.DestinationID, &service.Latitude, &service.Longitude, &service.Rating, &service.Reviews, &service.CreatedAt, &service.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
@@ -7,9 +7,20 @@ import (
 	"nomado-houses/internal/models"
 )
 
+// serviceTypeListSpec lists the fields service types can be sorted and filtered by
+var serviceTypeListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":           {column: "id", kind: listInt, sortable: true},
+		"name":         {column: "name", kind: listText, sortable: true},
+		"pricing_unit": {column: "pricing_unit", kind: listText, sortable: true},
+	},
+	defaultSort: "name",
+	idColumn:    "id",
+}
+
 // ServiceTypeRepository interface defines methods for service type operations
 type ServiceTypeRepository interface {
-	GetAllServiceTypes() ([]models.ServiceType, error)
+	GetAllServiceTypes(query models.ListQuery) ([]models.ServiceType, *models.PageInfo, error)
 	GetServiceTypeByID(id int) (*models.ServiceType, error)
 	CreateServiceType(serviceType *models.ServiceType) error
 	UpdateServiceType(serviceType *models.ServiceType) error
@@ -18,7 +29,7 @@ type ServiceTypeRepository interface {
 
 // serviceTypeRepository implements ServiceTypeRepository
 type serviceTypeRepository struct {
-	db *sql.DB
+	db     *sql.DB
 	logger *logger.Logger
 }
 
@@ -27,40 +38,34 @@ func NewServiceTypeRepository(db *sql.DB, logger *logger.Logger) ServiceTypeRepo
 	return &serviceTypeRepository{db: db, logger: logger}
 }
 
-// GetAllServiceTypes retrieves all service types
-func (r *serviceTypeRepository) GetAllServiceTypes() ([]models.ServiceType, error) {
-	query := `
-	    SELECT id, name, description
-		FROM service_types
-		ORDER BY name`
-
-	rows, err := r.db.Query(query)
+// GetAllServiceTypes retrieves a page of service types, sorted by name by default
+func (r *serviceTypeRepository) GetAllServiceTypes(query models.ListQuery) ([]models.ServiceType, *models.PageInfo, error) {
+	serviceTypes := []models.ServiceType{}
+	page, err := queryPage(r.db, serviceTypeListSpec, query, "id, name, description, pricing_unit", "service_types", nil, nil,
+		func(row rowScanner) error {
+			var serviceType models.ServiceType
+			err := row.Scan(&serviceType.ID, &serviceType.Name, &serviceType.Description, &serviceType.PricingUnit)
+			if err != nil {
+				return fmt.Errorf("failed to scan service type: %w", err)
+			}
+			serviceTypes = append(serviceTypes, serviceType)
+			return nil
+		})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get service types: %w", err)
+		return nil, nil, fmt.Errorf("failed to get service types: %w", err)
 	}
-	defer rows.Close()
-
-	var serviceTypes []models.ServiceType
-	for rows.Next() {
-		var serviceType models.ServiceType
-		err := rows.Scan(&serviceType.ID, &serviceType.Name, &serviceType.Description)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan service type: %w", err)
-		}
-		serviceTypes = append(serviceTypes, serviceType)
-	}
-	return serviceTypes, nil
+	return serviceTypes, page, nil
 }
 
 // GetServiceTypeByID retrieves a service type by ID
 func (r *serviceTypeRepository) GetServiceTypeByID(id int) (*models.ServiceType, error) {
 	serviceType := &models.ServiceType{}
 	query := `
-		SELECT id, name, description
+		SELECT id, name, description, pricing_unit
 		FROM service_types WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
-		&serviceType.ID, &serviceType.Name, &serviceType.Description,
+		&serviceType.ID, &serviceType.Name, &serviceType.Description, &serviceType.PricingUnit,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -74,11 +79,11 @@ func (r *serviceTypeRepository) GetServiceTypeByID(id int) (*models.ServiceType,
 // CreateServiceType creates a new service type
 func (r *serviceTypeRepository) CreateServiceType(serviceType *models.ServiceType) error {
 	query := `
-		INSERT INTO service_types (name, description)
-		VALUES ($1, $2)
+		INSERT INTO service_types (name, description, pricing_unit)
+		VALUES ($1, $2, $3)
 		RETURNING id`
 
-	err := r.db.QueryRow(query, serviceType.Name, serviceType.Description).Scan(&serviceType.ID)
+	err := r.db.QueryRow(query, serviceType.Name, serviceType.Description, serviceType.PricingUnit).Scan(&serviceType.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create service type: %w", err)
 	}
@@ -89,10 +94,10 @@ func (r *serviceTypeRepository) CreateServiceType(serviceType *models.ServiceTyp
 func (r *serviceTypeRepository) UpdateServiceType(serviceType *models.ServiceType) error {
 	query := `
 		UPDATE service_types 
-		SET name = $1, description = $2
-		WHERE id = $3`
+		SET name = $1, description = $2, pricing_unit = $3
+		WHERE id = $4`
 
-	_, err := r.db.Exec(query, serviceType.Name, serviceType.Description, serviceType.ID)
+	_, err := r.db.Exec(query, serviceType.Name, serviceType.Description, serviceType.PricingUnit, serviceType.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update service type: %w", err)
 	}
@@ -108,4 +113,4 @@ func (r *serviceTypeRepository) DeleteServiceType(id int) error {
 		return fmt.Errorf("failed to delete service type: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"time"
+)
+
+// Errors returned when a refresh token cannot be rotated
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token was already used")
+	ErrSessionRevoked       = errors.New("session was revoked")
+)
+
+// SessionRepository defines the interface for session and refresh token operations
+type SessionRepository interface {
+	CreateSession(session *models.AuthSession, tokenHash string) error
+	GetSessionByID(id string) (*models.AuthSession, error)
+	GetSessionsByUserID(userID int) ([]models.AuthSession, error)
+	RotateRefreshToken(tokenHash, newTokenHash string, expiresAt time.Time) (*models.AuthSession, error)
+	RevokeSessionByRefreshToken(tokenHash, reason string) (*models.AuthSession, error)
+	RevokeUserSessions(userID int, reason string) error
+	MarkTwoFactorVerified(sessionID string) error
+}
+
+// sessionRepository implements SessionRepository
+type sessionRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *sql.DB, logger *logger.Logger) SessionRepository {
+	return &sessionRepository{db: db, logger: logger}
+}
+
+// sessionColumns lists the session columns in the order scanSession reads them
+const sessionColumns = `id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason, two_factor_verified`
+
+// CreateSession creates a session with its first refresh token, which expires with the session
+func (r *sessionRepository) CreateSession(session *models.AuthSession, tokenHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO auth_sessions (id, user_id, user_agent, ip_address, expires_at, two_factor_verified)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, last_used_at`
+
+	err = tx.QueryRow(query, session.ID, session.UserID, session.UserAgent, session.IPAddress,
+		session.ExpiresAt, session.TwoFactorVerified).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	query = `INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(query, session.ID, tokenHash, session.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+// GetSessionByID retrieves a session by ID
+func (r *sessionRepository) GetSessionByID(id string) (*models.AuthSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM auth_sessions WHERE id = $1`
+
+	session, err := scanSession(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSessionsByUserID retrieves the active sessions of a user, most recently used first
+func (r *sessionRepository) GetSessionsByUserID(userID int) ([]models.AuthSession, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_used_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.AuthSession
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, nil
+}
+
+// RotateRefreshToken marks a refresh token as used and issues its successor in the same
+// session. Presenting a token that was already used means it was copied, so the whole
+// session is revoked and ErrRefreshTokenReused is returned.
+func (r *sessionRepository) RotateRefreshToken(tokenHash, newTokenHash string, expiresAt time.Time) (*models.AuthSession, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var tokenID int
+	var sessionID string
+	var tokenExpiresAt time.Time
+	var usedAt sql.NullTime
+	query := `
+		SELECT id, session_id, expires_at, used_at
+		FROM refresh_tokens WHERE token_hash = $1
+		FOR UPDATE`
+
+	err = tx.QueryRow(query, tokenHash).Scan(&tokenID, &sessionID, &tokenExpiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	session, err := scanSession(tx.QueryRow(`SELECT `+sessionColumns+` FROM auth_sessions WHERE id = $1 FOR UPDATE`, sessionID))
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	if usedAt.Valid {
+		if err := revokeSession(tx, session.ID, "refresh token reuse detected"); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit session revocation: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if !time.Now().Before(tokenExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, tokenID); err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	query = `INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(query, session.ID, newTokenHash, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	query = `
+		UPDATE auth_sessions
+		SET last_used_at = CURRENT_TIMESTAMP, expires_at = $1
+		WHERE id = $2
+		RETURNING last_used_at`
+	if err := tx.QueryRow(query, expiresAt, session.ID).Scan(&session.LastUsedAt); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	session.ExpiresAt = expiresAt
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return session, nil
+}
+
+// RevokeSessionByRefreshToken revokes the session a refresh token belongs to
+func (r *sessionRepository) RevokeSessionByRefreshToken(tokenHash, reason string) (*models.AuthSession, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM auth_sessions
+		WHERE id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)`
+
+	session, err := scanSession(r.db.QueryRow(query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	if err := revokeSession(r.db, session.ID, reason); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RevokeUserSessions revokes every active session of a user
+func (r *sessionRepository) RevokeUserSessions(userID int, reason string) error {
+	query := `
+		UPDATE auth_sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = $1
+		WHERE user_id = $2 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(query, reason, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// MarkTwoFactorVerified records that a session passed the second factor
+func (r *sessionRepository) MarkTwoFactorVerified(sessionID string) error {
+	query := `UPDATE auth_sessions SET two_factor_verified = TRUE WHERE id = $1`
+	if _, err := r.db.Exec(query, sessionID); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// revokeSession revokes a session unless it is already revoked
+func revokeSession(q queryer, sessionID, reason string) error {
+	query := `
+		UPDATE auth_sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = $1
+		WHERE id = $2 AND revoked_at IS NULL`
+
+	if _, err := q.Exec(query, reason, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// scanSession scans a row selected with sessionColumns
+func scanSession(row rowScanner) (*models.AuthSession, error) {
+	session := &models.AuthSession{}
+	var revokedAt sql.NullTime
+
+	err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt, &session.RevokedReason, &session.TwoFactorVerified)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
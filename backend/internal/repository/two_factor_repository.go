@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/logger"
+	"nomado-houses/internal/models"
+	"time"
+)
+
+// Errors returned by two-factor authentication operations
+var (
+	ErrTwoFactorNotFound       = errors.New("two-factor authentication is not set up")
+	ErrTwoFactorEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrTOTPStepUsed            = errors.New("authentication code was already used")
+	ErrRecoveryCodeNotFound    = errors.New("recovery code not found")
+	ErrTwoFactorChallengeState = errors.New("two-factor challenge is unknown, expired, used or exhausted")
+)
+
+// TwoFactorRepository defines the interface for TOTP enrolment, recovery code and login challenge operations
+type TwoFactorRepository interface {
+	GetTwoFactor(userID int) (*models.UserTwoFactor, error)
+	SaveSecret(userID int, secret string) error
+	EnableTwoFactor(userID int, step int64, recoveryCodeHashes []string) error
+	DisableTwoFactor(userID int) error
+	UseTOTPStep(userID int, step int64) error
+	ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) error
+	CountRecoveryCodes(userID int) (int, error)
+	CreateChallenge(challengeHash string, userID int, expiresAt time.Time) error
+	ClaimChallengeAttempt(challengeHash string, maxAttempts int) (int, error)
+	CompleteChallenge(challengeHash string) error
+}
+
+// twoFactorRepository implements TwoFactorRepository
+type twoFactorRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewTwoFactorRepository creates a new two-factor repository
+func NewTwoFactorRepository(db *sql.DB, logger *logger.Logger) TwoFactorRepository {
+	return &twoFactorRepository{db: db, logger: logger}
+}
+
+// GetTwoFactor retrieves the TOTP enrolment of a user
+func (r *twoFactorRepository) GetTwoFactor(userID int) (*models.UserTwoFactor, error) {
+	tf := &models.UserTwoFactor{}
+	var enabledAt sql.NullTime
+	query := `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at
+		FROM user_two_factor WHERE user_id = $1`
+
+	err := r.db.QueryRow(query, userID).Scan(&tf.UserID, &tf.Secret, &enabledAt, &tf.LastUsedStep, &tf.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTwoFactorNotFound
+		}
+		return nil, fmt.Errorf("failed to get two-factor authentication: %w", err)
+	}
+
+	if enabledAt.Valid {
+		tf.EnabledAt = &enabledAt.Time
+	}
+	return tf, nil
+}
+
+// SaveSecret stores the secret of an enrolment that is not finished yet, replacing an
+// earlier unfinished one. It returns ErrTwoFactorEnabled if the user finished enrolling.
+func (r *twoFactorRepository) SaveSecret(userID int, secret string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+		WHERE user_two_factor.enabled_at IS NULL`
+
+	result, err := r.db.Exec(query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// EnableTwoFactor finishes an enrolment with the time step of the code that proved it and
+// replaces the recovery codes of the user
+func (r *twoFactorRepository) EnableTwoFactor(userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_two_factor
+		SET enabled_at = CURRENT_TIMESTAMP, last_used_step = $1
+		WHERE user_id = $2 AND enabled_at IS NULL AND last_used_step < $1`
+
+	result, err := tx.Exec(query, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor enrolment: %w", err)
+	}
+	return nil
+}
+
+// DisableTwoFactor removes the TOTP enrolment and the recovery codes of a user
+func (r *twoFactorRepository) DisableTwoFactor(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_two_factor WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor removal: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns ErrTOTPStepUsed when
+// a code of the same or a later step was already accepted, so each code works once.
+func (r *twoFactorRepository) UseTOTPStep(userID int, step int64) error {
+	query := `UPDATE user_two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+
+	result, err := r.db.Exec(query, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record authentication code: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTOTPStepUsed
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces the recovery codes of a user
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode uses up a recovery code of a user
+func (r *twoFactorRepository) UseRecoveryCode(userID int, codeHash string) error {
+	query := `
+		UPDATE two_factor_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM two_factor_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE
+		)`
+
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *twoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	if err := r.db.QueryRow(query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// CreateChallenge stores the login challenge of a user who passed the password step
+func (r *twoFactorRepository) CreateChallenge(challengeHash string, userID int, expiresAt time.Time) error {
+	query := `INSERT INTO two_factor_challenges (id, user_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := r.db.Exec(query, challengeHash, userID, expiresAt); err != nil {
+		return fmt.Errorf("failed to create two-factor challenge: %w", err)
+	}
+	return nil
+}
+
+// ClaimChallengeAttempt counts an attempt at a challenge and returns the user it is for.
+// It returns ErrTwoFactorChallengeState when the challenge is unknown, expired, already
+// redeemed or out of attempts.
+func (r *twoFactorRepository) ClaimChallengeAttempt(challengeHash string, maxAttempts int) (int, error) {
+	var userID int
+	query := `
+		UPDATE two_factor_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND attempts < $2
+		RETURNING user_id`
+
+	err := r.db.QueryRow(query, challengeHash, maxAttempts).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrTwoFactorChallengeState
+		}
+		return 0, fmt.Errorf("failed to claim two-factor challenge: %w", err)
+	}
+	return userID, nil
+}
+
+// CompleteChallenge redeems a challenge so it cannot open a second session
+func (r *twoFactorRepository) CompleteChallenge(challengeHash string) error {
+	query := `UPDATE two_factor_challenges SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, challengeHash)
+	if err != nil {
+		return fmt.Errorf("failed to complete two-factor challenge: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTwoFactorChallengeState
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and stores new ones
+func replaceRecoveryCodes(q queryer, userID int, recoveryCodeHashes []string) error {
+	if _, err := q.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		query := `INSERT INTO two_factor_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+		if _, err := q.Exec(query, userID, hash); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return nil
+}
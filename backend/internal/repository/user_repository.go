@@ -5,8 +5,36 @@ import (
 	"fmt"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/models"
+
+	"github.com/lib/pq"
 )
 
+// userColumns selects a user with the company details of a provider
+const userColumns = `id, email, password, first_name, last_name, phone, role, email_verified,
+	COALESCE(company_name, ''), COALESCE(description, ''), COALESCE(website, ''), COALESCE(address, ''), verified,
+	created_at, updated_at`
+
+// userListSpec lists the fields users can be sorted and filtered by
+var userListSpec = &listSpec{
+	fields: map[string]listField{
+		"id":             {column: "id", kind: listInt, sortable: true},
+		"email":          {column: "email", kind: listText, sortable: true},
+		"first_name":     {column: "first_name", kind: listText, sortable: true},
+		"last_name":      {column: "last_name", kind: listText, sortable: true},
+		"company_name":   {column: "COALESCE(company_name, '')", kind: listText, sortable: true},
+		"role":           {column: "role", kind: listText, sortable: true},
+		"email_verified": {column: "email_verified", kind: listBool},
+		"verified":       {column: "verified", kind: listBool},
+		"created_at":     {column: "created_at", kind: listTime, sortable: true},
+	},
+	defaultSort: "-created_at",
+	idColumn:    "id",
+}
+
+// userWithPermissionsColumns selects a user together with the permissions of its role
+const userWithPermissionsColumns = userColumns + `,
+	ARRAY(SELECT permission FROM role_permissions WHERE role_name = users.role ORDER BY permission)`
+
 // UserRepository interface defines methods for user operations
 type UserRepository interface {
 	CreateUser(user *models.User) error
@@ -14,11 +42,10 @@ type UserRepository interface {
 	GetUserByID(id int) (*models.User, error)
 	UpdateUser(user *models.User) error
 	UpdateUserRole(userID int, role models.UserRole) error
-	GetAllUsers() ([]models.User, error)
+	UpdateProviderProfile(userID int, description, website string) error
+	GetAllUsers(query models.ListQuery) ([]models.User, *models.PageInfo, error)
 	GetUsersByRole(role models.UserRole) ([]models.User, error)
 	DeleteUser(id int) error
-	UpdateVerificationCode(email, code string) error
-	VerifyEmail(email, code string) error
 }
 
 // userRepository implements UserRepository
@@ -40,11 +67,11 @@ func (r *userRepository) CreateUser(user *models.User) error {
 	}
 
 	query := `
-		INSERT INTO users (email, password, first_name, last_name, phone, role, email_verified, verification_code)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (email, password, first_name, last_name, phone, role, email_verified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, user.Email, user.Password, user.FirstName, user.LastName, user.Phone, user.Role, false, user.VerificationCode).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRow(query, user.Email, user.Password, user.FirstName, user.LastName, user.Phone, user.Role, false).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -58,12 +85,14 @@ func (r *userRepository) CreateUser(user *models.User) error {
 func (r *userRepository) GetUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, role, email_verified, verification_code, created_at, updated_at
+		SELECT ` + userWithPermissionsColumns + `
 		FROM users WHERE email = $1`
 
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Email, &user.Password, &user.FirstName,
-		&user.LastName, &user.Phone, &user.Role, &user.EmailVerified, &user.VerificationCode, &user.CreatedAt, &user.UpdatedAt)
+		&user.LastName, &user.Phone, &user.Role, &user.EmailVerified,
+		&user.CompanyName, &user.Description, &user.Website, &user.Address, &user.Verified,
+		&user.CreatedAt, &user.UpdatedAt, pq.Array(&user.Permissions))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -78,12 +107,14 @@ func (r *userRepository) GetUserByEmail(email string) (*models.User, error) {
 func (r *userRepository) GetUserByID(id int) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, role, email_verified, verification_code, created_at, updated_at
+		SELECT ` + userWithPermissionsColumns + `
 		FROM users WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Email, &user.Password, &user.FirstName,
-		&user.LastName, &user.Phone, &user.Role, &user.EmailVerified, &user.VerificationCode, &user.CreatedAt, &user.UpdatedAt)
+		&user.LastName, &user.Phone, &user.Role, &user.EmailVerified,
+		&user.CompanyName, &user.Description, &user.Website, &user.Address, &user.Verified,
+		&user.CreatedAt, &user.UpdatedAt, pq.Array(&user.Permissions))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -120,40 +151,41 @@ func (r *userRepository) UpdateUserRole(userID int, role models.UserRole) error
 	return nil
 }
 
-// GetAllUsers retrieves all users
-func (r *userRepository) GetAllUsers() ([]models.User, error) {
+// UpdateProviderProfile updates the business details a provider may change without a new review
+func (r *userRepository) UpdateProviderProfile(userID int, description, website string) error {
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, role, email_verified, verification_code, created_at, updated_at
-		FROM users ORDER BY created_at DESC`
-
-	rows, err := r.db.Query(query)
+		UPDATE users
+		SET description = $1, website = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`
+	_, err := r.db.Exec(query, description, website, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get users: %w", err)
+		return fmt.Errorf("failed to update provider profile: %w", err)
 	}
-	defer rows.Close()
+	return nil
+}
 
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.Password, &user.FirstName,
-			&user.LastName, &user.Phone, &user.Role, &user.EmailVerified,
-			&user.VerificationCode, &user.CreatedAt, &user.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
-		}
-		// Clear password for security
-		user.Password = ""
-		users = append(users, user)
+// GetAllUsers retrieves a page of users, the most recent first by default
+func (r *userRepository) GetAllUsers(query models.ListQuery) ([]models.User, *models.PageInfo, error) {
+	users := []models.User{}
+	page, err := queryPage(r.db, userListSpec, query, userColumns, "users", nil, nil,
+		func(row rowScanner) error {
+			user, err := scanUser(row)
+			if err != nil {
+				return err
+			}
+			users = append(users, *user)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get users: %w", err)
 	}
-
-	return users, nil
+	return users, page, nil
 }
 
 // GetUsersByRole retrieves users by role
 func (r *userRepository) GetUsersByRole(role models.UserRole) ([]models.User, error) {
 	query := `
-		SELECT id, email, password, first_name, last_name, phone, role, email_verified, verification_code, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users WHERE role = $1 ORDER BY created_at DESC`
 
 	rows, err := r.db.Query(query, role)
@@ -164,22 +196,32 @@ func (r *userRepository) GetUsersByRole(role models.UserRole) ([]models.User, er
 
 	var users []models.User
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.Password, &user.FirstName,
-			&user.LastName, &user.Phone, &user.Role, &user.EmailVerified,
-			&user.VerificationCode, &user.CreatedAt, &user.UpdatedAt)
+		user, err := scanUser(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return nil, err
 		}
-		// Clear password for security
-		user.Password = ""
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	return users, nil
 }
 
+// scanUser scans a user row selected with userColumns, without its password
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Password, &user.FirstName,
+		&user.LastName, &user.Phone, &user.Role, &user.EmailVerified,
+		&user.CompanyName, &user.Description, &user.Website, &user.Address, &user.Verified,
+		&user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+	// Clear password for security
+	user.Password = ""
+	return user, nil
+}
+
 // DeleteUser deletes a user
 func (r *userRepository) DeleteUser(id int) error {
 	query := `DELETE FROM users WHERE id = $1`
@@ -189,40 +231,3 @@ func (r *userRepository) DeleteUser(id int) error {
 	}
 	return nil
 }
-
-// UpdateVerificationCode updates the verification code for a user
-func (r *userRepository) UpdateVerificationCode(email, code string) error {
-	query := `
-		UPDATE users 
-		SET verification_code = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE email = $2`
-	_, err := r.db.Exec(query, code, email)
-	if err != nil {
-		return fmt.Errorf("failed to update verification code: %w", err)
-	}
-	return nil
-}
-
-// VerifyEmail verifies a user's email with the provided code
-func (r *userRepository) VerifyEmail(email, code string) error {
-	query := `
-		UPDATE users 
-		SET email_verified = TRUE, verification_code = NULL, updated_at = CURRENT_TIMESTAMP
-		WHERE email = $1 AND verification_code = $2`
-
-	result, err := r.db.Exec(query, email, code)
-	if err != nil {
-		return fmt.Errorf("failed to verify email: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to check affected rows: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("invalid verification code or email")
-	}
-
-	return nil
-}
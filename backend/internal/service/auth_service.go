@@ -1,6 +1,12 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
@@ -15,23 +21,81 @@ import (
 // AuthService interface defines methods for authentication
 type AuthService interface {
 	Register(req *models.RegisterRequest) (*models.AuthResponse, error)
-	Login(req *models.LoginRequest) (*models.AuthResponse, error)
+	Login(req *models.LoginRequest) (*models.AuthResponse, *models.TwoFactorChallenge, error)
+	SignIn(user *models.User, client models.ClientInfo) (*models.AuthResponse, *models.TwoFactorChallenge, error)
+	VerifyTwoFactorLogin(req *models.TwoFactorLoginRequest) (*models.AuthResponse, error)
 	ValidateToken(tokenString string) (int, error)
+	ValidateSession(tokenString string) (*models.AuthSession, error)
+	TwoFactorRequired(role models.UserRole) bool
+	VerifyStaffSession(user *models.User, sessionID string) error
 	VerifyEmail(req *models.VerifyEmailRequest) error
 	ResendVerification(req *models.ResendVerificationRequest) error
+	RefreshToken(req *models.RefreshTokenRequest) (*models.AuthResponse, error)
+	Logout(refreshToken string) error
+	LogoutAll(userID int) error
+	GetSessions(userID int) ([]models.AuthSession, error)
+	ForgotPassword(req *models.ForgotPasswordRequest) error
+	ResetPassword(req *models.ResetPasswordRequest) error
+	UnlockAccount(req *models.UnlockAccountRequest) error
 }
 
+// Default token lifetimes, overridable with ACCESS_TOKEN_TTL, REFRESH_TOKEN_TTL and PASSWORD_RESET_TOKEN_TTL
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	defaultResetTokenTTL   = time.Hour
+)
+
+// Email verification limits
+const (
+	verificationCodeTTL      = 24 * time.Hour // promised by the verification email
+	maxVerificationAttempts  = 5
+	verificationResendDelay  = time.Minute
+	maxVerificationCodesHour = 5
+)
+
+// Password reset email limits
+const (
+	resetEmailDelay    = time.Minute
+	maxResetEmailsHour = 5
+)
+
+// Two-factor login challenge limits
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	maxTwoFactorAttempts  = 5
+)
+
 // authService implements AuthService
 type authService struct {
-	userRepo     repository.UserRepository
-	emailService EmailService
+	userRepo        repository.UserRepository
+	sessionRepo     repository.SessionRepository
+	resetRepo       repository.PasswordResetRepository
+	verifyRepo      repository.EmailVerificationRepository
+	twoFactorRepo   repository.TwoFactorRepository
+	emailService    EmailService
+	guard           *bruteForceGuard
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	resetTokenTTL   time.Duration
+	twoFactorRoles  map[models.UserRole]bool
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(userRepo repository.UserRepository) AuthService {
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, resetRepo repository.PasswordResetRepository, verifyRepo repository.EmailVerificationRepository, twoFactorRepo repository.TwoFactorRepository, throttleRepo repository.AuthThrottleRepository) AuthService {
+	emailService := NewEmailService()
 	return &authService{
-		userRepo:     userRepo,
-		emailService: NewEmailService(),
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		resetRepo:       resetRepo,
+		verifyRepo:      verifyRepo,
+		twoFactorRepo:   twoFactorRepo,
+		emailService:    emailService,
+		guard:           newBruteForceGuard(throttleRepo, emailService),
+		accessTokenTTL:  durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL),
+		refreshTokenTTL: durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL),
+		resetTokenTTL:   durationFromEnv("PASSWORD_RESET_TOKEN_TTL", defaultResetTokenTTL),
+		twoFactorRoles:  twoFactorRolesFromEnv(),
 	}
 }
 
@@ -59,19 +123,13 @@ func (s *authService) Register(req *models.RegisterRequest) (*models.AuthRespons
 
 	// Create user
 	user := &models.User{
-		Email:            req.Email,
-		Password:         string(hashedPassword),
-		FirstName:        req.FirstName,
-		LastName:         req.LastName,
-		Phone:            req.Phone,
-		Role:             req.Role, // Set role from request
-		EmailVerified:    false,
-		VerificationCode: s.emailService.GenerateVerificationCode(),
-	}
-
-	// Validate role
-	if !user.Role.IsValid() {
-		user.Role = models.RoleUser // Default to user role
+		Email:         req.Email,
+		Password:      string(hashedPassword),
+		FirstName:     req.FirstName,
+		LastName:      req.LastName,
+		Phone:         req.Phone,
+		Role:          models.RoleUser, // Other roles are granted after review, never at registration
+		EmailVerified: false,
 	}
 
 	if err := s.userRepo.CreateUser(user); err != nil {
@@ -79,52 +137,333 @@ func (s *authService) Register(req *models.RegisterRequest) (*models.AuthRespons
 	}
 
 	// Send verification email
-	if err := s.emailService.SendVerificationEmail(user.Email, user.FirstName, user.VerificationCode); err != nil {
+	if err := s.sendVerificationCode(user, nil); err != nil {
 		// Log the error but don't fail registration
 		fmt.Printf("Failed to send verification email: %v\n", err)
 	}
 
-	// Generate JWT token (user can login but some features may be restricted)
-	token, err := s.generateToken(user.ID)
+	// Clear password from response
+	user.Password = ""
+
+	// Start a session (user can login but some features may be restricted)
+	return s.startSession(user, req.ClientInfo, false)
+}
+
+// Login authenticates a user. Users with two-factor authentication get a challenge instead
+// of tokens, which VerifyTwoFactorLogin exchanges for a session. Repeated failures are
+// delayed and locked out per account and per client IP.
+func (s *authService) Login(req *models.LoginRequest) (*models.AuthResponse, *models.TwoFactorChallenge, error) {
+	if err := s.guard.Check(models.AuthActionLogin, req.Email, req.ClientInfo); err != nil {
+		return nil, nil, err
+	}
+
+	// Get user by email
+	user, err := s.userRepo.GetUserByEmail(req.Email)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, nil, s.fail(models.AuthActionLogin, failureUnknownAccount, req.Email, nil, req.ClientInfo, fmt.Errorf("invalid credentials"))
+	}
+
+	// Check password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return nil, nil, s.fail(models.AuthActionLogin, failureWrongPassword, req.Email, user, req.ClientInfo, fmt.Errorf("invalid credentials"))
+	}
+
+	response, challenge, err := s.SignIn(user, req.ClientInfo)
+	if err != nil || challenge != nil {
+		// The failures of users with two-factor authentication are only forgotten once they
+		// pass the second factor, so a known password does not reset the count of wrong codes
+		return response, challenge, err
+	}
+	if err := s.guard.Succeed(req.Email); err != nil {
+		return nil, nil, err
+	}
+	return response, nil, nil
+}
+
+// SignIn opens a session for a user whose first factor was checked, by password or by an
+// identity provider. Users with two-factor authentication get a challenge instead of tokens.
+func (s *authService) SignIn(user *models.User, client models.ClientInfo) (*models.AuthResponse, *models.TwoFactorChallenge, error) {
+	// Clear password from response
+	user.Password = ""
+
+	tf, err := s.twoFactorRepo.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return nil, nil, err
+	}
+	if tf == nil || !tf.IsEnabled() {
+		response, err := s.startSession(user, client, false)
+		return response, nil, err
+	}
+
+	challengeToken, err := generateSecretToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.twoFactorRepo.CreateChallenge(hashToken(challengeToken), user.ID, time.Now().Add(twoFactorChallengeTTL)); err != nil {
+		return nil, nil, err
+	}
+
+	return nil, &models.TwoFactorChallenge{
+		ChallengeToken: challengeToken,
+		ExpiresIn:      int(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// VerifyTwoFactorLogin completes a login with the challenge returned by Login and a TOTP
+// or recovery code. A challenge allows a few attempts and opens a single session; wrong codes
+// also count against the account like wrong passwords.
+func (s *authService) VerifyTwoFactorLogin(req *models.TwoFactorLoginRequest) (*models.AuthResponse, error) {
+	if req.ChallengeToken == "" {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	challengeHash := hashToken(req.ChallengeToken)
+
+	userID, err := s.twoFactorRepo.ClaimChallengeAttempt(challengeHash, maxTwoFactorAttempts)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorChallengeState) {
+			return nil, ErrInvalidTwoFactorChallenge
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+
+	tf, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return nil, ErrInvalidTwoFactorChallenge
+		}
+		return nil, err
+	}
+	if err := verifyGuardedSecondFactor(s.guard, s.twoFactorRepo, user, tf, req.Code, req.ClientInfo); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.CompleteChallenge(challengeHash); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorChallengeState) {
+			return nil, ErrInvalidTwoFactorChallenge
+		}
+		return nil, err
+	}
+	if err := s.guard.Succeed(user.Email); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(user, req.ClientInfo, true)
+}
+
+// TwoFactorRequired checks if the policy requires users of a role to pass a second factor
+func (s *authService) TwoFactorRequired(role models.UserRole) bool {
+	return s.twoFactorRoles[role]
+}
+
+// VerifyStaffSession checks that a session may use the staff permissions of its user: when
+// the role of the user requires two-factor authentication, the session must have passed it.
+// RoleMiddleware checks this on staff routes; services call it when a permission grants
+// access on routes open to every user.
+func (s *authService) VerifyStaffSession(user *models.User, sessionID string) error {
+	if !s.twoFactorRoles[user.Role] {
+		return nil
+	}
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return err
 	}
+	if session.UserID != user.ID || !session.TwoFactorVerified {
+		return ErrTwoFactorSessionRequired
+	}
+	return nil
+}
 
-	// Clear password and verification code from response
+// RefreshToken rotates a refresh token and issues a new access token for its session
+func (s *authService) RefreshToken(req *models.RefreshTokenRequest) (*models.AuthResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := generateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.RotateRefreshToken(hashToken(req.RefreshToken), hashToken(newRefreshToken), time.Now().Add(s.refreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) || errors.Is(err, repository.ErrRefreshTokenExpired) ||
+			errors.Is(err, repository.ErrRefreshTokenReused) || errors.Is(err, repository.ErrSessionRevoked) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, err
+	}
 	user.Password = ""
-	user.VerificationCode = ""
+
+	token, err := s.generateToken(user.ID, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
 
 	return &models.AuthResponse{
 		Token:         token,
+		RefreshToken:  newRefreshToken,
+		ExpiresIn:     int(s.accessTokenTTL.Seconds()),
 		User:          *user,
 		EmailVerified: user.EmailVerified,
 	}, nil
 }
 
-// Login authenticates a user
-func (s *authService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
-	// Get user by email
+// Logout revokes the session of a refresh token, which also invalidates its access tokens
+func (s *authService) Logout(refreshToken string) error {
+	if refreshToken == "" {
+		return ErrInvalidRefreshToken
+	}
+
+	if _, err := s.sessionRepo.RevokeSessionByRefreshToken(hashToken(refreshToken), "logout"); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	return nil
+}
+
+// LogoutAll revokes every session of a user
+func (s *authService) LogoutAll(userID int) error {
+	return s.sessionRepo.RevokeUserSessions(userID, "logout from all devices")
+}
+
+// GetSessions retrieves the active sessions of a user
+func (s *authService) GetSessions(userID int) ([]models.AuthSession, error) {
+	return s.sessionRepo.GetSessionsByUserID(userID)
+}
+
+// ForgotPassword emails a single-use password reset link. It succeeds whether or not the
+// email belongs to an account, so the endpoint cannot be used to discover accounts. Like
+// verification emails, reset emails are limited per account and requests are throttled by
+// the brute-force protection; a request over the limit succeeds without sending an email.
+func (s *authService) ForgotPassword(req *models.ForgotPasswordRequest) error {
+	if err := utils.ValidateEmail(req.Email); err != nil {
+		return err
+	}
+
+	const action = models.AuthActionForgotPassword
+	if err := s.guard.Check(action, req.Email, req.ClientInfo); err != nil {
+		return err
+	}
+
 	user, err := s.userRepo.GetUserByEmail(req.Email)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return s.fail(action, failureUnknownAccount, req.Email, nil, req.ClientInfo, nil)
 	}
 
-	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+	resetToken, err := generateSecretToken()
+	if err != nil {
+		return err
+	}
+	err = s.resetRepo.CreateResetToken(user.ID, hashToken(resetToken), time.Now().Add(s.resetTokenTTL), func(sentLastHour int, lastSentAt *time.Time) error {
+		if sentLastHour >= maxResetEmailsHour {
+			return ErrPasswordResetLimited
+		}
+		if lastSentAt != nil && time.Since(*lastSentAt) < resetEmailDelay {
+			return ErrPasswordResetLimited
+		}
+		return nil
+	})
+	if errors.Is(err, ErrPasswordResetLimited) {
+		return s.fail(action, failureResendLimited, req.Email, user, req.ClientInfo, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.emailService.SendPasswordResetEmail(user.Email, user.FirstName, resetToken, s.resetTokenTTL); err != nil {
+		// Log the error but answer as for any other email
+		fmt.Printf("Failed to send password reset email: %v\n", err)
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(user.ID)
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere
+func (s *authService) ResetPassword(req *models.ResetPasswordRequest) error {
+	if req.Token == "" {
+		return ErrInvalidResetToken
+	}
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Clear password from response
-	user.Password = ""
+	userID, err := s.resetRepo.ResetPassword(hashToken(req.Token), string(hashedPassword))
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) || errors.Is(err, repository.ErrResetTokenExpired) ||
+			errors.Is(err, repository.ErrResetTokenUsed) {
+			return fmt.Errorf("%w: %v", ErrInvalidResetToken, err)
+		}
+		return err
+	}
+
+	// The reset link proved the user owns the email, so a lockout of the account is lifted
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.guard.Succeed(user.Email)
+}
+
+// UnlockAccount lifts the lockout of an account with the token from the unlock email
+func (s *authService) UnlockAccount(req *models.UnlockAccountRequest) error {
+	return s.guard.Unlock(req.Token)
+}
+
+// fail records a failed attempt with the brute-force guard and returns err
+func (s *authService) fail(action, reason, email string, user *models.User, client models.ClientInfo, err error) error {
+	if guardErr := s.guard.Fail(action, reason, email, user, client); guardErr != nil {
+		return guardErr
+	}
+	return err
+}
+
+// startSession creates a session for a user and issues its first access and refresh tokens.
+// twoFactorVerified records whether the user passed a second factor to open it.
+func (s *authService) startSession(user *models.User, client models.ClientInfo, twoFactorVerified bool) (*models.AuthResponse, error) {
+	refreshToken, err := generateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.AuthSession{
+		ID:                randomHex(16),
+		UserID:            user.ID,
+		UserAgent:         client.UserAgent,
+		IPAddress:         client.ClientIP,
+		ExpiresAt:         time.Now().Add(s.refreshTokenTTL),
+		TwoFactorVerified: twoFactorVerified,
+	}
+	if err := s.sessionRepo.CreateSession(session, hashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	token, err := s.generateToken(user.ID, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
 
 	return &models.AuthResponse{
 		Token:         token,
+		RefreshToken:  refreshToken,
+		ExpiresIn:     int(s.accessTokenTTL.Seconds()),
 		User:          *user,
 		EmailVerified: user.EmailVerified,
 	}, nil
@@ -132,6 +471,15 @@ func (s *authService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 
 // ValidateToken validates a JWT token and returns user ID
 func (s *authService) ValidateToken(tokenString string) (int, error) {
+	session, err := s.ValidateSession(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return session.UserID, nil
+}
+
+// ValidateSession validates a JWT token and returns the session it belongs to
+func (s *authService) ValidateSession(tokenString string) (*models.AuthSession, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -140,24 +488,41 @@ func (s *authService) ValidateToken(tokenString string) (int, error) {
 	})
 
 	if err != nil {
-		return 0, fmt.Errorf("invalid token: %w", err)
+		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if userID, ok := claims["user_id"].(float64); ok {
-			return int(userID), nil
-		}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	sessionID, ok := claims["sid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	// Tokens die with their session, so logout takes effect before they expire
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if !session.IsActive(time.Now()) || session.UserID != int(userID) {
+		return nil, fmt.Errorf("invalid token: session is no longer active")
 	}
 
-	return 0, fmt.Errorf("invalid token claims")
+	return session, nil
 }
 
-// generateToken generates a JWT token for a user
-func (s *authService) generateToken(userID int) (string, error) {
+// generateToken generates a JWT access token for a session of a user
+func (s *authService) generateToken(userID int, sessionID string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": userID,
+		"sid":     sessionID,
 		"iat":     time.Now().Unix(), // Issued at
-		"exp":     time.Now().Add(time.Hour * 1).Unix(), // 1 hour
+		"exp":     time.Now().Add(s.accessTokenTTL).Unix(),
 	})
 
 	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
@@ -168,18 +533,70 @@ func (s *authService) generateToken(userID int) (string, error) {
 	return tokenString, nil
 }
 
+// generateSecretToken generates a random URL-safe token
+func generateSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken hashes a secret token for storage
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashVerificationCode hashes an email verification code for storage. The codes are only six
+// digits, so the hash is keyed with JWT_SECRET and bound to the user to keep leaked hashes from
+// being reversed by trying every code.
+func hashVerificationCode(userID int, code string) string {
+	key := sha256.Sum256([]byte("nomado-verification:" + os.Getenv("JWT_SECRET")))
+	mac := hmac.New(sha256.New, key[:])
+	fmt.Fprintf(mac, "%d:%s", userID, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// durationFromEnv reads a duration such as "15m" from an environment variable
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
 // VerifyEmail verifies a user's email with the provided code
 func (s *authService) VerifyEmail(req *models.VerifyEmailRequest) error {
-	// Verify the email with the code
-	if err := s.userRepo.VerifyEmail(req.Email, req.VerificationCode); err != nil {
-		return fmt.Errorf("email verification failed: %w", err)
+	const action = models.AuthActionVerifyEmail
+	if err := s.guard.Check(action, req.Email, req.ClientInfo); err != nil {
+		return err
 	}
 
-	// Get user details for welcome email
 	user, err := s.userRepo.GetUserByEmail(req.Email)
 	if err != nil {
-		// Email is verified but we couldn't get user details for welcome email
-		return nil
+		// Answer as for a wrong code so the endpoint does not reveal accounts
+		return s.fail(action, failureUnknownAccount, req.Email, nil, req.ClientInfo, ErrVerificationCodeInvalid)
+	}
+	if user.EmailVerified {
+		return s.fail(action, failureAlreadyVerified, req.Email, user, req.ClientInfo, fmt.Errorf("email is already verified"))
+	}
+
+	if err := s.verifyRepo.VerifyCode(user.ID, hashVerificationCode(user.ID, req.VerificationCode), maxVerificationAttempts); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVerificationCodeExpired):
+			return s.fail(action, failureExpiredCode, req.Email, user, req.ClientInfo, ErrVerificationCodeExpired)
+		case errors.Is(err, repository.ErrVerificationAttemptsExceeded):
+			return s.fail(action, failureAttemptsExceeded, req.Email, user, req.ClientInfo, ErrVerificationAttemptsExceeded)
+		case errors.Is(err, repository.ErrVerificationCodeMismatch), errors.Is(err, repository.ErrVerificationCodeNotFound):
+			return s.fail(action, failureInvalidCode, req.Email, user, req.ClientInfo, ErrVerificationCodeInvalid)
+		}
+		return fmt.Errorf("email verification failed: %w", err)
+	}
+
+	if err := s.guard.Succeed(req.Email); err != nil {
+		return err
 	}
 
 	// Send welcome email
@@ -191,31 +608,49 @@ func (s *authService) VerifyEmail(req *models.VerifyEmailRequest) error {
 	return nil
 }
 
-// ResendVerification resends the verification email
+// ResendVerification resends the verification email. Resends to an address are
+// throttled to one a minute and five an hour.
 func (s *authService) ResendVerification(req *models.ResendVerificationRequest) error {
+	const action = models.AuthActionResendVerification
+	if err := s.guard.Check(action, req.Email, req.ClientInfo); err != nil {
+		return err
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetUserByEmail(req.Email)
 	if err != nil {
-		return fmt.Errorf("user not found")
+		return s.fail(action, failureUnknownAccount, req.Email, nil, req.ClientInfo, fmt.Errorf("user not found"))
 	}
 
 	// Check if already verified
 	if user.EmailVerified {
-		return fmt.Errorf("email is already verified")
+		return s.fail(action, failureAlreadyVerified, req.Email, user, req.ClientInfo, fmt.Errorf("email is already verified"))
 	}
 
-	// Generate new verification code
-	newCode := s.emailService.GenerateVerificationCode()
+	err = s.sendVerificationCode(user, func(sentLastHour int, lastSentAt *time.Time) error {
+		if sentLastHour >= maxVerificationCodesHour {
+			return ErrVerificationResendLimited
+		}
+		if lastSentAt != nil && time.Since(*lastSentAt) < verificationResendDelay {
+			return ErrVerificationResendLimited
+		}
+		return nil
+	})
+	if errors.Is(err, ErrVerificationResendLimited) {
+		return s.fail(action, failureResendLimited, req.Email, user, req.ClientInfo, err)
+	}
+	return err
+}
 
-	// Update verification code in database
-	if err := s.userRepo.UpdateVerificationCode(req.Email, newCode); err != nil {
-		return fmt.Errorf("failed to update verification code: %w", err)
+// sendVerificationCode replaces the verification code of a user and emails the new one
+func (s *authService) sendVerificationCode(user *models.User, check func(sentLastHour int, lastSentAt *time.Time) error) error {
+	code := s.emailService.GenerateVerificationCode()
+	if err := s.verifyRepo.CreateCode(user.ID, hashVerificationCode(user.ID, code), time.Now().Add(verificationCodeTTL), check); err != nil {
+		return err
 	}
 
-	// Send verification email
-	if err := s.emailService.SendVerificationEmail(user.Email, user.FirstName, newCode); err != nil {
+	if err := s.emailService.SendVerificationEmail(user.Email, user.FirstName, code); err != nil {
 		return fmt.Errorf("failed to send verification email: %w", err)
 	}
-
 	return nil
 }
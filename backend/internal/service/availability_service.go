@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"sort"
+	"time"
+)
+
+// maxAvailabilityWindow bounds how far an availability lookup may reach
+const maxAvailabilityWindow = 366 * 24 * time.Hour
+
+// AvailabilityService interface defines methods for service availability
+type AvailabilityService interface {
+	GetServiceAvailability(serviceID int, from, to time.Time) (*models.ServiceAvailability, error)
+}
+
+// availabilityService implements AvailabilityService
+type availabilityService struct {
+	serviceRepo repository.ServiceRepository
+	bookingRepo repository.BookingRepository
+}
+
+// NewAvailabilityService creates a new availability service
+func NewAvailabilityService(serviceRepo repository.ServiceRepository, bookingRepo repository.BookingRepository) AvailabilityService {
+	return &availabilityService{serviceRepo: serviceRepo, bookingRepo: bookingRepo}
+}
+
+// GetServiceAvailability returns the free and blocked windows of a service between from and to
+func (s *availabilityService) GetServiceAvailability(serviceID int, from, to time.Time) (*models.ServiceAvailability, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("'to' must be after 'from'")
+	}
+	if to.Sub(from) > maxAvailabilityWindow {
+		return nil, fmt.Errorf("availability window cannot exceed 366 days")
+	}
+
+	svc, err := s.serviceRepo.GetServiceByID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &models.ServiceAvailability{
+		ServiceID: serviceID,
+		From:      from,
+		To:        to,
+		Capacity:  svc.Capacity,
+		Free:      []models.AvailabilityInterval{},
+		Blocked:   []models.AvailabilityInterval{},
+	}
+
+	// A service switched off by its provider is blocked for the whole window
+	if !svc.Availability {
+		availability.Blocked = append(availability.Blocked, models.AvailabilityInterval{Start: from, End: to})
+		return availability, nil
+	}
+
+	bookings, err := s.bookingRepo.GetActiveBookingsForService(serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, interval := range buildOccupancy(bookings, from, to, svc.Capacity) {
+		if interval.Remaining > 0 {
+			availability.Free = append(availability.Free, interval)
+		} else {
+			availability.Blocked = append(availability.Blocked, interval)
+		}
+	}
+
+	return availability, nil
+}
+
+// buildOccupancy splits [from, to) into consecutive intervals in which the number of
+// overlapping bookings does not change, merging neighbours with the same count
+func buildOccupancy(bookings []models.Booking, from, to time.Time, capacity int) []models.AvailabilityInterval {
+	boundaries := []time.Time{from, to}
+	for _, booking := range bookings {
+		if booking.BookingDateStart.After(from) && booking.BookingDateStart.Before(to) {
+			boundaries = append(boundaries, booking.BookingDateStart)
+		}
+		if booking.BookingDateEnd.After(from) && booking.BookingDateEnd.Before(to) {
+			boundaries = append(boundaries, booking.BookingDateEnd)
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+	var intervals []models.AvailabilityInterval
+	for i := 0; i < len(boundaries)-1; i++ {
+		start, end := boundaries[i], boundaries[i+1]
+		if !end.After(start) {
+			continue
+		}
+
+		booked := countOverlapping(bookings, start, end)
+		remaining := capacity - booked
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		if n := len(intervals); n > 0 && intervals[n-1].Booked == booked && intervals[n-1].End.Equal(start) {
+			intervals[n-1].End = end
+			continue
+		}
+		intervals = append(intervals, models.AvailabilityInterval{
+			Start:     start,
+			End:       end,
+			Booked:    booked,
+			Remaining: remaining,
+		})
+	}
+
+	return intervals
+}
+
+// peakOccupancy returns the highest number of bookings held at the same time within [from, to)
+func peakOccupancy(bookings []models.Booking, from, to time.Time) int {
+	peak := 0
+	for _, interval := range buildOccupancy(bookings, from, to, 0) {
+		if interval.Booked > peak {
+			peak = interval.Booked
+		}
+	}
+	return peak
+}
+
+// countOverlapping counts the bookings that overlap [start, end)
+func countOverlapping(bookings []models.Booking, start, end time.Time) int {
+	count := 0
+	for _, booking := range bookings {
+		if booking.BookingDateStart.Before(end) && booking.BookingDateEnd.After(start) {
+			count++
+		}
+	}
+	return count
+}
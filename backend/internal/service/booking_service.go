@@ -1,47 +1,507 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
+	"time"
 )
 
 // BookingService interface defines methods for booking operations
 type BookingService interface {
 	CreateBooking(booking *models.Booking) error
-	GetBookingsByUserID(userID int) ([]models.Booking, error)
-	GetBookingByID(id int) (*models.Booking, error)
-	UpdateBookingStatus(id int, status string) error
+	QuoteBooking(req *models.BookingQuoteRequest) (*models.BookingQuote, error)
+	GetBookingsByUserID(userID int, query models.ListQuery) ([]models.Booking, *models.PageInfo, error)
+	GetAllBookings(query models.ListQuery) ([]models.Booking, *models.PageInfo, error)
+	GetBookingByID(id, requesterID int, sessionID string) (*models.Booking, error)
+	UpdateBookingStatus(id int, status models.BookingStatus, changedBy int, reason string) error
+	GetBookingHistory(bookingID, requesterID int, sessionID string) ([]models.BookingStatusHistory, error)
+	CancelBooking(bookingID, userID int, reason string) (*models.CancellationResult, error)
+	AcceptBooking(bookingID int, requester *models.User) (*models.Booking, error)
+	DeclineBooking(bookingID int, requester *models.User, reason string) (*models.CancellationResult, error)
 	DeleteBooking(id int) error
 }
 
 // bookingService implements BookingService
 type bookingService struct {
-	bookingRepo repository.BookingRepository
+	bookingRepo    repository.BookingRepository
+	serviceRepo    repository.ServiceRepository
+	carRentalRepo  repository.CarRentalRepository
+	userRepo       repository.UserRepository
+	pricingService PricingService
+	policyService  CancellationPolicyService
+	paymentService PaymentService
+	teamService    ProviderTeamService
+	authService    AuthService
 }
 
 // NewBookingService creates a new booking service
-func NewBookingService(bookingRepo repository.BookingRepository) BookingService {
-	return &bookingService{bookingRepo: bookingRepo}
+func NewBookingService(
+	bookingRepo repository.BookingRepository,
+	serviceRepo repository.ServiceRepository,
+	carRentalRepo repository.CarRentalRepository,
+	userRepo repository.UserRepository,
+	pricingService PricingService,
+	policyService CancellationPolicyService,
+	paymentService PaymentService,
+	teamService ProviderTeamService,
+	authService AuthService,
+) BookingService {
+	return &bookingService{
+		bookingRepo:    bookingRepo,
+		serviceRepo:    serviceRepo,
+		carRentalRepo:  carRentalRepo,
+		userRepo:       userRepo,
+		pricingService: pricingService,
+		policyService:  policyService,
+		paymentService: paymentService,
+		teamService:    teamService,
+		authService:    authService,
+	}
 }
 
-// CreateBooking creates a new booking
+// QuoteBooking prices a booking without creating it
+func (s *bookingService) QuoteBooking(req *models.BookingQuoteRequest) (*models.BookingQuote, error) {
+	selection, err := s.selectVehicles(req.ServiceID, req.VehicleID, req.VehicleClassID)
+	if err != nil {
+		return nil, err
+	}
+	return s.pricingService.QuoteBooking(req.ServiceID, req.BookingDateStart, req.BookingDateEnd, selection.priceClass())
+}
+
+// CreateBooking creates a new booking once the service has a free slot for its dates; a
+// booking of a car rental is given the vehicle it asked for, or a free vehicle of the class
+// it asked for. The total price is always computed here, whatever the caller set on the booking.
 func (s *bookingService) CreateBooking(booking *models.Booking) error {
-	return s.bookingRepo.CreateBooking(booking)
+	if !booking.BookingDateEnd.After(booking.BookingDateStart) {
+		return ErrInvalidBookingDates
+	}
+	if booking.BookingDateStart.Before(time.Now()) {
+		return ErrBookingInPast
+	}
+
+	svc, err := s.serviceRepo.GetServiceByID(booking.ServiceID)
+	if err != nil {
+		return err
+	}
+	if !svc.Availability {
+		return ErrServiceUnavailable
+	}
+
+	selection, err := s.selectVehicles(booking.ServiceID, booking.VehicleID, booking.VehicleClassID)
+	if err != nil {
+		return err
+	}
+	if err := selection.setLocations(booking); err != nil {
+		return err
+	}
+
+	quote, err := s.pricingService.QuoteBooking(booking.ServiceID, booking.BookingDateStart, booking.BookingDateEnd, selection.priceClass())
+	if err != nil {
+		return err
+	}
+	booking.TotalPrice = quote.Total
+	booking.Status = models.BookingStatusPending
+
+	return s.bookingRepo.CreateBookingIfAvailable(booking, func(capacity int, overlapping []models.Booking) error {
+		if selection != nil {
+			return selection.assignVehicle(booking, overlapping)
+		}
+		if peakOccupancy(overlapping, booking.BookingDateStart, booking.BookingDateEnd) >= capacity {
+			return ErrServiceUnavailable
+		}
+		return nil
+	})
+}
+
+// vehicleSelection holds the vehicles of a car rental a booking can be given, all of the
+// class it is priced at
+type vehicleSelection struct {
+	rental     *models.CarRentalDetails
+	class      models.VehicleClass
+	candidates []models.Vehicle
+}
+
+// selectVehicles finds the active vehicles a booking of a car rental can be given: the vehicle
+// asked for, or those of the class asked for. It returns nil for other services, which cannot
+// be booked with a vehicle.
+func (s *bookingService) selectVehicles(serviceID int, vehicleID, classID *int) (*vehicleSelection, error) {
+	rentals, err := s.carRentalRepo.GetDetails([]int{serviceID})
+	if err != nil {
+		return nil, err
+	}
+	rental := rentals[serviceID]
+	if rental == nil {
+		if vehicleID != nil || classID != nil {
+			return nil, fmt.Errorf("%w: only car rentals are booked with a vehicle", ErrInvalidVehicleSelection)
+		}
+		return nil, nil
+	}
+	if vehicleID == nil && classID == nil {
+		return nil, fmt.Errorf("%w: choose a vehicle or a vehicle class", ErrInvalidVehicleSelection)
+	}
+
+	vehicles, err := s.carRentalRepo.GetVehicles(serviceID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	selection := &vehicleSelection{rental: rental}
+	if vehicleID != nil {
+		for _, vehicle := range vehicles {
+			if vehicle.ID == *vehicleID {
+				selection.candidates = append(selection.candidates, vehicle)
+			}
+		}
+		if len(selection.candidates) == 0 {
+			return nil, fmt.Errorf("%w: vehicle %d cannot be rented from this service", ErrInvalidVehicleSelection, *vehicleID)
+		}
+		if classID != nil && *classID != selection.candidates[0].ClassID {
+			return nil, fmt.Errorf("%w: vehicle %d is not of vehicle class %d", ErrInvalidVehicleSelection, *vehicleID, *classID)
+		}
+		classID = &selection.candidates[0].ClassID
+	}
+
+	found := false
+	for _, class := range rental.VehicleClasses {
+		if class.ID == *classID {
+			selection.class, found = class, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: unknown vehicle class %d", ErrInvalidVehicleSelection, *classID)
+	}
+
+	if vehicleID == nil {
+		for _, vehicle := range vehicles {
+			if vehicle.ClassID == selection.class.ID {
+				selection.candidates = append(selection.candidates, vehicle)
+			}
+		}
+		if len(selection.candidates) == 0 {
+			return nil, ErrServiceUnavailable
+		}
+	}
+	return selection, nil
 }
 
-// GetBookingsByUserID retrieves bookings by user ID
-func (s *bookingService) GetBookingsByUserID(userID int) ([]models.Booking, error) {
-	return s.bookingRepo.GetBookingsByUserID(userID)
+// priceClass returns the vehicle class a booking is priced at, nil outside car rentals
+func (v *vehicleSelection) priceClass() *models.VehicleClass {
+	if v == nil {
+		return nil
+	}
+	return &v.class
 }
 
-// GetBookingByID retrieves a booking by ID
-func (s *bookingService) GetBookingByID(id int) (*models.Booking, error) {
-	return s.bookingRepo.GetBookingByID(id)
+// setLocations checks where a booking of a car rental picks its vehicle up and drops it off,
+// dropping it off where it was picked up by default. Car rentals without locations take
+// none, and other services never do.
+func (v *vehicleSelection) setLocations(booking *models.Booking) error {
+	if v == nil || len(v.rental.Locations) == 0 {
+		if booking.PickupLocationID != nil || booking.DropoffLocationID != nil {
+			return fmt.Errorf("%w: this service has no pickup or dropoff locations", ErrInvalidVehicleSelection)
+		}
+		return nil
+	}
+
+	if booking.PickupLocationID == nil {
+		return fmt.Errorf("%w: choose a pickup location", ErrInvalidVehicleSelection)
+	}
+	if booking.DropoffLocationID == nil {
+		booking.DropoffLocationID = booking.PickupLocationID
+	}
+
+	pickup, dropoff := false, false
+	for _, location := range v.rental.Locations {
+		if location.ID == *booking.PickupLocationID && location.IsPickup {
+			pickup = true
+		}
+		if location.ID == *booking.DropoffLocationID && location.IsDropoff {
+			dropoff = true
+		}
+	}
+	if !pickup {
+		return fmt.Errorf("%w: location %d is not a pickup location of this service", ErrInvalidVehicleSelection, *booking.PickupLocationID)
+	}
+	if !dropoff {
+		return fmt.Errorf("%w: location %d is not a dropoff location of this service", ErrInvalidVehicleSelection, *booking.DropoffLocationID)
+	}
+	return nil
 }
 
-// UpdateBookingStatus updates booking status
-func (s *bookingService) UpdateBookingStatus(id int, status string) error {
-	return s.bookingRepo.UpdateBookingStatus(id, status)
+// assignVehicle gives a booking the first of its candidate vehicles that no overlapping
+// booking holds
+func (v *vehicleSelection) assignVehicle(booking *models.Booking, overlapping []models.Booking) error {
+	held := make(map[int]bool, len(overlapping))
+	for _, other := range overlapping {
+		if other.VehicleID != nil {
+			held[*other.VehicleID] = true
+		}
+	}
+
+	for _, vehicle := range v.candidates {
+		if !held[vehicle.ID] {
+			vehicleID, classID := vehicle.ID, v.class.ID
+			booking.VehicleID, booking.VehicleClassID = &vehicleID, &classID
+			return nil
+		}
+	}
+	return ErrServiceUnavailable
+}
+
+// GetBookingsByUserID retrieves a page of the bookings of a user
+func (s *bookingService) GetBookingsByUserID(userID int, query models.ListQuery) ([]models.Booking, *models.PageInfo, error) {
+	bookings, page, err := s.bookingRepo.GetBookingsByUserID(userID, query)
+	return bookings, page, listQueryError(err)
+}
+
+// GetAllBookings retrieves a page of the bookings of every user (staff with the bookings:read
+// permission)
+func (s *bookingService) GetAllBookings(query models.ListQuery) ([]models.Booking, *models.PageInfo, error) {
+	bookings, page, err := s.bookingRepo.GetAllBookings(query)
+	return bookings, page, listQueryError(err)
+}
+
+// GetBookingByID retrieves a booking for its owner or staff allowed to read bookings
+func (s *bookingService) GetBookingByID(id, requesterID int, sessionID string) (*models.Booking, error) {
+	booking, err := s.bookingRepo.GetBookingByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeBookingAccess(booking, requesterID, sessionID); err != nil {
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// UpdateBookingStatus moves a booking to a new status if the booking lifecycle allows it
+func (s *bookingService) UpdateBookingStatus(id int, status models.BookingStatus, changedBy int, reason string) error {
+	booking, err := s.bookingRepo.GetBookingByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := validateBookingTransition(booking.Status, status); err != nil {
+		return err
+	}
+
+	return s.bookingRepo.UpdateBookingStatus(id, booking.Status, status, changedBy, reason)
+}
+
+// CancelBooking cancels a booking on behalf of its owner and records the refund due under
+// the cancellation policy of the booked service
+func (s *bookingService) CancelBooking(bookingID, userID int, reason string) (*models.CancellationResult, error) {
+	booking, err := s.bookingRepo.GetBookingByID(bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.UserID != userID {
+		return nil, ErrBookingAccessDenied
+	}
+
+	if err := validateBookingTransition(booking.Status, models.BookingStatusCancelled); err != nil {
+		return nil, err
+	}
+
+	svc, err := s.serviceRepo.GetServiceByID(booking.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.policyService.GetPolicyForService(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	hoursBefore := time.Until(booking.BookingDateStart).Hours()
+	refundPercent := refundPercentFor(policy, hoursBefore)
+
+	if reason == "" {
+		reason = "cancelled by customer"
+	}
+
+	// The amount paid is read while the booking is locked, so a payment settling meanwhile is refunded too
+	var paid, refundAmount float64
+	refundID, err := s.bookingRepo.CancelBooking(booking, userID, reason, func(_ *models.Booking, netPaid float64) (float64, error) {
+		paid = math.Max(0, netPaid)
+		refundAmount = roundMoney(paid * refundPercent / 100)
+		return refundAmount, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CancellationResult{
+		Booking:         booking,
+		PolicyName:      policy.Name,
+		HoursBefore:     math.Max(0, math.Round(hoursBefore*100)/100),
+		RefundPercent:   refundPercent,
+		PaidAmount:      paid,
+		RefundAmount:    refundAmount,
+		RefundPaymentID: refundID,
+	}
+
+	if refundID != 0 {
+		// The cancellation stands even if the gateway refund fails: the refund stays pending
+		// and an admin can process it again
+		result.RefundStatus = models.PaymentStatusPending
+		if parts, err := s.paymentService.ProcessRefund(refundID); err == nil {
+			result.RefundStatus = refundStatus(parts)
+		}
+	}
+
+	return result, nil
+}
+
+// AcceptBooking confirms a pending booking of a service of the provider the requester acts
+// for. The guest can still pay the outstanding balance of a confirmed booking.
+func (s *bookingService) AcceptBooking(bookingID int, requester *models.User) (*models.Booking, error) {
+	booking, err := s.getProviderBooking(bookingID, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != models.BookingStatusPending {
+		return nil, fmt.Errorf("%w: only pending bookings can be accepted", ErrIllegalBookingTransition)
+	}
+
+	if err := s.bookingRepo.UpdateBookingStatus(booking.ID, booking.Status, models.BookingStatusConfirmed, requester.ID, "accepted by provider"); err != nil {
+		return nil, err
+	}
+
+	booking.Status = models.BookingStatusConfirmed
+	return booking, nil
+}
+
+// DeclineBooking cancels a pending booking of a service of the provider the requester acts
+// for and refunds everything the guest paid for it, whatever the cancellation policy of the service
+func (s *bookingService) DeclineBooking(bookingID int, requester *models.User, reason string) (*models.CancellationResult, error) {
+	booking, err := s.getProviderBooking(bookingID, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != models.BookingStatusPending {
+		return nil, fmt.Errorf("%w: only pending bookings can be declined", ErrIllegalBookingTransition)
+	}
+
+	if reason == "" {
+		reason = "declined by provider"
+	}
+
+	// As for a cancellation, the amount paid is read while the booking is locked
+	var paid float64
+	refundID, err := s.bookingRepo.CancelBooking(booking, requester.ID, reason, func(_ *models.Booking, netPaid float64) (float64, error) {
+		paid = math.Max(0, netPaid)
+		return roundMoney(paid), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CancellationResult{
+		Booking:         booking,
+		HoursBefore:     math.Max(0, math.Round(time.Until(booking.BookingDateStart).Hours()*100)/100),
+		RefundPercent:   100,
+		PaidAmount:      paid,
+		RefundAmount:    roundMoney(paid),
+		RefundPaymentID: refundID,
+	}
+
+	if refundID != 0 {
+		// As when the guest cancels, a failed gateway refund stays pending for an admin
+		result.RefundStatus = models.PaymentStatusPending
+		if parts, err := s.paymentService.ProcessRefund(refundID); err == nil {
+			result.RefundStatus = refundStatus(parts)
+		}
+	}
+
+	return result, nil
+}
+
+// getProviderBooking retrieves a booking of a service owned by the provider the requester
+// acts for, directly or as staff handling bookings
+func (s *bookingService) getProviderBooking(bookingID int, requester *models.User) (*models.Booking, error) {
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeBookings)
+	if err != nil {
+		return nil, err
+	}
+
+	booking, err := s.bookingRepo.GetBookingByID(bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	owns, err := s.ownsBookedService(booking, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !owns {
+		return nil, ErrBookingAccessDenied
+	}
+
+	return booking, nil
+}
+
+// ownsBookedService checks whether the service of a booking belongs to a provider
+func (s *bookingService) ownsBookedService(booking *models.Booking, providerID int) (bool, error) {
+	svc, err := s.serviceRepo.GetServiceByID(booking.ServiceID)
+	if err != nil {
+		return false, err
+	}
+	return svc.UserID != nil && *svc.UserID == providerID, nil
+}
+
+// GetBookingHistory retrieves the status history of a booking for its owner or staff allowed to read bookings
+func (s *bookingService) GetBookingHistory(bookingID, requesterID int, sessionID string) ([]models.BookingStatusHistory, error) {
+	booking, err := s.bookingRepo.GetBookingByID(bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeBookingAccess(booking, requesterID, sessionID); err != nil {
+		return nil, err
+	}
+
+	return s.bookingRepo.GetBookingStatusHistory(bookingID)
+}
+
+// authorizeBookingAccess checks that the requester owns the booking, has a role that grants
+// the bookings:read permission, or handles the bookings of the provider of the booked service.
+// Access through a permission needs a session that passed the second factor when required.
+func (s *bookingService) authorizeBookingAccess(booking *models.Booking, requesterID int, sessionID string) error {
+	if booking.UserID == requesterID {
+		return nil
+	}
+
+	requester, err := s.userRepo.GetUserByID(requesterID)
+	if err != nil {
+		return err
+	}
+	if requester.HasPermission(models.PermissionBookingsRead) {
+		return s.authService.VerifyStaffSession(requester, sessionID)
+	}
+
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeBookings)
+	if err != nil {
+		if errors.Is(err, ErrTeamAccessDenied) {
+			return ErrBookingAccessDenied
+		}
+		return err
+	}
+	owns, err := s.ownsBookedService(booking, provider.ID)
+	if err != nil {
+		return err
+	}
+	if !owns {
+		return ErrBookingAccessDenied
+	}
+	return s.authService.VerifyStaffSession(requester, sessionID)
 }
 
 // DeleteBooking deletes a booking
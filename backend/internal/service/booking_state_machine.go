@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"nomado-houses/internal/models"
+)
+
+// bookingTransitions lists, for each booking status, the statuses it may move to.
+// Completed, cancelled, no-show and expired bookings are final.
+var bookingTransitions = map[models.BookingStatus][]models.BookingStatus{
+	models.BookingStatusPending: {
+		models.BookingStatusConfirmed,
+		models.BookingStatusCancelled,
+		models.BookingStatusExpired,
+	},
+	models.BookingStatusConfirmed: {
+		models.BookingStatusCheckedIn,
+		models.BookingStatusCancelled,
+		models.BookingStatusNoShow,
+	},
+	models.BookingStatusCheckedIn: {
+		models.BookingStatusCompleted,
+	},
+}
+
+// CanTransitionBooking reports whether a booking may move from one status to another
+func CanTransitionBooking(from, to models.BookingStatus) bool {
+	for _, next := range bookingTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBookingTransition returns an error describing why a booking cannot move from one status to another
+func validateBookingTransition(from, to models.BookingStatus) error {
+	if !to.IsValid() {
+		return fmt.Errorf("%w: %s", ErrInvalidBookingStatus, to)
+	}
+	if !CanTransitionBooking(from, to) {
+		return fmt.Errorf("%w: cannot move booking from %s to %s", ErrIllegalBookingTransition, from, to)
+	}
+	return nil
+}
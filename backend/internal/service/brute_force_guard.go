@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"strings"
+	"time"
+)
+
+// bruteForceWindow is how long a failed attempt counts towards delays and lockouts
+const bruteForceWindow = 15 * time.Minute
+
+// Reasons recorded for failed attempts
+const (
+	failureUnknownAccount   = "unknown_account"
+	failureWrongPassword    = "wrong_password"
+	failureInvalidCode      = "invalid_code"
+	failureExpiredCode      = "expired_code"
+	failureAttemptsExceeded = "attempts_exceeded"
+	failureAlreadyVerified  = "already_verified"
+	failureResendLimited    = "resend_limited"
+	failureThrottled        = "throttled"
+)
+
+// throttlePolicy describes how failed attempts of an account or a client IP are slowed down.
+// After freeFailures, each attempt has to wait twice as long as the previous one, starting at
+// one second and up to maxDelay. After lockoutFailures, attempts are refused for lockoutDuration.
+type throttlePolicy struct {
+	freeFailures    int
+	maxDelay        time.Duration
+	lockoutFailures int
+	lockoutDuration time.Duration
+}
+
+// Throttle policies. A client IP is allowed more failures than an account, as many users may
+// share one address.
+var (
+	accountThrottlePolicy = throttlePolicy{freeFailures: 3, maxDelay: time.Minute, lockoutFailures: 10, lockoutDuration: 30 * time.Minute}
+	ipThrottlePolicy      = throttlePolicy{freeFailures: 10, maxDelay: time.Minute, lockoutFailures: 50, lockoutDuration: time.Hour}
+)
+
+// delay is how long to wait after the given number of failures
+func (p throttlePolicy) delay(failures int) time.Duration {
+	if failures <= p.freeFailures {
+		return 0
+	}
+	delay := time.Second
+	for i := p.freeFailures + 1; i < failures && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.maxDelay {
+		return p.maxDelay
+	}
+	return delay
+}
+
+// ThrottledError is returned when an attempt is refused by the brute-force protection.
+// It matches ErrTooManyAttempts with errors.Is.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed attempts, access is temporarily locked"
+	}
+	return "too many failed attempts, wait before trying again"
+}
+
+// Is makes ThrottledError match ErrTooManyAttempts
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// bruteForceGuard tracks failed attempts per account and per client IP, delays and locks out
+// further attempts, and keeps an audit record of every failure. Accounts are keyed by email,
+// whether or not a user has it, so the guard does not reveal which accounts exist.
+type bruteForceGuard struct {
+	repo         repository.AuthThrottleRepository
+	emailService EmailService
+}
+
+// newBruteForceGuard creates a new brute-force guard
+func newBruteForceGuard(repo repository.AuthThrottleRepository, emailService EmailService) *bruteForceGuard {
+	return &bruteForceGuard{repo: repo, emailService: emailService}
+}
+
+// Check refuses an attempt while the account or the client IP is delayed or locked out.
+// Refused attempts are recorded but do not count as failures.
+func (g *bruteForceGuard) Check(action, email string, client models.ClientInfo) error {
+	now := time.Now()
+	for _, target := range g.targets(email, client) {
+		throttle, err := g.repo.GetThrottle(target.key)
+		if err != nil {
+			if errors.Is(err, repository.ErrThrottleNotFound) {
+				continue
+			}
+			return err
+		}
+
+		var retryAt time.Time
+		locked := throttle.IsLocked(now)
+		if locked {
+			retryAt = *throttle.LockedUntil
+		} else if throttle.LastFailureAt.After(now.Add(-bruteForceWindow)) {
+			retryAt = throttle.LastFailureAt.Add(target.policy.delay(throttle.Failures))
+		}
+
+		if retryAt.After(now) {
+			if err := g.record(action, failureThrottled, email, nil, client); err != nil {
+				return err
+			}
+			return &ThrottledError{RetryAfter: retryAt.Sub(now), Locked: locked}
+		}
+	}
+	return nil
+}
+
+// Fail records a failed attempt and counts it against the account and the client IP. When
+// the account of a user gets locked out, the user is emailed a link to unlock it.
+func (g *bruteForceGuard) Fail(action, reason, email string, user *models.User, client models.ClientInfo) error {
+	var userID *int
+	if user != nil {
+		userID = &user.ID
+	}
+	if err := g.record(action, reason, email, userID, client); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, target := range g.targets(email, client) {
+		throttle, err := g.repo.IncrementFailures(target.key, now, now.Add(-bruteForceWindow))
+		if err != nil {
+			return err
+		}
+		if throttle.Failures < target.policy.lockoutFailures || throttle.LockedUntil != nil {
+			continue
+		}
+
+		if !target.account || user == nil {
+			if err := g.repo.Lock(target.key, now.Add(target.policy.lockoutDuration), nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		unlockToken, err := generateSecretToken()
+		if err != nil {
+			return err
+		}
+		unlockTokenHash := hashToken(unlockToken)
+		if err := g.repo.Lock(target.key, now.Add(target.policy.lockoutDuration), &unlockTokenHash); err != nil {
+			return err
+		}
+		if err := g.emailService.SendAccountLockedEmail(user.Email, user.FirstName, unlockToken, target.policy.lockoutDuration); err != nil {
+			// Log the error but keep the lockout; it runs out by itself
+			fmt.Printf("Failed to send account locked email: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Succeed forgets the failed attempts of an account after its owner proved who they are.
+// The failures of the client IP keep counting, so one valid account cannot be used to reset them.
+func (g *bruteForceGuard) Succeed(email string) error {
+	return g.repo.Clear(accountThrottleKey(email))
+}
+
+// Unlock lifts the lockout of an account with the token from the unlock email
+func (g *bruteForceGuard) Unlock(token string) error {
+	if token == "" {
+		return ErrInvalidUnlockToken
+	}
+	if _, err := g.repo.UnlockByToken(hashToken(token), time.Now()); err != nil {
+		if errors.Is(err, repository.ErrUnlockTokenInvalid) {
+			return ErrInvalidUnlockToken
+		}
+		return err
+	}
+	return nil
+}
+
+// throttleTarget is an account or a client IP that attempts count against
+type throttleTarget struct {
+	key     string
+	policy  throttlePolicy
+	account bool
+}
+
+// targets returns the account and the client IP an attempt counts against
+func (g *bruteForceGuard) targets(email string, client models.ClientInfo) []throttleTarget {
+	targets := []throttleTarget{{key: accountThrottleKey(email), policy: accountThrottlePolicy, account: true}}
+	if client.ClientIP != "" {
+		targets = append(targets, throttleTarget{key: "ip:" + truncateRunes(client.ClientIP, 45), policy: ipThrottlePolicy})
+	}
+	return targets
+}
+
+// accountThrottleKey keys the attempts on an account by its email, ignoring case and
+// surrounding spaces
+func accountThrottleKey(email string) string {
+	return "account:" + truncateRunes(strings.ToLower(strings.TrimSpace(email)), 255)
+}
+
+// record adds a failed attempt to the audit trail
+func (g *bruteForceGuard) record(action, reason, email string, userID *int, client models.ClientInfo) error {
+	return g.repo.RecordFailure(&models.AuthFailure{
+		Action:    action,
+		Email:     truncateRunes(strings.TrimSpace(email), 255),
+		UserID:    userID,
+		IPAddress: truncateRunes(client.ClientIP, 45),
+		UserAgent: client.UserAgent,
+		Reason:    reason,
+	})
+}
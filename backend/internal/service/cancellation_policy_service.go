@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"sort"
+	"strings"
+)
+
+// CancellationPolicyService interface defines methods for cancellation policy operations
+type CancellationPolicyService interface {
+	GetPlatformPolicies() ([]models.CancellationPolicy, error)
+	GetPoliciesByCreator(userID int) ([]models.CancellationPolicy, error)
+	CreatePolicy(policy *models.CancellationPolicy) error
+	ValidatePolicyID(policyID *int, requester, provider *models.User) error
+	GetPolicyForService(service *models.Service) (*models.CancellationPolicy, error)
+}
+
+// cancellationPolicyService implements CancellationPolicyService
+type cancellationPolicyService struct {
+	policyRepo repository.CancellationPolicyRepository
+}
+
+// NewCancellationPolicyService creates a new cancellation policy service
+func NewCancellationPolicyService(policyRepo repository.CancellationPolicyRepository) CancellationPolicyService {
+	return &cancellationPolicyService{policyRepo: policyRepo}
+}
+
+// GetPlatformPolicies retrieves the flexible, moderate and strict policies
+func (s *cancellationPolicyService) GetPlatformPolicies() ([]models.CancellationPolicy, error) {
+	return s.policyRepo.GetPlatformPolicies()
+}
+
+// GetPoliciesByCreator retrieves the custom policies of a provider
+func (s *cancellationPolicyService) GetPoliciesByCreator(userID int) ([]models.CancellationPolicy, error) {
+	return s.policyRepo.GetPoliciesByCreator(userID)
+}
+
+// CreatePolicy validates the refund tiers of a custom policy and creates it
+func (s *cancellationPolicyService) CreatePolicy(policy *models.CancellationPolicy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCancellationPolicy)
+	}
+
+	tiers, err := normalizeRefundTiers(policy.Tiers)
+	if err != nil {
+		return err
+	}
+	policy.Tiers = tiers
+
+	return s.policyRepo.CreatePolicy(policy)
+}
+
+// ValidatePolicyID checks that an optional policy ID refers to a policy a service of the
+// provider the requester acts for may use: a platform policy, or a custom policy of the
+// requester or of that provider. The policies of other providers are reported as unknown.
+func (s *cancellationPolicyService) ValidatePolicyID(policyID *int, requester, provider *models.User) error {
+	if policyID == nil {
+		return nil
+	}
+	policy, err := s.policyRepo.GetPolicyByID(*policyID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCancellationPolicy, err)
+	}
+	if policy.CreatedBy != nil && *policy.CreatedBy != requester.ID && *policy.CreatedBy != provider.ID {
+		return fmt.Errorf("%w: cancellation policy not found", ErrInvalidCancellationPolicy)
+	}
+	return nil
+}
+
+// GetPolicyForService retrieves the policy of a service, falling back to the platform default
+func (s *cancellationPolicyService) GetPolicyForService(service *models.Service) (*models.CancellationPolicy, error) {
+	if service.CancellationPolicyID != nil {
+		return s.policyRepo.GetPolicyByID(*service.CancellationPolicyID)
+	}
+	return s.policyRepo.GetDefaultPolicy()
+}
+
+// normalizeRefundTiers checks the refund tiers of a policy and sorts them from the
+// earliest to the latest cancellation. Refunds may only shrink as the start date gets closer.
+func normalizeRefundTiers(tiers []models.RefundTier) ([]models.RefundTier, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("%w: at least one refund tier is required", ErrInvalidCancellationPolicy)
+	}
+
+	sorted := make([]models.RefundTier, len(tiers))
+	copy(sorted, tiers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].HoursBefore > sorted[j].HoursBefore })
+
+	for i, tier := range sorted {
+		if tier.HoursBefore < 0 {
+			return nil, fmt.Errorf("%w: hours_before cannot be negative", ErrInvalidCancellationPolicy)
+		}
+		if tier.RefundPercent < 0 || tier.RefundPercent > 100 {
+			return nil, fmt.Errorf("%w: refund_percent must be between 0 and 100", ErrInvalidCancellationPolicy)
+		}
+		if i > 0 {
+			previous := sorted[i-1]
+			if tier.HoursBefore == previous.HoursBefore {
+				return nil, fmt.Errorf("%w: duplicate tier for %d hours", ErrInvalidCancellationPolicy, tier.HoursBefore)
+			}
+			if tier.RefundPercent > previous.RefundPercent {
+				return nil, fmt.Errorf("%w: a later cancellation cannot be refunded more than an earlier one", ErrInvalidCancellationPolicy)
+			}
+		}
+	}
+
+	return sorted, nil
+}
+
+// refundPercentFor returns the refund percentage a policy grants when a booking is
+// cancelled hoursBefore hours before it starts
+func refundPercentFor(policy *models.CancellationPolicy, hoursBefore float64) float64 {
+	best := 0.0
+	for _, tier := range policy.Tiers {
+		if hoursBefore >= float64(tier.HoursBefore) && tier.RefundPercent > best {
+			best = tier.RefundPercent
+		}
+	}
+	return best
+}
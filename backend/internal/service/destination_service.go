@@ -1,13 +1,26 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
 )
 
+// Limits on the locations of destinations and services and on radius searches
+const (
+	maxAreaPoints         = 100
+	defaultNearbyRadiusKm = 50
+	maxSearchRadiusKm     = 500
+	defaultNearbyLimit    = 50
+	maxNearbyLimit        = 200
+	defaultAreaRadiusKm   = 25
+)
+
 type DestinationService interface {
-	GetAllDestinations() ([]models.Destination, error)
+	GetAllDestinations(query models.ListQuery) ([]models.Destination, *models.PageInfo, error)
 	GetDestinationByID(id int) (*models.Destination, error)
+	GetNearbyDestinations(center models.GeoPoint, radiusKm float64, limit int) ([]models.NearbyDestination, error)
 	CreateDestination(destination *models.Destination) error
 	UpdateDestination(destination *models.Destination) error
 	DeleteDestination(id int) error
@@ -16,16 +29,18 @@ type DestinationService interface {
 // destinationService implements DestinationService
 type destinationService struct {
 	destinationRepo repository.DestinationRepository
+	placeRepo       repository.PlaceRepository
 }
 
 // NewDestinationService creates a new destination service
-func NewDestinationService(destinationRepo repository.DestinationRepository) DestinationService {
-	return &destinationService{destinationRepo: destinationRepo}
+func NewDestinationService(destinationRepo repository.DestinationRepository, placeRepo repository.PlaceRepository) DestinationService {
+	return &destinationService{destinationRepo: destinationRepo, placeRepo: placeRepo}
 }
 
-// GetAllDestinations retrieves all destinations
-func (s *destinationService) GetAllDestinations() ([]models.Destination, error) {
-	return s.destinationRepo.GetAllDestinations()
+// GetAllDestinations retrieves a page of destinations
+func (s *destinationService) GetAllDestinations(query models.ListQuery) ([]models.Destination, *models.PageInfo, error) {
+	destinations, page, err := s.destinationRepo.GetAllDestinations(query)
+	return destinations, page, listQueryError(err)
 }
 
 // GetDestinationByID retrieves a destination by ID
@@ -33,13 +48,46 @@ func (s *destinationService) GetDestinationByID(id int) (*models.Destination, er
 	return s.destinationRepo.GetDestinationByID(id)
 }
 
+// GetNearbyDestinations retrieves the destinations within a radius of a point, the closest
+// first. Destinations without coordinates are never near anything.
+func (s *destinationService) GetNearbyDestinations(center models.GeoPoint, radiusKm float64, limit int) ([]models.NearbyDestination, error) {
+	if err := validateGeoPoint(center); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNearbyQuery, err)
+	}
+	if radiusKm == 0 {
+		radiusKm = defaultNearbyRadiusKm
+	}
+	if !(radiusKm > 0 && radiusKm <= maxSearchRadiusKm) {
+		return nil, fmt.Errorf("%w: radius must be between 0 and %d km", ErrInvalidNearbyQuery, maxSearchRadiusKm)
+	}
+	if limit == 0 {
+		limit = defaultNearbyLimit
+	}
+	if limit < 0 || limit > maxNearbyLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidNearbyQuery, maxNearbyLimit)
+	}
+	return s.destinationRepo.GetNearbyDestinations(center, radiusKm, limit)
+}
+
 // CreateDestination creates a new destination
 func (s *destinationService) CreateDestination(destination *models.Destination) error {
+	if err := validateDestinationLocation(destination); err != nil {
+		return err
+	}
+	if err := s.resolvePlace(destination); err != nil {
+		return err
+	}
 	return s.destinationRepo.CreateDestination(destination)
 }
 
 // UpdateDestination updates a destination
 func (s *destinationService) UpdateDestination(destination *models.Destination) error {
+	if err := validateDestinationLocation(destination); err != nil {
+		return err
+	}
+	if err := s.resolvePlace(destination); err != nil {
+		return err
+	}
 	return s.destinationRepo.UpdateDestination(destination)
 }
 
@@ -47,3 +95,67 @@ func (s *destinationService) UpdateDestination(destination *models.Destination)
 func (s *destinationService) DeleteDestination(id int) error {
 	return s.destinationRepo.DeleteDestination(id)
 }
+
+// resolvePlace links a destination to the place its place code names, if any
+func (s *destinationService) resolvePlace(destination *models.Destination) error {
+	destination.PlaceID = nil
+	if destination.PlaceCode == "" {
+		return nil
+	}
+	place, err := s.placeRepo.GetPlaceByCode(normalizePlaceCode(destination.PlaceCode))
+	if errors.Is(err, repository.ErrPlaceNotFound) {
+		return fmt.Errorf("%w: unknown place %q", ErrInvalidDestination, destination.PlaceCode)
+	}
+	if err != nil {
+		return err
+	}
+	destination.PlaceID, destination.PlaceCode = &place.ID, place.Code
+	return nil
+}
+
+// validateDestinationLocation checks the coordinates and area of a destination. An area is a
+// polygon whose corners are given in order and needs the coordinates of the destination too.
+func validateDestinationLocation(destination *models.Destination) error {
+	if err := validateCoordinates(destination.Latitude, destination.Longitude); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDestination, err)
+	}
+	if len(destination.Area) == 0 {
+		destination.Area = nil
+		return nil
+	}
+	if destination.Latitude == nil {
+		return fmt.Errorf("%w: an area needs the latitude and longitude of the destination", ErrInvalidDestination)
+	}
+	if len(destination.Area) < 3 || len(destination.Area) > maxAreaPoints {
+		return fmt.Errorf("%w: an area has between 3 and %d points", ErrInvalidDestination, maxAreaPoints)
+	}
+	for _, point := range destination.Area {
+		if err := validateGeoPoint(point); err != nil {
+			return fmt.Errorf("%w: area: %v", ErrInvalidDestination, err)
+		}
+	}
+	return nil
+}
+
+// validateCoordinates checks optional coordinates, which are given both or not at all
+func validateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude and longitude go together")
+	}
+	if latitude == nil {
+		return nil
+	}
+	return validateGeoPoint(models.GeoPoint{Lat: *latitude, Lng: *longitude})
+}
+
+// validateGeoPoint checks that a point is on Earth
+func validateGeoPoint(point models.GeoPoint) error {
+	// Written so that NaN is out of range too
+	if !(point.Lat >= -90 && point.Lat <= 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if !(point.Lng >= -180 && point.Lng <= 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	devIdentityProviderName     = "dev"
+	devIdentityProviderClientID = "nomado-dev"
+	devAuthorizationCodeTTL     = time.Minute
+)
+
+// ErrDevLoginHintRequired is returned when the development identity provider is not told who signs in
+var ErrDevLoginHintRequired = errors.New("login_hint is required")
+
+// DevIdentityProvider is an in-process OpenID Connect identity provider for development and
+// offline testing, the way the fake payment gateway stands in for a real one. It signs in
+// whoever is named by the login_hint of the authorization request without a password,
+// with a verified email, and issues RS256 ID tokens with a key generated at startup.
+// Its HTTP endpoints are served by handlers.DevOIDCHandler.
+type DevIdentityProvider struct {
+	issuer string
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu    sync.Mutex
+	codes map[string]*devAuthorizationCode
+}
+
+// devAuthorizationCode is an authorization code issued by the development identity provider
+type devAuthorizationCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	name          string
+	expiresAt     time.Time
+}
+
+// NewDevIdentityProviderFromEnv creates the development identity provider when
+// OIDC_DEV_PROVIDER is "true". It is served under /dev/oidc and its issuer is
+// OIDC_DEV_ISSUER, defaulting to http://localhost:<PORT>/dev/oidc.
+func NewDevIdentityProviderFromEnv() (*DevIdentityProvider, error) {
+	if os.Getenv("OIDC_DEV_PROVIDER") != "true" {
+		return nil, nil
+	}
+
+	issuer := os.Getenv("OIDC_DEV_ISSUER")
+	if issuer == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		issuer = "http://localhost:" + port + "/dev/oidc"
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate development identity provider key: %w", err)
+	}
+
+	return &DevIdentityProvider{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		key:    key,
+		kid:    "dev-" + randomHex(4),
+		codes:  make(map[string]*devAuthorizationCode),
+	}, nil
+}
+
+// Issuer identifies the development identity provider
+func (p *DevIdentityProvider) Issuer() string {
+	return p.issuer
+}
+
+// Discovery returns the discovery document
+func (p *DevIdentityProvider) Discovery() map[string]interface{} {
+	return map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	}
+}
+
+// JWKS returns the public signing key
+func (p *DevIdentityProvider) JWKS() map[string]interface{} {
+	return map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": p.kid,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	}
+}
+
+// Authorize signs in the user named by login_hint and returns the redirect URI carrying the
+// authorization code and state. Only the S256 PKCE method is accepted.
+func (p *DevIdentityProvider) Authorize(params url.Values) (string, error) {
+	if params.Get("response_type") != "code" {
+		return "", fmt.Errorf("unsupported response_type")
+	}
+	if params.Get("client_id") != devIdentityProviderClientID {
+		return "", fmt.Errorf("unknown client_id")
+	}
+	redirectURI, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		return "", fmt.Errorf("invalid redirect_uri")
+	}
+	if params.Get("code_challenge") == "" || params.Get("code_challenge_method") != "S256" {
+		return "", fmt.Errorf("code_challenge with code_challenge_method S256 is required")
+	}
+	email := strings.TrimSpace(params.Get("login_hint"))
+	if email == "" {
+		return "", ErrDevLoginHintRequired
+	}
+
+	code := randomHex(16)
+	p.mu.Lock()
+	p.codes[code] = &devAuthorizationCode{
+		clientID:      params.Get("client_id"),
+		redirectURI:   params.Get("redirect_uri"),
+		codeChallenge: params.Get("code_challenge"),
+		nonce:         params.Get("nonce"),
+		email:         email,
+		name:          strings.TrimSpace(params.Get("name")),
+		expiresAt:     time.Now().Add(devAuthorizationCodeTTL),
+	}
+	p.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	if state := params.Get("state"); state != "" {
+		query.Set("state", state)
+	}
+	redirectURI.RawQuery = query.Encode()
+	return redirectURI.String(), nil
+}
+
+// Token redeems an authorization code and returns the token response
+func (p *DevIdentityProvider) Token(form url.Values) (map[string]interface{}, error) {
+	if form.Get("grant_type") != "authorization_code" {
+		return nil, fmt.Errorf("unsupported grant_type")
+	}
+
+	p.mu.Lock()
+	code, ok := p.codes[form.Get("code")]
+	delete(p.codes, form.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) {
+		return nil, fmt.Errorf("invalid or expired code")
+	}
+	if form.Get("client_id") != code.clientID || form.Get("redirect_uri") != code.redirectURI {
+		return nil, fmt.Errorf("client_id or redirect_uri does not match the authorization request")
+	}
+	if subtle.ConstantTimeCompare([]byte(pkceChallenge(form.Get("code_verifier"))), []byte(code.codeChallenge)) != 1 {
+		return nil, fmt.Errorf("code_verifier does not match the code_challenge")
+	}
+
+	subject := sha256.Sum256([]byte(strings.ToLower(code.email)))
+	givenName, familyName, _ := strings.Cut(code.name, " ")
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            "dev-" + hex.EncodeToString(subject[:8]),
+		"aud":            code.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(10 * time.Minute).Unix(),
+		"email":          code.email,
+		"email_verified": true,
+		"given_name":     givenName,
+		"family_name":    familyName,
+	}
+	if code.nonce != "" {
+		claims["nonce"] = code.nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign ID token: %w", err)
+	}
+
+	return map[string]interface{}{
+		"access_token": randomHex(16),
+		"token_type":   "Bearer",
+		"expires_in":   600,
+		"id_token":     idToken,
+	}, nil
+}
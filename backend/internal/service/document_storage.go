@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// defaultDocumentStorageDir is where uploaded documents are kept unless PROVIDER_DOCUMENTS_DIR says otherwise
+const defaultDocumentStorageDir = "uploads/provider-documents"
+
+// storageKeyPattern matches the keys generated for stored documents, so a key can never
+// point outside the storage directory
+var storageKeyPattern = regexp.MustCompile(`^[0-9a-f]{32,64}$`)
+
+// ErrDocumentNotStored is returned when a stored document cannot be found
+var ErrDocumentNotStored = errors.New("document is missing from storage")
+
+// DocumentStorage keeps the files of uploaded documents under opaque keys
+type DocumentStorage interface {
+	Save(key string, content io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// NewDocumentStorageFromEnv creates the storage configured by PROVIDER_DOCUMENTS_DIR, a
+// directory on the local disk
+func NewDocumentStorageFromEnv() (DocumentStorage, error) {
+	dir := os.Getenv("PROVIDER_DOCUMENTS_DIR")
+	if dir == "" {
+		dir = defaultDocumentStorageDir
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create document storage directory: %w", err)
+	}
+	return &localDocumentStorage{dir: dir}, nil
+}
+
+// localDocumentStorage stores documents as files in a directory readable by the server only
+type localDocumentStorage struct {
+	dir string
+}
+
+// Save writes a document, failing if the key is already used
+func (s *localDocumentStorage) Save(key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+	return nil
+}
+
+// Open reads a document
+func (s *localDocumentStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrDocumentNotStored
+		}
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	return file, nil
+}
+
+// Delete removes a document; removing a missing document is not an error
+func (s *localDocumentStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a document key
+func (s *localDocumentStorage) path(key string) (string, error) {
+	if !storageKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid document storage key")
+	}
+	return filepath.Join(s.dir, key), nil
+}
@@ -5,14 +5,23 @@ import (
 	"crypto/rand"
 	"fmt"
 	"html/template"
+	"math/big"
 	"net/smtp"
+	"net/url"
+	"nomado-houses/internal/models"
 	"os"
+	"strings"
+	"time"
 )
 
 // EmailService interface defines methods for email operations
 type EmailService interface {
 	SendVerificationEmail(email, firstName, verificationCode string) error
 	SendWelcomeEmail(email, firstName string) error
+	SendPasswordResetEmail(email, firstName, resetToken string, validFor time.Duration) error
+	SendAccountLockedEmail(email, firstName, unlockToken string, lockedFor time.Duration) error
+	SendProviderApplicationReviewedEmail(email, firstName string, status models.ProviderApplicationStatus, note string) error
+	SendTeamInvitationEmail(email, organisationName, inviterName string, role models.OrganisationRole, invitationToken string, validFor time.Duration) error
 	GenerateVerificationCode() string
 }
 
@@ -38,21 +47,12 @@ func NewEmailService() EmailService {
 
 // GenerateVerificationCode generates a random 6-digit verification code
 func (s *emailService) GenerateVerificationCode() string {
-	bytes := make([]byte, 3)
-	rand.Read(bytes)
-
-	// Convert to 6-digit number
-	code := ""
-	for _, b := range bytes {
-		code += fmt.Sprintf("%02d", int(b)%100)
-	}
-
-	// Ensure it's exactly 6 digits
-	if len(code) > 6 {
-		code = code[:6]
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("failed to generate verification code: %v", err))
 	}
-
-	return code
+	return fmt.Sprintf("%06d", n.Int64())
 }
 
 // SendVerificationEmail sends a verification email
@@ -233,6 +233,333 @@ func (s *emailService) SendWelcomeEmail(email, firstName string) error {
 	return s.sendEmail(email, subject, body.String(), true)
 }
 
+// SendPasswordResetEmail sends a link to choose a new password
+func (s *emailService) SendPasswordResetEmail(email, firstName, resetToken string, validFor time.Duration) error {
+	subject := "Reset Your Nomado Password"
+
+	htmlTemplate := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<title>Reset Your Password</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0; background-color: #f4f4f4; }
+			.container { max-width: 600px; margin: 0 auto; background: white; padding: 20px; border-radius: 10px; box-shadow: 0 0 10px rgba(0,0,0,0.1); }
+			.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+			.content { padding: 30px; }
+			.button { display: inline-block; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 12px 30px; text-decoration: none; border-radius: 25px; margin: 20px 0; }
+			.link { word-break: break-all; color: #667eea; font-size: 12px; }
+			.footer { text-align: center; color: #666; font-size: 12px; margin-top: 30px; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>🔐 Reset Your Password</h1>
+				<p>Nomado account security</p>
+			</div>
+			<div class="content">
+				<h2>Hello {{.FirstName}}!</h2>
+				<p>We received a request to reset the password of your Nomado account. Click the button below to choose a new one:</p>
+				
+				<div style="text-align: center;">
+					<a href="{{.ResetURL}}" class="button">Reset My Password</a>
+				</div>
+				
+				<p>If the button does not work, copy this link into your browser:</p>
+				<p class="link">{{.ResetURL}}</p>
+				
+				<p><strong>Important:</strong> This link can be used once and expires in {{.ValidFor}}. Resetting your password signs you out of all your devices.</p>
+			</div>
+			<div class="footer">
+				<p>If you didn't ask to reset your password, please ignore this email. Your password will not change.</p>
+				<p>© 2025 Nomado. All rights reserved.</p>
+			</div>
+		</div>
+	</body>
+	</html>`
+
+	tmpl, err := template.New("password-reset").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse password reset email template: %w", err)
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, struct {
+		FirstName string
+		ResetURL  string
+		ValidFor  string
+	}{
+		FirstName: firstName,
+		ResetURL:  fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("FRONTEND_URL"), url.QueryEscape(resetToken)),
+		ValidFor:  formatValidity(validFor),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to execute password reset email template: %w", err)
+	}
+
+	return s.sendEmail(email, subject, body.String(), true)
+}
+
+// SendAccountLockedEmail warns that an account was locked after repeated failed attempts and
+// sends a link that lifts the lockout
+func (s *emailService) SendAccountLockedEmail(email, firstName, unlockToken string, lockedFor time.Duration) error {
+	subject := "Your Nomado Account Was Temporarily Locked"
+
+	htmlTemplate := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<title>Account Temporarily Locked</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0; background-color: #f4f4f4; }
+			.container { max-width: 600px; margin: 0 auto; background: white; padding: 20px; border-radius: 10px; box-shadow: 0 0 10px rgba(0,0,0,0.1); }
+			.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+			.content { padding: 30px; }
+			.button { display: inline-block; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 12px 30px; text-decoration: none; border-radius: 25px; margin: 20px 0; }
+			.link { word-break: break-all; color: #667eea; font-size: 12px; }
+			.footer { text-align: center; color: #666; font-size: 12px; margin-top: 30px; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>🔒 Account Temporarily Locked</h1>
+				<p>Nomado account security</p>
+			</div>
+			<div class="content">
+				<h2>Hello {{.FirstName}}!</h2>
+				<p>There were too many failed attempts to sign in to your Nomado account, so we locked it for {{.LockedFor}}.</p>
+				<p>If it was you, click the button below to unlock your account now:</p>
+				
+				<div style="text-align: center;">
+					<a href="{{.UnlockURL}}" class="button">Unlock My Account</a>
+				</div>
+				
+				<p>If the button does not work, copy this link into your browser:</p>
+				<p class="link">{{.UnlockURL}}</p>
+				
+				<p><strong>Important:</strong> If it was not you, someone may be trying to guess your password. Leave your account locked and consider resetting your password.</p>
+			</div>
+			<div class="footer">
+				<p>The lockout ends by itself after {{.LockedFor}}.</p>
+				<p>© 2025 Nomado. All rights reserved.</p>
+			</div>
+		</div>
+	</body>
+	</html>`
+
+	tmpl, err := template.New("account-locked").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse account locked email template: %w", err)
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, struct {
+		FirstName string
+		UnlockURL string
+		LockedFor string
+	}{
+		FirstName: firstName,
+		UnlockURL: fmt.Sprintf("%s/unlock-account?token=%s", os.Getenv("FRONTEND_URL"), url.QueryEscape(unlockToken)),
+		LockedFor: formatValidity(lockedFor),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to execute account locked email template: %w", err)
+	}
+
+	return s.sendEmail(email, subject, body.String(), true)
+}
+
+// SendProviderApplicationReviewedEmail tells an applicant about the decision of a reviewer on
+// their provider application
+func (s *emailService) SendProviderApplicationReviewedEmail(email, firstName string, status models.ProviderApplicationStatus, note string) error {
+	subject := "Your Nomado Provider Application Was Reviewed"
+	switch status {
+	case models.ProviderApplicationApproved:
+		subject = "Welcome Aboard - Your Nomado Provider Application Was Approved"
+	case models.ProviderApplicationChangesRequested:
+		subject = "Your Nomado Provider Application Needs Changes"
+	case models.ProviderApplicationRevoked:
+		subject = "Your Nomado Provider Verification Was Revoked"
+	}
+
+	htmlTemplate := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<title>Provider Application Reviewed</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0; background-color: #f4f4f4; }
+			.container { max-width: 600px; margin: 0 auto; background: white; padding: 20px; border-radius: 10px; box-shadow: 0 0 10px rgba(0,0,0,0.1); }
+			.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+			.content { padding: 30px; }
+			.button { display: inline-block; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 12px 30px; text-decoration: none; border-radius: 25px; margin: 20px 0; }
+			.note { background: #f8f9ff; padding: 15px; border-radius: 8px; }
+			.footer { text-align: center; color: #666; font-size: 12px; margin-top: 30px; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				{{if eq .Status "approved"}}<h1>🎉 You Are Now a Nomado Provider</h1>{{else}}<h1>Provider Application Reviewed</h1>{{end}}
+				<p>Nomado provider program</p>
+			</div>
+			<div class="content">
+				<h2>Hello {{.FirstName}}!</h2>
+				{{if eq .Status "approved"}}
+				<p>Our team reviewed your application and approved it. You can now list your services on Nomado.</p>
+				<div style="text-align: center;">
+					<a href="{{.DashboardURL}}" class="button">Open My Dashboard</a>
+				</div>
+				{{else if eq .Status "changes_requested"}}
+				<p>Our team reviewed your application and needs a few changes before deciding. Update your details or documents and resubmit the application.</p>
+				<div style="text-align: center;">
+					<a href="{{.DashboardURL}}" class="button">Update My Application</a>
+				</div>
+				{{else if eq .Status "revoked"}}
+				<p>Our team withdrew the verification of your provider account. Your services cannot be published or changed until you are verified again.</p>
+				{{else}}
+				<p>Our team reviewed your application and could not approve it this time. You are welcome to apply again once the points below are addressed.</p>
+				{{end}}
+				{{if .Note}}<p class="note"><strong>Note from our team:</strong> {{.Note}}</p>{{end}}
+			</div>
+			<div class="footer">
+				<p>© 2025 Nomado. All rights reserved.</p>
+			</div>
+		</div>
+	</body>
+	</html>`
+
+	tmpl, err := template.New("provider-application-reviewed").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse provider application email template: %w", err)
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, struct {
+		FirstName    string
+		Status       string
+		Note         string
+		DashboardURL string
+	}{
+		FirstName:    firstName,
+		Status:       string(status),
+		Note:         note,
+		DashboardURL: os.Getenv("FRONTEND_URL") + "/dashboard",
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to execute provider application email template: %w", err)
+	}
+
+	return s.sendEmail(email, subject, body.String(), true)
+}
+
+// SendTeamInvitationEmail invites someone to join the team of a provider organisation with a
+// link to accept the invitation
+func (s *emailService) SendTeamInvitationEmail(email, organisationName, inviterName string, role models.OrganisationRole, invitationToken string, validFor time.Duration) error {
+	subject := fmt.Sprintf("You Are Invited to Join %s on Nomado", organisationName)
+
+	htmlTemplate := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="UTF-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<title>Team Invitation</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0; background-color: #f4f4f4; }
+			.container { max-width: 600px; margin: 0 auto; background: white; padding: 20px; border-radius: 10px; box-shadow: 0 0 10px rgba(0,0,0,0.1); }
+			.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
+			.content { padding: 30px; }
+			.button { display: inline-block; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 12px 30px; text-decoration: none; border-radius: 25px; margin: 20px 0; }
+			.link { word-break: break-all; color: #667eea; font-size: 12px; }
+			.footer { text-align: center; color: #666; font-size: 12px; margin-top: 30px; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>🤝 Join {{.OrganisationName}}</h1>
+				<p>Nomado provider teams</p>
+			</div>
+			<div class="content">
+				<h2>Hello!</h2>
+				<p>{{.InviterName}} invited you to join the team of {{.OrganisationName}} on Nomado as <strong>{{.Role}}</strong>.</p>
+				<p>Sign in or create a Nomado account with this email address, then click the button below to accept the invitation:</p>
+				
+				<div style="text-align: center;">
+					<a href="{{.AcceptURL}}" class="button">Accept Invitation</a>
+				</div>
+				
+				<p>If the button does not work, copy this link into your browser:</p>
+				<p class="link">{{.AcceptURL}}</p>
+			</div>
+			<div class="footer">
+				<p>This invitation expires in {{.ValidFor}}. If you were not expecting it, you can ignore this email.</p>
+				<p>© 2025 Nomado. All rights reserved.</p>
+			</div>
+		</div>
+	</body>
+	</html>`
+
+	tmpl, err := template.New("team-invitation").Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse team invitation email template: %w", err)
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, struct {
+		OrganisationName string
+		InviterName      string
+		Role             string
+		AcceptURL        string
+		ValidFor         string
+	}{
+		OrganisationName: organisationName,
+		InviterName:      inviterName,
+		Role:             strings.ReplaceAll(string(role), "_", " "),
+		AcceptURL:        fmt.Sprintf("%s/team/accept?token=%s", os.Getenv("FRONTEND_URL"), url.QueryEscape(invitationToken)),
+		ValidFor:         formatValidity(validFor),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to execute team invitation email template: %w", err)
+	}
+
+	return s.sendEmail(email, subject, body.String(), true)
+}
+
+// formatValidity describes how long a link stays valid, e.g. "1 hour" or "30 minutes"
+func formatValidity(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		if days := int(d / day); days != 1 {
+			return fmt.Sprintf("%d days", days)
+		}
+		return "1 day"
+	}
+	if d >= time.Hour && d%time.Hour == 0 {
+		if hours := int(d / time.Hour); hours != 1 {
+			return fmt.Sprintf("%d hours", hours)
+		}
+		return "1 hour"
+	}
+	if minutes := int(d / time.Minute); minutes != 1 {
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return "1 minute"
+}
+
 // sendEmail sends an email using SMTP
 func (s *emailService) sendEmail(to, subject, body string, isHTML bool) error {
 	// Validate configuration
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"nomado-houses/internal/repository"
+)
+
+// Errors returned by the service layer that handlers map to specific HTTP status codes
+var (
+	ErrInvalidRefreshToken  = errors.New("invalid or expired refresh token")
+	ErrInvalidResetToken    = errors.New("invalid or expired password reset token")
+	ErrPasswordResetLimited = errors.New("a password reset email was sent recently, try again later")
+
+	ErrTooManyAttempts    = errors.New("too many failed attempts")
+	ErrInvalidUnlockToken = errors.New("invalid or expired unlock link")
+
+	ErrVerificationCodeInvalid      = errors.New("invalid verification code")
+	ErrVerificationCodeExpired      = errors.New("verification code has expired, request a new one")
+	ErrVerificationAttemptsExceeded = errors.New("too many failed attempts, request a new verification code")
+	ErrVerificationResendLimited    = errors.New("a verification email was sent recently, try again later")
+
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired two-factor challenge, login again")
+	ErrInvalidTwoFactorCode      = errors.New("invalid two-factor authentication code")
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp         = errors.New("two-factor authentication setup was not started")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequiredByPolicy = errors.New("two-factor authentication is required for your role")
+	ErrTwoFactorSessionRequired  = errors.New("two-factor authentication required: enable it or login again with your authentication code")
+
+	ErrInvalidRole    = errors.New("invalid role")
+	ErrRoleNotFound   = errors.New("role not found")
+	ErrRoleExists     = errors.New("a role with this name already exists")
+	ErrBuiltInRole    = errors.New("built-in roles cannot be changed or deleted")
+	ErrRoleInUse      = errors.New("role is still assigned to users")
+	ErrRoleEscalation = errors.New("you cannot grant permissions you do not have")
+	ErrOwnRole        = errors.New("you cannot change your own role")
+
+	ErrInvalidProviderApplication           = errors.New("invalid provider application")
+	ErrProviderApplicationNotFound          = errors.New("provider application not found")
+	ErrProviderApplicationPending           = errors.New("you already have a provider application in review")
+	ErrIllegalProviderApplicationTransition = errors.New("illegal provider application status transition")
+	ErrProviderApplicationAccessDenied      = errors.New("you do not have access to this provider application")
+	ErrAlreadyVerifiedProvider              = errors.New("you are already a verified provider")
+	ErrProviderNotVerified                  = errors.New("provider is not verified, apply to become a verified provider first")
+
+	ErrInvalidProviderDocument  = errors.New("invalid provider document")
+	ErrProviderDocumentNotFound = errors.New("provider document not found")
+	ErrProviderDocumentAttached = errors.New("provider document is part of an application and cannot be deleted")
+
+	ErrInvalidTeamInvitation  = errors.New("invalid team invitation")
+	ErrTeamInvitationNotFound = errors.New("team invitation not found or expired")
+	ErrTeamEmailNotVerified   = errors.New("verify your email address before joining a team")
+	ErrTeamMemberNotFound     = errors.New("team member not found")
+	ErrAlreadyTeamMember      = errors.New("user already belongs to a provider team")
+	ErrTeamAccessDenied       = errors.New("you are not a provider or your team role does not allow this")
+
+	ErrOIDCProviderNotFound = errors.New("identity provider is not configured")
+	ErrInvalidOIDCState     = errors.New("invalid or expired login state, start the login again")
+	ErrOIDCLoginFailed      = errors.New("identity provider login failed")
+	ErrOIDCEmailNotVerified = errors.New("the identity provider has not verified your email address")
+	ErrOIDCEmailMissing     = errors.New("the identity provider did not share a usable email address")
+
+	ErrInvalidBookingDates = errors.New("booking end date must be after its start date")
+	ErrBookingInPast       = errors.New("booking cannot start in the past")
+	ErrServiceUnavailable  = errors.New("service is not available for the selected dates")
+
+	ErrInvalidBookingStatus     = errors.New("invalid booking status")
+	ErrIllegalBookingTransition = errors.New("illegal booking status transition")
+	ErrBookingAccessDenied      = errors.New("you do not have access to this booking")
+
+	ErrInvalidListQuery      = errors.New("invalid list query")
+	ErrInvalidDashboardQuery = errors.New("invalid dashboard query")
+
+	ErrServiceNotFound           = errors.New("service not found")
+	ErrServiceAccessDenied       = errors.New("you can only change your own services")
+	ErrInvalidServiceQuery       = errors.New("invalid service search")
+	ErrInvalidAccommodation      = errors.New("invalid accommodation details")
+	ErrInvalidCarRental          = errors.New("invalid car rental details")
+	ErrInvalidServiceDestination = errors.New("invalid service destination")
+	ErrInvalidServiceLocation    = errors.New("invalid service location")
+
+	ErrInvalidDestination    = errors.New("invalid destination")
+	ErrDestinationNotFound   = errors.New("destination not found")
+	ErrDestinationNotLocated = errors.New("destination has no coordinates or area")
+	ErrInvalidNearbyQuery    = errors.New("invalid nearby search")
+
+	ErrInvalidPlace  = errors.New("invalid place")
+	ErrPlaceNotFound = errors.New("place not found")
+	ErrPlaceExists   = errors.New("a place with this code already exists")
+	ErrPlaceInUse    = errors.New("place still has places or destinations below it")
+
+	ErrNotCarRental            = errors.New("service is not a car rental")
+	ErrInvalidVehicle          = errors.New("invalid vehicle")
+	ErrVehicleNotFound         = errors.New("vehicle not found")
+	ErrVehiclePlateTaken       = errors.New("a vehicle with this plate number is already listed")
+	ErrVehicleBooked           = errors.New("vehicle has upcoming bookings, deactivate it instead")
+	ErrInvalidVehicleSelection = errors.New("invalid vehicle selection")
+
+	ErrInvalidCancellationPolicy = errors.New("invalid cancellation policy")
+
+	ErrInvalidPayment        = errors.New("invalid payment")
+	ErrBookingNotPayable     = errors.New("booking cannot be paid")
+	ErrPaymentExceedsBalance = errors.New("payment exceeds the outstanding balance")
+	ErrPaymentAccessDenied   = errors.New("you do not have access to this payment")
+	ErrPaymentDeclined       = errors.New("payment was declined")
+	ErrPaymentNotPending     = errors.New("payment is not awaiting confirmation")
+)
+
+// listQueryError maps the list query errors of repositories to ErrInvalidListQuery
+func listQueryError(err error) error {
+	var queryErr *repository.ListQueryError
+	if errors.As(err, &queryErr) {
+		return fmt.Errorf("%w: %s", ErrInvalidListQuery, queryErr.Reason)
+	}
+	return err
+}
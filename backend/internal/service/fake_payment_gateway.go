@@ -0,0 +1,265 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const fakeGatewayName = "fake"
+
+// Test tokens understood by the fake gateway. Any other token succeeds.
+const (
+	FakeTokenSuccess     = "tok_success"
+	FakeTokenDecline     = "tok_decline"
+	FakeToken3DS         = "tok_3ds"
+	FakeToken3DSDeclined = "tok_3ds_decline"
+	FakeTokenTimeout     = "tok_timeout"
+)
+
+// fakePaymentGateway is an in-process PaymentGateway for development and offline testing.
+// The payment token picks the outcome:
+//   - tok_decline is declined
+//   - tok_3ds requires customer authentication, which the next status check treats as
+//     completed; tok_3ds_decline fails that authentication
+//   - tok_timeout authorises the payment but times out the first request, as if the
+//     response was lost; retrying with the same idempotency key returns the intent
+type fakePaymentGateway struct {
+	mu       sync.Mutex
+	intents  map[string]*fakeIntent
+	byKey    map[string]string
+	refunded map[string]float64
+}
+
+// fakeIntent is a payment intent held by the fake gateway
+type fakeIntent struct {
+	intent GatewayIntent
+	token  string
+}
+
+// NewFakePaymentGateway creates a new fake payment gateway
+func NewFakePaymentGateway() PaymentGateway {
+	return &fakePaymentGateway{
+		intents:  make(map[string]*fakeIntent),
+		byKey:    make(map[string]string),
+		refunded: make(map[string]float64),
+	}
+}
+
+// Name identifies the fake gateway
+func (g *fakePaymentGateway) Name() string {
+	return fakeGatewayName
+}
+
+// CreateIntent authorises a payment according to its test token
+func (g *fakePaymentGateway) CreateIntent(req GatewayIntentRequest) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if req.IdempotencyKey != "" {
+		if reference, ok := g.byKey[req.IdempotencyKey]; ok {
+			intent := g.intents[reference].intent
+			return &intent, nil
+		}
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("fake gateway: amount must be greater than zero")
+	}
+
+	stored := &fakeIntent{
+		intent: GatewayIntent{
+			Reference: "fake_pi_" + randomHex(12),
+			Amount:    req.Amount,
+			Currency:  req.Currency,
+		},
+		token: req.PaymentToken,
+	}
+
+	switch req.PaymentToken {
+	case FakeTokenDecline:
+		stored.intent.Status = GatewayFailed
+		stored.intent.FailureReason = "card declined"
+	case FakeToken3DS, FakeToken3DSDeclined:
+		stored.intent.Status = GatewayRequiresAction
+		stored.intent.NextActionURL = "https://fake-gateway.local/3ds/" + stored.intent.Reference
+	default:
+		stored.intent.Status = GatewayRequiresCapture
+	}
+
+	g.intents[stored.intent.Reference] = stored
+	if req.IdempotencyKey != "" {
+		g.byKey[req.IdempotencyKey] = stored.intent.Reference
+	}
+
+	if req.PaymentToken == FakeTokenTimeout {
+		return nil, ErrGatewayTimeout
+	}
+
+	intent := stored.intent
+	return &intent, nil
+}
+
+// Capture collects an authorised payment
+func (g *fakePaymentGateway) Capture(reference string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.intents[reference]
+	if !ok {
+		return nil, ErrGatewayNotFound
+	}
+	g.resolveAction(stored)
+
+	switch stored.intent.Status {
+	case GatewayRequiresCapture:
+		stored.intent.Status = GatewaySucceeded
+	case GatewaySucceeded, GatewayFailed:
+	default:
+		return nil, fmt.Errorf("fake gateway: cannot capture a payment that is %s", stored.intent.Status)
+	}
+
+	intent := stored.intent
+	return &intent, nil
+}
+
+// Cancel voids a payment that has not been captured
+func (g *fakePaymentGateway) Cancel(reference string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.intents[reference]
+	if !ok {
+		return nil, ErrGatewayNotFound
+	}
+
+	switch stored.intent.Status {
+	case GatewayRequiresAction, GatewayRequiresCapture, GatewayProcessing:
+		stored.intent.Status = GatewayFailed
+		stored.intent.FailureReason = "payment cancelled"
+	case GatewayFailed:
+	default:
+		return nil, fmt.Errorf("fake gateway: cannot cancel a payment that is %s", stored.intent.Status)
+	}
+
+	intent := stored.intent
+	return &intent, nil
+}
+
+// Refund gives back part or all of a captured payment
+func (g *fakePaymentGateway) Refund(reference string, amount float64) (*GatewayRefund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.intents[reference]
+	if !ok {
+		return nil, ErrGatewayNotFound
+	}
+
+	refund := &GatewayRefund{Reference: "fake_re_" + randomHex(12), Amount: amount}
+	switch {
+	case stored.intent.Status != GatewaySucceeded:
+		refund.Status = GatewayFailed
+		refund.FailureReason = "payment has not been captured"
+	case g.refunded[reference]+amount > stored.intent.Amount+paymentTolerance:
+		refund.Status = GatewayFailed
+		refund.FailureReason = "refund exceeds the captured amount"
+	default:
+		g.refunded[reference] += amount
+		refund.Status = GatewaySucceeded
+	}
+	return refund, nil
+}
+
+// GetStatus fetches the current state of a payment
+func (g *fakePaymentGateway) GetStatus(reference string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.intents[reference]
+	if !ok {
+		return nil, ErrGatewayNotFound
+	}
+	g.resolveAction(stored)
+
+	intent := stored.intent
+	return &intent, nil
+}
+
+// FindIntent fetches the current state of the payment created with an idempotency key
+func (g *fakePaymentGateway) FindIntent(idempotencyKey string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reference, ok := g.byKey[idempotencyKey]
+	if !ok {
+		return nil, ErrGatewayNotFound
+	}
+	stored := g.intents[reference]
+	g.resolveAction(stored)
+
+	intent := stored.intent
+	return &intent, nil
+}
+
+// fakeEvent is the webhook payload format of the fake gateway
+type fakeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		PaymentReference  string  `json:"payment_reference"`
+		MerchantReference string  `json:"merchant_reference"`
+		RefundReference   string  `json:"refund_reference"`
+		Amount            float64 `json:"amount"`
+		FailureReason     string  `json:"failure_reason"`
+	} `json:"data"`
+}
+
+// ParseEvent reads a fake gateway webhook payload
+func (g *fakePaymentGateway) ParseEvent(payload []byte) (*GatewayEvent, error) {
+	var event fakeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("fake gateway: invalid event: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, fmt.Errorf("fake gateway: event id and type are required")
+	}
+
+	parsed := &GatewayEvent{
+		ID:                event.ID,
+		Type:              GatewayEventType(event.Type),
+		Reference:         event.Data.PaymentReference,
+		MerchantReference: event.Data.MerchantReference,
+		RefundReference:   event.Data.RefundReference,
+		Amount:            event.Data.Amount,
+		FailureReason:     event.Data.FailureReason,
+	}
+	if event.Created > 0 {
+		parsed.OccurredAt = time.Unix(event.Created, 0)
+	}
+	return parsed, nil
+}
+
+// resolveAction completes the customer authentication of an intent that requires it
+func (g *fakePaymentGateway) resolveAction(stored *fakeIntent) {
+	if stored.intent.Status != GatewayRequiresAction {
+		return
+	}
+	stored.intent.NextActionURL = ""
+	if stored.token == FakeToken3DSDeclined {
+		stored.intent.Status = GatewayFailed
+		stored.intent.FailureReason = "authentication failed"
+		return
+	}
+	stored.intent.Status = GatewayRequiresCapture
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
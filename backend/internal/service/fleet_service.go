@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on the vehicles of a car rental
+const (
+	maxVehicleNameLength = 50
+	maxPlateNumberLength = 20
+	maxVehicleSeats      = 60
+	minVehicleYear       = 1950
+)
+
+// FleetService interface defines methods for the vehicles of car rentals: guests see the
+// vehicles they can book and providers manage their fleet
+type FleetService interface {
+	GetAvailableVehicles(serviceID int, from, to time.Time) ([]models.Vehicle, error)
+	GetFleet(serviceID int, requester *models.User) ([]models.Vehicle, error)
+	AddVehicle(serviceID int, req *models.VehicleRequest, requester *models.User) (*models.Vehicle, error)
+	UpdateVehicle(serviceID, vehicleID int, req *models.VehicleRequest, requester *models.User) (*models.Vehicle, error)
+	RemoveVehicle(serviceID, vehicleID int, requester *models.User) error
+}
+
+// fleetService implements FleetService
+type fleetService struct {
+	serviceRepo   repository.ServiceRepository
+	carRentalRepo repository.CarRentalRepository
+	bookingRepo   repository.BookingRepository
+	teamService   ProviderTeamService
+}
+
+// NewFleetService creates a new fleet service
+func NewFleetService(
+	serviceRepo repository.ServiceRepository,
+	carRentalRepo repository.CarRentalRepository,
+	bookingRepo repository.BookingRepository,
+	teamService ProviderTeamService,
+) FleetService {
+	return &fleetService{
+		serviceRepo:   serviceRepo,
+		carRentalRepo: carRentalRepo,
+		bookingRepo:   bookingRepo,
+		teamService:   teamService,
+	}
+}
+
+// GetAvailableVehicles retrieves the active vehicles of a car rental without their plate
+// numbers. With a window, only the vehicles no booking holds during it are returned.
+func (s *fleetService) GetAvailableVehicles(serviceID int, from, to time.Time) ([]models.Vehicle, error) {
+	windowed := !from.IsZero() || !to.IsZero()
+	if windowed && !to.After(from) {
+		return nil, ErrInvalidBookingDates
+	}
+
+	if _, err := s.getCarRental(serviceID); err != nil {
+		return nil, err
+	}
+
+	vehicles, err := s.carRentalRepo.GetVehicles(serviceID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[int]bool)
+	if windowed {
+		bookings, err := s.bookingRepo.GetActiveBookingsForService(serviceID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, booking := range bookings {
+			if booking.VehicleID != nil {
+				held[*booking.VehicleID] = true
+			}
+		}
+	}
+
+	available := []models.Vehicle{}
+	for _, vehicle := range vehicles {
+		if held[vehicle.ID] {
+			continue
+		}
+		vehicle.PlateNumber = ""
+		available = append(available, vehicle)
+	}
+	return available, nil
+}
+
+// GetFleet retrieves every vehicle of a car rental of the provider the requester acts for
+func (s *fleetService) GetFleet(serviceID int, requester *models.User) ([]models.Vehicle, error) {
+	if _, err := s.authorizeFleet(serviceID, requester); err != nil {
+		return nil, err
+	}
+	return s.carRentalRepo.GetVehicles(serviceID, false)
+}
+
+// AddVehicle adds a vehicle to the fleet of a car rental of the provider the requester acts for
+func (s *fleetService) AddVehicle(serviceID int, req *models.VehicleRequest, requester *models.User) (*models.Vehicle, error) {
+	rental, err := s.authorizeFleet(serviceID, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	vehicle, err := vehicleFromRequest(req, rental)
+	if err != nil {
+		return nil, err
+	}
+	vehicle.ServiceID = serviceID
+
+	if err := s.carRentalRepo.CreateVehicle(vehicle); err != nil {
+		if errors.Is(err, repository.ErrVehiclePlateTaken) {
+			return nil, ErrVehiclePlateTaken
+		}
+		return nil, err
+	}
+	return vehicle, nil
+}
+
+// UpdateVehicle changes a vehicle of the fleet of a car rental. Deactivated vehicles keep the
+// bookings they already have but cannot be booked again.
+func (s *fleetService) UpdateVehicle(serviceID, vehicleID int, req *models.VehicleRequest, requester *models.User) (*models.Vehicle, error) {
+	rental, err := s.authorizeFleet(serviceID, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	vehicle, err := vehicleFromRequest(req, rental)
+	if err != nil {
+		return nil, err
+	}
+	vehicle.ID, vehicle.ServiceID = vehicleID, serviceID
+
+	if err := s.carRentalRepo.UpdateVehicle(vehicle); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVehicleNotFound):
+			return nil, ErrVehicleNotFound
+		case errors.Is(err, repository.ErrVehiclePlateTaken):
+			return nil, ErrVehiclePlateTaken
+		}
+		return nil, err
+	}
+	return vehicle, nil
+}
+
+// RemoveVehicle removes a vehicle from the fleet of a car rental, unless a booking that has
+// not ended yet holds it
+func (s *fleetService) RemoveVehicle(serviceID, vehicleID int, requester *models.User) error {
+	if _, err := s.authorizeFleet(serviceID, requester); err != nil {
+		return err
+	}
+
+	err := s.carRentalRepo.DeleteVehicle(serviceID, vehicleID)
+	switch {
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		return ErrVehicleNotFound
+	case errors.Is(err, repository.ErrVehicleBooked):
+		return ErrVehicleBooked
+	}
+	return err
+}
+
+// authorizeFleet checks that the requester manages the catalogue of the provider who owns a
+// car rental, and returns its details
+func (s *fleetService) authorizeFleet(serviceID int, requester *models.User) (*models.CarRentalDetails, error) {
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeServices)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizePublishing(provider); err != nil {
+		return nil, err
+	}
+
+	svc, err := s.serviceRepo.GetServiceByID(serviceID)
+	if errors.Is(err, repository.ErrServiceNotFound) {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeServiceOwnership(svc, provider); err != nil {
+		return nil, err
+	}
+
+	return s.getCarRental(serviceID)
+}
+
+// getCarRental retrieves the car rental details of a service, which must have them
+func (s *fleetService) getCarRental(serviceID int) (*models.CarRentalDetails, error) {
+	rentals, err := s.carRentalRepo.GetDetails([]int{serviceID})
+	if err != nil {
+		return nil, err
+	}
+	rental := rentals[serviceID]
+	if rental == nil {
+		if _, err := s.serviceRepo.GetServiceByID(serviceID); errors.Is(err, repository.ErrServiceNotFound) {
+			return nil, ErrServiceNotFound
+		}
+		return nil, ErrNotCarRental
+	}
+	return rental, nil
+}
+
+// vehicleFromRequest validates a vehicle of a car rental, whose class must be one of the
+// classes of the car rental. Plate numbers are stored in upper case with single spaces.
+func vehicleFromRequest(req *models.VehicleRequest, rental *models.CarRentalDetails) (*models.Vehicle, error) {
+	vehicle := &models.Vehicle{
+		ClassID:      req.ClassID,
+		Make:         strings.TrimSpace(req.Make),
+		Model:        strings.TrimSpace(req.Model),
+		Year:         req.Year,
+		PlateNumber:  strings.ToUpper(strings.Join(strings.Fields(req.PlateNumber), " ")),
+		Seats:        req.Seats,
+		Transmission: req.Transmission,
+		FuelPolicy:   req.FuelPolicy,
+		Active:       true,
+	}
+	if req.Active != nil {
+		vehicle.Active = *req.Active
+	}
+
+	for _, class := range rental.VehicleClasses {
+		if class.ID == vehicle.ClassID {
+			vehicle.ClassName = class.Name
+		}
+	}
+	if vehicle.ClassName == "" {
+		return nil, fmt.Errorf("%w: unknown vehicle class %d", ErrInvalidVehicle, vehicle.ClassID)
+	}
+
+	if vehicle.Make == "" || utf8.RuneCountInString(vehicle.Make) > maxVehicleNameLength {
+		return nil, fmt.Errorf("%w: make is required and at most %d characters", ErrInvalidVehicle, maxVehicleNameLength)
+	}
+	if vehicle.Model == "" || utf8.RuneCountInString(vehicle.Model) > maxVehicleNameLength {
+		return nil, fmt.Errorf("%w: model is required and at most %d characters", ErrInvalidVehicle, maxVehicleNameLength)
+	}
+	if vehicle.PlateNumber == "" || utf8.RuneCountInString(vehicle.PlateNumber) > maxPlateNumberLength {
+		return nil, fmt.Errorf("%w: plate number is required and at most %d characters", ErrInvalidVehicle, maxPlateNumberLength)
+	}
+	if vehicle.Seats <= 0 || vehicle.Seats > maxVehicleSeats {
+		return nil, fmt.Errorf("%w: seats must be between 1 and %d", ErrInvalidVehicle, maxVehicleSeats)
+	}
+	// Next year's models are sold from the middle of this year
+	if latest := time.Now().Year() + 1; vehicle.Year != nil && (*vehicle.Year < minVehicleYear || *vehicle.Year > latest) {
+		return nil, fmt.Errorf("%w: year must be between %d and %d", ErrInvalidVehicle, minVehicleYear, latest)
+	}
+	if !vehicle.Transmission.IsValid() {
+		return nil, fmt.Errorf("%w: transmission must be manual or automatic", ErrInvalidVehicle)
+	}
+	if !vehicle.FuelPolicy.IsValid() {
+		return nil, fmt.Errorf("%w: fuel policy must be full_to_full, same_to_same or pre_purchase", ErrInvalidVehicle)
+	}
+	return vehicle, nil
+}
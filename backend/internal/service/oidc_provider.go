@@ -0,0 +1,363 @@
+package service
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefreshInterval limits how often the signing keys of a provider are fetched again
+// when an ID token is signed with a key that is not known yet
+const jwksRefreshInterval = time.Minute
+
+// OIDCClaims are the claims of a verified ID token
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// OIDCProvider is an OpenID Connect identity provider that users can sign in with
+type OIDCProvider interface {
+	// Name identifies the provider in URLs and in user_identities.provider
+	Name() string
+	// AuthorizationURL builds the URL that sends the browser to sign in at the provider
+	AuthorizationURL(state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems an authorization code with its PKCE verifier and returns the claims of
+	// the ID token, after checking its signature, issuer, audience, expiry and nonce
+	Exchange(code, codeVerifier, nonce string) (*OIDCClaims, error)
+}
+
+// OIDCProviderConfig configures an OpenID Connect provider
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// ResponseMode is sent as response_mode when set (Apple requires form_post for the email scope)
+	ResponseMode string
+}
+
+// oidcDiscovery is the part of the discovery document of a provider that is used
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider implements OIDCProvider with discovery, the authorization code flow with
+// PKCE, and RS256 ID tokens, which is what Google, Apple and most providers offer
+type oidcProvider struct {
+	config OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider creates an OpenID Connect provider. The discovery document is fetched
+// when the provider is first used.
+func NewOIDCProvider(config OIDCProviderConfig) OIDCProvider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &oidcProvider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name identifies the provider
+func (p *oidcProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthorizationURL builds the authorization request of the authorization code flow with PKCE (S256)
+func (p *oidcProvider) AuthorizationURL(state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.config.ClientID)
+	values.Set("redirect_uri", p.config.RedirectURL)
+	values.Set("scope", strings.Join(p.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+	if p.config.ResponseMode != "" {
+		values.Set("response_mode", p.config.ResponseMode)
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and verifies the ID token
+func (p *oidcProvider) Exchange(code, codeVerifier, nonce string) (*OIDCClaims, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	resp, err := p.client.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("%s: token request failed: %w", p.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read token response: %w", p.config.Name, err)
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("%s: invalid token response: %w", p.config.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("%s: token request refused: %s %s", p.config.Name, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%s: token response has no id_token", p.config.Name)
+	}
+
+	return p.verifyIDToken(token.IDToken, discovery.Issuer, nonce)
+}
+
+// verifyIDToken checks the signature and the claims of an ID token
+func (p *oidcProvider) verifyIDToken(idToken, issuer, nonce string) (*OIDCClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid ID token: %w", p.config.Name, err)
+	}
+
+	// Google also issues tokens with the issuer without its scheme
+	if tokenIssuer, _ := claims["iss"].(string); tokenIssuer != issuer && tokenIssuer != strings.TrimPrefix(issuer, "https://") {
+		return nil, fmt.Errorf("%s: invalid ID token: issuer %q does not match", p.config.Name, tokenIssuer)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%s: invalid ID token: nonce does not match", p.config.Name)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%s: invalid ID token: subject missing", p.config.Name)
+	}
+
+	result := &OIDCClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.GivenName, _ = claims["given_name"].(string)
+	result.FamilyName, _ = claims["family_name"].(string)
+	// Apple sends email_verified as the string "true"
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+	return result, nil
+}
+
+// getDiscovery fetches the discovery document of the provider once
+func (p *oidcProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("%s: discovery issuer %q does not match %q", p.config.Name, discovery.Issuer, p.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%s: incomplete discovery document", p.config.Name)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// getKey returns a signing key of the provider, fetching the keys again when the key is
+// unknown, as providers rotate them
+func (p *oidcProvider) getKey(kid string) (*rsa.PublicKey, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// getJSON fetches a JSON document of the provider
+func (p *oidcProvider) getJSON(url string, v interface{}) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("%s: request failed: %w", p.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s returned %d", p.config.Name, url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%s: invalid response from %s: %w", p.config.Name, url, err)
+	}
+	return nil
+}
+
+// Issuers of the providers that only need a client ID and secret to be configured
+var wellKnownOIDCIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+	"apple":  "https://appleid.apple.com",
+}
+
+// NewOIDCProvidersFromEnv creates the providers listed in OIDC_PROVIDERS (for example
+// "google,apple"), each configured by OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL, OIDC_<NAME>_SCOPES and
+// OIDC_<NAME>_RESPONSE_MODE. The development identity provider is added as "dev" when it
+// is enabled.
+func NewOIDCProvidersFromEnv(dev *DevIdentityProvider) (map[string]OIDCProvider, error) {
+	providers := make(map[string]OIDCProvider)
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			ResponseMode: os.Getenv(prefix + "RESPONSE_MODE"),
+		}
+		if config.Issuer == "" {
+			config.Issuer = wellKnownOIDCIssuers[name]
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			config.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+		if config.RedirectURL == "" {
+			config.RedirectURL = os.Getenv("FRONTEND_URL") + "/auth/callback/" + name
+		}
+
+		if config.Issuer == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("identity provider %s needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+		providers[name] = NewOIDCProvider(config)
+	}
+
+	if dev != nil {
+		redirectURL := os.Getenv("OIDC_DEV_REDIRECT_URL")
+		if redirectURL == "" {
+			redirectURL = os.Getenv("FRONTEND_URL") + "/auth/callback/" + devIdentityProviderName
+		}
+		providers[devIdentityProviderName] = NewOIDCProvider(OIDCProviderConfig{
+			Name:        devIdentityProviderName,
+			Issuer:      dev.Issuer(),
+			ClientID:    devIdentityProviderClientID,
+			RedirectURL: redirectURL,
+		})
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"nomado-houses/internal/utils"
+	"sort"
+	"time"
+)
+
+// oidcLoginStateTTL is how long a user has to sign in at the provider
+const oidcLoginStateTTL = 10 * time.Minute
+
+// OIDCService interface defines methods for signing in with OpenID Connect providers
+type OIDCService interface {
+	GetProviders() []string
+	StartLogin(provider string) (*models.OIDCAuthorization, error)
+	CompleteLogin(provider string, req *models.OIDCCallbackRequest) (*models.AuthResponse, *models.TwoFactorChallenge, error)
+}
+
+// oidcService implements OIDCService
+type oidcService struct {
+	oidcRepo    repository.OIDCRepository
+	userRepo    repository.UserRepository
+	authService AuthService
+	providers   map[string]OIDCProvider
+}
+
+// NewOIDCService creates a new OpenID Connect service
+func NewOIDCService(oidcRepo repository.OIDCRepository, userRepo repository.UserRepository, authService AuthService, providers map[string]OIDCProvider) OIDCService {
+	return &oidcService{
+		oidcRepo:    oidcRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		providers:   providers,
+	}
+}
+
+// GetProviders lists the names of the configured providers
+func (s *oidcService) GetProviders() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartLogin starts the authorization code flow with PKCE. Only a hash of the state is
+// stored; the state itself travels through the browser and comes back with the code.
+func (s *oidcService) StartLogin(providerName string) (*models.OIDCAuthorization, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	state, err := generateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := generateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := provider.AuthorizationURL(state, nonce, pkceChallenge(codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	loginState := &models.OIDCLoginState{
+		ID:           hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginStateTTL),
+	}
+	if err := s.oidcRepo.CreateLoginState(loginState); err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCAuthorization{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+		ExpiresIn:        int(oidcLoginStateTTL.Seconds()),
+	}, nil
+}
+
+// CompleteLogin redeems the code the provider redirected back with and signs the user in.
+// A known identity signs in its user. Otherwise the identity is linked to the user with the
+// same email, or a user is created, provided the provider verified the email. Users with
+// two-factor authentication get a challenge, as with a password login.
+func (s *oidcService) CompleteLogin(providerName string, req *models.OIDCCallbackRequest) (*models.AuthResponse, *models.TwoFactorChallenge, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, nil, ErrOIDCProviderNotFound
+	}
+	if req.State == "" || req.Code == "" {
+		return nil, nil, ErrInvalidOIDCState
+	}
+
+	state, err := s.oidcRepo.ConsumeLoginState(hashToken(req.State), providerName)
+	if err != nil {
+		if errors.Is(err, repository.ErrOIDCStateInvalid) {
+			return nil, nil, ErrInvalidOIDCState
+		}
+		return nil, nil, err
+	}
+
+	claims, err := provider.Exchange(req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	user, err := s.findOrCreateUser(providerName, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.authService.SignIn(user, req.ClientInfo)
+}
+
+// findOrCreateUser resolves the user an identity signs in as
+func (s *oidcService) findOrCreateUser(providerName string, claims *OIDCClaims) (*models.User, error) {
+	identity, err := s.oidcRepo.GetIdentity(providerName, claims.Subject)
+	if err == nil {
+		if err := s.oidcRepo.TouchIdentity(identity.ID, claims.Email); err != nil {
+			return nil, err
+		}
+		return s.userRepo.GetUserByID(identity.UserID)
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if utils.ValidateEmail(claims.Email) != nil || len(claims.Email) > 100 {
+		return nil, ErrOIDCEmailMissing
+	}
+	// Without a verified email, the identity could claim an address that is not its own
+	if !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	identity = &models.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	if user, err := s.userRepo.GetUserByEmail(claims.Email); err == nil {
+		identity.UserID = user.ID
+		if err := s.oidcRepo.LinkIdentity(identity, true); err != nil {
+			return nil, err
+		}
+		// Reload, as linking verifies the email of the user
+		return s.userRepo.GetUserByID(user.ID)
+	}
+
+	user := &models.User{
+		Email:         claims.Email,
+		FirstName:     truncateRunes(claims.GivenName, 50),
+		LastName:      truncateRunes(claims.FamilyName, 50),
+		Role:          models.RoleUser,
+		EmailVerified: true,
+	}
+	if err := s.oidcRepo.CreateUserWithIdentity(user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// pkceChallenge derives the S256 code challenge of a PKCE code verifier
+func pkceChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// truncateRunes shortens a string to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
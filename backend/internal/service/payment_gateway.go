@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Errors returned by payment gateways
+var (
+	ErrGatewayTimeout  = errors.New("payment gateway timed out")
+	ErrGatewayNotFound = errors.New("payment intent not found at the gateway")
+)
+
+// GatewayIntentStatus is the state of a payment intent at a payment gateway
+type GatewayIntentStatus string
+
+const (
+	// GatewayRequiresAction means the customer still has to authenticate the payment (3-D Secure, STK push)
+	GatewayRequiresAction GatewayIntentStatus = "requires_action"
+	// GatewayRequiresCapture means the payment is authorised and waits to be captured
+	GatewayRequiresCapture GatewayIntentStatus = "requires_capture"
+	// GatewayProcessing means the gateway has not decided yet
+	GatewayProcessing GatewayIntentStatus = "processing"
+	GatewaySucceeded  GatewayIntentStatus = "succeeded"
+	GatewayFailed     GatewayIntentStatus = "failed"
+)
+
+// GatewayIntentRequest describes a payment to authorise at a gateway
+type GatewayIntentRequest struct {
+	// IdempotencyKey makes retries of the same request return the same intent
+	IdempotencyKey string
+	Amount         float64
+	Currency       string
+	PaymentMethod  string
+	PaymentToken   string
+	Description    string
+}
+
+// GatewayIntent is a payment as seen by a gateway
+type GatewayIntent struct {
+	Reference     string
+	Status        GatewayIntentStatus
+	Amount        float64
+	Currency      string
+	NextActionURL string
+	FailureReason string
+}
+
+// GatewayRefund is a refund as seen by a gateway
+type GatewayRefund struct {
+	Reference     string
+	Status        GatewayIntentStatus
+	Amount        float64
+	FailureReason string
+}
+
+// GatewayEventType is the kind of change a payment gateway reports through its webhook
+type GatewayEventType string
+
+const (
+	GatewayEventPaymentSucceeded GatewayEventType = "payment.succeeded"
+	GatewayEventPaymentFailed    GatewayEventType = "payment.failed"
+	GatewayEventRefundSucceeded  GatewayEventType = "refund.succeeded"
+	GatewayEventRefundFailed     GatewayEventType = "refund.failed"
+)
+
+// GatewayEvent is a webhook event translated from the format of its gateway
+type GatewayEvent struct {
+	ID   string
+	Type GatewayEventType
+	// Reference is the gateway reference of the payment the event is about
+	Reference string
+	// MerchantReference is the idempotency key the payment was created with
+	MerchantReference string
+	// RefundReference is set on refund events
+	RefundReference string
+	Amount          float64
+	FailureReason   string
+	OccurredAt      time.Time
+}
+
+// PaymentGateway is implemented by every payment provider (Stripe, Paystack, Flutterwave,
+// M-Pesa, ...) so the payment service does not depend on any of them
+type PaymentGateway interface {
+	// Name identifies the gateway in payments.provider
+	Name() string
+	// CreateIntent authorises a payment
+	CreateIntent(req GatewayIntentRequest) (*GatewayIntent, error)
+	// Capture collects an authorised payment
+	Capture(reference string) (*GatewayIntent, error)
+	// Cancel voids a payment that has not been captured
+	Cancel(reference string) (*GatewayIntent, error)
+	// Refund gives back part or all of a captured payment
+	Refund(reference string, amount float64) (*GatewayRefund, error)
+	// GetStatus fetches the current state of a payment
+	GetStatus(reference string) (*GatewayIntent, error)
+	// FindIntent fetches the current state of the payment created with an idempotency key,
+	// or returns ErrGatewayNotFound when the gateway never received it
+	FindIntent(idempotencyKey string) (*GatewayIntent, error)
+	// ParseEvent reads the body of a webhook delivery, whose signature has already been verified
+	ParseEvent(payload []byte) (*GatewayEvent, error)
+}
+
+// NewPaymentGatewayFromEnv creates the payment gateway named by PAYMENT_GATEWAY. There is no
+// default: the fake gateway approves payments without charging anyone, so it has to be chosen
+// explicitly with PAYMENT_GATEWAY=fake.
+func NewPaymentGatewayFromEnv() (PaymentGateway, error) {
+	name := os.Getenv("PAYMENT_GATEWAY")
+	switch name {
+	case fakeGatewayName:
+		return NewFakePaymentGateway(), nil
+	case "":
+		return nil, fmt.Errorf("PAYMENT_GATEWAY is not set")
+	default:
+		return nil, fmt.Errorf("unsupported payment gateway: %s", name)
+	}
+}
@@ -0,0 +1,487 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paymentTolerance absorbs rounding differences when comparing amounts in cents
+const paymentTolerance = 0.005
+
+// paymentIdempotencyPrefix starts the idempotency key a payment is sent to the gateway with
+const paymentIdempotencyPrefix = "nomado-payment-"
+
+// PaymentService interface defines methods for payment operations
+type PaymentService interface {
+	PayBooking(bookingID, userID int, req *models.CreatePaymentRequest) (*models.PaymentReceipt, error)
+	ConfirmPayment(paymentID, userID int, sessionID string) (*models.PaymentReceipt, error)
+	ProcessRefund(refundID int) ([]models.Payment, error)
+	GetPaymentsByUserID(userID int) ([]models.Payment, error)
+	GetPaymentByID(id, requesterID int, sessionID string) (*models.Payment, error)
+	GetAllPayments(query models.ListQuery) ([]models.Payment, *models.PageInfo, error)
+	GetPaymentsByBookingID(bookingID int) ([]models.Payment, error)
+}
+
+// paymentService implements PaymentService
+type paymentService struct {
+	paymentRepo repository.PaymentRepository
+	userRepo    repository.UserRepository
+	gateway     PaymentGateway
+	authService AuthService
+}
+
+// NewPaymentService creates a new payment service
+func NewPaymentService(paymentRepo repository.PaymentRepository, userRepo repository.UserRepository, gateway PaymentGateway, authService AuthService) PaymentService {
+	return &paymentService{paymentRepo: paymentRepo, userRepo: userRepo, gateway: gateway, authService: authService}
+}
+
+// PayBooking pays all or part of the outstanding balance of a booking of the user through
+// the payment gateway. The payment is recorded as pending first so that its amount is
+// reserved against the balance, then settled with the outcome of the gateway. A pending
+// booking is confirmed once it is fully paid. Pending payments of the booking whose hold
+// has lapsed are voided at the gateway.
+func (s *paymentService) PayBooking(bookingID, userID int, req *models.CreatePaymentRequest) (*models.PaymentReceipt, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be greater than zero", ErrInvalidPayment)
+	}
+	if !isPaymentMethod(req.PaymentMethod) {
+		return nil, fmt.Errorf("%w: unsupported payment method %q", ErrInvalidPayment, req.PaymentMethod)
+	}
+
+	payment := &models.Payment{
+		UserID:        userID,
+		BookingID:     bookingID,
+		Amount:        roundMoney(req.Amount),
+		PaymentDate:   time.Now(),
+		PaymentMethod: req.PaymentMethod,
+		Status:        models.PaymentStatusPending,
+		PaymentType:   models.PaymentTypePayment,
+		Provider:      s.gateway.Name(),
+	}
+
+	var paidBefore float64
+	booking, err := s.paymentRepo.CreateBookingPayment(payment, func(booking *models.Booking, netPaid, pendingPaid float64) (bool, error) {
+		if booking.UserID != userID {
+			return false, ErrBookingAccessDenied
+		}
+		if !bookingPayable(booking.Status) {
+			return false, fmt.Errorf("%w: booking is %s", ErrBookingNotPayable, booking.Status)
+		}
+
+		outstanding := roundMoney(booking.TotalPrice - netPaid - pendingPaid)
+		if payment.Amount > outstanding+paymentTolerance {
+			return false, fmt.Errorf("%w: outstanding balance is %.2f", ErrPaymentExceedsBalance, outstanding)
+		}
+
+		paidBefore = netPaid
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.voidStalePayments(bookingID)
+
+	intent, err := s.gateway.CreateIntent(GatewayIntentRequest{
+		IdempotencyKey: paymentIdempotencyKey(payment.ID),
+		Amount:         payment.Amount,
+		Currency:       defaultCurrency,
+		PaymentMethod:  payment.PaymentMethod,
+		PaymentToken:   req.PaymentToken,
+		Description:    fmt.Sprintf("Nomado booking #%d", bookingID),
+	})
+	if err != nil {
+		if errors.Is(err, ErrGatewayTimeout) {
+			// The outcome is unknown: the payment stays pending until it is confirmed
+			return &models.PaymentReceipt{
+				Payment:            payment,
+				BookingStatus:      booking.Status,
+				AmountPaid:         roundMoney(paidBefore),
+				OutstandingBalance: roundMoney(booking.TotalPrice - paidBefore),
+			}, nil
+		}
+		return nil, err
+	}
+
+	return s.settleIntent(payment.ID, intent)
+}
+
+// ConfirmPayment checks a pending payment with the gateway again, after the customer has
+// completed a 3-D Secure style challenge or when the first request timed out
+func (s *paymentService) ConfirmPayment(paymentID, userID int, sessionID string) (*models.PaymentReceipt, error) {
+	payment, err := s.GetPaymentByID(paymentID, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.PaymentType != models.PaymentTypePayment || payment.Status != models.PaymentStatusPending {
+		return nil, fmt.Errorf("%w: payment is %s", ErrPaymentNotPending, payment.Status)
+	}
+	if payment.Provider != s.gateway.Name() {
+		return nil, fmt.Errorf("%w: payment was not made through %s", ErrPaymentNotPending, s.gateway.Name())
+	}
+
+	var intent *GatewayIntent
+	if payment.ProviderReference == "" {
+		// The intent was never acknowledged: look it up by the key it was created with
+		intent, err = s.gateway.FindIntent(paymentIdempotencyKey(payment.ID))
+		if errors.Is(err, ErrGatewayNotFound) {
+			// The request never reached the gateway, so nothing was charged
+			intent, err = &GatewayIntent{Status: GatewayFailed, FailureReason: "the payment never reached the gateway"}, nil
+		}
+	} else {
+		intent, err = s.gateway.GetStatus(payment.ProviderReference)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.settleIntent(payment.ID, intent)
+}
+
+// settleIntent records the outcome of an intent on its payment and captures the intent once
+// it is authorised. A payment for a booking that was cancelled, declined or expired meanwhile
+// is not captured: its intent is cancelled, or refunded when the gateway already took the money.
+func (s *paymentService) settleIntent(paymentID int, intent *GatewayIntent) (*models.PaymentReceipt, error) {
+	settled, err := s.recordIntent(paymentID, intent)
+	if err != nil {
+		return nil, err
+	}
+
+	if intent.Status == GatewayRequiresCapture && settled.payment.Status == models.PaymentStatusPending {
+		// A failed capture leaves the payment pending so it can be confirmed later
+		if captured, err := s.gateway.Capture(intent.Reference); err == nil {
+			if settled, err = s.recordIntent(paymentID, captured); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch {
+	case settled.rejected:
+		if _, err := s.gateway.Cancel(intent.Reference); err != nil {
+			// Log the error; an authorisation that is never captured lapses at the gateway
+			fmt.Printf("Failed to cancel payment intent %s: %v\n", intent.Reference, err)
+		}
+		return nil, fmt.Errorf("%w: booking is %s", ErrBookingNotPayable, settled.booking.Status)
+	case settled.refund != nil:
+		// The refund is recorded with the payment; if the gateway refund fails it stays pending for an admin
+		if _, err := s.ProcessRefund(settled.refund.ID); err != nil {
+			fmt.Printf("Failed to refund payment %d: %v\n", paymentID, err)
+		}
+		if !bookingPayable(settled.booking.Status) {
+			return nil, fmt.Errorf("%w: booking is %s, the payment is refunded", ErrBookingNotPayable, settled.booking.Status)
+		}
+	case settled.payment.Status == models.PaymentStatusFailed:
+		return nil, fmt.Errorf("%w: %s", ErrPaymentDeclined, settled.payment.FailureReason)
+	}
+
+	receipt := &models.PaymentReceipt{
+		Payment:            settled.payment,
+		BookingStatus:      settled.booking.Status,
+		AmountPaid:         roundMoney(settled.paid),
+		OutstandingBalance: roundMoney(settled.booking.TotalPrice - settled.paid),
+	}
+	if settled.payment.Status == models.PaymentStatusPending && intent.Status == GatewayRequiresAction {
+		receipt.NextActionURL = intent.NextActionURL
+	}
+	return receipt, nil
+}
+
+// settledPayment is a payment after recordIntent, with its booking and what became of it
+type settledPayment struct {
+	payment *models.Payment
+	booking *models.Booking
+	paid    float64
+	// rejected is set when the payment failed because its booking can no longer be paid
+	rejected bool
+	// refund is the pending refund of money taken for a booking that can no longer be paid,
+	// or of the part of the payment beyond the price of the booking
+	refund *models.Payment
+}
+
+// recordIntent records the state of an intent on a pending payment while its booking is locked
+func (s *paymentService) recordIntent(paymentID int, intent *GatewayIntent) (*settledPayment, error) {
+	settled := &settledPayment{}
+	payment, booking, err := s.paymentRepo.SettlePayment(paymentID, func(payment *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error) {
+		settled.paid = netPaid
+		if payment.Status != models.PaymentStatusPending {
+			return false, nil, nil
+		}
+
+		payment.ProviderReference = intent.Reference
+		switch intent.Status {
+		case GatewaySucceeded:
+			payment.Status = models.PaymentStatusCompleted
+			if !bookingPayable(booking.Status) {
+				settled.refund = paymentRefund(payment)
+				return false, settled.refund, nil
+			}
+			// A stale intent may settle after the balance was paid another way
+			settled.refund = excessRefund(payment, booking, netPaid)
+			settled.paid = netPaid + payment.Amount
+			if settled.refund != nil {
+				settled.paid -= settled.refund.Amount
+			}
+			return settled.paid >= booking.TotalPrice-paymentTolerance && booking.Status == models.BookingStatusPending, settled.refund, nil
+		case GatewayFailed:
+			payment.Status = models.PaymentStatusFailed
+			payment.FailureReason = intent.FailureReason
+		default:
+			if !bookingPayable(booking.Status) {
+				payment.Status = models.PaymentStatusFailed
+				payment.FailureReason = fmt.Sprintf("booking is %s", booking.Status)
+				settled.rejected = true
+			}
+		}
+		return false, nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settled.payment, settled.booking = payment, booking
+	return settled, nil
+}
+
+// voidStalePayments cancels at the gateway the pending payments of a booking whose hold has
+// lapsed and records how they ended. A stale payment the gateway took anyway is kept, less a
+// refund of what it paid beyond the price of the booking. Failures are logged and the
+// payments stay pending, to be voided on the next payment.
+func (s *paymentService) voidStalePayments(bookingID int) {
+	stale, err := s.paymentRepo.GetStalePayments(bookingID)
+	if err != nil {
+		fmt.Printf("Failed to get stale payments of booking %d: %v\n", bookingID, err)
+		return
+	}
+
+	for i := range stale {
+		payment := &stale[i]
+		if payment.Provider != s.gateway.Name() {
+			continue
+		}
+
+		intent, err := s.voidIntent(payment)
+		if err != nil {
+			fmt.Printf("Failed to void payment %d: %v\n", payment.ID, err)
+			continue
+		}
+		settled, err := s.recordIntent(payment.ID, intent)
+		if err != nil {
+			fmt.Printf("Failed to record voided payment %d: %v\n", payment.ID, err)
+			continue
+		}
+		if settled.refund != nil {
+			if _, err := s.ProcessRefund(settled.refund.ID); err != nil {
+				fmt.Printf("Failed to refund payment %d: %v\n", payment.ID, err)
+			}
+		}
+	}
+}
+
+// voidIntent cancels the intent of a pending payment at the gateway. It returns the state of
+// the intent when it can no longer be cancelled.
+func (s *paymentService) voidIntent(payment *models.Payment) (*GatewayIntent, error) {
+	reference := payment.ProviderReference
+	if reference == "" {
+		// The intent was never acknowledged: look it up by the key it was created with
+		intent, err := s.gateway.FindIntent(paymentIdempotencyKey(payment.ID))
+		if errors.Is(err, ErrGatewayNotFound) {
+			return &GatewayIntent{Status: GatewayFailed, FailureReason: "the payment expired before reaching the gateway"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		reference = intent.Reference
+	}
+
+	if intent, err := s.gateway.Cancel(reference); err == nil {
+		return intent, nil
+	}
+	return s.gateway.GetStatus(reference)
+}
+
+// ProcessRefund sends a pending refund to the gateway that took the payments it is for.
+// Refunds of payments recorded without a gateway stay pending for manual processing.
+// It returns the refund records, which are split when the refund spans several payments.
+func (s *paymentService) ProcessRefund(refundID int) ([]models.Payment, error) {
+	refund, err := s.paymentRepo.GetPaymentByID(refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.PaymentType != models.PaymentTypeRefund || refund.Status != models.PaymentStatusPending {
+		return nil, fmt.Errorf("%w: payment %d is not a pending refund", ErrPaymentNotPending, refundID)
+	}
+
+	parts, err := s.paymentRepo.AllocateRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	results := make([]models.Payment, 0, len(parts))
+	for _, part := range parts {
+		if part.Provider != s.gateway.Name() {
+			results = append(results, part)
+			continue
+		}
+
+		refund, err := s.refundThroughGateway(&part)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			results = append(results, part)
+			continue
+		}
+		results = append(results, *refund)
+	}
+
+	return results, firstErr
+}
+
+// refundThroughGateway refunds part of a payment at the gateway and records the outcome
+func (s *paymentService) refundThroughGateway(part *models.Payment) (*models.Payment, error) {
+	parent, err := s.paymentRepo.GetPaymentByID(*part.ParentPaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.gateway.Refund(parent.ProviderReference, part.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, _, err := s.paymentRepo.SettlePayment(part.ID, func(refund *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error) {
+		if refund.Status != models.PaymentStatusPending {
+			return false, nil, nil
+		}
+		refund.ProviderReference = result.Reference
+		switch result.Status {
+		case GatewaySucceeded:
+			refund.Status = models.PaymentStatusCompleted
+		case GatewayFailed:
+			refund.Status = models.PaymentStatusFailed
+			refund.FailureReason = result.FailureReason
+		}
+		return false, nil, nil
+	})
+	return refund, err
+}
+
+// GetPaymentsByUserID retrieves the payments of a user
+func (s *paymentService) GetPaymentsByUserID(userID int) ([]models.Payment, error) {
+	return s.paymentRepo.GetPaymentsByUserID(userID)
+}
+
+// GetPaymentByID retrieves a payment for its owner or staff allowed to read payments, from a
+// session that passed the second factor when their role requires it
+func (s *paymentService) GetPaymentByID(id, requesterID int, sessionID string) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.UserID != requesterID {
+		requester, err := s.userRepo.GetUserByID(requesterID)
+		if err != nil {
+			return nil, err
+		}
+		if !requester.HasPermission(models.PermissionPaymentsRead) {
+			return nil, ErrPaymentAccessDenied
+		}
+		if err := s.authService.VerifyStaffSession(requester, sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return payment, nil
+}
+
+// GetAllPayments retrieves a page of payments and refunds (admin only)
+func (s *paymentService) GetAllPayments(query models.ListQuery) ([]models.Payment, *models.PageInfo, error) {
+	payments, page, err := s.paymentRepo.GetAllPayments(query)
+	return payments, page, listQueryError(err)
+}
+
+// GetPaymentsByBookingID retrieves the payments and refunds of a booking (admin only)
+func (s *paymentService) GetPaymentsByBookingID(bookingID int) ([]models.Payment, error) {
+	return s.paymentRepo.GetPaymentsByBookingID(bookingID)
+}
+
+// refundStatus summarises the status of the parts of a refund
+func refundStatus(parts []models.Payment) models.PaymentStatus {
+	status := models.PaymentStatusCompleted
+	for _, part := range parts {
+		switch part.Status {
+		case models.PaymentStatusFailed:
+			return models.PaymentStatusFailed
+		case models.PaymentStatusPending:
+			status = models.PaymentStatusPending
+		}
+	}
+	return status
+}
+
+// bookingPayable checks if a booking in a status can still take payments
+func bookingPayable(status models.BookingStatus) bool {
+	return status == models.BookingStatusPending || status == models.BookingStatusConfirmed
+}
+
+// paymentRefund is a pending refund of the whole of a payment taken through a gateway
+func paymentRefund(payment *models.Payment) *models.Payment {
+	parentID := payment.ID
+	return &models.Payment{
+		UserID:          payment.UserID,
+		BookingID:       payment.BookingID,
+		Amount:          payment.Amount,
+		PaymentDate:     time.Now(),
+		PaymentMethod:   payment.PaymentMethod,
+		Status:          models.PaymentStatusPending,
+		PaymentType:     models.PaymentTypeRefund,
+		Provider:        payment.Provider,
+		ParentPaymentID: &parentID,
+	}
+}
+
+// excessRefund is a pending refund of what a payment adds beyond the price of its booking,
+// or nil when the payment does not overpay it
+func excessRefund(payment *models.Payment, booking *models.Booking, netPaid float64) *models.Payment {
+	excess := roundMoney(netPaid + payment.Amount - booking.TotalPrice)
+	if excess <= paymentTolerance {
+		return nil
+	}
+
+	refund := paymentRefund(payment)
+	refund.Amount = math.Min(excess, payment.Amount)
+	return refund
+}
+
+// paymentIdempotencyKey identifies a payment to the gateway so retried requests are not charged twice
+func paymentIdempotencyKey(paymentID int) string {
+	return paymentIdempotencyPrefix + strconv.Itoa(paymentID)
+}
+
+// paymentIDFromIdempotencyKey reads the payment ID back from an idempotency key
+func paymentIDFromIdempotencyKey(key string) (int, bool) {
+	if !strings.HasPrefix(key, paymentIdempotencyPrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(key, paymentIdempotencyPrefix))
+	return id, err == nil
+}
+
+// isPaymentMethod checks if a payment method is accepted
+func isPaymentMethod(method string) bool {
+	for _, accepted := range models.PaymentMethods {
+		if method == accepted {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhookSignatureTolerance is how old a signed webhook delivery may be before it is refused
+const webhookSignatureTolerance = 5 * time.Minute
+
+// Errors returned by the payment webhook service
+var (
+	ErrUnknownPaymentProvider   = errors.New("unknown payment provider")
+	ErrWebhookNotConfigured     = errors.New("payment webhook secret is not configured")
+	ErrInvalidWebhookSignature  = errors.New("invalid webhook signature")
+	ErrInvalidWebhookPayload    = errors.New("invalid webhook payload")
+	ErrPaymentEventAlreadyKnown = errors.New("payment event was already processed")
+)
+
+// PaymentWebhookService interface defines methods for handling payment gateway webhooks
+type PaymentWebhookService interface {
+	HandleWebhook(provider string, payload []byte, signature string) (*models.PaymentEvent, error)
+}
+
+// paymentWebhookService implements PaymentWebhookService
+type paymentWebhookService struct {
+	eventRepo   repository.PaymentEventRepository
+	paymentRepo repository.PaymentRepository
+	gateway     PaymentGateway
+}
+
+// NewPaymentWebhookService creates a new payment webhook service
+func NewPaymentWebhookService(eventRepo repository.PaymentEventRepository, paymentRepo repository.PaymentRepository, gateway PaymentGateway) PaymentWebhookService {
+	return &paymentWebhookService{eventRepo: eventRepo, paymentRepo: paymentRepo, gateway: gateway}
+}
+
+// HandleWebhook verifies a webhook delivery, stores its event and applies it once.
+// Events are applied by status precedence rather than arrival order: a payment only moves
+// from pending to failed to completed, and a refund event implies that its payment was
+// captured, so the same set of events always leaves the same rows behind.
+// It returns ErrPaymentEventAlreadyKnown for a retried delivery.
+func (s *paymentWebhookService) HandleWebhook(provider string, payload []byte, signature string) (*models.PaymentEvent, error) {
+	if provider != s.gateway.Name() {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, provider)
+	}
+
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET_" + strings.ToUpper(provider))
+	if secret == "" {
+		return nil, ErrWebhookNotConfigured
+	}
+	if err := verifyWebhookSignature(secret, signature, payload, time.Now()); err != nil {
+		return nil, err
+	}
+
+	parsed, err := s.gateway.ParseEvent(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	event := &models.PaymentEvent{
+		Provider:  provider,
+		EventID:   parsed.ID,
+		EventType: string(parsed.Type),
+		Payload:   payload,
+	}
+	if !parsed.OccurredAt.IsZero() {
+		event.OccurredAt = &parsed.OccurredAt
+	}
+	if err := s.eventRepo.SaveEvent(event); err != nil {
+		return nil, err
+	}
+	if event.Status != models.PaymentEventReceived {
+		return event, ErrPaymentEventAlreadyKnown
+	}
+
+	switch parsed.Type {
+	case GatewayEventPaymentSucceeded, GatewayEventPaymentFailed, GatewayEventRefundSucceeded, GatewayEventRefundFailed:
+	default:
+		return event, s.ignoreEvent(event, "unhandled event type")
+	}
+
+	isRefund := parsed.Type == GatewayEventRefundSucceeded || parsed.Type == GatewayEventRefundFailed
+	if isRefund && parsed.RefundReference == "" {
+		return event, s.ignoreEvent(event, "refund reference missing")
+	}
+
+	payment, err := s.findPayment(parsed)
+	if err != nil {
+		return event, s.ignoreEvent(event, "unknown payment")
+	}
+
+	err = s.eventRepo.ApplyEvent(event.ID, payment.ID, parsed.RefundReference, roundMoney(parsed.Amount), func(payment, refund *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error) {
+		return applyGatewayEvent(parsed, payment, refund, booking, netPaid)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrPaymentEventProcessed) {
+			return event, ErrPaymentEventAlreadyKnown
+		}
+		return event, err
+	}
+
+	event.Status = models.PaymentEventProcessed
+	event.PaymentID = &payment.ID
+	return event, nil
+}
+
+// findPayment finds the payment an event is about by its gateway reference, or by the
+// idempotency key it was created with when the gateway reference was never recorded
+func (s *paymentWebhookService) findPayment(event *GatewayEvent) (*models.Payment, error) {
+	if event.Reference != "" {
+		payment, err := s.paymentRepo.GetPaymentByProviderReference(s.gateway.Name(), event.Reference)
+		if err == nil {
+			return payment, nil
+		}
+	}
+
+	id, ok := paymentIDFromIdempotencyKey(event.MerchantReference)
+	if !ok {
+		return nil, fmt.Errorf("payment not found")
+	}
+	payment, err := s.paymentRepo.GetPaymentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Provider != s.gateway.Name() || payment.PaymentType != models.PaymentTypePayment {
+		return nil, fmt.Errorf("payment not found")
+	}
+	return payment, nil
+}
+
+// ignoreEvent records that an event does not change anything
+func (s *paymentWebhookService) ignoreEvent(event *models.PaymentEvent, reason string) error {
+	if err := s.eventRepo.IgnoreEvent(event.ID, reason); err != nil {
+		return err
+	}
+	event.Status = models.PaymentEventIgnored
+	event.Error = reason
+	return nil
+}
+
+// applyGatewayEvent updates a payment, and the refund of a refund event, from a gateway
+// event. It returns whether the booking is confirmed and the refund to record.
+func applyGatewayEvent(event *GatewayEvent, payment, refund *models.Payment, booking *models.Booking, netPaid float64) (bool, *models.Payment, error) {
+	if payment.ProviderReference == "" {
+		payment.ProviderReference = event.Reference
+	}
+
+	confirm := false
+	switch event.Type {
+	case GatewayEventPaymentSucceeded, GatewayEventRefundSucceeded, GatewayEventRefundFailed:
+		// A refund can only exist for a captured payment, even if the capture event is late
+		if paymentStatusRank(payment.Status) < paymentStatusRank(models.PaymentStatusCompleted) {
+			payment.Status = models.PaymentStatusCompleted
+			payment.FailureReason = ""
+			confirm = booking.Status == models.BookingStatusPending &&
+				netPaid+payment.Amount >= booking.TotalPrice-paymentTolerance
+			if event.Type == GatewayEventPaymentSucceeded && !bookingPayable(booking.Status) {
+				// The money was taken for a booking that can no longer be paid: it is owed back
+				return false, paymentRefund(payment), nil
+			}
+			if event.Type == GatewayEventPaymentSucceeded {
+				// A stale intent may settle after the balance was paid another way
+				if excess := excessRefund(payment, booking, netPaid); excess != nil {
+					return confirm, excess, nil
+				}
+			}
+		}
+	case GatewayEventPaymentFailed:
+		if paymentStatusRank(payment.Status) < paymentStatusRank(models.PaymentStatusFailed) {
+			payment.Status = models.PaymentStatusFailed
+			payment.FailureReason = event.FailureReason
+		}
+	}
+
+	if event.Type != GatewayEventRefundSucceeded && event.Type != GatewayEventRefundFailed {
+		return confirm, nil, nil
+	}
+
+	status := models.PaymentStatusCompleted
+	if event.Type == GatewayEventRefundFailed {
+		status = models.PaymentStatusFailed
+	}
+
+	if refund == nil {
+		// The refund was issued directly at the gateway
+		parentID := payment.ID
+		refund = &models.Payment{
+			UserID:            payment.UserID,
+			BookingID:         payment.BookingID,
+			Amount:            roundMoney(math.Abs(event.Amount)),
+			PaymentDate:       time.Now(),
+			PaymentMethod:     payment.PaymentMethod,
+			Status:            status,
+			PaymentType:       models.PaymentTypeRefund,
+			Provider:          payment.Provider,
+			ProviderReference: event.RefundReference,
+			ParentPaymentID:   &parentID,
+		}
+		if status == models.PaymentStatusFailed {
+			refund.FailureReason = event.FailureReason
+		}
+		return confirm, refund, nil
+	}
+
+	refund.ProviderReference = event.RefundReference
+	if paymentStatusRank(refund.Status) < paymentStatusRank(status) {
+		refund.Status = status
+		refund.FailureReason = ""
+		if status == models.PaymentStatusFailed {
+			refund.FailureReason = event.FailureReason
+		}
+	}
+	return confirm, refund, nil
+}
+
+// paymentStatusRank orders payment statuses so that events can only move a payment forward
+func paymentStatusRank(status models.PaymentStatus) int {
+	switch status {
+	case models.PaymentStatusPending:
+		return 0
+	case models.PaymentStatusFailed:
+		return 1
+	case models.PaymentStatusCompleted:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// verifyWebhookSignature checks a signature header of the form "t=<unix time>,v1=<hex>",
+// where v1 is the HMAC-SHA256 of "<unix time>.<payload>" keyed with the webhook secret
+func verifyWebhookSignature(secret, header string, payload []byte, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidWebhookSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > webhookSignatureTolerance || age < -webhookSignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidWebhookSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	// A delivery may carry several v1 signatures while the provider rotates its secret
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
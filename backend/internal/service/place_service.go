@@ -0,0 +1,223 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxPlaceNameLength = 100
+
+// placeCodePatterns are the codes of each level: UN M49 area codes for continents, ISO 3166-1
+// alpha-2 codes for countries, ISO 3166-2 codes for regions and UN/LOCODEs without their space
+// for cities
+var placeCodePatterns = map[models.PlaceLevel]*regexp.Regexp{
+	models.PlaceContinent: regexp.MustCompile(`^[0-9]{3}$`),
+	models.PlaceCountry:   regexp.MustCompile(`^[A-Z]{2}$`),
+	models.PlaceRegion:    regexp.MustCompile(`^[A-Z]{2}-[A-Z0-9]{1,3}$`),
+	models.PlaceCity:      regexp.MustCompile(`^[A-Z]{2}[A-Z2-9]{3}$`),
+}
+
+// placeParentLevels are the levels the parent of a place of each level can be at; cities can be
+// placed right below their country when it has no regions
+var placeParentLevels = map[models.PlaceLevel][]models.PlaceLevel{
+	models.PlaceCountry: {models.PlaceContinent},
+	models.PlaceRegion:  {models.PlaceCountry},
+	models.PlaceCity:    {models.PlaceCountry, models.PlaceRegion},
+}
+
+// PlaceService interface defines methods for browsing and managing the place hierarchy
+type PlaceService interface {
+	GetPlaces(query models.ListQuery) ([]models.Place, *models.PageInfo, error)
+	GetPlace(code string) (*models.Place, error)
+	GetChildren(code string, query models.ListQuery) ([]models.Place, *models.PageInfo, error)
+	GetDestinations(code string, query models.ListQuery) ([]models.Destination, *models.PageInfo, error)
+	CreatePlace(req *models.PlaceRequest) (*models.Place, error)
+	UpdatePlace(code string, req *models.PlaceRequest) (*models.Place, error)
+	DeletePlace(code string) error
+}
+
+// placeService implements PlaceService
+type placeService struct {
+	placeRepo       repository.PlaceRepository
+	destinationRepo repository.DestinationRepository
+}
+
+// NewPlaceService creates a new place service
+func NewPlaceService(placeRepo repository.PlaceRepository, destinationRepo repository.DestinationRepository) PlaceService {
+	return &placeService{placeRepo: placeRepo, destinationRepo: destinationRepo}
+}
+
+// GetPlaces retrieves a page of places of every level
+func (s *placeService) GetPlaces(query models.ListQuery) ([]models.Place, *models.PageInfo, error) {
+	places, page, err := s.placeRepo.GetPlaces(nil, query)
+	return places, page, listQueryError(err)
+}
+
+// GetPlace retrieves a place by its code with the places above it
+func (s *placeService) GetPlace(code string) (*models.Place, error) {
+	place, err := s.getPlace(code)
+	if err != nil {
+		return nil, err
+	}
+	if place.Ancestors, err = s.placeRepo.GetAncestors(place.ID); err != nil {
+		return nil, err
+	}
+	return place, nil
+}
+
+// GetChildren retrieves a page of the places right below a place
+func (s *placeService) GetChildren(code string, query models.ListQuery) ([]models.Place, *models.PageInfo, error) {
+	place, err := s.getPlace(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	places, page, err := s.placeRepo.GetPlaces(&place.ID, query)
+	return places, page, listQueryError(err)
+}
+
+// GetDestinations retrieves a page of the destinations of a place, including those of the
+// places below it
+func (s *placeService) GetDestinations(code string, query models.ListQuery) ([]models.Destination, *models.PageInfo, error) {
+	place, err := s.getPlace(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	destinations, page, err := s.destinationRepo.GetDestinationsInPlace(place.ID, query)
+	return destinations, page, listQueryError(err)
+}
+
+// CreatePlace adds a country, region or city below its parent. Continents are fixed.
+func (s *placeService) CreatePlace(req *models.PlaceRequest) (*models.Place, error) {
+	place := &models.Place{
+		Code:  normalizePlaceCode(req.Code),
+		Name:  strings.TrimSpace(req.Name),
+		Level: req.Level,
+	}
+	if place.Level == models.PlaceContinent {
+		return nil, fmt.Errorf("%w: continents cannot be added", ErrInvalidPlace)
+	}
+	if err := s.validatePlace(place, req.ParentCode); err != nil {
+		return nil, err
+	}
+
+	if err := s.placeRepo.CreatePlace(place); err != nil {
+		if errors.Is(err, repository.ErrPlaceExists) {
+			return nil, ErrPlaceExists
+		}
+		return nil, err
+	}
+	return place, nil
+}
+
+// UpdatePlace renames a place or moves it below another parent of the same country; without a
+// parent code it stays where it is
+func (s *placeService) UpdatePlace(code string, req *models.PlaceRequest) (*models.Place, error) {
+	place, err := s.getPlace(code)
+	if err != nil {
+		return nil, err
+	}
+	place.Name = strings.TrimSpace(req.Name)
+	parentCode := req.ParentCode
+	if parentCode == "" {
+		parentCode = place.ParentCode
+	}
+	if place.Level != models.PlaceContinent {
+		if err := s.validatePlace(place, parentCode); err != nil {
+			return nil, err
+		}
+	} else if err := validatePlaceName(place.Name); err != nil {
+		return nil, err
+	}
+
+	if err := s.placeRepo.UpdatePlace(place); err != nil {
+		if errors.Is(err, repository.ErrPlaceNotFound) {
+			return nil, ErrPlaceNotFound
+		}
+		return nil, err
+	}
+	return place, nil
+}
+
+// DeletePlace deletes a place that has neither places nor destinations below it
+func (s *placeService) DeletePlace(code string) error {
+	place, err := s.getPlace(code)
+	if err != nil {
+		return err
+	}
+
+	err = s.placeRepo.DeletePlace(place.ID)
+	switch {
+	case errors.Is(err, repository.ErrPlaceNotFound):
+		return ErrPlaceNotFound
+	case errors.Is(err, repository.ErrPlaceInUse):
+		return ErrPlaceInUse
+	}
+	return err
+}
+
+// getPlace retrieves a place by its code, in any case
+func (s *placeService) getPlace(code string) (*models.Place, error) {
+	place, err := s.placeRepo.GetPlaceByCode(normalizePlaceCode(code))
+	if errors.Is(err, repository.ErrPlaceNotFound) {
+		return nil, ErrPlaceNotFound
+	}
+	return place, err
+}
+
+// validatePlace checks the name, code and parent of a place and sets its parent. Regions and
+// cities belong to the country their code starts with.
+func (s *placeService) validatePlace(place *models.Place, parentCode string) error {
+	if err := validatePlaceName(place.Name); err != nil {
+		return err
+	}
+	pattern, ok := placeCodePatterns[place.Level]
+	if !ok {
+		return fmt.Errorf("%w: level must be country, region or city", ErrInvalidPlace)
+	}
+	if !pattern.MatchString(place.Code) {
+		return fmt.Errorf("%w: %q is not a valid %s code", ErrInvalidPlace, place.Code, place.Level)
+	}
+
+	if strings.TrimSpace(parentCode) == "" {
+		return fmt.Errorf("%w: a %s needs a parent place", ErrInvalidPlace, place.Level)
+	}
+	parent, err := s.placeRepo.GetPlaceByCode(normalizePlaceCode(parentCode))
+	if errors.Is(err, repository.ErrPlaceNotFound) {
+		return fmt.Errorf("%w: unknown parent place %q", ErrInvalidPlace, parentCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	allowed := false
+	for _, level := range placeParentLevels[place.Level] {
+		allowed = allowed || parent.Level == level
+	}
+	if !allowed {
+		return fmt.Errorf("%w: a %s cannot be placed below a %s", ErrInvalidPlace, place.Level, parent.Level)
+	}
+	if place.Level != models.PlaceCountry && parent.Code[:2] != place.Code[:2] {
+		return fmt.Errorf("%w: %s is not in %s", ErrInvalidPlace, place.Code, parent.Code)
+	}
+
+	place.ParentID, place.ParentCode = &parent.ID, parent.Code
+	return nil
+}
+
+// validatePlaceName checks the name of a place
+func validatePlaceName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxPlaceNameLength {
+		return fmt.Errorf("%w: name is required and at most %d characters", ErrInvalidPlace, maxPlaceNameLength)
+	}
+	return nil
+}
+
+// normalizePlaceCode writes a place code the way places are stored, in upper case
+func normalizePlaceCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultCurrency is the currency all service prices are stored in
+const defaultCurrency = "USD"
+
+// PricingService interface defines methods for pricing bookings
+type PricingService interface {
+	QuoteBooking(serviceID int, start, end time.Time, vehicleClass *models.VehicleClass) (*models.BookingQuote, error)
+}
+
+// pricingService implements PricingService
+type pricingService struct {
+	serviceRepo     repository.ServiceRepository
+	serviceTypeRepo repository.ServiceTypeRepository
+	serviceFeeRate  float64
+	taxRate         float64
+}
+
+// NewPricingService creates a new pricing service.
+// The service fee and tax rates are read from BOOKING_SERVICE_FEE_RATE and BOOKING_TAX_RATE
+// as fractions (0.16 for 16%).
+func NewPricingService(serviceRepo repository.ServiceRepository, serviceTypeRepo repository.ServiceTypeRepository) PricingService {
+	return &pricingService{
+		serviceRepo:     serviceRepo,
+		serviceTypeRepo: serviceTypeRepo,
+		serviceFeeRate:  rateFromEnv("BOOKING_SERVICE_FEE_RATE"),
+		taxRate:         rateFromEnv("BOOKING_TAX_RATE"),
+	}
+}
+
+// QuoteBooking computes the itemised price of booking a service between start and end. Car
+// rentals are priced at the daily rate of the vehicle class booked, when one is given.
+func (s *pricingService) QuoteBooking(serviceID int, start, end time.Time, vehicleClass *models.VehicleClass) (*models.BookingQuote, error) {
+	if !end.After(start) {
+		return nil, ErrInvalidBookingDates
+	}
+
+	svc, err := s.serviceRepo.GetServiceByID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceType, err := s.serviceTypeRepo.GetServiceTypeByID(svc.ServiceTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := serviceType.PricingUnit
+	units := billableUnits(unit, start, end)
+	unitPrice, description := svc.Price, svc.Name
+	if vehicleClass != nil {
+		unitPrice, description = vehicleClass.DailyRate, fmt.Sprintf("%s, %s", svc.Name, vehicleClass.Name)
+	}
+	subtotal := roundMoney(unitPrice * float64(units))
+	serviceFee := roundMoney(subtotal * s.serviceFeeRate)
+	taxes := roundMoney((subtotal + serviceFee) * s.taxRate)
+
+	quote := &models.BookingQuote{
+		ServiceID:        serviceID,
+		BookingDateStart: start,
+		BookingDateEnd:   end,
+		PricingUnit:      unit,
+		Units:            units,
+		UnitPrice:        unitPrice,
+		Subtotal:         subtotal,
+		ServiceFee:       serviceFee,
+		Taxes:            taxes,
+		Total:            roundMoney(subtotal + serviceFee + taxes),
+		Currency:         defaultCurrency,
+		LineItems: []models.QuoteLineItem{
+			{
+				Description: fmt.Sprintf("%s (%s)", description, unitLabel(unit, units)),
+				Quantity:    units,
+				UnitPrice:   unitPrice,
+				Amount:      subtotal,
+			},
+		},
+	}
+
+	if vehicleClass != nil {
+		quote.VehicleClass = vehicleClass.Name
+		quote.ExtraKmFee = vehicleClass.ExtraKmFee
+		if vehicleClass.MileageLimitKm != nil {
+			includedKm := *vehicleClass.MileageLimitKm * units
+			quote.IncludedKm = &includedKm
+		}
+	}
+
+	if serviceFee > 0 {
+		quote.LineItems = append(quote.LineItems, models.QuoteLineItem{
+			Description: "Service fee",
+			Quantity:    1,
+			UnitPrice:   serviceFee,
+			Amount:      serviceFee,
+		})
+	}
+	if taxes > 0 {
+		quote.LineItems = append(quote.LineItems, models.QuoteLineItem{
+			Description: "Taxes",
+			Quantity:    1,
+			UnitPrice:   taxes,
+			Amount:      taxes,
+		})
+	}
+
+	return quote, nil
+}
+
+// billableUnits returns how many pricing units a booking between start and end spans.
+// Nights are counted as calendar date changes, days and hours are rounded up, and
+// every booking is billed at least one unit.
+func billableUnits(unit models.PricingUnit, start, end time.Time) int {
+	var units int
+	switch unit {
+	case models.PricingPerNight:
+		startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		endDate := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+		units = int(endDate.Sub(startDate).Hours() / 24)
+	case models.PricingPerDay:
+		units = int(math.Ceil(end.Sub(start).Hours() / 24))
+	case models.PricingPerHour:
+		units = int(math.Ceil(end.Sub(start).Minutes() / 60))
+	default:
+		units = 1
+	}
+
+	if units < 1 {
+		units = 1
+	}
+	return units
+}
+
+// unitLabel describes a number of pricing units for a quote line item
+func unitLabel(unit models.PricingUnit, units int) string {
+	var name string
+	switch unit {
+	case models.PricingPerNight:
+		name = "night"
+	case models.PricingPerDay:
+		name = "day"
+	case models.PricingPerHour:
+		name = "hour"
+	default:
+		return "flat rate"
+	}
+
+	if units != 1 {
+		name += "s"
+	}
+	return fmt.Sprintf("%d %s", units, name)
+}
+
+// roundMoney rounds an amount to cents
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// rateFromEnv reads a non-negative fractional rate from the environment, defaulting to 0
+func rateFromEnv(key string) float64 {
+	rate, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || rate < 0 {
+		return 0
+	}
+	return rate
+}
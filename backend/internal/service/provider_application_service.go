@@ -0,0 +1,435 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"path/filepath"
+	"strings"
+)
+
+// Limits on provider documents
+const (
+	MaxProviderDocumentSize    = 10 << 20 // bytes per uploaded file
+	maxProviderDocuments       = 10       // documents per application
+	maxStoredProviderDocuments = 30       // documents a user may keep uploaded
+)
+
+// providerDocumentContentTypes are the file formats accepted for provider documents
+var providerDocumentContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// ProviderApplicationService interface defines methods for provider onboarding: uploading
+// documents, applying, reviewing applications and keeping the business profile up to date
+type ProviderApplicationService interface {
+	UploadDocument(userID int, documentType models.ProviderDocumentType, fileName string, content io.Reader) (*models.ProviderDocument, error)
+	GetDocuments(userID int) ([]models.ProviderDocument, error)
+	OpenDocument(id, requesterID int, sessionID string) (*models.ProviderDocument, io.ReadCloser, error)
+	DeleteDocument(id, userID int) error
+	Apply(userID int, req *models.ProviderApplicationRequest) (*models.ProviderApplication, error)
+	Resubmit(id, userID int, req *models.ProviderApplicationRequest) (*models.ProviderApplication, error)
+	GetApplicationsByUserID(userID int) ([]models.ProviderApplication, error)
+	GetApplications(status models.ProviderApplicationStatus) ([]models.ProviderApplication, error)
+	GetApplicationByID(id, requesterID int, sessionID string) (*models.ProviderApplication, error)
+	GetApplicationHistory(id, requesterID int, sessionID string) ([]models.ProviderApplicationStatusHistory, error)
+	UpdateApplicationStatus(id int, status models.ProviderApplicationStatus, reviewerID int, note string) (*models.ProviderApplication, error)
+	GetProviderProfile(userID int) (*models.User, error)
+	UpdateProviderProfile(userID int, req *models.UpdateProviderProfileRequest) (*models.User, error)
+}
+
+// providerApplicationService implements ProviderApplicationService
+type providerApplicationService struct {
+	applicationRepo repository.ProviderApplicationRepository
+	documentRepo    repository.ProviderDocumentRepository
+	userRepo        repository.UserRepository
+	storage         DocumentStorage
+	emailService    EmailService
+	authService     AuthService
+}
+
+// NewProviderApplicationService creates a new provider application service
+func NewProviderApplicationService(
+	applicationRepo repository.ProviderApplicationRepository,
+	documentRepo repository.ProviderDocumentRepository,
+	userRepo repository.UserRepository,
+	storage DocumentStorage,
+	authService AuthService,
+) ProviderApplicationService {
+	return &providerApplicationService{
+		applicationRepo: applicationRepo,
+		documentRepo:    documentRepo,
+		userRepo:        userRepo,
+		storage:         storage,
+		emailService:    NewEmailService(),
+		authService:     authService,
+	}
+}
+
+// UploadDocument stores a PDF, JPEG or PNG file of at most MaxProviderDocumentSize bytes.
+// The format is detected from the content, not from the name or the declared type.
+func (s *providerApplicationService) UploadDocument(userID int, documentType models.ProviderDocumentType, fileName string, content io.Reader) (*models.ProviderDocument, error) {
+	if !documentType.IsValid() {
+		return nil, fmt.Errorf("%w: document_type must be registration_certificate, identity_document or other", ErrInvalidProviderDocument)
+	}
+
+	existing, err := s.documentRepo.GetDocumentsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxStoredProviderDocuments {
+		return nil, fmt.Errorf("%w: at most %d documents can be kept, delete unused ones first", ErrInvalidProviderDocument, maxStoredProviderDocuments)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, MaxProviderDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidProviderDocument)
+	}
+	if len(data) > MaxProviderDocumentSize {
+		return nil, fmt.Errorf("%w: files may be at most %d MB", ErrInvalidProviderDocument, MaxProviderDocumentSize>>20)
+	}
+	contentType := http.DetectContentType(data)
+	if !providerDocumentContentTypes[contentType] {
+		return nil, fmt.Errorf("%w: only PDF, JPEG and PNG files are accepted", ErrInvalidProviderDocument)
+	}
+
+	document := &models.ProviderDocument{
+		UserID:      userID,
+		Type:        documentType,
+		FileName:    cleanDocumentFileName(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  randomHex(16),
+	}
+	if err := s.storage.Save(document.StorageKey, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if err := s.documentRepo.CreateDocument(document); err != nil {
+		s.storage.Delete(document.StorageKey)
+		return nil, err
+	}
+	return document, nil
+}
+
+// GetDocuments retrieves the documents uploaded by a user
+func (s *providerApplicationService) GetDocuments(userID int) ([]models.ProviderDocument, error) {
+	return s.documentRepo.GetDocumentsByUserID(userID)
+}
+
+// OpenDocument opens the file of a document for its owner or staff reviewing provider applications
+func (s *providerApplicationService) OpenDocument(id, requesterID int, sessionID string) (*models.ProviderDocument, io.ReadCloser, error) {
+	document, err := s.getDocument(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.authorizeApplicantAccess(document.UserID, requesterID, sessionID); err != nil {
+		if errors.Is(err, ErrTwoFactorSessionRequired) {
+			return nil, nil, err
+		}
+		// Do not reveal that the document exists
+		return nil, nil, ErrProviderDocumentNotFound
+	}
+
+	file, err := s.storage.Open(document.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, file, nil
+}
+
+// DeleteDocument deletes a document of a user that is not part of an application
+func (s *providerApplicationService) DeleteDocument(id, userID int) error {
+	document, err := s.getDocument(id)
+	if err != nil {
+		return err
+	}
+	if document.UserID != userID {
+		return ErrProviderDocumentNotFound
+	}
+
+	if err := s.documentRepo.DeleteDocument(id, userID); err != nil {
+		if errors.Is(err, repository.ErrProviderDocumentAttached) {
+			return ErrProviderDocumentAttached
+		}
+		return err
+	}
+	if err := s.storage.Delete(document.StorageKey); err != nil {
+		// Log the error but keep the deletion; the file is no longer reachable
+		fmt.Printf("Failed to delete provider document file: %v\n", err)
+	}
+	return nil
+}
+
+// Apply validates and submits the application of a user to become a provider
+func (s *providerApplicationService) Apply(userID int, req *models.ProviderApplicationRequest) (*models.ProviderApplication, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Verified {
+		return nil, ErrAlreadyVerifiedProvider
+	}
+
+	application, documentIDs, err := s.normalizeApplication(userID, 0, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applicationRepo.CreateApplication(application, documentIDs); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProviderApplicationPending):
+			return nil, ErrProviderApplicationPending
+		case errors.Is(err, repository.ErrProviderDocumentUnavailable):
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProviderApplication, err)
+		}
+		return nil, err
+	}
+	return application, nil
+}
+
+// Resubmit updates an application a reviewer asked changes for and puts it back in review
+func (s *providerApplicationService) Resubmit(id, userID int, req *models.ProviderApplicationRequest) (*models.ProviderApplication, error) {
+	existing, err := s.getApplication(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.UserID != userID {
+		return nil, ErrProviderApplicationAccessDenied
+	}
+	if existing.Status != models.ProviderApplicationChangesRequested {
+		return nil, fmt.Errorf("%w: only applications waiting for changes can be resubmitted", ErrIllegalProviderApplicationTransition)
+	}
+
+	application, documentIDs, err := s.normalizeApplication(userID, id, req)
+	if err != nil {
+		return nil, err
+	}
+	application.ID = id
+
+	if err := s.applicationRepo.ResubmitApplication(application, documentIDs); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProviderApplicationStatusChanged):
+			return nil, fmt.Errorf("%w: %v", ErrIllegalProviderApplicationTransition, err)
+		case errors.Is(err, repository.ErrProviderDocumentUnavailable):
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProviderApplication, err)
+		}
+		return nil, err
+	}
+	return application, nil
+}
+
+// GetApplicationsByUserID retrieves the applications of a user
+func (s *providerApplicationService) GetApplicationsByUserID(userID int) ([]models.ProviderApplication, error) {
+	return s.applicationRepo.GetApplicationsByUserID(userID)
+}
+
+// GetApplications retrieves the applications with a status, or all of them when status is empty
+func (s *providerApplicationService) GetApplications(status models.ProviderApplicationStatus) ([]models.ProviderApplication, error) {
+	if status != "" && !status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidProviderApplication, status)
+	}
+	return s.applicationRepo.GetApplications(status)
+}
+
+// GetApplicationByID retrieves an application for its applicant or staff reviewing provider applications
+func (s *providerApplicationService) GetApplicationByID(id, requesterID int, sessionID string) (*models.ProviderApplication, error) {
+	application, err := s.getApplication(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeApplicantAccess(application.UserID, requesterID, sessionID); err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+// GetApplicationHistory retrieves the status changes of an application for its applicant or
+// staff reviewing provider applications
+func (s *providerApplicationService) GetApplicationHistory(id, requesterID int, sessionID string) ([]models.ProviderApplicationStatusHistory, error) {
+	if _, err := s.GetApplicationByID(id, requesterID, sessionID); err != nil {
+		return nil, err
+	}
+	return s.applicationRepo.GetApplicationHistory(id)
+}
+
+// UpdateApplicationStatus records the decision of a reviewer and lets the applicant know.
+// Approving makes the applicant a verified provider and revoking withdraws the verification.
+// Every decision but an approval needs a note telling the applicant why.
+func (s *providerApplicationService) UpdateApplicationStatus(id int, status models.ProviderApplicationStatus, reviewerID int, note string) (*models.ProviderApplication, error) {
+	note = strings.TrimSpace(note)
+	if note == "" && status != models.ProviderApplicationApproved {
+		return nil, fmt.Errorf("%w: a note explaining the decision is required", ErrInvalidProviderApplication)
+	}
+
+	existing, err := s.getApplication(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateProviderApplicationTransition(existing.Status, status); err != nil {
+		return nil, err
+	}
+
+	application, err := s.applicationRepo.UpdateApplicationStatus(id, existing.Status, status, reviewerID, note)
+	if err != nil {
+		if errors.Is(err, repository.ErrProviderApplicationStatusChanged) {
+			return nil, fmt.Errorf("%w: %v", ErrIllegalProviderApplicationTransition, err)
+		}
+		return nil, err
+	}
+
+	applicant, err := s.userRepo.GetUserByID(application.UserID)
+	if err != nil {
+		// Log the error but keep the decision; the applicant sees it in their applications
+		fmt.Printf("Failed to get provider applicant: %v\n", err)
+		return application, nil
+	}
+	if err := s.emailService.SendProviderApplicationReviewedEmail(applicant.Email, applicant.FirstName, status, note); err != nil {
+		fmt.Printf("Failed to send provider application email: %v\n", err)
+	}
+	return application, nil
+}
+
+// GetProviderProfile retrieves the business profile of a user, which holds the details of their
+// last approved application and the verified flag
+func (s *providerApplicationService) GetProviderProfile(userID int) (*models.User, error) {
+	return s.userRepo.GetUserByID(userID)
+}
+
+// UpdateProviderProfile changes the business details of a verified provider that do not need
+// a new review. The company name and address only change through an approved application.
+func (s *providerApplicationService) UpdateProviderProfile(userID int, req *models.UpdateProviderProfileRequest) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Verified {
+		return nil, ErrProviderNotVerified
+	}
+
+	website := strings.TrimSpace(req.Website)
+	if website != "" && !isWebURL(website) {
+		return nil, fmt.Errorf("%w: website must be an http or https URL", ErrInvalidProviderApplication)
+	}
+	if err := s.userRepo.UpdateProviderProfile(userID, strings.TrimSpace(req.Description), website); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetUserByID(userID)
+}
+
+// normalizeApplication validates the details of an application and its documents. The
+// documents must belong to the applicant and must not be part of another application than
+// applicationID; they must include a registration certificate and an identity document.
+func (s *providerApplicationService) normalizeApplication(userID, applicationID int, req *models.ProviderApplicationRequest) (*models.ProviderApplication, []int, error) {
+	application := &models.ProviderApplication{
+		UserID:      userID,
+		CompanyName: truncateRunes(strings.TrimSpace(req.CompanyName), 255),
+		Description: strings.TrimSpace(req.Description),
+		Website:     strings.TrimSpace(req.Website),
+		Address:     strings.TrimSpace(req.Address),
+	}
+	if application.CompanyName == "" {
+		return nil, nil, fmt.Errorf("%w: company_name is required", ErrInvalidProviderApplication)
+	}
+	if application.Address == "" {
+		return nil, nil, fmt.Errorf("%w: address is required", ErrInvalidProviderApplication)
+	}
+	if application.Website != "" && (!isWebURL(application.Website) || len(application.Website) > 255) {
+		return nil, nil, fmt.Errorf("%w: website must be an http or https URL", ErrInvalidProviderApplication)
+	}
+
+	uploaded, err := s.documentRepo.GetDocumentsByUserID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	available := make(map[int]models.ProviderDocument, len(uploaded))
+	for _, document := range uploaded {
+		if document.ApplicationID == nil || *document.ApplicationID == applicationID {
+			available[document.ID] = document
+		}
+	}
+
+	seen := make(map[int]bool, len(req.DocumentIDs))
+	types := make(map[models.ProviderDocumentType]bool)
+	var documentIDs []int
+	for _, id := range req.DocumentIDs {
+		if seen[id] {
+			continue
+		}
+		document, ok := available[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: document %d is not one of your unused uploads", ErrInvalidProviderApplication, id)
+		}
+		seen[id] = true
+		types[document.Type] = true
+		documentIDs = append(documentIDs, id)
+	}
+	if len(documentIDs) > maxProviderDocuments {
+		return nil, nil, fmt.Errorf("%w: at most %d documents are allowed", ErrInvalidProviderApplication, maxProviderDocuments)
+	}
+	if !types[models.ProviderDocumentRegistrationCertificate] || !types[models.ProviderDocumentIdentity] {
+		return nil, nil, fmt.Errorf("%w: a registration_certificate and an identity_document are required", ErrInvalidProviderApplication)
+	}
+
+	return application, documentIDs, nil
+}
+
+// authorizeApplicantAccess checks that the requester is the applicant or has a role that
+// grants the providers:review permission, from a session that passed the second factor when
+// the role requires it
+func (s *providerApplicationService) authorizeApplicantAccess(applicantID, requesterID int, sessionID string) error {
+	if applicantID == requesterID {
+		return nil
+	}
+
+	requester, err := s.userRepo.GetUserByID(requesterID)
+	if err != nil {
+		return err
+	}
+	if !requester.HasPermission(models.PermissionProvidersReview) {
+		return ErrProviderApplicationAccessDenied
+	}
+	return s.authService.VerifyStaffSession(requester, sessionID)
+}
+
+// getApplication retrieves an application, translating the not found error
+func (s *providerApplicationService) getApplication(id int) (*models.ProviderApplication, error) {
+	application, err := s.applicationRepo.GetApplicationByID(id)
+	if errors.Is(err, repository.ErrProviderApplicationNotFound) {
+		return nil, ErrProviderApplicationNotFound
+	}
+	return application, err
+}
+
+// getDocument retrieves a document, translating the not found error
+func (s *providerApplicationService) getDocument(id int) (*models.ProviderDocument, error) {
+	document, err := s.documentRepo.GetDocumentByID(id)
+	if errors.Is(err, repository.ErrProviderDocumentNotFound) {
+		return nil, ErrProviderDocumentNotFound
+	}
+	return document, err
+}
+
+// cleanDocumentFileName keeps the base name of an uploaded file for display
+func cleanDocumentFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "document"
+	}
+	return truncateRunes(name, 255)
+}
+
+// isWebURL checks that a string is an absolute http or https URL
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"fmt"
+	"nomado-houses/internal/models"
+)
+
+// providerApplicationTransitions lists, for each status of a provider application, the
+// statuses a reviewer may move it to. An application waiting for changes goes back to
+// pending when the applicant resubmits it. Rejected and revoked applications are final;
+// the user may apply again.
+var providerApplicationTransitions = map[models.ProviderApplicationStatus][]models.ProviderApplicationStatus{
+	models.ProviderApplicationPending: {
+		models.ProviderApplicationApproved,
+		models.ProviderApplicationChangesRequested,
+		models.ProviderApplicationRejected,
+	},
+	models.ProviderApplicationChangesRequested: {
+		models.ProviderApplicationRejected,
+	},
+	models.ProviderApplicationApproved: {
+		models.ProviderApplicationRevoked,
+	},
+}
+
+// CanTransitionProviderApplication reports whether a reviewer may move a provider
+// application from one status to another
+func CanTransitionProviderApplication(from, to models.ProviderApplicationStatus) bool {
+	for _, next := range providerApplicationTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProviderApplicationTransition returns an error describing why a reviewer cannot
+// move a provider application from one status to another
+func validateProviderApplicationTransition(from, to models.ProviderApplicationStatus) error {
+	if !to.IsValid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidProviderApplication, to)
+	}
+	if !CanTransitionProviderApplication(from, to) {
+		return fmt.Errorf("%w: cannot move application from %s to %s", ErrIllegalProviderApplicationTransition, from, to)
+	}
+	return nil
+}
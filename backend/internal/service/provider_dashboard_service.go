@@ -0,0 +1,227 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"time"
+)
+
+// Limits on the windows of the provider dashboard
+const (
+	defaultDashboardWindow = 30 * 24 * time.Hour
+	maxDashboardWindow     = 366 * 24 * time.Hour
+	defaultCheckInDays     = 7
+	maxCheckInDays         = 90
+	defaultRevenuePeriod   = "day"
+)
+
+// revenuePeriods are the periods revenue can be split into
+var revenuePeriods = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// ProviderDashboardService interface defines methods for the dashboard of a provider, which
+// only ever covers the services the provider owns. Staff of the provider see the parts of the
+// dashboard their team role grants: bookings for front desk, finance for accountants.
+type ProviderDashboardService interface {
+	GetBookings(requester *models.User, status models.BookingStatus) ([]models.ProviderBooking, error)
+	GetUpcomingCheckIns(requester *models.User, days int) ([]models.ProviderBooking, error)
+	GetRevenue(requester *models.User, period string, from, to time.Time) (*models.RevenueReport, error)
+	GetOccupancy(requester *models.User, from, to time.Time) (*models.OccupancyReport, error)
+	GetCancellations(requester *models.User, from, to time.Time) (*models.CancellationReport, error)
+}
+
+// providerDashboardService implements ProviderDashboardService
+type providerDashboardService struct {
+	dashboardRepo repository.ProviderDashboardRepository
+	teamService   ProviderTeamService
+}
+
+// NewProviderDashboardService creates a new provider dashboard service
+func NewProviderDashboardService(dashboardRepo repository.ProviderDashboardRepository, teamService ProviderTeamService) ProviderDashboardService {
+	return &providerDashboardService{dashboardRepo: dashboardRepo, teamService: teamService}
+}
+
+// GetBookings retrieves the bookings of the services of a provider, optionally with a status
+func (s *providerDashboardService) GetBookings(requester *models.User, status models.BookingStatus) ([]models.ProviderBooking, error) {
+	if status != "" && !status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown booking status %q", ErrInvalidDashboardQuery, status)
+	}
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeBookings)
+	if err != nil {
+		return nil, err
+	}
+	return s.dashboardRepo.GetBookings(provider.ID, status)
+}
+
+// GetUpcomingCheckIns retrieves the confirmed bookings starting within the next days,
+// 7 by default and at most 90
+func (s *providerDashboardService) GetUpcomingCheckIns(requester *models.User, days int) ([]models.ProviderBooking, error) {
+	if days == 0 {
+		days = defaultCheckInDays
+	}
+	if days < 0 || days > maxCheckInDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidDashboardQuery, maxCheckInDays)
+	}
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeBookings)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return s.dashboardRepo.GetCheckIns(provider.ID, now, now.AddDate(0, 0, days))
+}
+
+// GetRevenue reports the money received and refunded for the bookings of a provider, split
+// into days, weeks (starting on Monday) or months. Every period of the window is listed,
+// including those without payments.
+func (s *providerDashboardService) GetRevenue(requester *models.User, period string, from, to time.Time) (*models.RevenueReport, error) {
+	if period == "" {
+		period = defaultRevenuePeriod
+	}
+	if !revenuePeriods[period] {
+		return nil, fmt.Errorf("%w: period must be day, week or month", ErrInvalidDashboardQuery)
+	}
+	from, to, err := dashboardWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeFinance)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.dashboardRepo.GetRevenue(provider.ID, period, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byStart := make(map[int64]models.RevenuePeriod, len(rows))
+	for _, row := range rows {
+		byStart[row.PeriodStart.Unix()] = row
+	}
+
+	report := &models.RevenueReport{Period: period, From: from, To: to, Periods: []models.RevenuePeriod{}}
+	for start := truncateToPeriod(from, period); start.Before(to); start = nextPeriod(start, period) {
+		p := byStart[start.Unix()]
+		p.PeriodStart = start
+		p.Gross = roundMoney(p.Gross)
+		p.Refunded = roundMoney(p.Refunded)
+		p.Net = roundMoney(p.Gross - p.Refunded)
+
+		report.Gross += p.Gross
+		report.Refunded += p.Refunded
+		report.Periods = append(report.Periods, p)
+	}
+	report.Gross = roundMoney(report.Gross)
+	report.Refunded = roundMoney(report.Refunded)
+	report.Net = roundMoney(report.Gross - report.Refunded)
+
+	return report, nil
+}
+
+// GetOccupancy reports, for each service of a provider, the share of its capacity held by
+// confirmed, checked-in and completed bookings over a window
+func (s *providerDashboardService) GetOccupancy(requester *models.User, from, to time.Time) (*models.OccupancyReport, error) {
+	from, to, err := dashboardWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeBookings)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := s.dashboardRepo.GetOccupancy(provider.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	days := to.Sub(from).Hours() / 24
+	for i := range services {
+		o := &services[i]
+		o.AvailableDays = roundRate(float64(o.Capacity) * days)
+		o.BookedDays = roundRate(o.BookedDays)
+		if o.AvailableDays > 0 {
+			o.OccupancyRate = roundRate(math.Min(1, o.BookedDays/o.AvailableDays))
+		}
+	}
+
+	return &models.OccupancyReport{From: from, To: to, Services: services}, nil
+}
+
+// GetCancellations reports the share of the bookings made over a window that were cancelled,
+// by the guest or declined by the provider
+func (s *providerDashboardService) GetCancellations(requester *models.User, from, to time.Time) (*models.CancellationReport, error) {
+	from, to, err := dashboardWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeFinance)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.dashboardRepo.GetCancellations(provider.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if report.TotalBookings > 0 {
+		report.CancellationRate = roundRate(float64(report.CancelledBookings) / float64(report.TotalBookings))
+	}
+	return report, nil
+}
+
+// dashboardWindow fills in a missing window with the last 30 days and checks that it spans
+// at most a year
+func dashboardWindow(from, to time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultDashboardWindow)
+	}
+	from, to = from.UTC(), to.UTC()
+
+	if !to.After(from) {
+		return from, to, fmt.Errorf("%w: 'to' must be after 'from'", ErrInvalidDashboardQuery)
+	}
+	if to.Sub(from) > maxDashboardWindow {
+		return from, to, fmt.Errorf("%w: the window can span at most 366 days", ErrInvalidDashboardQuery)
+	}
+	return from, to, nil
+}
+
+// truncateToPeriod returns the start of the day, week or month of t, like date_trunc in PostgreSQL
+func truncateToPeriod(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case "week":
+		// Weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriod returns the start of the period following the one starting at start
+func nextPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// roundRate rounds a rate or a number of days to 4 decimals
+func roundRate(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
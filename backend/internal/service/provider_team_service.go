@@ -0,0 +1,257 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"nomado-houses/internal/utils"
+	"strings"
+	"time"
+)
+
+// teamInvitationTTL is how long an invitation to join a provider team can be accepted
+const teamInvitationTTL = 7 * 24 * time.Hour
+
+// ProviderTeamService interface defines methods for provider organisations: the provider who
+// owns one invites staff by email and gives them an organisation role, and staff act on behalf
+// of the provider within the scopes their role grants
+type ProviderTeamService interface {
+	GetTeam(owner *models.User) (*models.ProviderOrganisation, error)
+	InviteMember(owner *models.User, req *models.InviteTeamMemberRequest) (*models.OrganisationInvitation, error)
+	RevokeInvitation(owner *models.User, invitationID int) error
+	UpdateMemberRole(owner *models.User, userID int, role models.OrganisationRole) (*models.OrganisationMember, error)
+	RemoveMember(owner *models.User, userID int) error
+	AcceptInvitation(user *models.User, token string) (*models.OrganisationMembership, error)
+	GetMembership(userID int) (*models.OrganisationMembership, error)
+	LeaveOrganisation(userID int) error
+	ResolveProvider(requester *models.User, scope models.OrganisationScope) (*models.User, error)
+}
+
+// providerTeamService implements ProviderTeamService
+type providerTeamService struct {
+	organisationRepo repository.OrganisationRepository
+	userRepo         repository.UserRepository
+	emailService     EmailService
+}
+
+// NewProviderTeamService creates a new provider team service
+func NewProviderTeamService(organisationRepo repository.OrganisationRepository, userRepo repository.UserRepository) ProviderTeamService {
+	return &providerTeamService{
+		organisationRepo: organisationRepo,
+		userRepo:         userRepo,
+		emailService:     NewEmailService(),
+	}
+}
+
+// GetTeam retrieves the organisation of a provider with its staff and pending invitations.
+// The organisation is created the first time, named after the company of the provider.
+func (s *providerTeamService) GetTeam(owner *models.User) (*models.ProviderOrganisation, error) {
+	organisation, err := s.getOrganisation(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if organisation.Members, err = s.organisationRepo.GetMembers(organisation.ID); err != nil {
+		return nil, err
+	}
+	if organisation.Invitations, err = s.organisationRepo.GetInvitations(organisation.ID); err != nil {
+		return nil, err
+	}
+	return organisation, nil
+}
+
+// InviteMember invites someone to join the team of a provider and emails them a link to accept.
+// Inviting the same address again replaces the previous invitation.
+func (s *providerTeamService) InviteMember(owner *models.User, req *models.InviteTeamMemberRequest) (*models.OrganisationInvitation, error) {
+	email := strings.TrimSpace(req.Email)
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTeamInvitation, err)
+	}
+	if !req.Role.IsValid() {
+		return nil, fmt.Errorf("%w: role must be manager, front_desk or accountant", ErrInvalidTeamInvitation)
+	}
+	if strings.EqualFold(email, owner.Email) {
+		return nil, fmt.Errorf("%w: you cannot invite yourself", ErrInvalidTeamInvitation)
+	}
+
+	// Someone who already has an account must be free to join
+	if existing, err := s.userRepo.GetUserByEmail(email); err == nil {
+		if existing.HasPermission(models.PermissionServicesWrite) {
+			return nil, fmt.Errorf("%w: providers cannot join another team", ErrInvalidTeamInvitation)
+		}
+		if _, err := s.organisationRepo.GetMembershipByUserID(existing.ID); err == nil {
+			return nil, ErrAlreadyTeamMember
+		} else if !errors.Is(err, repository.ErrOrganisationMemberNotFound) {
+			return nil, err
+		}
+	}
+
+	organisation, err := s.getOrganisation(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.OrganisationInvitation{
+		OrganisationID: organisation.ID,
+		Email:          email,
+		Role:           req.Role,
+		InvitedBy:      &owner.ID,
+		ExpiresAt:      time.Now().Add(teamInvitationTTL),
+	}
+	if err := s.organisationRepo.CreateInvitation(invitation, hashToken(token)); err != nil {
+		return nil, err
+	}
+
+	inviterName := strings.TrimSpace(owner.FirstName + " " + owner.LastName)
+	if err := s.emailService.SendTeamInvitationEmail(email, organisation.Name, inviterName, req.Role, token, teamInvitationTTL); err != nil {
+		// Log error but don't fail the invitation, the provider can send it again
+		fmt.Printf("Failed to send team invitation email: %v\n", err)
+	}
+
+	return invitation, nil
+}
+
+// RevokeInvitation withdraws an invitation that was not accepted yet
+func (s *providerTeamService) RevokeInvitation(owner *models.User, invitationID int) error {
+	organisation, err := s.getOrganisation(owner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.organisationRepo.DeleteInvitation(organisation.ID, invitationID); err != nil {
+		if errors.Is(err, repository.ErrOrganisationInvitationNotFound) {
+			return ErrTeamInvitationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateMemberRole changes the organisation role of a staff member
+func (s *providerTeamService) UpdateMemberRole(owner *models.User, userID int, role models.OrganisationRole) (*models.OrganisationMember, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("%w: role must be manager, front_desk or accountant", ErrInvalidTeamInvitation)
+	}
+
+	organisation, err := s.getOrganisation(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.organisationRepo.UpdateMemberRole(organisation.ID, userID, role)
+	if errors.Is(err, repository.ErrOrganisationMemberNotFound) {
+		return nil, ErrTeamMemberNotFound
+	}
+	return member, err
+}
+
+// RemoveMember removes a staff member from the team, who immediately loses access
+func (s *providerTeamService) RemoveMember(owner *models.User, userID int) error {
+	organisation, err := s.getOrganisation(owner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.organisationRepo.DeleteMember(organisation.ID, userID); err != nil {
+		if errors.Is(err, repository.ErrOrganisationMemberNotFound) {
+			return ErrTeamMemberNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AcceptInvitation makes a user a staff member of the organisation that invited them. The
+// invitation must have been sent to the verified email address of the user.
+func (s *providerTeamService) AcceptInvitation(user *models.User, token string) (*models.OrganisationMembership, error) {
+	if user.HasPermission(models.PermissionServicesWrite) {
+		return nil, fmt.Errorf("%w: providers cannot join another team", ErrInvalidTeamInvitation)
+	}
+	if !user.EmailVerified {
+		return nil, ErrTeamEmailNotVerified
+	}
+	if strings.TrimSpace(token) == "" {
+		return nil, ErrTeamInvitationNotFound
+	}
+
+	membership, err := s.organisationRepo.AcceptInvitation(hashToken(token), user.ID, user.Email)
+	switch {
+	case errors.Is(err, repository.ErrOrganisationInvitationNotFound),
+		errors.Is(err, repository.ErrOrganisationInvitationEmail):
+		// Do not reveal whether the token exists for someone else
+		return nil, ErrTeamInvitationNotFound
+	case errors.Is(err, repository.ErrAlreadyOrganisationMember):
+		return nil, ErrAlreadyTeamMember
+	}
+	return membership, err
+}
+
+// GetMembership retrieves the organisation a staff member works for
+func (s *providerTeamService) GetMembership(userID int) (*models.OrganisationMembership, error) {
+	membership, err := s.organisationRepo.GetMembershipByUserID(userID)
+	if errors.Is(err, repository.ErrOrganisationMemberNotFound) {
+		return nil, ErrTeamMemberNotFound
+	}
+	return membership, err
+}
+
+// LeaveOrganisation removes a staff member from the organisation they work for
+func (s *providerTeamService) LeaveOrganisation(userID int) error {
+	membership, err := s.GetMembership(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.organisationRepo.DeleteMember(membership.OrganisationID, userID); err != nil {
+		if errors.Is(err, repository.ErrOrganisationMemberNotFound) {
+			return ErrTeamMemberNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ResolveProvider returns the provider a requester acts for within a scope. Providers act for
+// themselves; staff act for the owner of their organisation when their role grants the scope
+// and the owner is still allowed to offer services.
+func (s *providerTeamService) ResolveProvider(requester *models.User, scope models.OrganisationScope) (*models.User, error) {
+	if requester.HasPermission(models.PermissionServicesWrite) {
+		return requester, nil
+	}
+
+	membership, err := s.organisationRepo.GetMembershipByUserID(requester.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrganisationMemberNotFound) {
+			return nil, ErrTeamAccessDenied
+		}
+		return nil, err
+	}
+	if !membership.Role.Grants(scope) {
+		return nil, ErrTeamAccessDenied
+	}
+
+	owner, err := s.userRepo.GetUserByID(membership.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if !owner.HasPermission(models.PermissionServicesWrite) {
+		return nil, ErrTeamAccessDenied
+	}
+	return owner, nil
+}
+
+// getOrganisation retrieves the organisation of a provider, named after their company or
+// their full name
+func (s *providerTeamService) getOrganisation(owner *models.User) (*models.ProviderOrganisation, error) {
+	name := strings.TrimSpace(owner.CompanyName)
+	if name == "" {
+		name = strings.TrimSpace(owner.FirstName + " " + owner.LastName)
+	}
+	return s.organisationRepo.EnsureOrganisation(owner.ID, name)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// roleNamePattern restricts role names to lowercase letters, digits and underscores
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// RoleService interface defines methods for managing roles and their permissions
+type RoleService interface {
+	GetPermissions() ([]models.Permission, error)
+	GetRoles() ([]models.Role, error)
+	GetRole(name models.UserRole) (*models.Role, error)
+	CreateRole(requester *models.User, req *models.RoleRequest) (*models.Role, error)
+	UpdateRole(requester *models.User, name models.UserRole, req *models.RoleRequest) (*models.Role, error)
+	DeleteRole(name models.UserRole) error
+}
+
+// roleService implements RoleService
+type roleService struct {
+	roleRepo repository.RoleRepository
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(roleRepo repository.RoleRepository) RoleService {
+	return &roleService{roleRepo: roleRepo}
+}
+
+// GetPermissions retrieves every permission roles can grant
+func (s *roleService) GetPermissions() ([]models.Permission, error) {
+	return s.roleRepo.GetPermissions()
+}
+
+// GetRoles retrieves every role with its permissions
+func (s *roleService) GetRoles() ([]models.Role, error) {
+	return s.roleRepo.GetRoles()
+}
+
+// GetRole retrieves a role with its permissions
+func (s *roleService) GetRole(name models.UserRole) (*models.Role, error) {
+	role, err := s.roleRepo.GetRole(name)
+	if errors.Is(err, repository.ErrRoleNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+// CreateRole validates and creates a role granting permissions the requester holds
+func (s *roleService) CreateRole(requester *models.User, req *models.RoleRequest) (*models.Role, error) {
+	name := models.UserRole(strings.TrimSpace(string(req.Name)))
+	if !roleNamePattern.MatchString(string(name)) {
+		return nil, fmt.Errorf("%w: name must be 2 to 50 lowercase letters, digits or underscores, starting with a letter", ErrInvalidRole)
+	}
+
+	permissions, err := s.normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantable(requester, permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Permissions: permissions,
+	}
+	if err := s.roleRepo.CreateRole(role); err != nil {
+		if errors.Is(err, repository.ErrRoleExists) {
+			return nil, ErrRoleExists
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole replaces the description and the permissions of a role. Built-in roles
+// cannot be changed, as the code relies on what they grant. The requester must hold every
+// permission the role grants before and after the change.
+func (s *roleService) UpdateRole(requester *models.User, name models.UserRole, req *models.RoleRequest) (*models.Role, error) {
+	existing, err := s.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing.BuiltIn {
+		return nil, ErrBuiltInRole
+	}
+	if err := checkGrantable(requester, existing.Permissions); err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantable(requester, permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Permissions: permissions,
+	}
+	if err := s.roleRepo.UpdateRole(role); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole deletes a role that is not built in and no longer assigned to any user
+func (s *roleService) DeleteRole(name models.UserRole) error {
+	err := s.roleRepo.DeleteRole(name)
+	switch {
+	case errors.Is(err, repository.ErrRoleNotFound):
+		return ErrRoleNotFound
+	case errors.Is(err, repository.ErrRoleBuiltIn):
+		return ErrBuiltInRole
+	case errors.Is(err, repository.ErrRoleInUse):
+		return ErrRoleInUse
+	}
+	return err
+}
+
+// checkGrantable refuses permissions the requester does not hold
+func checkGrantable(requester *models.User, permissions []string) error {
+	for _, permission := range permissions {
+		if !requester.HasPermission(permission) {
+			return fmt.Errorf("%w: %s", ErrRoleEscalation, permission)
+		}
+	}
+	return nil
+}
+
+// normalizePermissions checks that every permission exists and returns them sorted without duplicates
+func (s *roleService) normalizePermissions(permissions []string) ([]string, error) {
+	known, err := s.roleRepo.GetPermissions()
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(known))
+	for _, permission := range known {
+		exists[permission.Name] = true
+	}
+
+	seen := make(map[string]bool, len(permissions))
+	normalized := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		if !exists[permission] {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrInvalidRole, permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			normalized = append(normalized, permission)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
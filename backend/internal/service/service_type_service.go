@@ -1,13 +1,14 @@
 package service
 
 import (
+	"fmt"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
 )
 
 // ServiceTypeService interface defines methods for service type operations
 type ServiceTypeService interface {
-	GetAllServiceTypes() ([]models.ServiceType, error)
+	GetAllServiceTypes(query models.ListQuery) ([]models.ServiceType, *models.PageInfo, error)
 	GetServiceTypeByID(id int) (*models.ServiceType, error)
 	CreateServiceType(serviceType *models.ServiceType) error
 	UpdateServiceType(serviceType *models.ServiceType) error
@@ -24,9 +25,10 @@ func NewServiceTypeService(serviceTypeRepo repository.ServiceTypeRepository) Ser
 	return &serviceTypeService{serviceTypeRepo: serviceTypeRepo}
 }
 
-// GetAllServiceTypes retrieves all service types
-func (s *serviceTypeService) GetAllServiceTypes() ([]models.ServiceType, error) {
-	return s.serviceTypeRepo.GetAllServiceTypes()
+// GetAllServiceTypes retrieves a page of service types
+func (s *serviceTypeService) GetAllServiceTypes(query models.ListQuery) ([]models.ServiceType, *models.PageInfo, error) {
+	serviceTypes, page, err := s.serviceTypeRepo.GetAllServiceTypes(query)
+	return serviceTypes, page, listQueryError(err)
 }
 
 // GetServiceTypeByID retrieves a service type by ID
@@ -36,14 +38,38 @@ func (s *serviceTypeService) GetServiceTypeByID(id int) (*models.ServiceType, er
 
 // CreateServiceType creates a new service type
 func (s *serviceTypeService) CreateServiceType(serviceType *models.ServiceType) error {
+	if err := normalizePricingUnit(serviceType); err != nil {
+		return err
+	}
 	return s.serviceTypeRepo.CreateServiceType(serviceType)
 }
 
-// UpdateServiceType updates a service type
+// UpdateServiceType updates a service type, keeping its pricing unit when none is given
 func (s *serviceTypeService) UpdateServiceType(serviceType *models.ServiceType) error {
+	if serviceType.PricingUnit == "" {
+		existing, err := s.serviceTypeRepo.GetServiceTypeByID(serviceType.ID)
+		if err != nil {
+			return err
+		}
+		serviceType.PricingUnit = existing.PricingUnit
+	}
+	if err := normalizePricingUnit(serviceType); err != nil {
+		return err
+	}
 	return s.serviceTypeRepo.UpdateServiceType(serviceType)
 }
 
+// normalizePricingUnit defaults an empty pricing unit to flat and rejects unknown ones
+func normalizePricingUnit(serviceType *models.ServiceType) error {
+	if serviceType.PricingUnit == "" {
+		serviceType.PricingUnit = models.PricingFlat
+	}
+	if !serviceType.PricingUnit.IsValid() {
+		return fmt.Errorf("invalid pricing unit: %s", serviceType.PricingUnit)
+	}
+	return nil
+}
+
 // DeleteServiceType deletes a service type
 func (s *serviceTypeService) DeleteServiceType(id int) error {
 	return s.serviceTypeRepo.DeleteServiceType(id)
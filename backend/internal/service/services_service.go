@@ -1,56 +1,819 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on the accommodation details of a service
+const (
+	maxRoomTypes            = 50
+	maxRoomGuests           = 50
+	maxBedsPerConfiguration = 20
+	maxRoomTypeNameLength   = 100
+	maxPropertyAddress      = 500
+	defaultCheckInTime      = "14:00"
+	defaultCheckOutTime     = "11:00"
+)
+
+// Limits on the car rental details of a service
+const (
+	maxRentalLocations     = 20
+	maxVehicleClasses      = 20
+	maxRentalNameLength    = 100
+	maxRentalAddressLength = 500
+	minDriverAge           = 16
+	maxDriverAge           = 99
+	defaultMinDriverAge    = 18
+)
+
+// Limits on catalogue searches
+const (
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 100
+	maxSearchTextLength = 200
+	maxServiceRating    = 5
 )
 
 // ServiceService interface defines methods for service operations
 type ServiceService interface {
-	GetAllServices() ([]models.Service, error)
-	GetServicesByCategory(category string) ([]models.Service, error)
+	SearchServices(filter models.ServiceFilter, query models.ListQuery) ([]models.Service, *models.PageInfo, error)
+	SearchCatalogue(query models.ServiceSearchQuery) (*models.ServiceSearchResult, error)
+	GetServicesInDestination(destinationID int, radiusKm float64, query models.ListQuery) ([]models.Service, *models.PageInfo, error)
+	GetAmenities() ([]models.Amenity, error)
+	GetServicesByOwner(requester *models.User) ([]models.Service, error)
 	GetServiceByID(id int) (*models.Service, error)
-	CreateService(service *models.Service) error
-	UpdateService(service *models.Service) error
-	DeleteService(id int) error
+	CreateService(service *models.Service, requester *models.User) error
+	UpdateService(service *models.Service, requester *models.User) error
+	DeleteService(id int, requester *models.User) error
 }
 
 // serviceService implements ServiceService
 type serviceService struct {
-	serviceRepo repository.ServiceRepository
+	serviceRepo       repository.ServiceRepository
+	serviceTypeRepo   repository.ServiceTypeRepository
+	accommodationRepo repository.AccommodationRepository
+	carRentalRepo     repository.CarRentalRepository
+	destinationRepo   repository.DestinationRepository
+	policyService     CancellationPolicyService
+	teamService       ProviderTeamService
 }
 
 // NewServiceService creates a new service service
-func NewServiceService(serviceRepo repository.ServiceRepository) ServiceService {
-	return &serviceService{serviceRepo: serviceRepo}
+func NewServiceService(
+	serviceRepo repository.ServiceRepository,
+	serviceTypeRepo repository.ServiceTypeRepository,
+	accommodationRepo repository.AccommodationRepository,
+	carRentalRepo repository.CarRentalRepository,
+	destinationRepo repository.DestinationRepository,
+	policyService CancellationPolicyService,
+	teamService ProviderTeamService,
+) ServiceService {
+	return &serviceService{
+		serviceRepo:       serviceRepo,
+		serviceTypeRepo:   serviceTypeRepo,
+		accommodationRepo: accommodationRepo,
+		carRentalRepo:     carRentalRepo,
+		destinationRepo:   destinationRepo,
+		policyService:     policyService,
+		teamService:       teamService,
+	}
+}
+
+// SearchServices retrieves a page of the available services matching a filter with their
+// accommodation and car rental details
+func (s *serviceService) SearchServices(filter models.ServiceFilter, query models.ListQuery) ([]models.Service, *models.PageInfo, error) {
+	if err := s.validateFilter(&filter); err != nil {
+		return nil, nil, err
+	}
+
+	services, page, err := s.serviceRepo.SearchServices(filter, query)
+	if err != nil {
+		return nil, nil, listQueryError(err)
+	}
+	return services, page, s.attachDetails(services)
+}
+
+// GetServicesInDestination retrieves a page of the available services located inside the area
+// of a destination with their details. Without an area, services within a radius of the
+// destination are retrieved, 25 km by default.
+func (s *serviceService) GetServicesInDestination(destinationID int, radiusKm float64, query models.ListQuery) ([]models.Service, *models.PageInfo, error) {
+	area, err := s.destinationArea(destinationID, radiusKm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	services, page, err := s.serviceRepo.GetServicesInArea(area, query)
+	if err != nil {
+		return nil, nil, listQueryError(err)
+	}
+	return services, page, s.attachDetails(services)
+}
+
+// SearchCatalogue retrieves a page of the available services matching a full-text search with
+// their details and the facet counts of every matching service. Results are sorted by
+// relevance when there are search words and by name otherwise.
+func (s *serviceService) SearchCatalogue(query models.ServiceSearchQuery) (*models.ServiceSearchResult, error) {
+	if err := s.validateFilter(&query.ServiceFilter); err != nil {
+		return nil, err
+	}
+
+	query.Text = strings.TrimSpace(query.Text)
+	if utf8.RuneCountInString(query.Text) > maxSearchTextLength {
+		return nil, fmt.Errorf("%w: search words must be at most %d characters", ErrInvalidServiceQuery, maxSearchTextLength)
+	}
+	if query.ServiceTypeID < 0 || query.DestinationID < 0 {
+		return nil, fmt.Errorf("%w: service type and destination IDs must be positive", ErrInvalidServiceQuery)
+	}
+	if (query.MinPrice != nil && *query.MinPrice < 0) || (query.MaxPrice != nil && *query.MaxPrice < 0) {
+		return nil, fmt.Errorf("%w: prices cannot be negative", ErrInvalidServiceQuery)
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return nil, fmt.Errorf("%w: minimum price is above the maximum price", ErrInvalidServiceQuery)
+	}
+	if query.MinRating < 0 || query.MinRating > maxServiceRating {
+		return nil, fmt.Errorf("%w: minimum rating must be between 0 and %d", ErrInvalidServiceQuery, maxServiceRating)
+	}
+	if query.From.IsZero() != query.To.IsZero() {
+		return nil, fmt.Errorf("%w: both from and to are required to search by dates", ErrInvalidServiceQuery)
+	}
+	if !query.From.IsZero() && !query.To.After(query.From) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidServiceQuery, ErrInvalidBookingDates)
+	}
+
+	if query.Sort == "" {
+		query.Sort = models.ServiceSortName
+		if query.Text != "" {
+			query.Sort = models.ServiceSortRelevance
+		}
+	}
+	if !query.Sort.IsValid() {
+		return nil, fmt.Errorf("%w: unknown sort order %q", ErrInvalidServiceQuery, query.Sort)
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultSearchLimit
+	}
+	if query.Limit < 0 || query.Limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidServiceQuery, maxSearchLimit)
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset cannot be negative", ErrInvalidServiceQuery)
+	}
+
+	result, err := s.serviceRepo.SearchCatalogue(query)
+	if err != nil {
+		return nil, err
+	}
+	return result, s.attachDetails(result.Services)
 }
 
-// GetAllServices retrieves all services
-func (s *serviceService) GetAllServices() ([]models.Service, error) {
-	return s.serviceRepo.GetAllServices()
+// validateFilter checks the accommodation criteria of a service filter and replaces its
+// amenities with their catalogue codes
+func (s *serviceService) validateFilter(filter *models.ServiceFilter) error {
+	if filter.Guests < 0 {
+		return fmt.Errorf("%w: guests must be positive", ErrInvalidServiceQuery)
+	}
+	if filter.BedType != "" && !filter.BedType.IsValid() {
+		return fmt.Errorf("%w: unknown bed type %q", ErrInvalidServiceQuery, filter.BedType)
+	}
+	if len(filter.Amenities) > 0 {
+		amenities, err := s.resolveAmenities(filter.Amenities)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidServiceQuery, err)
+		}
+		filter.Amenities = filter.Amenities[:0]
+		for _, amenity := range amenities {
+			filter.Amenities = append(filter.Amenities, amenity.Code)
+		}
+	}
+	return nil
 }
 
-// GetServicesByCategory retrieves services by category
-func (s *serviceService) GetServicesByCategory(category string) ([]models.Service, error) {
-	return s.serviceRepo.GetServicesByCategory(category)
+// GetAmenities retrieves the amenity catalogue accommodations choose from
+func (s *serviceService) GetAmenities() ([]models.Amenity, error) {
+	return s.accommodationRepo.GetAmenities()
 }
 
-// GetServiceByID retrieves a service by ID
+// GetServicesByOwner retrieves the catalogue of the provider the requester acts for, including
+// unavailable services
+func (s *serviceService) GetServicesByOwner(requester *models.User) ([]models.Service, error) {
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeServices)
+	if err != nil {
+		return nil, err
+	}
+	services, err := s.serviceRepo.GetServicesByUserID(provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	return services, s.attachDetails(services)
+}
+
+// GetServiceByID retrieves a service by ID with its accommodation or car rental details
 func (s *serviceService) GetServiceByID(id int) (*models.Service, error) {
-	return s.serviceRepo.GetServiceByID(id)
+	service, err := s.serviceRepo.GetServiceByID(id)
+	if errors.Is(err, repository.ErrServiceNotFound) {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := s.accommodationRepo.GetDetails([]int{service.ID})
+	if err != nil {
+		return nil, err
+	}
+	service.Accommodation = details[service.ID]
+
+	rentals, err := s.carRentalRepo.GetDetails([]int{service.ID})
+	if err != nil {
+		return nil, err
+	}
+	service.CarRental = rentals[service.ID]
+	return service, nil
 }
 
-// CreateService creates a new service
-func (s *serviceService) CreateService(service *models.Service) error {
-	return s.serviceRepo.CreateService(service)
+// CreateService creates a new service owned by the provider the requester acts for; providers
+// must be verified to publish services
+func (s *serviceService) CreateService(service *models.Service, requester *models.User) error {
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeServices)
+	if err != nil {
+		return err
+	}
+	if err := authorizePublishing(provider); err != nil {
+		return err
+	}
+	service.UserID = &provider.ID
+	if err := s.validateDestination(service.DestinationID); err != nil {
+		return err
+	}
+	if err := validateCoordinates(service.Latitude, service.Longitude); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidServiceLocation, err)
+	}
+	if err := s.prepareAccommodation(service); err != nil {
+		return err
+	}
+	if err := s.prepareCarRental(service, nil); err != nil {
+		return err
+	}
+	if service.Capacity <= 0 {
+		service.Capacity = 1
+	}
+	if err := s.policyService.ValidatePolicyID(service.CancellationPolicyID, requester, provider); err != nil {
+		return err
+	}
+	if err := s.serviceRepo.CreateService(service); err != nil {
+		return err
+	}
+	if err := s.saveAccommodation(service); err != nil {
+		return err
+	}
+	return s.saveCarRental(service)
 }
 
-// UpdateService updates a service
-func (s *serviceService) UpdateService(service *models.Service) error {
-	return s.serviceRepo.UpdateService(service)
+// UpdateService updates a service; providers must be verified to publish services
+func (s *serviceService) UpdateService(service *models.Service, requester *models.User) error {
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeServices)
+	if err != nil {
+		return err
+	}
+	if err := authorizePublishing(provider); err != nil {
+		return err
+	}
+	existing, err := s.GetServiceByID(service.ID)
+	if err != nil {
+		return err
+	}
+	if err := authorizeServiceOwnership(existing, provider); err != nil {
+		return err
+	}
+	service.UserID = existing.UserID
+	if err := s.validateDestination(service.DestinationID); err != nil {
+		return err
+	}
+	if err := validateCoordinates(service.Latitude, service.Longitude); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidServiceLocation, err)
+	}
+	// Without new accommodation details the service keeps its own, as long as its type still takes them
+	keepAccommodation := service.Accommodation == nil
+	if keepAccommodation {
+		accepts, err := s.acceptsAccommodation(service.ServiceTypeID)
+		if err != nil {
+			return err
+		}
+		if accepts && existing.Accommodation != nil {
+			service.Accommodation = existing.Accommodation
+			if service.Capacity <= 0 {
+				service.Capacity = existing.Accommodation.NumberOfRooms
+			}
+		}
+	} else if err := s.prepareAccommodation(service); err != nil {
+		return err
+	}
+	// The same goes for car rental details, but a car rental keeps its fleet while it is one
+	keepCarRental := service.CarRental == nil
+	if keepCarRental {
+		accepts, err := s.acceptsCarRental(service.ServiceTypeID)
+		if err != nil {
+			return err
+		}
+		if accepts && existing.CarRental != nil {
+			service.CarRental = existing.CarRental
+			service.Capacity = fleetCapacity(existing.CarRental)
+		} else if existing.CarRental != nil {
+			if err := s.checkFleetRemovable(service.ID); err != nil {
+				return err
+			}
+		}
+	} else if err := s.prepareCarRental(service, existing.CarRental); err != nil {
+		return err
+	}
+	// Without a new capacity the service keeps its own
+	if service.Capacity <= 0 {
+		service.Capacity = existing.Capacity
+	}
+	if err := s.policyService.ValidatePolicyID(service.CancellationPolicyID, requester, provider); err != nil {
+		return err
+	}
+	if err := s.serviceRepo.UpdateService(service); err != nil {
+		if errors.Is(err, repository.ErrServiceNotFound) {
+			return ErrServiceNotFound
+		}
+		return err
+	}
+
+	switch {
+	case service.Accommodation == nil && existing.Accommodation != nil:
+		if err := s.accommodationRepo.DeleteDetails(service.ID); err != nil {
+			return err
+		}
+	case !keepAccommodation:
+		if err := s.saveAccommodation(service); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case service.CarRental == nil && existing.CarRental != nil:
+		return s.carRentalRepo.DeleteDetails(service.ID)
+	case keepCarRental:
+		return nil
+	default:
+		return s.saveCarRental(service)
+	}
 }
 
-// DeleteService deletes a service
-func (s *serviceService) DeleteService(id int) error {
+// DeleteService deletes a service of the provider the requester acts for
+func (s *serviceService) DeleteService(id int, requester *models.User) error {
+	provider, err := s.teamService.ResolveProvider(requester, models.OrganisationScopeServices)
+	if err != nil {
+		return err
+	}
+	existing, err := s.GetServiceByID(id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeServiceOwnership(existing, provider); err != nil {
+		return err
+	}
 	return s.serviceRepo.DeleteService(id)
 }
+
+// authorizePublishing checks that a provider passed the verification review. Staff roles
+// that manage the catalogue do not need to be verified.
+func authorizePublishing(requester *models.User) error {
+	if requester.IsProvider() && !requester.Verified {
+		return ErrProviderNotVerified
+	}
+	return nil
+}
+
+// authorizeServiceOwnership checks that the requester owns a service or has a role that grants
+// the services:manage permission. Platform services without an owner need the permission.
+func authorizeServiceOwnership(service *models.Service, requester *models.User) error {
+	if service.UserID != nil && *service.UserID == requester.ID {
+		return nil
+	}
+	if !requester.HasPermission(models.PermissionServicesManage) {
+		return ErrServiceAccessDenied
+	}
+	return nil
+}
+
+// attachDetails loads the accommodation and car rental details of services
+func (s *serviceService) attachDetails(services []models.Service) error {
+	if len(services) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(services))
+	for i, service := range services {
+		ids[i] = service.ID
+	}
+	details, err := s.accommodationRepo.GetDetails(ids)
+	if err != nil {
+		return err
+	}
+	rentals, err := s.carRentalRepo.GetDetails(ids)
+	if err != nil {
+		return err
+	}
+	for i := range services {
+		services[i].Accommodation = details[services[i].ID]
+		services[i].CarRental = rentals[services[i].ID]
+	}
+	return nil
+}
+
+// prepareAccommodation validates the accommodation details of a service, fills in their
+// defaults and resolves their amenity codes. Only services priced per night can have them;
+// a service without a capacity can hold as many bookings as it has rooms.
+func (s *serviceService) prepareAccommodation(service *models.Service) error {
+	details := service.Accommodation
+	if details == nil {
+		return nil
+	}
+
+	accepts, err := s.acceptsAccommodation(service.ServiceTypeID)
+	if err != nil {
+		return err
+	}
+	if !accepts {
+		return fmt.Errorf("%w: only services priced per night can have accommodation details", ErrInvalidAccommodation)
+	}
+
+	details.PropertyAddress = strings.TrimSpace(details.PropertyAddress)
+	if utf8.RuneCountInString(details.PropertyAddress) > maxPropertyAddress {
+		return fmt.Errorf("%w: property address is longer than %d characters", ErrInvalidAccommodation, maxPropertyAddress)
+	}
+	if details.CheckInTime, err = normalizeClockTime(details.CheckInTime, defaultCheckInTime); err != nil {
+		return fmt.Errorf("%w: check-in time must be HH:MM", ErrInvalidAccommodation)
+	}
+	if details.CheckOutTime, err = normalizeClockTime(details.CheckOutTime, defaultCheckOutTime); err != nil {
+		return fmt.Errorf("%w: check-out time must be HH:MM", ErrInvalidAccommodation)
+	}
+
+	if len(details.RoomTypes) == 0 || len(details.RoomTypes) > maxRoomTypes {
+		return fmt.Errorf("%w: between 1 and %d room types are required", ErrInvalidAccommodation, maxRoomTypes)
+	}
+	rooms := 0
+	for i := range details.RoomTypes {
+		if err := validateRoomType(&details.RoomTypes[i]); err != nil {
+			return err
+		}
+		rooms += details.RoomTypes[i].Quantity
+	}
+
+	codes := make([]string, len(details.Amenities))
+	for i, amenity := range details.Amenities {
+		codes[i] = amenity.Code
+	}
+	if details.Amenities, err = s.resolveAmenities(codes); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAccommodation, err)
+	}
+
+	if service.Capacity <= 0 {
+		service.Capacity = rooms
+	}
+	return nil
+}
+
+// acceptsAccommodation checks whether services of a type can have accommodation details,
+// which is the case of the types priced per night
+func (s *serviceService) acceptsAccommodation(serviceTypeID int) (bool, error) {
+	serviceType, err := s.serviceTypeRepo.GetServiceTypeByID(serviceTypeID)
+	if err != nil {
+		return false, err
+	}
+	return serviceType.PricingUnit == models.PricingPerNight, nil
+}
+
+// saveAccommodation stores the accommodation details of a service, if it has any
+func (s *serviceService) saveAccommodation(service *models.Service) error {
+	if service.Accommodation == nil {
+		return nil
+	}
+	service.Accommodation.ServiceID = service.ID
+	return s.accommodationRepo.SaveDetails(service.Accommodation)
+}
+
+// validateDestination checks that the destination a service is offered at exists
+func (s *serviceService) validateDestination(destinationID *int) error {
+	if destinationID == nil {
+		return nil
+	}
+	_, err := s.destinationRepo.GetDestinationByID(*destinationID)
+	if errors.Is(err, repository.ErrDestinationNotFound) {
+		return fmt.Errorf("%w: destination %d does not exist", ErrInvalidServiceDestination, *destinationID)
+	}
+	return err
+}
+
+// destinationArea returns where the services of a destination are: inside its area or, without
+// one, within a radius of its coordinates
+func (s *serviceService) destinationArea(destinationID int, radiusKm float64) (models.GeoArea, error) {
+	if radiusKm == 0 {
+		radiusKm = defaultAreaRadiusKm
+	}
+	if !(radiusKm > 0 && radiusKm <= maxSearchRadiusKm) {
+		return models.GeoArea{}, fmt.Errorf("%w: radius must be between 0 and %d km", ErrInvalidServiceQuery, maxSearchRadiusKm)
+	}
+
+	destination, err := s.destinationRepo.GetDestinationByID(destinationID)
+	if errors.Is(err, repository.ErrDestinationNotFound) {
+		return models.GeoArea{}, ErrDestinationNotFound
+	}
+	if err != nil {
+		return models.GeoArea{}, err
+	}
+	if len(destination.Area) > 0 {
+		return models.GeoArea{Polygon: destination.Area}, nil
+	}
+	if destination.Latitude == nil || destination.Longitude == nil {
+		return models.GeoArea{}, ErrDestinationNotLocated
+	}
+	return models.GeoArea{
+		Center:   models.GeoPoint{Lat: *destination.Latitude, Lng: *destination.Longitude},
+		RadiusKm: radiusKm,
+	}, nil
+}
+
+// resolveAmenities looks amenity codes up in the catalogue, ignoring case and duplicates
+func (s *serviceService) resolveAmenities(codes []string) ([]models.Amenity, error) {
+	if len(codes) == 0 {
+		return []models.Amenity{}, nil
+	}
+
+	catalogue, err := s.accommodationRepo.GetAmenities()
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.Amenity, len(catalogue))
+	for _, amenity := range catalogue {
+		byCode[amenity.Code] = amenity
+	}
+
+	amenities := []models.Amenity{}
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if seen[code] {
+			continue
+		}
+		amenity, ok := byCode[code]
+		if !ok {
+			return nil, fmt.Errorf("unknown amenity %q", code)
+		}
+		seen[code] = true
+		amenities = append(amenities, amenity)
+	}
+	return amenities, nil
+}
+
+// validateRoomType checks a room type and its beds, a missing quantity meaning one room
+func validateRoomType(room *models.RoomType) error {
+	room.Name = strings.TrimSpace(room.Name)
+	room.Description = strings.TrimSpace(room.Description)
+	if room.Name == "" || utf8.RuneCountInString(room.Name) > maxRoomTypeNameLength {
+		return fmt.Errorf("%w: room type names are required and at most %d characters", ErrInvalidAccommodation, maxRoomTypeNameLength)
+	}
+	if room.MaxGuests <= 0 || room.MaxGuests > maxRoomGuests {
+		return fmt.Errorf("%w: room type %q must sleep between 1 and %d guests", ErrInvalidAccommodation, room.Name, maxRoomGuests)
+	}
+	if room.Quantity == 0 {
+		room.Quantity = 1
+	}
+	if room.Quantity < 0 {
+		return fmt.Errorf("%w: room type %q must have at least one room", ErrInvalidAccommodation, room.Name)
+	}
+
+	if len(room.Beds) == 0 {
+		return fmt.Errorf("%w: room type %q needs at least one bed", ErrInvalidAccommodation, room.Name)
+	}
+	for _, bed := range room.Beds {
+		if !bed.Type.IsValid() {
+			return fmt.Errorf("%w: unknown bed type %q", ErrInvalidAccommodation, bed.Type)
+		}
+		if bed.Count <= 0 || bed.Count > maxBedsPerConfiguration {
+			return fmt.Errorf("%w: room type %q must have between 1 and %d beds of each type", ErrInvalidAccommodation, room.Name, maxBedsPerConfiguration)
+		}
+	}
+	return nil
+}
+
+// normalizeClockTime checks a time of day written HH:MM, falling back to a default when empty
+func normalizeClockTime(value, fallback string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format("15:04"), nil
+}
+
+// prepareCarRental validates the car rental details of a service against its current ones and
+// fills in their defaults. Only services priced per day can have them; a car rental can hold
+// as many bookings as it has active vehicles.
+func (s *serviceService) prepareCarRental(service *models.Service, existing *models.CarRentalDetails) error {
+	details := service.CarRental
+	if details == nil {
+		return nil
+	}
+
+	accepts, err := s.acceptsCarRental(service.ServiceTypeID)
+	if err != nil {
+		return err
+	}
+	if !accepts {
+		return fmt.Errorf("%w: only services priced per day can have car rental details", ErrInvalidCarRental)
+	}
+
+	if details.MinDriverAge == 0 {
+		details.MinDriverAge = defaultMinDriverAge
+	}
+	if details.MinDriverAge < minDriverAge || details.MinDriverAge > maxDriverAge {
+		return fmt.Errorf("%w: minimum driver age must be between %d and %d", ErrInvalidCarRental, minDriverAge, maxDriverAge)
+	}
+
+	current := &models.CarRentalDetails{}
+	if existing != nil {
+		current = existing
+	}
+
+	if len(details.Locations) > maxRentalLocations {
+		return fmt.Errorf("%w: at most %d locations are allowed", ErrInvalidCarRental, maxRentalLocations)
+	}
+	knownLocations := make(map[int]bool, len(current.Locations))
+	for _, location := range current.Locations {
+		knownLocations[location.ID] = true
+	}
+	hasPickup, hasDropoff := false, false
+	for i := range details.Locations {
+		location := &details.Locations[i]
+		if err := validateRentalLocation(location, knownLocations); err != nil {
+			return err
+		}
+		hasPickup = hasPickup || location.IsPickup
+		hasDropoff = hasDropoff || location.IsDropoff
+	}
+	if len(details.Locations) > 0 && (!hasPickup || !hasDropoff) {
+		return fmt.Errorf("%w: at least one pickup and one dropoff location are required", ErrInvalidCarRental)
+	}
+
+	if len(details.VehicleClasses) == 0 || len(details.VehicleClasses) > maxVehicleClasses {
+		return fmt.Errorf("%w: between 1 and %d vehicle classes are required", ErrInvalidCarRental, maxVehicleClasses)
+	}
+	knownClasses := make(map[int]bool, len(current.VehicleClasses))
+	for _, class := range current.VehicleClasses {
+		knownClasses[class.ID] = true
+	}
+	names := make(map[string]bool, len(details.VehicleClasses))
+	kept := make(map[int]bool, len(details.VehicleClasses))
+	for i := range details.VehicleClasses {
+		class := &details.VehicleClasses[i]
+		if err := validateVehicleClass(class, knownClasses); err != nil {
+			return err
+		}
+		name := strings.ToLower(class.Name)
+		if names[name] {
+			return fmt.Errorf("%w: duplicate vehicle class %q", ErrInvalidCarRental, class.Name)
+		}
+		names[name] = true
+		kept[class.ID] = true
+	}
+
+	// Classes can only be removed once no vehicle belongs to them
+	removesClass := false
+	for _, class := range current.VehicleClasses {
+		removesClass = removesClass || !kept[class.ID]
+	}
+	if removesClass {
+		vehicles, err := s.carRentalRepo.GetVehicles(service.ID, false)
+		if err != nil {
+			return err
+		}
+		for _, vehicle := range vehicles {
+			if !kept[vehicle.ClassID] {
+				return fmt.Errorf("%w: vehicle class %q still has vehicles, move or remove them first", ErrInvalidCarRental, vehicle.ClassName)
+			}
+		}
+	}
+
+	service.Capacity = fleetCapacity(existing)
+	return nil
+}
+
+// acceptsCarRental checks whether services of a type can have car rental details, which is
+// the case of the types priced per day
+func (s *serviceService) acceptsCarRental(serviceTypeID int) (bool, error) {
+	serviceType, err := s.serviceTypeRepo.GetServiceTypeByID(serviceTypeID)
+	if err != nil {
+		return false, err
+	}
+	return serviceType.PricingUnit == models.PricingPerDay, nil
+}
+
+// saveCarRental stores the car rental details of a service, if it has any, and reloads them
+// with their vehicle counts
+func (s *serviceService) saveCarRental(service *models.Service) error {
+	if service.CarRental == nil {
+		return nil
+	}
+	service.CarRental.ServiceID = service.ID
+	if err := s.carRentalRepo.SaveDetails(service.CarRental); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRentalLocationNotFound):
+			return fmt.Errorf("%w: unknown rental location", ErrInvalidCarRental)
+		case errors.Is(err, repository.ErrVehicleClassNotFound):
+			return fmt.Errorf("%w: unknown vehicle class", ErrInvalidCarRental)
+		}
+		return err
+	}
+
+	details, err := s.carRentalRepo.GetDetails([]int{service.ID})
+	if err != nil {
+		return err
+	}
+	service.CarRental = details[service.ID]
+	return nil
+}
+
+// checkFleetRemovable checks that a car rental has no vehicles left before it stops being one
+func (s *serviceService) checkFleetRemovable(serviceID int) error {
+	vehicles, err := s.carRentalRepo.GetVehicles(serviceID, false)
+	if err != nil {
+		return err
+	}
+	if len(vehicles) > 0 {
+		return fmt.Errorf("%w: remove the vehicles of the car rental before changing its service type", ErrInvalidCarRental)
+	}
+	return nil
+}
+
+// fleetCapacity returns the capacity of a car rental, the number of its active vehicles but
+// at least one
+func fleetCapacity(details *models.CarRentalDetails) int {
+	if details == nil || details.NumberOfVehicles < 1 {
+		return 1
+	}
+	return details.NumberOfVehicles
+}
+
+// validateRentalLocation checks a pickup or dropoff location. A location with an ID must be
+// one of the known locations of the car rental, and is taken off them once seen.
+func validateRentalLocation(location *models.RentalLocation, known map[int]bool) error {
+	location.Name = strings.TrimSpace(location.Name)
+	location.Address = strings.TrimSpace(location.Address)
+	if location.Name == "" || utf8.RuneCountInString(location.Name) > maxRentalNameLength {
+		return fmt.Errorf("%w: location names are required and at most %d characters", ErrInvalidCarRental, maxRentalNameLength)
+	}
+	if utf8.RuneCountInString(location.Address) > maxRentalAddressLength {
+		return fmt.Errorf("%w: location %q has an address longer than %d characters", ErrInvalidCarRental, location.Name, maxRentalAddressLength)
+	}
+	if !location.IsPickup && !location.IsDropoff {
+		return fmt.Errorf("%w: location %q must be a pickup location, a dropoff location or both", ErrInvalidCarRental, location.Name)
+	}
+	if location.ID != 0 {
+		if !known[location.ID] {
+			return fmt.Errorf("%w: unknown rental location %d", ErrInvalidCarRental, location.ID)
+		}
+		delete(known, location.ID)
+	}
+	return nil
+}
+
+// validateVehicleClass checks a vehicle class and rounds its rates to cents. A class with an
+// ID must be one of the known classes of the car rental, and is taken off them once seen.
+func validateVehicleClass(class *models.VehicleClass, known map[int]bool) error {
+	class.Name = strings.TrimSpace(class.Name)
+	class.Description = strings.TrimSpace(class.Description)
+	if class.Name == "" || utf8.RuneCountInString(class.Name) > maxRentalNameLength {
+		return fmt.Errorf("%w: vehicle class names are required and at most %d characters", ErrInvalidCarRental, maxRentalNameLength)
+	}
+	if class.DailyRate <= 0 {
+		return fmt.Errorf("%w: vehicle class %q needs a positive daily rate", ErrInvalidCarRental, class.Name)
+	}
+	if class.MileageLimitKm != nil && *class.MileageLimitKm <= 0 {
+		return fmt.Errorf("%w: vehicle class %q needs a positive mileage limit, or none for unlimited mileage", ErrInvalidCarRental, class.Name)
+	}
+	if class.ExtraKmFee < 0 {
+		return fmt.Errorf("%w: vehicle class %q cannot have a negative extra km fee", ErrInvalidCarRental, class.Name)
+	}
+	class.DailyRate = roundMoney(class.DailyRate)
+	class.ExtraKmFee = roundMoney(class.ExtraKmFee)
+
+	if class.ID != 0 {
+		if !known[class.ID] {
+			return fmt.Errorf("%w: unknown vehicle class %d", ErrInvalidCarRental, class.ID)
+		}
+		delete(known, class.ID)
+	}
+	return nil
+}
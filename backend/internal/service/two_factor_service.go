@@ -0,0 +1,297 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"nomado-houses/internal/models"
+	"nomado-houses/internal/repository"
+	"os"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is how many recovery codes are generated at once
+const recoveryCodeCount = 10
+
+// TwoFactorService interface defines methods for managing TOTP two-factor authentication
+type TwoFactorService interface {
+	GetStatus(userID int) (*models.TwoFactorStatus, error)
+	Setup(userID int) (*models.TwoFactorSetup, error)
+	Enable(userID int, sessionID, code string) (*models.RecoveryCodesResponse, error)
+	Disable(userID int, code string, client models.ClientInfo) error
+	RegenerateRecoveryCodes(userID int, code string, client models.ClientInfo) (*models.RecoveryCodesResponse, error)
+}
+
+// twoFactorService implements TwoFactorService
+type twoFactorService struct {
+	twoFactorRepo repository.TwoFactorRepository
+	userRepo      repository.UserRepository
+	sessionRepo   repository.SessionRepository
+	guard         *bruteForceGuard
+	requiredRoles map[models.UserRole]bool
+}
+
+// NewTwoFactorService creates a new two-factor service
+func NewTwoFactorService(twoFactorRepo repository.TwoFactorRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, throttleRepo repository.AuthThrottleRepository) TwoFactorService {
+	return &twoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		guard:         newBruteForceGuard(throttleRepo, NewEmailService()),
+		requiredRoles: twoFactorRolesFromEnv(),
+	}
+}
+
+// GetStatus describes the two-factor authentication of a user
+func (s *twoFactorService) GetStatus(userID int) (*models.TwoFactorStatus, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Required: s.requiredRoles[user.Role]}
+	tf, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return status, nil
+		}
+		return nil, err
+	}
+	if !tf.IsEnabled() {
+		return status, nil
+	}
+
+	status.Enabled = true
+	status.EnabledAt = tf.EnabledAt
+	status.RecoveryCodesRemaining, err = s.twoFactorRepo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Setup starts enrolling an authenticator app. Two-factor authentication is only enabled
+// once Enable receives a code from the app.
+func (s *twoFactorService) Setup(userID int) (*models.TwoFactorSetup, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.SaveSecret(userID, encrypted); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorEnabled) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &models.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(secret, user.Email),
+	}, nil
+}
+
+// Enable finishes enrolling with a code from the authenticator app and returns the recovery
+// codes. The session it is called from counts as having passed the second factor.
+func (s *twoFactorService) Enable(userID int, sessionID, code string) (*models.RecoveryCodesResponse, error) {
+	tf, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return nil, ErrTwoFactorNotSetUp
+		}
+		return nil, err
+	}
+	if tf.IsEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := decryptTOTPSecret(tf.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.EnableTwoFactor(userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorEnabled) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	if sessionID != "" {
+		if err := s.sessionRepo.MarkTwoFactorVerified(sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off after checking a code. Users whose role
+// requires two-factor authentication cannot turn it off.
+func (s *twoFactorService) Disable(userID int, code string, client models.ClientInfo) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if s.requiredRoles[user.Role] {
+		return ErrTwoFactorRequiredByPolicy
+	}
+
+	tf, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	if err := verifyGuardedSecondFactor(s.guard, s.twoFactorRepo, user, tf, code, client); err != nil {
+		return err
+	}
+
+	return s.twoFactorRepo.DisableTwoFactor(userID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user after checking a code
+func (s *twoFactorService) RegenerateRecoveryCodes(userID int, code string, client models.ClientInfo) (*models.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tf, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyGuardedSecondFactor(s.guard, s.twoFactorRepo, user, tf, code, client); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// enabledTwoFactor retrieves the TOTP enrolment of a user who finished enrolling
+func (s *twoFactorService) enabledTwoFactor(userID int) (*models.UserTwoFactor, error) {
+	tf, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, err
+	}
+	if !tf.IsEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return tf, nil
+}
+
+// verifyGuardedSecondFactor checks the second factor of a user under the brute-force guard.
+// Wrong codes count against the account like wrong passwords, so it gets delayed and locked
+// out however the codes are tried.
+func verifyGuardedSecondFactor(guard *bruteForceGuard, repo repository.TwoFactorRepository, user *models.User, tf *models.UserTwoFactor, code string, client models.ClientInfo) error {
+	const action = models.AuthActionTwoFactor
+	if err := guard.Check(action, user.Email, client); err != nil {
+		return err
+	}
+
+	err := verifySecondFactor(repo, tf, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if guardErr := guard.Fail(action, failureInvalidCode, user.Email, user, client); guardErr != nil {
+			return guardErr
+		}
+	}
+	return err
+}
+
+// verifySecondFactor checks a TOTP code, which works once, or uses up a recovery code
+func verifySecondFactor(repo repository.TwoFactorRepository, tf *models.UserTwoFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if len(code) == totpDigits && strings.Trim(code, "0123456789") == "" {
+		secret, err := decryptTOTPSecret(tf.Secret)
+		if err != nil {
+			return err
+		}
+		step, ok := validateTOTP(secret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		if err := repo.UseTOTPStep(tf.UserID, step); err != nil {
+			if errors.Is(err, repository.ErrTOTPStepUsed) {
+				return ErrInvalidTwoFactorCode
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := repo.UseRecoveryCode(tf.UserID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	return nil
+}
+
+// generateRecoveryCodes generates recovery codes such as "k3v9q-x7m2p" and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789" // 32 symbols, so bytes map without bias
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		for j := range b {
+			b[j] = alphabet[int(b[j])%len(alphabet)]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes in recovery codes
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+// twoFactorRolesFromEnv reads the roles that must use two-factor authentication from
+// TWO_FACTOR_REQUIRED_ROLES, a comma-separated list such as "admin,provider"
+func twoFactorRolesFromEnv() map[models.UserRole]bool {
+	roles := make(map[models.UserRole]bool)
+	for _, role := range strings.Split(os.Getenv("TWO_FACTOR_REQUIRED_ROLES"), ",") {
+		role := models.UserRole(strings.TrimSpace(role))
+		if role != "" {
+			roles[role] = true
+		}
+	}
+	return roles
+}
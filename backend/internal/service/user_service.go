@@ -1,6 +1,8 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
 )
@@ -10,8 +12,8 @@ type UserService interface {
 	GetUserByID(id int) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	UpdateUser(user *models.User) error
-	UpdateUserRole(userID int, role models.UserRole) error
-	GetAllUsers() ([]models.User, error)
+	UpdateUserRole(requester *models.User, userID int, role models.UserRole) error
+	GetAllUsers(query models.ListQuery) ([]models.User, *models.PageInfo, error)
 	GetUsersByRole(role models.UserRole) ([]models.User, error)
 	DeleteUser(id int) error
 }
@@ -19,11 +21,12 @@ type UserService interface {
 // userService implements UserService
 type userService struct {
 	userRepo repository.UserRepository
+	roleRepo repository.RoleRepository
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository) UserService {
+	return &userService{userRepo: userRepo, roleRepo: roleRepo}
 }
 
 // GetUserByID retrieves a user by ID
@@ -41,18 +44,47 @@ func (s *userService) UpdateUser(user *models.User) error {
 	return s.userRepo.UpdateUser(user)
 }
 
-// UpdateUserRole updates a user's role
-func (s *userService) UpdateUserRole(userID int, role models.UserRole) error {
+// UpdateUserRole assigns an existing role to another user. The requester must hold every
+// permission of both the new role and the current role of the user, so nobody can hand out
+// or take away more than they have.
+func (s *userService) UpdateUserRole(requester *models.User, userID int, role models.UserRole) error {
+	if userID == requester.ID {
+		return ErrOwnRole
+	}
+
+	granted, err := s.roleRepo.GetRole(role)
+	if err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+		}
+		return err
+	}
+	if err := checkGrantable(requester, granted.Permissions); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkGrantable(requester, user.Permissions); err != nil {
+		return err
+	}
+
 	return s.userRepo.UpdateUserRole(userID, role)
 }
 
-// GetAllUsers retrieves all users (admin only)
-func (s *userService) GetAllUsers() ([]models.User, error) {
-	return s.userRepo.GetAllUsers()
+// GetAllUsers retrieves a page of users (admin only)
+func (s *userService) GetAllUsers(query models.ListQuery) ([]models.User, *models.PageInfo, error) {
+	users, page, err := s.userRepo.GetAllUsers(query)
+	return users, page, listQueryError(err)
 }
 
 // GetUsersByRole retrieves users by role
 func (s *userService) GetUsersByRole(role models.UserRole) ([]models.User, error) {
+	if err := s.checkRoleExists(role); err != nil {
+		return nil, err
+	}
 	return s.userRepo.GetUsersByRole(role)
 }
 
@@ -60,3 +92,14 @@ func (s *userService) GetUsersByRole(role models.UserRole) ([]models.User, error
 func (s *userService) DeleteUser(id int) error {
 	return s.userRepo.DeleteUser(id)
 }
+
+// checkRoleExists checks that a role is stored in the roles table
+func (s *userService) checkRoleExists(role models.UserRole) error {
+	if _, err := s.roleRepo.GetRole(role); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+		}
+		return err
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	appHandlers "nomado-houses/internal/handlers"
 	"nomado-houses/internal/logger"
 	"nomado-houses/internal/middleware"
+	"nomado-houses/internal/models"
 	"nomado-houses/internal/repository"
 	"nomado-houses/internal/service"
 
@@ -66,17 +67,63 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(database.DB, logInstance)
 	destinationRepo := repository.NewDestinationRepository(database.DB, logInstance)
+	placeRepo := repository.NewPlaceRepository(database.DB, logInstance)
 	serviceRepo := repository.NewServiceRepository(database.DB, logInstance)
 	serviceTypeRepo := repository.NewServiceTypeRepository(database.DB, logInstance)
 	bookingRepo := repository.NewBookingRepository(database.DB, logInstance)
+	paymentRepo := repository.NewPaymentRepository(database.DB, logInstance)
+	cancellationPolicyRepo := repository.NewCancellationPolicyRepository(database.DB, logInstance)
+	paymentEventRepo := repository.NewPaymentEventRepository(database.DB, logInstance)
+	sessionRepo := repository.NewSessionRepository(database.DB, logInstance)
+	passwordResetRepo := repository.NewPasswordResetRepository(database.DB, logInstance)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(database.DB, logInstance)
+	twoFactorRepo := repository.NewTwoFactorRepository(database.DB, logInstance)
+	oidcRepo := repository.NewOIDCRepository(database.DB, logInstance)
+	authThrottleRepo := repository.NewAuthThrottleRepository(database.DB, logInstance)
+	roleRepo := repository.NewRoleRepository(database.DB, logInstance)
+	providerApplicationRepo := repository.NewProviderApplicationRepository(database.DB, logInstance)
+	providerDocumentRepo := repository.NewProviderDocumentRepository(database.DB, logInstance)
+	providerDashboardRepo := repository.NewProviderDashboardRepository(database.DB, logInstance)
+	organisationRepo := repository.NewOrganisationRepository(database.DB, logInstance)
+	accommodationRepo := repository.NewAccommodationRepository(database.DB, logInstance)
+	carRentalRepo := repository.NewCarRentalRepository(database.DB, logInstance)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo)
-	authService := service.NewAuthService(userRepo)
-	destinationService := service.NewDestinationService(destinationRepo)
-	serviceService := service.NewServiceService(serviceRepo)
+	userService := service.NewUserService(userRepo, roleRepo)
+	roleService := service.NewRoleService(roleRepo)
+	documentStorage, err := service.NewDocumentStorageFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize document storage:", err)
+	}
+	authService := service.NewAuthService(userRepo, sessionRepo, passwordResetRepo, emailVerificationRepo, twoFactorRepo, authThrottleRepo)
+	providerApplicationService := service.NewProviderApplicationService(providerApplicationRepo, providerDocumentRepo, userRepo, documentStorage, authService)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, userRepo, sessionRepo, authThrottleRepo)
+	devIdentityProvider, err := service.NewDevIdentityProviderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize development identity provider:", err)
+	}
+	oidcProviders, err := service.NewOIDCProvidersFromEnv(devIdentityProvider)
+	if err != nil {
+		log.Fatal("Failed to initialize identity providers:", err)
+	}
+	oidcService := service.NewOIDCService(oidcRepo, userRepo, authService, oidcProviders)
+	destinationService := service.NewDestinationService(destinationRepo, placeRepo)
+	placeService := service.NewPlaceService(placeRepo, destinationRepo)
+	cancellationPolicyService := service.NewCancellationPolicyService(cancellationPolicyRepo)
+	providerTeamService := service.NewProviderTeamService(organisationRepo, userRepo)
+	serviceService := service.NewServiceService(serviceRepo, serviceTypeRepo, accommodationRepo, carRentalRepo, destinationRepo, cancellationPolicyService, providerTeamService)
 	serviceTypeService := service.NewServiceTypeService(serviceTypeRepo)
-	bookingService := service.NewBookingService(bookingRepo)
+	pricingService := service.NewPricingService(serviceRepo, serviceTypeRepo)
+	paymentGateway, err := service.NewPaymentGatewayFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize payment gateway:", err)
+	}
+	paymentService := service.NewPaymentService(paymentRepo, userRepo, paymentGateway, authService)
+	paymentWebhookService := service.NewPaymentWebhookService(paymentEventRepo, paymentRepo, paymentGateway)
+	bookingService := service.NewBookingService(bookingRepo, serviceRepo, carRentalRepo, userRepo, pricingService, cancellationPolicyService, paymentService, providerTeamService, authService)
+	availabilityService := service.NewAvailabilityService(serviceRepo, bookingRepo)
+	fleetService := service.NewFleetService(serviceRepo, carRentalRepo, bookingRepo, providerTeamService)
+	providerDashboardService := service.NewProviderDashboardService(providerDashboardRepo, providerTeamService)
 	travelPayoutsService := service.NewTravelPayoutsService()
 
 	// Initialize middleware
@@ -84,11 +131,23 @@ func main() {
 
 	// Initialize handlers
 	authHandler := appHandlers.NewAuthHandler(authService, logInstance)
+	twoFactorHandler := appHandlers.NewTwoFactorHandler(twoFactorService, logInstance)
+	oidcHandler := appHandlers.NewOIDCHandler(oidcService, logInstance)
 	userHandler := appHandlers.NewUserHandler(userService, logInstance)
+	roleHandler := appHandlers.NewRoleHandler(roleService, logInstance)
+	providerApplicationHandler := appHandlers.NewProviderApplicationHandler(providerApplicationService, logInstance)
 	destinationHandler := appHandlers.NewDestinationHandler(destinationService, logInstance)
+	placeHandler := appHandlers.NewPlaceHandler(placeService, logInstance)
 	serviceHandler := appHandlers.NewServiceHandler(serviceService, logInstance)
 	serviceTypeHandler := appHandlers.NewServiceTypeHandler(serviceTypeService, logInstance)
 	bookingHandler := appHandlers.NewBookingHandler(bookingService, logInstance)
+	paymentHandler := appHandlers.NewPaymentHandler(paymentService, logInstance)
+	paymentWebhookHandler := appHandlers.NewPaymentWebhookHandler(paymentWebhookService, logInstance)
+	availabilityHandler := appHandlers.NewAvailabilityHandler(availabilityService, logInstance)
+	providerDashboardHandler := appHandlers.NewProviderDashboardHandler(providerDashboardService, logInstance)
+	providerTeamHandler := appHandlers.NewProviderTeamHandler(providerTeamService, logInstance)
+	fleetHandler := appHandlers.NewFleetHandler(fleetService, logInstance)
+	cancellationPolicyHandler := appHandlers.NewCancellationPolicyHandler(cancellationPolicyService, logInstance)
 	// hotelHandler := appHandlers.NewHotelHandler(travelPayoutsService, logInstance)
 	flightHandler := appHandlers.NewFlightHandler(travelPayoutsService, logInstance)
 
@@ -101,14 +160,37 @@ func main() {
 	// Public routes
 	api.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
 	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+	api.HandleFunc("/auth/2fa/verify", authHandler.VerifyTwoFactor).Methods("POST")
 	api.HandleFunc("/auth/verify-email", authHandler.VerifyEmail).Methods("POST")
 	api.HandleFunc("/auth/resend-verification", authHandler.ResendVerification).Methods("POST")
+	api.HandleFunc("/auth/refresh", authHandler.RefreshToken).Methods("POST")
+	api.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+	api.HandleFunc("/auth/forgot-password", authHandler.ForgotPassword).Methods("POST")
+	api.HandleFunc("/auth/reset-password", authHandler.ResetPassword).Methods("POST")
+	api.HandleFunc("/auth/unlock", authHandler.UnlockAccount).Methods("POST")
+	api.HandleFunc("/auth/oidc/providers", oidcHandler.GetProviders).Methods("GET")
+	api.HandleFunc("/auth/oidc/{provider}/start", oidcHandler.StartLogin).Methods("POST")
+	api.HandleFunc("/auth/oidc/{provider}/callback", oidcHandler.Callback).Methods("POST")
 	api.HandleFunc("/destinations", destinationHandler.GetAllDestinations).Methods("GET")
+	api.HandleFunc("/destinations/nearby", destinationHandler.GetNearbyDestinations).Methods("GET")
 	api.HandleFunc("/destinations/{id}", destinationHandler.GetDestinationByID).Methods("GET")
+	api.HandleFunc("/destinations/{id}/services", serviceHandler.GetDestinationServices).Methods("GET")
+	api.HandleFunc("/places", placeHandler.GetPlaces).Methods("GET")
+	api.HandleFunc("/places/{code}", placeHandler.GetPlace).Methods("GET")
+	api.HandleFunc("/places/{code}/children", placeHandler.GetPlaceChildren).Methods("GET")
+	api.HandleFunc("/places/{code}/destinations", placeHandler.GetPlaceDestinations).Methods("GET")
 	api.HandleFunc("/services", serviceHandler.GetAllServices).Methods("GET")
+	api.HandleFunc("/services/search", serviceHandler.SearchServices).Methods("GET")
 	api.HandleFunc("/services/{id}", serviceHandler.GetServiceByID).Methods("GET")
+	api.HandleFunc("/services/{id}/availability", availabilityHandler.GetServiceAvailability).Methods("GET")
+	api.HandleFunc("/services/{id}/vehicles", fleetHandler.GetServiceVehicles).Methods("GET")
+	api.HandleFunc("/amenities", serviceHandler.GetAmenities).Methods("GET")
 	api.HandleFunc("/service-types", serviceTypeHandler.GetAllServiceTypes).Methods("GET")
 	api.HandleFunc("/service-types/{id}", serviceTypeHandler.GetServiceTypeByID).Methods("GET")
+	api.HandleFunc("/cancellation-policies", cancellationPolicyHandler.GetPlatformPolicies).Methods("GET")
+
+	// Payment gateway webhooks (authenticated by their signature)
+	api.HandleFunc("/webhooks/payments/{provider}", paymentWebhookHandler.HandlePaymentWebhook).Methods("POST")
 
 	// // Hotel routes
 	// api.HandleFunc("/hotels/search", hotelHandler.SearchHotels).Methods("GET")
@@ -124,45 +206,151 @@ func main() {
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(authHandler.AuthMiddleware)
 
+	// Session routes (any authenticated user)
+	protected.HandleFunc("/auth/sessions", authHandler.GetSessions).Methods("GET")
+	protected.HandleFunc("/auth/logout-all", authHandler.LogoutAll).Methods("POST")
+
+	// Two-factor authentication enrolment (any authenticated user)
+	protected.HandleFunc("/auth/2fa", twoFactorHandler.GetStatus).Methods("GET")
+	protected.HandleFunc("/auth/2fa/setup", twoFactorHandler.Setup).Methods("POST")
+	protected.HandleFunc("/auth/2fa/enable", twoFactorHandler.Enable).Methods("POST")
+	protected.HandleFunc("/auth/2fa/disable", twoFactorHandler.Disable).Methods("POST")
+	protected.HandleFunc("/auth/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
+
 	// User profile routes (any authenticated user)
 	protected.HandleFunc("/user/profile", userHandler.GetProfile).Methods("GET")
 	protected.HandleFunc("/user/profile", userHandler.UpdateProfile).Methods("PUT")
 
 	// User booking routes (any authenticated user)
 	protected.HandleFunc("/bookings", bookingHandler.CreateBooking).Methods("POST")
+	protected.HandleFunc("/bookings/quote", bookingHandler.QuoteBooking).Methods("POST")
 	protected.HandleFunc("/bookings", bookingHandler.GetUserBookings).Methods("GET")
 	protected.HandleFunc("/bookings/{id}", bookingHandler.GetBookingByID).Methods("GET")
+	protected.HandleFunc("/bookings/{id}/history", bookingHandler.GetBookingHistory).Methods("GET")
+	protected.HandleFunc("/bookings/{id}/cancel", bookingHandler.CancelBooking).Methods("POST")
+
+	// User payment routes (any authenticated user)
+	protected.HandleFunc("/bookings/{id}/payments", paymentHandler.PayBooking).Methods("POST")
+	protected.HandleFunc("/payments", paymentHandler.GetUserPayments).Methods("GET")
+	protected.HandleFunc("/payments/{id}", paymentHandler.GetPaymentByID).Methods("GET")
+	protected.HandleFunc("/payments/{id}/confirm", paymentHandler.ConfirmPayment).Methods("POST")
+
+	// Provider applications and documents (any user can apply to become a provider)
+	protected.HandleFunc("/provider/applications", providerApplicationHandler.Apply).Methods("POST")
+	protected.HandleFunc("/provider/applications", providerApplicationHandler.GetOwnApplications).Methods("GET")
+	protected.HandleFunc("/provider/applications/{id}", providerApplicationHandler.GetOwnApplicationByID).Methods("GET")
+	protected.HandleFunc("/provider/applications/{id}", providerApplicationHandler.Resubmit).Methods("PUT")
+	protected.HandleFunc("/provider/applications/{id}/history", providerApplicationHandler.GetOwnApplicationHistory).Methods("GET")
+	protected.HandleFunc("/provider/documents", providerApplicationHandler.UploadDocument).Methods("POST")
+	protected.HandleFunc("/provider/documents", providerApplicationHandler.GetDocuments).Methods("GET")
+	protected.HandleFunc("/provider/documents/{id}/file", providerApplicationHandler.DownloadDocument).Methods("GET")
+	protected.HandleFunc("/provider/documents/{id}", providerApplicationHandler.DeleteDocument).Methods("DELETE")
+	protected.HandleFunc("/provider/profile", providerApplicationHandler.GetProviderProfile).Methods("GET")
+	protected.HandleFunc("/provider/profile", providerApplicationHandler.UpdateProviderProfile).Methods("PUT")
+
+	// Staff routes are guarded per route by the permission their roles must grant
+	requirePermission := func(permission string, handler http.HandlerFunc) http.Handler {
+		return roleMiddleware.RequirePermission(permission)(handler)
+	}
 
-	// Provider routes (provider or admin only)
-	providerRoutes := api.PathPrefix("/provider").Subrouter()
-	providerRoutes.Use(roleMiddleware.RequireAdminOrProvider())
+	// Routes open to providers and their staff authorise against team membership in the services
+	requireUser := func(handler http.HandlerFunc) http.Handler {
+		return roleMiddleware.RequireAnyRole()(handler)
+	}
 
-	// Services management (providers can create/manage their services)
-	providerRoutes.HandleFunc("/services", serviceHandler.CreateService).Methods("POST")
-	providerRoutes.HandleFunc("/services/{id}", serviceHandler.UpdateService).Methods("PUT")
-	providerRoutes.HandleFunc("/services/{id}", serviceHandler.DeleteService).Methods("DELETE")
+	// Provider routes
+	providerRoutes := api.PathPrefix("/provider").Subrouter()
 
-	// Admin routes (admin only)
+	// Services management (providers and their managers can create/manage their services)
+	providerRoutes.Handle("/services", requireUser(serviceHandler.GetProviderServices)).Methods("GET")
+	providerRoutes.Handle("/services", requireUser(serviceHandler.CreateService)).Methods("POST")
+	providerRoutes.Handle("/services/{id}", requireUser(serviceHandler.UpdateService)).Methods("PUT")
+	providerRoutes.Handle("/services/{id}", requireUser(serviceHandler.DeleteService)).Methods("DELETE")
+	providerRoutes.Handle("/services/{id}/vehicles", requireUser(fleetHandler.GetFleet)).Methods("GET")
+	providerRoutes.Handle("/services/{id}/vehicles", requireUser(fleetHandler.AddVehicle)).Methods("POST")
+	providerRoutes.Handle("/services/{id}/vehicles/{vehicleId}", requireUser(fleetHandler.UpdateVehicle)).Methods("PUT")
+	providerRoutes.Handle("/services/{id}/vehicles/{vehicleId}", requireUser(fleetHandler.RemoveVehicle)).Methods("DELETE")
+
+	// Dashboard (providers and their staff see and answer the bookings of their own services)
+	providerRoutes.Handle("/dashboard/bookings", requireUser(providerDashboardHandler.GetBookings)).Methods("GET")
+	providerRoutes.Handle("/dashboard/bookings/{id}/accept", requireUser(bookingHandler.AcceptBooking)).Methods("POST")
+	providerRoutes.Handle("/dashboard/bookings/{id}/decline", requireUser(bookingHandler.DeclineBooking)).Methods("POST")
+	providerRoutes.Handle("/dashboard/check-ins", requireUser(providerDashboardHandler.GetCheckIns)).Methods("GET")
+	providerRoutes.Handle("/dashboard/revenue", requireUser(providerDashboardHandler.GetRevenue)).Methods("GET")
+	providerRoutes.Handle("/dashboard/occupancy", requireUser(providerDashboardHandler.GetOccupancy)).Methods("GET")
+	providerRoutes.Handle("/dashboard/cancellations", requireUser(providerDashboardHandler.GetCancellations)).Methods("GET")
+
+	// Team (providers invite staff; staff accept invitations and can leave)
+	providerRoutes.Handle("/team", requirePermission(models.PermissionServicesWrite, providerTeamHandler.GetTeam)).Methods("GET")
+	providerRoutes.Handle("/team/invitations", requirePermission(models.PermissionServicesWrite, providerTeamHandler.InviteMember)).Methods("POST")
+	providerRoutes.Handle("/team/invitations/accept", requireUser(providerTeamHandler.AcceptInvitation)).Methods("POST")
+	providerRoutes.Handle("/team/invitations/{id}", requirePermission(models.PermissionServicesWrite, providerTeamHandler.RevokeInvitation)).Methods("DELETE")
+	providerRoutes.Handle("/team/members/{userId}", requirePermission(models.PermissionServicesWrite, providerTeamHandler.UpdateMember)).Methods("PUT")
+	providerRoutes.Handle("/team/members/{userId}", requirePermission(models.PermissionServicesWrite, providerTeamHandler.RemoveMember)).Methods("DELETE")
+	providerRoutes.Handle("/team/membership", requireUser(providerTeamHandler.GetMembership)).Methods("GET")
+	providerRoutes.Handle("/team/membership", requireUser(providerTeamHandler.LeaveTeam)).Methods("DELETE")
+
+	// Cancellation policies (providers can define custom refund tiers)
+	providerRoutes.Handle("/cancellation-policies", requirePermission(models.PermissionPoliciesWrite, cancellationPolicyHandler.GetProviderPolicies)).Methods("GET")
+	providerRoutes.Handle("/cancellation-policies", requirePermission(models.PermissionPoliciesWrite, cancellationPolicyHandler.CreatePolicy)).Methods("POST")
+
+	// Admin routes
 	adminRoutes := api.PathPrefix("/admin").Subrouter()
-	adminRoutes.Use(roleMiddleware.RequireAdmin())
 
 	// User management
-	adminRoutes.HandleFunc("/users", userHandler.GetAllUsers).Methods("GET")
-	adminRoutes.HandleFunc("/users/role/{role}", userHandler.GetUsersByRole).Methods("GET")
-	adminRoutes.HandleFunc("/users/{id}/role", userHandler.UpdateUserRole).Methods("PUT")
-
-	// Destinations management (admin only)
-	adminRoutes.HandleFunc("/destinations", destinationHandler.CreateDestination).Methods("POST")
-	adminRoutes.HandleFunc("/destinations/{id}", destinationHandler.UpdateDestination).Methods("PUT")
-	adminRoutes.HandleFunc("/destinations/{id}", destinationHandler.DeleteDestination).Methods("DELETE")
-
-	// Service types management (admin only)
-	adminRoutes.HandleFunc("/service-types", serviceTypeHandler.CreateServiceType).Methods("POST")
-	adminRoutes.HandleFunc("/service-types/{id}", serviceTypeHandler.UpdateServiceType).Methods("PUT")
-	adminRoutes.HandleFunc("/service-types/{id}", serviceTypeHandler.DeleteServiceType).Methods("DELETE")
-
-	// Booking status management (admin only)
-	adminRoutes.HandleFunc("/bookings/{id}/status", bookingHandler.UpdateBookingStatus).Methods("PUT")
+	adminRoutes.Handle("/users", requirePermission(models.PermissionUsersRead, userHandler.GetAllUsers)).Methods("GET")
+	adminRoutes.Handle("/users/role/{role}", requirePermission(models.PermissionUsersRead, userHandler.GetUsersByRole)).Methods("GET")
+	adminRoutes.Handle("/users/{id}/role", requirePermission(models.PermissionUsersRoles, userHandler.UpdateUserRole)).Methods("PUT")
+
+	// Roles and permissions management
+	adminRoutes.Handle("/permissions", requirePermission(models.PermissionRolesManage, roleHandler.GetPermissions)).Methods("GET")
+	adminRoutes.Handle("/roles", requirePermission(models.PermissionRolesManage, roleHandler.GetRoles)).Methods("GET")
+	adminRoutes.Handle("/roles", requirePermission(models.PermissionRolesManage, roleHandler.CreateRole)).Methods("POST")
+	adminRoutes.Handle("/roles/{name}", requirePermission(models.PermissionRolesManage, roleHandler.GetRole)).Methods("GET")
+	adminRoutes.Handle("/roles/{name}", requirePermission(models.PermissionRolesManage, roleHandler.UpdateRole)).Methods("PUT")
+	adminRoutes.Handle("/roles/{name}", requirePermission(models.PermissionRolesManage, roleHandler.DeleteRole)).Methods("DELETE")
+
+	// Provider applications review
+	adminRoutes.Handle("/provider-applications", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.GetApplications)).Methods("GET")
+	adminRoutes.Handle("/provider-applications/{id}", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.GetApplicationByID)).Methods("GET")
+	adminRoutes.Handle("/provider-applications/{id}/history", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.GetApplicationHistory)).Methods("GET")
+	adminRoutes.Handle("/provider-applications/{id}/approve", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.ApproveApplication)).Methods("POST")
+	adminRoutes.Handle("/provider-applications/{id}/request-changes", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.RequestChanges)).Methods("POST")
+	adminRoutes.Handle("/provider-applications/{id}/reject", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.RejectApplication)).Methods("POST")
+	adminRoutes.Handle("/provider-applications/{id}/revoke", requirePermission(models.PermissionProvidersReview, providerApplicationHandler.RevokeApplication)).Methods("POST")
+
+	// Destinations management
+	adminRoutes.Handle("/destinations", requirePermission(models.PermissionDestinationsWrite, destinationHandler.CreateDestination)).Methods("POST")
+	adminRoutes.Handle("/destinations/{id}", requirePermission(models.PermissionDestinationsWrite, destinationHandler.UpdateDestination)).Methods("PUT")
+	adminRoutes.Handle("/destinations/{id}", requirePermission(models.PermissionDestinationsWrite, destinationHandler.DeleteDestination)).Methods("DELETE")
+	adminRoutes.Handle("/places", requirePermission(models.PermissionDestinationsWrite, placeHandler.CreatePlace)).Methods("POST")
+	adminRoutes.Handle("/places/{code}", requirePermission(models.PermissionDestinationsWrite, placeHandler.UpdatePlace)).Methods("PUT")
+	adminRoutes.Handle("/places/{code}", requirePermission(models.PermissionDestinationsWrite, placeHandler.DeletePlace)).Methods("DELETE")
+
+	// Service types management
+	adminRoutes.Handle("/service-types", requirePermission(models.PermissionServiceTypesWrite, serviceTypeHandler.CreateServiceType)).Methods("POST")
+	adminRoutes.Handle("/service-types/{id}", requirePermission(models.PermissionServiceTypesWrite, serviceTypeHandler.UpdateServiceType)).Methods("PUT")
+	adminRoutes.Handle("/service-types/{id}", requirePermission(models.PermissionServiceTypesWrite, serviceTypeHandler.DeleteServiceType)).Methods("DELETE")
+
+	// Bookings overview and status management
+	adminRoutes.Handle("/bookings", requirePermission(models.PermissionBookingsRead, bookingHandler.GetAllBookings)).Methods("GET")
+	adminRoutes.Handle("/bookings/{id}/status", requirePermission(models.PermissionBookingsWrite, bookingHandler.UpdateBookingStatus)).Methods("PUT")
+
+	// Payments overview and refunds
+	adminRoutes.Handle("/payments", requirePermission(models.PermissionPaymentsRead, paymentHandler.GetAllPayments)).Methods("GET")
+	adminRoutes.Handle("/payments/{id}/refund", requirePermission(models.PermissionPaymentsRefund, paymentHandler.ProcessRefund)).Methods("POST")
+	adminRoutes.Handle("/bookings/{id}/payments", requirePermission(models.PermissionPaymentsRead, paymentHandler.GetBookingPayments)).Methods("GET")
+
+	// Development identity provider (only when OIDC_DEV_PROVIDER=true)
+	if devIdentityProvider != nil {
+		devOIDCHandler := appHandlers.NewDevOIDCHandler(devIdentityProvider, logInstance)
+		devOIDC := r.PathPrefix("/dev/oidc").Subrouter()
+		devOIDC.HandleFunc("/.well-known/openid-configuration", devOIDCHandler.Discovery).Methods("GET")
+		devOIDC.HandleFunc("/jwks", devOIDCHandler.JWKS).Methods("GET")
+		devOIDC.HandleFunc("/authorize", devOIDCHandler.Authorize).Methods("GET")
+		devOIDC.HandleFunc("/token", devOIDCHandler.Token).Methods("POST")
+		log.Println("Development identity provider enabled at /dev/oidc")
+	}
 
 	// Swagger documentation
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)